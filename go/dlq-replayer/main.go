@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+func main() {
+	target := flag.String("target", targetJobsTodo, "Where to re-send dead letters: jobs-todo for processor failures, eventbridge for ingester failures")
+	unwrap := flag.Bool("unwrap", true, "Re-send the original message from each dead-letter envelope, set false to re-send bodies as they are")
+	limit := flag.Int("max", 0, "Replay at most this many dead letters, 0 replays them all")
+	flag.Parse()
+
+	// Log JSON lines at LOG_LEVEL (info by default) and above, like the services
+	slog.SetDefault(joblib.NewLogger(os.Stderr, joblib.ParseLogLevel(os.Getenv("LOG_LEVEL"), slog.LevelInfo)))
+
+	if err := validateTarget(*target); err != nil {
+		log.Fatal(err)
+	}
+	if *limit < 0 {
+		log.Fatalf("-max must not be negative, got %d", *limit)
+	}
+
+	// Stop cleanly on Ctrl+C or SIGTERM, leaving unsent dead letters on the queue
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serviceConfig := joblib.LoadServiceConfig(os.LookupEnv)
+
+	// Load AWS configuration
+	cfg, err := joblib.LoadAWSConfig(ctx)
+	if err != nil {
+		log.Fatalf("unable to load AWS SDK config: %v", err)
+	}
+
+	r := replayer{
+		queues:        sqs.NewFromConfig(cfg),
+		events:        eventbridge.NewFromConfig(cfg),
+		deadLetterURL: serviceConfig.DeadLetterURL,
+		jobsTodoURL:   serviceConfig.JobsTodoURL,
+		target:        *target,
+		unwrap:        *unwrap,
+		clock:         joblib.SystemClock{},
+	}
+	summary, err := r.replay(ctx, *limit)
+	fmt.Println(summary)
+	if err != nil {
+		log.Fatalf("replay stopped early: %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	ebtypes "github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// Replay targets, selected with -target
+const (
+	targetJobsTodo    = "jobs-todo"   // straight onto the processor's queue
+	targetEventBridge = "eventbridge" // back through the ingester, as the generator sends jobs
+)
+
+// replayVisibilityTimeout hides dead letters left on the queue for the rest
+// of a run so each is only looked at once.
+const replayVisibilityTimeout = 60
+
+// validateTarget checks a -target value is one of the replay targets.
+func validateTarget(target string) error {
+	switch target {
+	case targetJobsTodo, targetEventBridge:
+		return nil
+	default:
+		return fmt.Errorf("-target must be %s or %s, got %q", targetJobsTodo, targetEventBridge, target)
+	}
+}
+
+// sqsMover is the subset of the SQS client used to move dead letters
+type sqsMover interface {
+	ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error)
+	DeleteMessage(ctx context.Context, params *sqs.DeleteMessageInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error)
+	SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
+}
+
+// eventPutter is the subset of the EventBridge client used to re-put jobs
+type eventPutter interface {
+	PutEvents(ctx context.Context, params *eventbridge.PutEventsInput, optFns ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error)
+}
+
+// replayer moves dead letters off the dead-letter queue onto its target.
+type replayer struct {
+	queues        sqsMover
+	events        eventPutter
+	deadLetterURL string
+	jobsTodoURL   string
+	target        string // one of the replay targets
+	unwrap        bool   // re-send the original body of each envelope rather than the body as dead-lettered
+	clock         joblib.Clock
+}
+
+// replaySummary counts what a replay did with each dead letter it received.
+type replaySummary struct {
+	Replayed int // re-sent and deleted from the dead-letter queue
+	Expired  int // envelopes past their TTL, left on the queue
+	Kept     int // not envelopes so nothing to unwrap, left on the queue
+	Failed   int // the re-send failed, left on the queue to try again
+}
+
+func (s replaySummary) String() string {
+	return fmt.Sprintf("Replayed %d dead letters, left %d on the queue (%d expired, %d not envelopes, %d failed to send)",
+		s.Replayed, s.Expired+s.Kept+s.Failed, s.Expired, s.Kept, s.Failed)
+}
+
+// replay moves up to limit dead letters onto the target, or all of them when
+// limit is 0. A dead letter is only deleted once it has been re-sent, so one
+// that fails stays on the queue for a later run.
+func (r replayer) replay(ctx context.Context, limit int) (replaySummary, error) {
+	var summary replaySummary
+	for limit == 0 || summary.Replayed < limit {
+		batch := int32(10)
+		if remaining := limit - summary.Replayed; limit > 0 && remaining < int(batch) {
+			batch = int32(remaining)
+		}
+		output, err := r.queues.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(r.deadLetterURL),
+			MaxNumberOfMessages: batch,
+			VisibilityTimeout:   replayVisibilityTimeout,
+		})
+		if err != nil {
+			return summary, fmt.Errorf("failed to receive from %s: %w", r.deadLetterURL, err)
+		}
+		if len(output.Messages) == 0 {
+			return summary, nil
+		}
+
+		for _, message := range output.Messages {
+			id := aws.ToString(message.MessageId)
+			body := aws.ToString(message.Body)
+			if r.unwrap {
+				envelope, err := joblib.ParseDeadLetterEnvelope([]byte(body))
+				if err != nil {
+					log.Printf("keeping dead letter %s: %v", id, err)
+					summary.Kept++
+					continue
+				}
+				if envelope.Expired(r.clock.Now()) {
+					log.Printf("keeping dead letter %s, expired at %s", id, envelope.ExpiresAt)
+					summary.Expired++
+					continue
+				}
+				original, err := envelope.RecoverOriginal()
+				if err != nil {
+					log.Printf("keeping dead letter %s: %v", id, err)
+					summary.Kept++
+					continue
+				}
+				body = string(original)
+			}
+
+			if err := r.send(ctx, body); err != nil {
+				log.Printf("failed to replay dead letter %s to %s: %v", id, r.target, err)
+				summary.Failed++
+				continue
+			}
+			if _, err := r.queues.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+				QueueUrl:      aws.String(r.deadLetterURL),
+				ReceiptHandle: message.ReceiptHandle,
+			}); err != nil {
+				log.Printf("replayed dead letter %s but failed to delete it: %v", id, err)
+			}
+			log.Printf("Replayed dead letter %s to %s", id, r.target)
+			summary.Replayed++
+		}
+	}
+	return summary, nil
+}
+
+// send re-sends a dead letter's body to the target.
+func (r replayer) send(ctx context.Context, body string) error {
+	if r.target == targetJobsTodo {
+		_, err := r.queues.SendMessage(ctx, &sqs.SendMessageInput{
+			QueueUrl:    aws.String(r.jobsTodoURL),
+			MessageBody: aws.String(body),
+		})
+		return err
+	}
+
+	output, err := r.events.PutEvents(ctx, &eventbridge.PutEventsInput{
+		Entries: []ebtypes.PutEventsRequestEntry{
+			{
+				Source:       aws.String("jobs"),
+				DetailType:   aws.String("JobEvent"),
+				Detail:       aws.String(body),
+				EventBusName: aws.String("default"),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if output.FailedEntryCount > 0 && len(output.Entries) > 0 {
+		return fmt.Errorf("event rejected: %s", aws.ToString(output.Entries[0].ErrorMessage))
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+const (
+	deadLetterURL = "http://localhost:4566/000000000000/dead-letter-queue"
+	jobsTodoURL   = "http://localhost:4566/000000000000/jobs-todo"
+	job           = `{"job_type": "data_cleanup", "message": {"target_table": "users", "retention": 30}}`
+)
+
+// fakeDeadLetters holds dead letters, hiding each once received as a
+// visibility timeout would
+type fakeDeadLetters struct {
+	messages []sqstypes.Message
+	received map[string]bool
+	deleted  map[string]bool
+	sent     []*sqs.SendMessageInput
+	sendErr  error
+}
+
+func newFakeDeadLetters(bodies ...string) *fakeDeadLetters {
+	queue := &fakeDeadLetters{received: map[string]bool{}, deleted: map[string]bool{}}
+	for i, body := range bodies {
+		queue.messages = append(queue.messages, sqstypes.Message{
+			MessageId:     aws.String(fmt.Sprintf("m-%d", i)),
+			ReceiptHandle: aws.String(fmt.Sprintf("h-%d", i)),
+			Body:          aws.String(body),
+		})
+	}
+	return queue
+}
+
+func (f *fakeDeadLetters) ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+	var visible []sqstypes.Message
+	for _, message := range f.messages {
+		handle := aws.ToString(message.ReceiptHandle)
+		if !f.received[handle] && !f.deleted[handle] && len(visible) < int(params.MaxNumberOfMessages) {
+			f.received[handle] = true
+			visible = append(visible, message)
+		}
+	}
+	return &sqs.ReceiveMessageOutput{Messages: visible}, nil
+}
+
+func (f *fakeDeadLetters) DeleteMessage(ctx context.Context, params *sqs.DeleteMessageInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error) {
+	f.deleted[aws.ToString(params.ReceiptHandle)] = true
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+func (f *fakeDeadLetters) SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	if f.sendErr != nil {
+		return nil, f.sendErr
+	}
+	f.sent = append(f.sent, params)
+	return &sqs.SendMessageOutput{MessageId: aws.String("replayed")}, nil
+}
+
+// fakeBus records the events put on it
+type fakeBus struct {
+	entries []string
+}
+
+func (f *fakeBus) PutEvents(ctx context.Context, params *eventbridge.PutEventsInput, optFns ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error) {
+	for _, entry := range params.Entries {
+		f.entries = append(f.entries, aws.ToString(entry.Detail))
+	}
+	return &eventbridge.PutEventsOutput{}, nil
+}
+
+type fixedClock struct{ now time.Time }
+
+func (c fixedClock) Now() time.Time { return c.now }
+
+var now = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// envelope wraps body as the services dead-letter it, expiring at expiresAt
+// unless that is zero.
+func envelope(t *testing.T, body string, expiresAt time.Time) string {
+	t.Helper()
+	e := joblib.NewDeadLetterEnvelope(body, "validate", "missing target_table", "")
+	if !expiresAt.IsZero() {
+		e.ExpiresAt = expiresAt.Format(time.RFC3339)
+	}
+	marshalled, err := e.Marshal()
+	if err != nil {
+		t.Fatalf("failed to marshal envelope: %v", err)
+	}
+	return marshalled
+}
+
+func TestReplayUnwrapsEnvelopes(t *testing.T) {
+	queue := newFakeDeadLetters(
+		envelope(t, job, time.Time{}),
+		envelope(t, job, now.Add(time.Hour)),
+		envelope(t, job, now.Add(-time.Hour)),
+		job,
+	)
+	r := replayer{queues: queue, deadLetterURL: deadLetterURL, jobsTodoURL: jobsTodoURL, target: targetJobsTodo, unwrap: true, clock: fixedClock{now}}
+
+	summary, err := r.replay(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary != (replaySummary{Replayed: 2, Expired: 1, Kept: 1}) {
+		t.Errorf("expected 2 replayed, 1 expired and 1 kept, got %+v", summary)
+	}
+	for _, sent := range queue.sent {
+		if aws.ToString(sent.QueueUrl) != jobsTodoURL {
+			t.Errorf("expected replay to %s, got %s", jobsTodoURL, aws.ToString(sent.QueueUrl))
+		}
+		if aws.ToString(sent.MessageBody) != job {
+			t.Errorf("expected the original body, got %s", aws.ToString(sent.MessageBody))
+		}
+	}
+	for handle, deleted := range map[string]bool{"h-0": true, "h-1": true, "h-2": false, "h-3": false} {
+		if queue.deleted[handle] != deleted {
+			t.Errorf("expected %s deleted to be %t", handle, deleted)
+		}
+	}
+}
+
+func TestReplayWithoutUnwrap(t *testing.T) {
+	body := envelope(t, job, now.Add(-time.Hour))
+	queue := newFakeDeadLetters(body, job)
+	r := replayer{queues: queue, deadLetterURL: deadLetterURL, jobsTodoURL: jobsTodoURL, target: targetJobsTodo, clock: fixedClock{now}}
+
+	summary, err := r.replay(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary.Replayed != 2 || len(queue.sent) != 2 {
+		t.Fatalf("expected both bodies replayed as they are, got %+v", summary)
+	}
+	if aws.ToString(queue.sent[0].MessageBody) != body {
+		t.Errorf("expected the envelope re-sent unchanged, got %s", aws.ToString(queue.sent[0].MessageBody))
+	}
+}
+
+func TestReplayKeepsFailedSends(t *testing.T) {
+	queue := newFakeDeadLetters(envelope(t, job, time.Time{}))
+	queue.sendErr = errors.New("queue does not exist")
+	r := replayer{queues: queue, deadLetterURL: deadLetterURL, jobsTodoURL: jobsTodoURL, target: targetJobsTodo, unwrap: true, clock: fixedClock{now}}
+
+	summary, err := r.replay(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary != (replaySummary{Failed: 1}) {
+		t.Errorf("expected 1 failed, got %+v", summary)
+	}
+	if len(queue.deleted) != 0 {
+		t.Errorf("expected the dead letter left on the queue, got %v deleted", queue.deleted)
+	}
+}
+
+func TestReplayMax(t *testing.T) {
+	var bodies []string
+	for i := 0; i < 25; i++ {
+		bodies = append(bodies, envelope(t, job, time.Time{}))
+	}
+	queue := newFakeDeadLetters(bodies...)
+	r := replayer{queues: queue, deadLetterURL: deadLetterURL, jobsTodoURL: jobsTodoURL, target: targetJobsTodo, unwrap: true, clock: fixedClock{now}}
+
+	summary, err := r.replay(context.Background(), 13)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary.Replayed != 13 || len(queue.deleted) != 13 || len(queue.received) != 13 {
+		t.Errorf("expected 13 received and replayed, got %+v with %d received", summary, len(queue.received))
+	}
+}
+
+func TestReplayToEventBridge(t *testing.T) {
+	queue := newFakeDeadLetters(envelope(t, job, time.Time{}))
+	bus := &fakeBus{}
+	r := replayer{queues: queue, events: bus, deadLetterURL: deadLetterURL, jobsTodoURL: jobsTodoURL, target: targetEventBridge, unwrap: true, clock: fixedClock{now}}
+
+	summary, err := r.replay(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary.Replayed != 1 || len(queue.sent) != 0 {
+		t.Fatalf("expected 1 replayed to EventBridge only, got %+v", summary)
+	}
+	if len(bus.entries) != 1 || bus.entries[0] != job {
+		t.Errorf("expected the original job put on the bus, got %v", bus.entries)
+	}
+}
+
+func TestValidateTarget(t *testing.T) {
+	for target, valid := range map[string]bool{targetJobsTodo: true, targetEventBridge: true, "": false, "sns": false} {
+		if err := validateTarget(target); (err == nil) != valid {
+			t.Errorf("expected %q valid to be %t, got %v", target, valid, err)
+		}
+	}
+}
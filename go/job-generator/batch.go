@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+)
+
+// maxBatchSize is the most entries EventBridge takes in one PutEvents call
+const maxBatchSize = 10
+
+// retryRejected retries the entries of a PutEvents call that EventBridge
+// failed to put once, set from -retry-rejected
+var retryRejected bool
+
+// validateBatchSize checks the -batch-size and -batch-interval flags. Events
+// are only batched between the ticks of a -rate, otherwise each is sent as it
+// is built.
+func validateBatchSize(size int, interval time.Duration, rate float64) error {
+	if size < 1 || size > maxBatchSize {
+		return fmt.Errorf("-batch-size must be between 1 and %d, got %d", maxBatchSize, size)
+	}
+	if interval <= 0 {
+		return fmt.Errorf("-batch-interval must be positive, got %s", interval)
+	}
+	if size > 1 && rate == 0 {
+		return errors.New("-batch-size above 1 needs a -rate to batch events between sends")
+	}
+	return nil
+}
+
+// entrySize is how much of the PutEvents request size limit an event takes
+// up, its detail plus the source and detail type it is sent with.
+func entrySize(eventJSON []byte) int {
+	return len(eventJSON) + len(eventSource) + len(eventDetailType)
+}
+
+// putEvents sends eventJSONs to EventBridge in a single PutEvents call,
+// returning an error for each event in the same order, nil for those put.
+// Oversized events are skipped with errEventTooLarge. Entries EventBridge
+// fails to put are retried once when retryRejected is set, then returned
+// with errEventRejected and counted in rejectedEvents.
+func putEvents(ctx context.Context, client eventPutter, eventJSONs [][]byte) []error {
+	errs := make([]error, len(eventJSONs))
+	var pending []int
+	for i, eventJSON := range eventJSONs {
+		if errs[i] = checkEventSize(eventJSON); errs[i] == nil {
+			pending = append(pending, i)
+		}
+	}
+
+	for attempt := 1; len(pending) > 0; attempt++ {
+		entries := make([]types.PutEventsRequestEntry, len(pending))
+		for j, i := range pending {
+			entries[j] = types.PutEventsRequestEntry{
+				Source:       aws.String(eventSource),
+				DetailType:   aws.String(eventDetailType),
+				Detail:       aws.String(string(eventJSONs[i])),
+				EventBusName: aws.String(eventBusName),
+			}
+		}
+		output, err := client.PutEvents(ctx, &eventbridge.PutEventsInput{Entries: entries})
+		if err != nil {
+			for _, i := range pending {
+				errs[i] = err
+			}
+			return errs
+		}
+
+		// PutEvents succeeds even when entries fail, each saying why in the
+		// position of the entry it was sent
+		var failed []int
+		for j, i := range pending {
+			var entry types.PutEventsResultEntry
+			if j < len(output.Entries) {
+				entry = output.Entries[j]
+			}
+			if entry.ErrorCode != nil || entry.ErrorMessage != nil {
+				errs[i] = fmt.Errorf("%w on bus %s: %s: %s", errEventRejected, eventBusName, aws.ToString(entry.ErrorCode), aws.ToString(entry.ErrorMessage))
+				failed = append(failed, i)
+				continue
+			}
+			errs[i] = nil
+			if entry.EventId != nil {
+				log.Printf("Event sent successfully with ID: %s", *entry.EventId)
+			}
+		}
+		if output.FailedEntryCount > 0 && len(failed) == 0 {
+			// Failed without saying which, so none of them can be trusted
+			for _, i := range pending {
+				errs[i] = fmt.Errorf("%w on bus %s", errEventRejected, eventBusName)
+			}
+			failed = pending
+		}
+
+		if len(failed) == 0 || !retryRejected || attempt > 1 {
+			rejectedEvents.Add(int64(len(failed)))
+			return errs
+		}
+		log.Printf("Retrying %d of %d events EventBridge failed to put", len(failed), len(pending))
+		pending = failed
+	}
+	return errs
+}
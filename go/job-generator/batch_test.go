@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+)
+
+// batchBus records the details of each PutEvents call, failing the entries
+// whose detail contains a string in failing until they have been sent
+// failures times
+type batchBus struct {
+	mu       sync.Mutex
+	calls    [][]string
+	failing  []string
+	failures int
+	attempts map[string]int
+}
+
+func (b *batchBus) PutEvents(ctx context.Context, params *eventbridge.PutEventsInput, optFns ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.attempts == nil {
+		b.attempts = map[string]int{}
+	}
+
+	var details []string
+	output := &eventbridge.PutEventsOutput{}
+	for i, entry := range params.Entries {
+		detail := aws.ToString(entry.Detail)
+		details = append(details, detail)
+		result := types.PutEventsResultEntry{EventId: aws.String(fmt.Sprintf("event-%d-%d", len(b.calls), i))}
+		for _, failing := range b.failing {
+			if strings.Contains(detail, failing) && b.attempts[detail] < b.failures {
+				b.attempts[detail]++
+				result = types.PutEventsResultEntry{ErrorCode: aws.String("ThrottlingException"), ErrorMessage: aws.String("rate exceeded")}
+				output.FailedEntryCount++
+			}
+		}
+		output.Entries = append(output.Entries, result)
+	}
+	b.calls = append(b.calls, details)
+	return output, nil
+}
+
+// withRejectedEvents counts rejected events from zero, with retryRejected set
+// to retry
+func withRejectedEvents(t *testing.T, retry bool) {
+	t.Helper()
+	previous, previousRetry := rejectedEvents.Load(), retryRejected
+	rejectedEvents.Store(0)
+	retryRejected = retry
+	t.Cleanup(func() {
+		rejectedEvents.Store(previous)
+		retryRejected = previousRetry
+	})
+}
+
+func TestValidateBatchSize(t *testing.T) {
+	tests := []struct {
+		name      string
+		size      int
+		interval  time.Duration
+		rate      float64
+		expectErr bool
+	}{
+		{name: "One at a time without a rate", size: 1, interval: time.Second},
+		{name: "A full batch at a rate", size: 10, interval: time.Second, rate: 50},
+		{name: "Empty batches", size: 0, interval: time.Second, rate: 50, expectErr: true},
+		{name: "More than PutEvents takes", size: 11, interval: time.Second, rate: 50, expectErr: true},
+		{name: "Batches without a rate", size: 5, interval: time.Second, expectErr: true},
+		{name: "No flush interval", size: 5, rate: 50, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateBatchSize(tt.size, tt.interval, tt.rate); (err != nil) != tt.expectErr {
+				t.Errorf("expected error %v, got %v", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestPutEventsInOneCall(t *testing.T) {
+	withRejectedEvents(t, false)
+	bus := &batchBus{failing: []string{"Report 2"}, failures: 1}
+
+	oversized := []byte(`{"job_type":"report_generation","message":{"report_name":"` + strings.Repeat("x", maxEventDetailBytes) + `"}}`)
+	errs := putEvents(context.Background(), bus, [][]byte{
+		[]byte(`{"job_type":"report_generation","message":{"report_name":"Report 1"}}`),
+		[]byte(`{"job_type":"report_generation","message":{"report_name":"Report 2"}}`),
+		oversized,
+		[]byte(`{"job_type":"report_generation","message":{"report_name":"Report 3"}}`),
+	})
+
+	if len(bus.calls) != 1 || len(bus.calls[0]) != 3 {
+		t.Fatalf("expected the events that fit sent in one call, got %v", bus.calls)
+	}
+	if errs[0] != nil || errs[3] != nil {
+		t.Errorf("expected the events EventBridge put to succeed, got %v", errs)
+	}
+	if !errors.Is(errs[1], errEventRejected) || !strings.Contains(errs[1].Error(), "ThrottlingException") {
+		t.Errorf("expected the failed entry returned with its error code, got %v", errs[1])
+	}
+	if !errors.Is(errs[2], errEventTooLarge) {
+		t.Errorf("expected the oversized event skipped, got %v", errs[2])
+	}
+	if rejected := rejectedEvents.Load(); rejected != 1 {
+		t.Errorf("expected 1 rejected event counted, got %d", rejected)
+	}
+}
+
+func TestPutEventsRetriesRejectedOnce(t *testing.T) {
+	eventJSONs := [][]byte{
+		[]byte(`{"job_type":"report_generation","message":{"report_name":"Report 1"}}`),
+		[]byte(`{"job_type":"report_generation","message":{"report_name":"Report 2"}}`),
+	}
+
+	t.Run("Put on retry", func(t *testing.T) {
+		withRejectedEvents(t, true)
+		bus := &batchBus{failing: []string{"Report 2"}, failures: 1}
+		for i, err := range putEvents(context.Background(), bus, eventJSONs) {
+			if err != nil {
+				t.Errorf("event %d: expected put on retry, got %v", i, err)
+			}
+		}
+		if len(bus.calls) != 2 || len(bus.calls[1]) != 1 || !strings.Contains(bus.calls[1][0], "Report 2") {
+			t.Errorf("expected only the failed entry retried, got %v", bus.calls)
+		}
+		if rejected := rejectedEvents.Load(); rejected != 0 {
+			t.Errorf("expected nothing counted as rejected, got %d", rejected)
+		}
+	})
+
+	t.Run("Still failing", func(t *testing.T) {
+		withRejectedEvents(t, true)
+		bus := &batchBus{failing: []string{"Report 2"}, failures: 5}
+		errs := putEvents(context.Background(), bus, eventJSONs)
+		if errs[0] != nil || !errors.Is(errs[1], errEventRejected) {
+			t.Errorf("expected the entry still failing after a retry rejected, got %v", errs)
+		}
+		if len(bus.calls) != 2 {
+			t.Errorf("expected a single retry, got %d calls", len(bus.calls))
+		}
+		if rejected := rejectedEvents.Load(); rejected != 1 {
+			t.Errorf("expected 1 rejected event counted, got %d", rejected)
+		}
+	})
+}
+
+func TestSendConcurrentlyInBatches(t *testing.T) {
+	bus := &batchBus{}
+	ticks := make(chan time.Time)
+	flushes := make(chan time.Time)
+	ctx, stop := context.WithCancel(context.Background())
+	done := make(chan int)
+	go func() { done <- sendConcurrently(ctx, bus, countingSource(), 1, ticks, 3, flushes, func(bool) {}) }()
+
+	// Seven events fill two batches, the flush sends the seventh on its own
+	for i := 0; i < 7; i++ {
+		ticks <- time.Now()
+	}
+	flushes <- time.Now()
+	// The two events batched when the generator stops are still sent
+	ticks <- time.Now()
+	ticks <- time.Now()
+	stop()
+
+	if sent := <-done; sent != 9 {
+		t.Errorf("expected 9 messages sent, got %d", sent)
+	}
+	var sizes []int
+	for _, call := range bus.calls {
+		sizes = append(sizes, len(call))
+	}
+	if fmt.Sprint(sizes) != "[3 3 1 2]" {
+		t.Errorf("expected batches of 3, 3, 1 and 2, got %v", sizes)
+	}
+}
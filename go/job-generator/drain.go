@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// queueAttributesGetter is the subset of the SQS client used to read queue depth
+type queueAttributesGetter interface {
+	GetQueueAttributes(ctx context.Context, params *sqs.GetQueueAttributesInput, optFns ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error)
+}
+
+// queueDepth returns the approximate number of messages waiting on queueURL.
+func queueDepth(ctx context.Context, client queueAttributesGetter, queueURL string) (int, error) {
+	output, err := client.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(queueURL),
+		AttributeNames: []types.QueueAttributeName{types.QueueAttributeNameApproximateNumberOfMessages},
+	})
+	if err != nil {
+		return 0, err
+	}
+	value, ok := output.Attributes[string(types.QueueAttributeNameApproximateNumberOfMessages)]
+	if !ok {
+		return 0, fmt.Errorf("queue %s did not report its depth", queueURL)
+	}
+	depth, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("queue %s reported an invalid depth %q: %w", queueURL, value, err)
+	}
+	return depth, nil
+}
+
+// drainEstimate describes how long the backlog on queueURL takes to drain at
+// throughputPerSec.
+func drainEstimate(ctx context.Context, client queueAttributesGetter, queueURL string, throughputPerSec float64) (string, error) {
+	depth, err := queueDepth(ctx, client, queueURL)
+	if err != nil {
+		return "", err
+	}
+	estimate := joblib.EstimateDrainTime(depth, throughputPerSec)
+	switch {
+	case depth == 0:
+		return "jobs-todo is empty", nil
+	case estimate == joblib.NeverDrains:
+		return fmt.Sprintf("%d jobs waiting on jobs-todo will never drain with no throughput", depth), nil
+	default:
+		return fmt.Sprintf("%d jobs waiting on jobs-todo will drain in about %s at %.2f jobs/s", depth, estimate.Round(time.Second), throughputPerSec), nil
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// fakeQueueAttributes reports a fixed set of queue attributes
+type fakeQueueAttributes struct {
+	attributes map[string]string
+}
+
+func (f fakeQueueAttributes) GetQueueAttributes(ctx context.Context, params *sqs.GetQueueAttributesInput, optFns ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error) {
+	return &sqs.GetQueueAttributesOutput{Attributes: f.attributes}, nil
+}
+
+func TestDrainEstimate(t *testing.T) {
+	tests := []struct {
+		name        string
+		attributes  map[string]string
+		throughput  float64
+		expected    string
+		expectError bool
+	}{
+		{
+			name:       "Backlog draining",
+			attributes: map[string]string{"ApproximateNumberOfMessages": "120"},
+			throughput: 2,
+			expected:   "120 jobs waiting on jobs-todo will drain in about 1m0s at 2.00 jobs/s",
+		},
+		{
+			name:       "Zero throughput",
+			attributes: map[string]string{"ApproximateNumberOfMessages": "5"},
+			throughput: 0,
+			expected:   "5 jobs waiting on jobs-todo will never drain with no throughput",
+		},
+		{
+			name:       "Empty queue",
+			attributes: map[string]string{"ApproximateNumberOfMessages": "0"},
+			throughput: 0,
+			expected:   "jobs-todo is empty",
+		},
+		{
+			name:        "Depth missing",
+			attributes:  map[string]string{},
+			throughput:  1,
+			expectError: true,
+		},
+		{
+			name:        "Depth invalid",
+			attributes:  map[string]string{"ApproximateNumberOfMessages": "lots"},
+			throughput:  1,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := drainEstimate(context.Background(), fakeQueueAttributes{attributes: tt.attributes}, "jobs-todo", tt.throughput)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
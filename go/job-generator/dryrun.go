@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// dryRunBus is an eventPutter that prints each event it would have sent,
+// prefixed with its job type, instead of sending it. Events are numbered so
+// the logged event IDs match the printed lines.
+type dryRunBus struct {
+	mu   sync.Mutex
+	out  io.Writer
+	sent int
+}
+
+func (b *dryRunBus) PutEvents(ctx context.Context, params *eventbridge.PutEventsInput, optFns ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	output := &eventbridge.PutEventsOutput{}
+	for _, entry := range params.Entries {
+		detail := aws.ToString(entry.Detail)
+		if _, err := fmt.Fprintf(b.out, "%s %s\n", dryRunJobType(detail), detail); err != nil {
+			return nil, err
+		}
+		b.sent++
+		output.Entries = append(output.Entries, types.PutEventsResultEntry{EventId: aws.String(fmt.Sprintf("dry-run-%d", b.sent))})
+	}
+	return output, nil
+}
+
+// dryRunJobType is the job type of an event detail, or "-" when it has none.
+func dryRunJobType(detail string) string {
+	var jobMessage joblib.JobMessage
+	if err := json.Unmarshal([]byte(detail), &jobMessage); err != nil || jobMessage.JobType == "" {
+		return "-"
+	}
+	return jobMessage.JobType
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestDryRunBus(t *testing.T) {
+	var out bytes.Buffer
+	bus := &dryRunBus{out: &out}
+
+	good := []byte(`{"job_type":"report_generation","message":{"report_name":"Sales Report","filters":"region=US"}}`)
+	bad := []byte(`{"message":{"report_name":"Missing Type"}}`)
+	for _, eventJSON := range [][]byte{good, bad} {
+		if err := sendToEventBridge(context.Background(), bus, eventJSON); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	expected := "report_generation " + string(good) + "\n- " + string(bad) + "\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
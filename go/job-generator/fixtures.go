@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// objectGetter is the subset of the S3 client used to fetch fixtures
+type objectGetter interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// fixtureStore fetches s3:// fixture sources, set once AWS is configured
+var fixtureStore objectGetter
+
+// readFixture reads a fixture source, either a local file or an
+// s3://bucket/key object.
+func readFixture(source string) ([]byte, error) {
+	location, ok := strings.CutPrefix(source, "s3://")
+	if !ok {
+		return os.ReadFile(source)
+	}
+
+	bucket, key, ok := strings.Cut(location, "/")
+	if !ok || bucket == "" || key == "" {
+		return nil, fmt.Errorf("invalid S3 fixture source %q, expected s3://bucket/key", source)
+	}
+	if fixtureStore == nil {
+		return nil, fmt.Errorf("no S3 client configured to read %s", source)
+	}
+	output, err := fixtureStore.GetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", source, err)
+	}
+	defer output.Body.Close()
+	return io.ReadAll(output.Body)
+}
+
+// parseMessages decodes a fixture: a JSON array of JobMessage like
+// good_jobs.json, or NDJSON with a JobMessage on each line when its first
+// non-space byte isn't [. Blank lines in NDJSON are skipped.
+func parseMessages(data []byte) ([]joblib.JobMessage, error) {
+	var messages []joblib.JobMessage
+	if trimmed := bytes.TrimLeftFunc(data, unicode.IsSpace); len(trimmed) == 0 || trimmed[0] == '[' {
+		err := json.Unmarshal(data, &messages)
+		return messages, err
+	}
+
+	// Lines are read whole, however long, rather than with a bufio.Scanner's limit
+	reader := bufio.NewReader(bytes.NewReader(data))
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var jobMessage joblib.JobMessage
+			if unmarshalErr := json.Unmarshal(line, &jobMessage); unmarshalErr != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, unmarshalErr)
+			}
+			messages = append(messages, jobMessage)
+		}
+		if errors.Is(err, io.EOF) {
+			return messages, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readMessageDir merges the job messages of every *.json and *.ndjson file in
+// dir, see parseMessages, so teams can keep fixtures of their own. Files are
+// read in name order and logged with how many messages they held and their
+// indexes in the merged set, which fixture tags and -validate-fixtures refer
+// to as dir#index.
+func readMessageDir(dir string) ([]joblib.JobMessage, error) {
+	var files []string
+	for _, pattern := range []string{"*.json", "*.ndjson"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no *.json or *.ndjson fixtures in %s", dir)
+	}
+	sort.Strings(files)
+
+	var messages []joblib.JobMessage
+	for _, file := range files {
+		fileMessages, err := readMessages(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		log.Printf("Loaded %d messages from %s (#%d-#%d)", len(fileMessages), file, len(messages), len(messages)+len(fileMessages)-1)
+		messages = append(messages, fileMessages...)
+	}
+	return messages, nil
+}
+
+// fixtureMismatch is a fixture whose parse result contradicts the file it is
+// in: a good job that fails ParseJob, or a bad job that passes it.
+type fixtureMismatch struct {
+	Source string
+	Index  int
+	Err    error // why a good job failed, nil for a bad job that passed
+}
+
+func (m fixtureMismatch) String() string {
+	if m.Err != nil {
+		return fmt.Sprintf("%s#%d should be valid but failed: %v", m.Source, m.Index, m.Err)
+	}
+	return fmt.Sprintf("%s#%d should be invalid but passed", m.Source, m.Index)
+}
+
+// validateFixtures runs ParseJob over both fixtures, reporting the good jobs
+// that fail and the bad jobs that pass.
+func validateFixtures(goodSource string, good []joblib.JobMessage, badSource string, bad []joblib.JobMessage) []fixtureMismatch {
+	var mismatches []fixtureMismatch
+	for i, jobMessage := range good {
+		if _, _, _, err := joblib.ParseJob([]byte(jobMessage.String())); err != nil {
+			mismatches = append(mismatches, fixtureMismatch{Source: goodSource, Index: i, Err: err})
+		}
+	}
+	for i, jobMessage := range bad {
+		if _, _, _, err := joblib.ParseJob([]byte(jobMessage.String())); err == nil {
+			mismatches = append(mismatches, fixtureMismatch{Source: badSource, Index: i})
+		}
+	}
+	return mismatches
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// fakeS3 serves objects from memory, keyed by bucket/key
+type fakeS3 struct {
+	objects map[string][]byte
+}
+
+func (f *fakeS3) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	object, ok := f.objects[aws.ToString(params.Bucket)+"/"+aws.ToString(params.Key)]
+	if !ok {
+		return nil, errors.New("NoSuchKey")
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(object))}, nil
+}
+
+func TestReadMessagesFromS3(t *testing.T) {
+	fixture, err := os.ReadFile("good_jobs.json")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	previous := fixtureStore
+	fixtureStore = &fakeS3{objects: map[string][]byte{"fixtures/jobs/good_jobs.json": fixture}}
+	defer func() { fixtureStore = previous }()
+
+	fromS3, err := readMessages("s3://fixtures/jobs/good_jobs.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	local, err := readMessages("good_jobs.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fromS3) == 0 || len(fromS3) != len(local) {
+		t.Fatalf("expected %d messages from S3, got %d", len(local), len(fromS3))
+	}
+	for i := range local {
+		if fromS3[i].String() != local[i].String() {
+			t.Errorf("message %d: expected %s, got %s", i, local[i], fromS3[i])
+		}
+	}
+}
+
+func TestReadMessagesFromS3Errors(t *testing.T) {
+	previous := fixtureStore
+	fixtureStore = &fakeS3{objects: map[string][]byte{"fixtures/not_json.json": []byte("not json")}}
+	defer func() { fixtureStore = previous }()
+
+	for _, source := range []string{
+		"s3://fixtures",
+		"s3:///good_jobs.json",
+		"s3://fixtures/missing.json",
+		"s3://fixtures/not_json.json",
+	} {
+		t.Run(source, func(t *testing.T) {
+			if _, err := readMessages(source); err == nil {
+				t.Errorf("expected an error but got none")
+			}
+		})
+	}
+}
+
+func TestValidateFixtures(t *testing.T) {
+	good, err := readMessages("good_jobs.json")
+	if err != nil {
+		t.Fatalf("failed to read good fixtures: %v", err)
+	}
+	bad, err := readMessages("bad_jobs.json")
+	if err != nil {
+		t.Fatalf("failed to read bad fixtures: %v", err)
+	}
+
+	if mismatches := validateFixtures("good_jobs.json", good, "bad_jobs.json", bad); len(mismatches) != 0 {
+		t.Errorf("expected the shipped fixtures to be classified correctly, got %v", mismatches)
+	}
+
+	// Swap a fixture into the other file so both are mislabeled
+	good, bad = append(good, bad[0]), append(bad, good[0])
+	mismatches := validateFixtures("good_jobs.json", good, "bad_jobs.json", bad)
+	if len(mismatches) != 2 {
+		t.Fatalf("expected 2 mismatches, got %v", mismatches)
+	}
+	if actual, expected := mismatches[0].Source, "good_jobs.json"; actual != expected || mismatches[0].Index != len(good)-1 || mismatches[0].Err == nil {
+		t.Errorf("expected the bad job in good_jobs.json to fail, got %s", mismatches[0])
+	}
+	if actual, expected := mismatches[1].String(), fmt.Sprintf("bad_jobs.json#%d should be invalid but passed", len(bad)-1); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
+func TestReadMessageDir(t *testing.T) {
+	good, err := readMessages("good_jobs.json")
+	if err != nil {
+		t.Fatalf("failed to read good fixtures: %v", err)
+	}
+	dir := t.TempDir()
+	for name, fixture := range map[string][]joblib.JobMessage{"a_reports.json": good[:1], "b_cleanup.json": good[1:3]} {
+		data, err := json.Marshal(fixture)
+		if err != nil {
+			t.Fatalf("failed to marshal fixture: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatalf("failed to write fixture: %v", err)
+		}
+	}
+	// Only *.json and *.ndjson files are fixtures
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("team fixtures"), 0o644); err != nil {
+		t.Fatalf("failed to write README: %v", err)
+	}
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	messages, err := readMessageDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(messages) != 3 {
+		t.Fatalf("expected 3 messages merged, got %d", len(messages))
+	}
+	for i := range messages {
+		if messages[i].String() != good[i].String() {
+			t.Errorf("message %d: expected %s, got %s", i, good[i], messages[i])
+		}
+	}
+	for _, expected := range []string{"Loaded 1 messages from " + filepath.Join(dir, "a_reports.json"), "Loaded 2 messages from " + filepath.Join(dir, "b_cleanup.json") + " (#1-#2)"} {
+		if !strings.Contains(logs.String(), expected) {
+			t.Errorf("expected %q logged, got %s", expected, logs.String())
+		}
+	}
+}
+
+func TestReadMessageDirErrors(t *testing.T) {
+	empty := t.TempDir()
+	invalid := t.TempDir()
+	if err := os.WriteFile(filepath.Join(invalid, "broken.json"), []byte("not json"), 0o644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	for name, dir := range map[string]string{"No fixtures": empty, "Invalid fixture": invalid} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := readMessageDir(dir); err == nil {
+				t.Errorf("expected an error but got none")
+			}
+		})
+	}
+}
+
+func TestReadMessagesNDJSON(t *testing.T) {
+	dir := t.TempDir()
+	fixtures := map[string]string{
+		"jobs.json": `[
+			{"job_type": "data_cleanup", "message": {"target_table": "users", "retention": 30}},
+			{"job_type": "user_onboarding", "message": {"user_id": "user-001", "user_name": "John Doe"}}
+		]`,
+		// Blank lines and CRLF line endings are allowed
+		"jobs.ndjson": "\n" +
+			`{"job_type": "data_cleanup", "message": {"target_table": "users", "retention": 30}}` + "\r\n\n" +
+			`{"job_type": "user_onboarding", "message": {"user_id": "user-001", "user_name": "John Doe"}}`,
+	}
+	for name, fixture := range fixtures {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(fixture), 0o644); err != nil {
+			t.Fatalf("failed to write fixture: %v", err)
+		}
+	}
+
+	array, err := readMessages(filepath.Join(dir, "jobs.json"))
+	if err != nil {
+		t.Fatalf("failed to read the array fixture: %v", err)
+	}
+	lines, err := readMessages(filepath.Join(dir, "jobs.ndjson"))
+	if err != nil {
+		t.Fatalf("failed to read the NDJSON fixture: %v", err)
+	}
+	if len(array) != 2 || len(lines) != len(array) {
+		t.Fatalf("expected 2 messages from each fixture, got %d and %d", len(array), len(lines))
+	}
+	for i := range array {
+		if lines[i].String() != array[i].String() {
+			t.Errorf("message %d: expected %s, got %s", i, array[i], lines[i])
+		}
+	}
+}
+
+func TestReadMessagesNDJSONErrors(t *testing.T) {
+	_, err := parseMessages([]byte(`{"job_type": "data_cleanup", "message": {}}` + "\n" + `{"job_type": `))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected the malformed line reported, got %v", err)
+	}
+	if _, err := parseMessages([]byte("  ")); err == nil {
+		t.Errorf("expected an error for an empty fixture")
+	}
+}
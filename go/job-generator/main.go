@@ -3,153 +3,388 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
+	"log/slog"
+	"math"
 	"math/rand"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
-	"github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
 )
 
 func main() {
 	// Define a flag for the runtime duration in minutes
 	runMinutes := flag.Int("minutes", 0, "Number of minutes to run the job generator")
+	tagFixtures := flag.Bool("tag-fixtures", false, "Tag each job with the fixture it came from so it can be found on spans (demo only)")
+	verify := flag.Bool("verify-wiring", false, "Send a probe event and check it reaches jobs-todo or the dead-letter queue, then exit")
+	verifyTimeout := flag.Duration("verify-timeout", time.Minute, "How long -verify-wiring waits for the probe")
+	estimateDrain := flag.Bool("estimate-drain", false, "Print how long the jobs-todo backlog takes to drain at -throughput, then exit")
+	throughput := flag.Float64("throughput", 1, "Jobs completed per second, used by -estimate-drain")
+	loadProfileFile := flag.String("load-profile", "", "Send good jobs following the ramp/hold/ramp-down profile in this JSON file (see load_profile.json), then exit")
+	releaseQuarantined := flag.Bool("release-quarantine", false, "Move quarantined messages that now validate back onto jobs-todo, then exit")
+	redrive := flag.Bool("redrive-dead-letters", false, "Send dead-lettered jobs back onto jobs-todo, skipping envelopes past their TTL, then exit")
+	purgeExpired := flag.Bool("purge-expired", false, "Delete expired dead letters during -redrive-dead-letters rather than leaving them on the queue")
+	goodJobs := flag.String("good-jobs", "good_jobs.json", "Fixture of valid jobs, a local file or s3://bucket/key")
+	badJobs := flag.String("bad-jobs", "bad_jobs.json", "Fixture of invalid jobs, a local file or s3://bucket/key")
+	jobsDir := flag.String("jobs-dir", "", "Merge the valid jobs of every *.json fixture in this directory, instead of reading -good-jobs")
+	badDir := flag.String("bad-dir", "", "Merge the invalid jobs of every *.json fixture in this directory, instead of reading -bad-jobs")
+	checkFixtures := flag.Bool("validate-fixtures", false, "Check the good fixtures parse and the bad fixtures are rejected, then exit")
+	allowBad := flag.Bool("allow-bad", true, "Mix in invalid jobs from -bad-jobs for the demo, set false when pointed at a real bus")
+	badRate := flag.Float64("bad-rate", 0.2, "Fraction of messages, 0 to 1, sent from -bad-jobs when -allow-bad is set")
+	reportInterval := flag.Duration("report-interval", 0, "Log how many messages have been sent and the current rate this often, 0 disables")
+	rate := flag.Float64("rate", 0, "Send this many messages per second, paced by a ticker, rather than sleeping 2-10s between messages")
+	workers := flag.Int("workers", 1, "Goroutines sending messages concurrently at -rate")
+	batchSize := flag.Int("batch-size", 1, "Send up to this many messages, at most 10, in each PutEvents call at -rate, 1 sends them one at a time")
+	batchInterval := flag.Duration("batch-interval", time.Second, "Send a partly filled -batch-size batch after this long")
+	flag.BoolVar(&retryRejected, "retry-rejected", false, "Retry the messages EventBridge failed to put once before counting them as rejected")
+	weightsFile := flag.String("weights", "", "Pick good jobs by type using the weights in this JSON file (see job_weights.json), rather than sending each in turn")
+	replay := flag.String("replay", "", "Parse and enrich a single job message (JSON, or - for stdin) locally and print the enriched payload, then exit")
+	dryRun := flag.Bool("dry-run", false, "Print each job to stdout, prefixed with its job type, instead of sending it to EventBridge")
+	busSource := flag.String("source", eventSource, "EventBridge source jobs are sent with")
+	busDetailType := flag.String("detail-type", eventDetailType, "EventBridge detail type jobs are sent with")
+	busName := flag.String("bus", eventBusName, "EventBridge event bus jobs are sent to")
+	httpAddr := flag.String("http-addr", "", "Serve /healthz and /stats on this address, e.g. :8080, while sending jobs")
+	seed := flag.Int64("seed", 0, "Seed the job picks, randomised fields and sleeps so a run can be reproduced, seeded from the clock when unset")
 	flag.Parse()
+	eventSource, eventDetailType, eventBusName = *busSource, *busDetailType, *busName
 
-	endTime := time.Time{}
-	// Calculate the end time
+	// Log JSON lines at LOG_LEVEL (info by default) and above, like the services
+	slog.SetDefault(joblib.NewLogger(os.Stderr, joblib.ParseLogLevel(os.Getenv("LOG_LEVEL"), slog.LevelInfo)))
+
+	// Seed once, logging the seed so any run can be reproduced with -seed
+	if !flagSet("seed") {
+		*seed = time.Now().UnixNano()
+	}
+	rng = rand.New(rand.NewSource(*seed))
+	log.Printf("Job generator seeded with %d", *seed)
+
+	if err := validateBadRate(*badRate); err != nil {
+		log.Fatal(err)
+	}
+	if err := validateWorkers(*workers, *rate); err != nil {
+		log.Fatal(err)
+	}
+	if err := validateBatchSize(*batchSize, *batchInterval, *rate); err != nil {
+		log.Fatal(err)
+	}
+
+	// Debug a single job message without AWS
+	if *replay != "" {
+		input, err := replayInput(*replay, os.Stdin)
+		if err != nil {
+			log.Fatalf("failed to read job message: %v", err)
+		}
+		output, err := replayEvent(input, joblib.SystemClock{})
+		if err != nil {
+			log.Fatalf("job message rejected: %v", err)
+		}
+		fmt.Println(string(output))
+		return
+	}
+
+	// Stop cleanly on Ctrl+C or SIGTERM rather than dying mid-send
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Generating jobs also stops once the runtime ends, through the same context
+	runCtx := ctx
 	if *runMinutes > 0 {
-		endTime = time.Now().Add(time.Duration(*runMinutes) * time.Minute)
+		endTime := time.Now().Add(time.Duration(*runMinutes) * time.Minute)
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithDeadline(ctx, endTime)
+		defer cancel()
 		log.Printf("Job generator will run for %d minutes (until %s)", *runMinutes, endTime.Format(time.RFC3339))
 	} else {
 		log.Printf("Job generator will run indefinitely. Use Ctrl+C to stop.")
 	}
 
-	// Load AWS configuration
-	cfg, err := config.LoadDefaultConfig(context.TODO(),
-		config.WithRegion("us-east-1"),
-		config.WithEndpointResolver(aws.EndpointResolverFunc(func(service, region string) (aws.Endpoint, error) {
-			if service == eventbridge.ServiceID {
-				return aws.Endpoint{URL: "http://localhost:4566"}, nil
-			}
-			return aws.Endpoint{}, fmt.Errorf("unknown endpoint requested")
-		})),
-	)
+	// Load AWS configuration, LocalStack unless AWS_ENDPOINT_URL says otherwise
+	cfg, err := joblib.LoadAWSConfig(context.TODO())
 	if err != nil {
 		log.Fatalf("unable to load SDK config, %v", err)
 	}
 
-	// Create EventBridge client
-	client := eventbridge.NewFromConfig(cfg)
+	// Create EventBridge client, or print jobs instead of sending them on a dry run
+	var client eventPutter = eventbridge.NewFromConfig(cfg)
+	if *dryRun {
+		client = &dryRunBus{out: os.Stdout}
+	}
+
+	// Fixtures may live in S3 as well as on disk
+	fixtureStore = s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.UsePathStyle = true // LocalStack doesn't create the bucket DNS entries
+	})
 
-	// Read the messages from the good JSON file
-	goodMessages, err := readMessages("good_jobs.json")
+	// Estimate how long the current backlog takes to drain
+	if *estimateDrain {
+		estimate, err := drainEstimate(ctx, sqs.NewFromConfig(cfg), "http://localhost:4566/000000000000/jobs-todo", *throughput)
+		if err != nil {
+			log.Fatalf("failed to estimate drain time: %v", err)
+		}
+		log.Println(estimate)
+		return
+	}
+
+	// Release poison messages an operator has fixed or judged safe to retry
+	if *releaseQuarantined {
+		released, kept, err := releaseQuarantine(ctx, sqs.NewFromConfig(cfg),
+			"http://localhost:4566/000000000000/jobs-quarantine",
+			"http://localhost:4566/000000000000/jobs-todo")
+		if err != nil {
+			log.Fatalf("failed to release quarantined messages: %v", err)
+		}
+		log.Printf("Released %d quarantined messages, %d still fail validation", released, kept)
+		return
+	}
+
+	// Retry dead-lettered jobs that are still worth running
+	if *redrive {
+		result, err := redriveDeadLetters(ctx, sqs.NewFromConfig(cfg),
+			"http://localhost:4566/000000000000/dead-letter-queue",
+			"http://localhost:4566/000000000000/jobs-todo",
+			joblib.SystemClock{}, *purgeExpired)
+		if err != nil {
+			log.Fatalf("failed to redrive dead letters: %v", err)
+		}
+		log.Printf("Redrove %d dead letters, %d expired, %d are not envelopes", result.Redriven, result.Expired, result.Kept)
+		return
+	}
+
+	// Check the EventBridge rule delivers to the pipeline instead of generating jobs
+	if *verify {
+		queueURL, err := verifyWiring(ctx, client, sqs.NewFromConfig(cfg), []string{
+			"http://localhost:4566/000000000000/jobs-todo",
+			"http://localhost:4566/000000000000/dead-letter-queue",
+		}, *verifyTimeout, time.Second)
+		if err != nil {
+			log.Fatalf("pipeline wiring check failed: %v", err)
+		}
+		log.Printf("Pipeline is wired correctly, probe arrived on %s", queueURL)
+		return
+	}
+
+	// Read the messages from the good JSON file, or every file in -jobs-dir
+	readGood, readBad := readMessages, readMessages
+	if *jobsDir != "" {
+		*goodJobs, readGood = *jobsDir, readMessageDir
+	}
+	if *badDir != "" {
+		*badJobs, readBad = *badDir, readMessageDir
+	}
+	goodMessages, err := readGood(*goodJobs)
 	if err != nil {
 		log.Fatalf("failed to read good messages: %v", err)
 	}
 
-	// Read the messages from the good JSON file
-	badMessages, err := readMessages("bad_jobs.json")
-	if err != nil {
-		log.Fatalf("failed to read bad messages: %v", err)
+	// Read the messages from the bad JSON file or -bad-dir, unless bad messages are disabled
+	var badMessages []joblib.JobMessage
+	if *allowBad || *checkFixtures {
+		badMessages, err = readBad(*badJobs)
+		if err != nil {
+			log.Fatalf("failed to read bad messages: %v", err)
+		}
 	}
 
-	// Process messages until the runtime duration ends
-	for {
-		// Check if the current time has exceeded the end time
-		if !endTime.IsZero() && time.Now().After(endTime) {
-			log.Println("Job generator has completed its runtime.")
-			break
+	// Check each fixture is classified correctly instead of generating jobs
+	if *checkFixtures {
+		mismatches := validateFixtures(*goodJobs, goodMessages, *badJobs, badMessages)
+		for _, mismatch := range mismatches {
+			log.Println(mismatch)
+		}
+		if len(mismatches) > 0 {
+			log.Fatalf("%d fixtures are mislabeled", len(mismatches))
 		}
+		log.Printf("All %d good and %d bad fixtures are classified correctly", len(goodMessages), len(badMessages))
+		return
+	}
 
-		// Process each message
-		for _, jobMessage := range goodMessages {
-			// Marshal the job message to JSON
-			eventJSON, err := json.Marshal(jobMessage)
-			if err != nil {
-				log.Printf("failed to marshal job message: %v", err)
-				continue
-			}
+	// Optionally weight the mix of job types, e.g. mostly reports for load testing
+	var weights *jobWeights
+	if *weightsFile != "" {
+		weights, err = readJobWeights(*weightsFile, goodMessages)
+		if err != nil {
+			log.Fatalf("failed to read job weights: %v", err)
+		}
+	}
 
-			randomiseMessageParameters(&jobMessage)
+	// Periodically summarise progress rather than leaving operators to tail every message
+	rates := newRateReporter(joblib.SystemClock{})
+	if *reportInterval > 0 {
+		ticker := time.NewTicker(*reportInterval)
+		defer ticker.Stop()
+		go reportRates(runCtx, rates, ticker.C, func(summary rateSummary) { log.Println(summary) })
+	}
 
-			// Randomly pick a good or bad message
-			if rand.Intn(5) == 0 { // 20% chance to pick a bad message
-				randomIndex := rand.Intn(len(badMessages))
-				eventJSON, err = json.Marshal(badMessages[randomIndex])
-				log.Printf("Sending a bad message: %v", badMessages[randomIndex])
-			} else {
-				eventJSON, err = json.Marshal(jobMessage)
-				log.Printf("Sending a good message: %v", jobMessage)
-			}
+	// Let an orchestrator health check a long-lived generator and see what it has sent
+	if *httpAddr != "" {
+		status := newStatusServer(rates, joblib.SystemClock{})
+		addr, shutdown, err := serveStatus(*httpAddr, status)
+		if err != nil {
+			log.Fatalf("failed to start the status server: %v", err)
+		}
+		defer shutdown()
+		log.Printf("Serving /healthz and /stats on %s", addr)
+		status.running.Store(true)
+		go func() {
+			<-runCtx.Done()
+			status.running.Store(false)
+		}()
+	}
 
-			// Send the message to EventBridge
-			err = sendToEventBridge(client, eventJSON)
+	// Benchmark with a reproducible load profile instead of random traffic
+	if *loadProfileFile != "" {
+		profile, err := readLoadProfile(*loadProfileFile)
+		if err != nil {
+			log.Fatalf("failed to read load profile: %v", err)
+		}
+		var eventJSONs [][]byte
+		for _, jobMessage := range goodMessages {
+			eventJSON, err := json.Marshal(jobMessage)
 			if err != nil {
-				log.Printf("failed to send job message to EventBridge: %v", err)
+				log.Fatalf("failed to marshal job message: %v", err)
 			}
+			eventJSONs = append(eventJSONs, eventJSON)
+		}
+		runLoadProfile(runCtx, client, eventJSONs, profile, rates.record)
+		exitIfRejected()
+		return
+	}
+
+	source := &messageSource{
+		goodMessages: goodMessages,
+		badMessages:  badMessages,
+		goodJobs:     *goodJobs,
+		badJobs:      *badJobs,
+		tagFixtures:  *tagFixtures,
+		allowBad:     *allowBad,
+		badRate:      *badRate,
+		weights:      weights,
+	}
 
-			// Sleep for a random interval between 2 and 10 seconds
-			sleepDuration := time.Duration(rand.Intn(9)+2) * time.Second
-			log.Printf("Sleeping for %v before sending the next message...", sleepDuration)
-			time.Sleep(sleepDuration)
+	// Send at a steady rate from a pool of workers for load tests
+	if *rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / *rate))
+		defer ticker.Stop()
+		var flushes <-chan time.Time
+		if *batchSize > 1 {
+			flushTicker := time.NewTicker(*batchInterval)
+			defer flushTicker.Stop()
+			flushes = flushTicker.C
 		}
+		log.Printf("Sending %.2f messages/s from %d workers in batches of up to %d", *rate, *workers, *batchSize)
+		sent := sendConcurrently(runCtx, client, source.next, *workers, ticker.C, *batchSize, flushes, rates.record)
+		log.Printf("%s, sent %d messages.", stopReason(runCtx), sent)
+		exitIfRejected()
+		return
 	}
 
+	// Process messages until the runtime ends or the generator is stopped,
+	// sleeping for a random interval between 2 and 10 seconds after each
+	sent := sendWithPauses(runCtx, client, source.next, func() time.Duration {
+		return time.Duration(rng.Intn(9)+2) * time.Second
+	}, rates.record)
+	log.Printf("%s, sent %d messages.", stopReason(runCtx), sent)
+	exitIfRejected()
 }
 
-func readMessages(filename string) ([]joblib.JobMessage, error) {
-	data, err := ioutil.ReadFile(filename)
-	if err != nil {
-		return nil, err
+// flagSet reports whether the named flag was given on the command line.
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
+	})
+	return set
+}
+
+// stopReason describes why the generator's run context ended.
+func stopReason(ctx context.Context) string {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "Job generator has completed its runtime"
 	}
+	return "Job generator was stopped"
+}
 
-	var messages []joblib.JobMessage
-	err = json.Unmarshal(data, &messages)
+func readMessages(filename string) ([]joblib.JobMessage, error) {
+	data, err := readFixture(filename)
 	if err != nil {
 		return nil, err
 	}
 
-	return messages, nil
+	return parseMessages(data)
 }
 
-func sendToEventBridge(client *eventbridge.Client, eventJSON []byte) error {
-	output, err := client.PutEvents(context.TODO(), &eventbridge.PutEventsInput{
-		Entries: []types.PutEventsRequestEntry{
-			{
-				Source:       aws.String("jobs"),
-				DetailType:   aws.String("JobEvent"),
-				Detail:       aws.String(string(eventJSON)),
-				EventBusName: aws.String("default"), // Use "default" for the default event bus
-			},
-		},
-	})
-	if err != nil {
-		return err
+// eventPutter is the subset of the EventBridge client used to send jobs
+type eventPutter interface {
+	PutEvents(ctx context.Context, params *eventbridge.PutEventsInput, optFns ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error)
+}
+
+// Where jobs are sent on EventBridge, set from -source, -detail-type and -bus
+var (
+	eventSource     = "jobs"
+	eventDetailType = "JobEvent"
+	eventBusName    = "default"
+)
+
+// errEventRejected is returned for events EventBridge accepted the request
+// for but failed to put, counted in rejectedEvents.
+var errEventRejected = errors.New("EventBridge failed to put the event")
+
+// rejectedEvents counts the events EventBridge failed to put this run, which
+// make the generator exit non-zero.
+var rejectedEvents atomic.Int64
+
+func sendToEventBridge(ctx context.Context, client eventPutter, eventJSON []byte) error {
+	return putEvents(ctx, client, [][]byte{eventJSON})[0]
+}
+
+// exitIfRejected exits non-zero when EventBridge failed to put any event this
+// run, so a misconfigured bus isn't mistaken for a clean run.
+func exitIfRejected() {
+	if rejected := rejectedEvents.Load(); rejected > 0 {
+		log.Fatalf("WARNING: EventBridge failed to put %d events on bus %s, check the bus exists and its quotas", rejected, eventBusName)
 	}
+}
 
-	// Log the result
-	for _, entry := range output.Entries {
-		if entry.EventId != nil {
-			log.Printf("Event sent successfully with ID: %s", *entry.EventId)
-		} else {
-			log.Printf("Failed to send event: %v", entry.ErrorMessage)
-		}
+// pickBadMessage decides whether to send a bad message in place of the next
+// good one, returning the index of the bad fixture to send. A badRate
+// fraction of messages is bad, and none are when allowBad is false.
+func pickBadMessage(badMessages []joblib.JobMessage, allowBad bool, badRate float64, float64n func() float64, intn func(int) int) (int, bool) {
+	if !allowBad || len(badMessages) == 0 || float64n() >= badRate {
+		return 0, false
 	}
+	return intn(len(badMessages)), true
+}
 
+// validateBadRate checks a bad-message rate is a fraction between 0 and 1.
+func validateBadRate(rate float64) error {
+	if rate < 0 || rate > 1 || math.IsNaN(rate) {
+		return fmt.Errorf("-bad-rate must be between 0 and 1, got %v", rate)
+	}
 	return nil
 }
 
-func randomiseMessageParameters(jobMessage *joblib.JobMessage) {
-	// Seed the random number generator
-	rand.Seed(time.Now().UnixNano())
+// tagFixture records which fixture file and index a job came from so it can
+// be found on the ingester and processor spans.
+func tagFixture(jobMessage *joblib.JobMessage, filename string, index int) {
+	jobMessage.Fixture = fmt.Sprintf("%s#%d", filename, index)
+}
+
+// rng drives every random choice the generator makes, seeded once in main so
+// -seed can reproduce a run. It is only used from the goroutine building
+// events.
+var rng = rand.New(rand.NewSource(time.Now().UnixNano()))
 
+func randomiseMessageParameters(jobMessage *joblib.JobMessage) {
 	var messageMap map[string]interface{}
 	if err := json.Unmarshal(jobMessage.Message, &messageMap); err != nil {
 		log.Printf("failed to unmarshal job message for randomization: %v", err)
@@ -160,31 +395,35 @@ func randomiseMessageParameters(jobMessage *joblib.JobMessage) {
 	switch jobMessage.JobType {
 	case "report_generation":
 		if _, ok := messageMap["report_name"]; ok {
-			messageMap["report_name"] = fmt.Sprintf("Random Report %d", rand.Intn(100))
+			messageMap["report_name"] = fmt.Sprintf("Random Report %d", rng.Intn(100))
 		}
 		if _, ok := messageMap["filters"]; ok {
-			messageMap["filters"] = fmt.Sprintf("region=%d", rand.Intn(100))
+			messageMap["filters"] = fmt.Sprintf("region=%d", rng.Intn(100))
 		}
 	case "data_cleanup":
 		if _, ok := messageMap["target_table"]; ok {
-			messageMap["target_table"] = fmt.Sprintf("table_%d", rand.Intn(100))
+			messageMap["target_table"] = fmt.Sprintf("table_%d", rng.Intn(100))
 		}
 		if _, ok := messageMap["retention"]; ok {
-			messageMap["retention"] = rand.Intn(365) // Random retention between 0 and 365 days
+			messageMap["retention"] = rng.Intn(365) // Random retention between 0 and 365 days
 		}
 	case "user_onboarding":
 		if _, ok := messageMap["user_id"]; ok {
-			messageMap["user_id"] = fmt.Sprintf("user_%d", rand.Intn(10000))
+			messageMap["user_id"] = fmt.Sprintf("user_%d", rng.Intn(10000))
 		}
 		if _, ok := messageMap["user_name"]; ok {
-			messageMap["user_name"] = fmt.Sprintf("Random User %d", rand.Intn(100))
+			messageMap["user_name"] = fmt.Sprintf("Random User %d", rng.Intn(100))
 		}
 	case "long_running_job":
 		if _, ok := messageMap["task_name"]; ok {
-			messageMap["task_name"] = fmt.Sprintf("Task %d", rand.Intn(100))
+			messageMap["task_name"] = fmt.Sprintf("Task %d", rng.Intn(100))
 		}
 		if _, ok := messageMap["timeout"]; ok {
-			messageMap["timeout"] = rand.Intn(600) + 1 // Random timeout
+			messageMap["timeout"] = rng.Intn(600) + 1 // Random timeout
+		}
+	case "email_notification":
+		if _, ok := messageMap["subject"]; ok {
+			messageMap["subject"] = fmt.Sprintf("Notification %d", rng.Intn(100))
 		}
 	default:
 		log.Printf("Unknown job type: %s", jobMessage.JobType)
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+func TestTagFixture(t *testing.T) {
+	messages, err := readMessages("good_jobs.json")
+	if err != nil {
+		t.Fatalf("failed to read fixtures: %v", err)
+	}
+
+	jobMessage := messages[1]
+	tagFixture(&jobMessage, "good_jobs.json", 1)
+
+	eventJSON, err := json.Marshal(jobMessage)
+	if err != nil {
+		t.Fatalf("failed to marshal job message: %v", err)
+	}
+
+	// The tag must survive the trip through EventBridge into the services
+	attrs := joblib.FixtureAttributes(eventJSON)
+	if len(attrs) != 1 || attrs[0].Value.AsString() != "good_jobs.json#1" {
+		t.Errorf("expected demo.fixture=good_jobs.json#1, got %v", attrs)
+	}
+	if _, _, _, err := joblib.ParseJob(eventJSON); err != nil {
+		t.Errorf("tagged job should still parse: %v", err)
+	}
+}
+
+func TestRandomisedUserIDFormat(t *testing.T) {
+	messages, err := readMessages("good_jobs.json")
+	if err != nil {
+		t.Fatalf("failed to read fixtures: %v", err)
+	}
+
+	pattern := joblib.ParseUserIDPattern(joblib.UserIDFormat)
+	for _, jobMessage := range messages {
+		if jobMessage.JobType != string(joblib.UserOnboarding) {
+			continue
+		}
+		for _, randomise := range []bool{false, true} {
+			if randomise {
+				randomiseMessageParameters(&jobMessage)
+			}
+			var message joblib.UserOnboardingJob
+			if err := json.Unmarshal(jobMessage.Message, &message); err != nil {
+				t.Fatalf("failed to unmarshal message: %v", err)
+			}
+			if !pattern.MatchString(message.UserID) {
+				t.Errorf("expected user_id %q to match %s", message.UserID, joblib.UserIDFormat)
+			}
+		}
+	}
+}
+
+func TestPickBadMessage(t *testing.T) {
+	badMessages, err := readMessages("bad_jobs.json")
+	if err != nil {
+		t.Fatalf("failed to read fixtures: %v", err)
+	}
+	lowestRoll := func() float64 { return 0 } // every roll picks a bad message when any are allowed
+	firstFixture := func(n int) int { return 0 }
+
+	tests := []struct {
+		name        string
+		badMessages []joblib.JobMessage
+		allowBad    bool
+		badRate     float64
+		expectBad   bool
+	}{
+		{name: "Bad messages allowed", badMessages: badMessages, allowBad: true, badRate: 0.2, expectBad: true},
+		{name: "Bad messages disabled", badMessages: badMessages, allowBad: false, badRate: 0.2},
+		{name: "No bad fixtures loaded", allowBad: true, badRate: 0.2},
+		{name: "Zero bad rate", badMessages: badMessages, allowBad: true, badRate: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, bad := pickBadMessage(tt.badMessages, tt.allowBad, tt.badRate, lowestRoll, firstFixture); bad != tt.expectBad {
+				t.Errorf("expected bad to be %v, got %v", tt.expectBad, bad)
+			}
+		})
+	}
+}
+
+func TestBadRate(t *testing.T) {
+	badMessages, err := readMessages("bad_jobs.json")
+	if err != nil {
+		t.Fatalf("failed to read fixtures: %v", err)
+	}
+
+	for _, rate := range []float64{0, 0.2, 0.5, 1} {
+		t.Run(fmt.Sprint(rate), func(t *testing.T) {
+			random := rand.New(rand.NewSource(1))
+			const draws = 10000
+			bad := 0
+			for i := 0; i < draws; i++ {
+				if _, isBad := pickBadMessage(badMessages, true, rate, random.Float64, random.Intn); isBad {
+					bad++
+				}
+			}
+			if actual := float64(bad) / draws; math.Abs(actual-rate) > 0.02 {
+				t.Errorf("expected %.0f%% bad messages, got %.1f%%", rate*100, actual*100)
+			}
+		})
+	}
+
+	for _, rate := range []float64{-0.1, 1.5, math.NaN()} {
+		if err := validateBadRate(rate); err == nil {
+			t.Errorf("expected bad rate %v to be rejected", rate)
+		}
+	}
+	for _, rate := range []float64{0, 0.2, 1} {
+		if err := validateBadRate(rate); err != nil {
+			t.Errorf("unexpected error for bad rate %v: %v", rate, err)
+		}
+	}
+}
+
+func TestSeedReproducesRun(t *testing.T) {
+	goodMessages, err := readMessages("good_jobs.json")
+	if err != nil {
+		t.Fatalf("failed to read fixtures: %v", err)
+	}
+	badMessages, err := readMessages("bad_jobs.json")
+	if err != nil {
+		t.Fatalf("failed to read fixtures: %v", err)
+	}
+	weights, err := readJobWeights("job_weights.json", goodMessages)
+	if err != nil {
+		t.Fatalf("failed to read weights: %v", err)
+	}
+
+	previous := rng
+	defer func() { rng = previous }()
+	run := func(seed int64) []string {
+		rng = rand.New(rand.NewSource(seed))
+		source := &messageSource{goodMessages: goodMessages, badMessages: badMessages, allowBad: true, badRate: 0.2, weights: weights}
+		var events []string
+		for i := 0; i < 50; i++ {
+			eventJSON, _, err := source.next()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			events = append(events, string(eventJSON))
+		}
+		return events
+	}
+
+	if first, second := run(42), run(42); !reflect.DeepEqual(first, second) {
+		t.Errorf("expected the same seed to generate the same events")
+	}
+	if first, other := run(42), run(43); reflect.DeepEqual(first, other) {
+		t.Errorf("expected different seeds to generate different events")
+	}
+}
+
+// fakeEventBridge records the entries it is sent and fails them when
+// errorCode is set, as PutEvents does for entries it can't put
+type fakeEventBridge struct {
+	entries   []types.PutEventsRequestEntry
+	errorCode string
+}
+
+func (f *fakeEventBridge) PutEvents(ctx context.Context, params *eventbridge.PutEventsInput, optFns ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error) {
+	f.entries = append(f.entries, params.Entries...)
+	if f.errorCode != "" {
+		return &eventbridge.PutEventsOutput{
+			FailedEntryCount: 1,
+			Entries:          []types.PutEventsResultEntry{{ErrorCode: aws.String(f.errorCode), ErrorMessage: aws.String("event bus does not exist")}},
+		}, nil
+	}
+	return &eventbridge.PutEventsOutput{Entries: []types.PutEventsResultEntry{{EventId: aws.String("event-1")}}}, nil
+}
+
+func TestSendToConfiguredBus(t *testing.T) {
+	previousSource, previousDetailType, previousBus := eventSource, eventDetailType, eventBusName
+	defer func() { eventSource, eventDetailType, eventBusName = previousSource, previousDetailType, previousBus }()
+
+	bus := &fakeEventBridge{}
+	if err := sendToEventBridge(context.Background(), bus, []byte(`{"job_type":"data_cleanup"}`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	eventSource, eventDetailType, eventBusName = "jobs.team-a", "TeamJobEvent", "team-a"
+	if err := sendToEventBridge(context.Background(), bus, []byte(`{"job_type":"data_cleanup"}`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := [][3]string{{"jobs", "JobEvent", "default"}, {"jobs.team-a", "TeamJobEvent", "team-a"}}
+	for i, entry := range bus.entries {
+		if actual := [3]string{aws.ToString(entry.Source), aws.ToString(entry.DetailType), aws.ToString(entry.EventBusName)}; actual != expected[i] {
+			t.Errorf("event %d: expected source, detail type and bus %v, got %v", i, expected[i], actual)
+		}
+	}
+}
+
+func TestSendFailedEntry(t *testing.T) {
+	previous := rejectedEvents.Load()
+	defer rejectedEvents.Store(previous)
+	rejectedEvents.Store(0)
+
+	bus := &fakeEventBridge{errorCode: "InternalFailure"}
+	err := sendToEventBridge(context.Background(), bus, []byte(`{"job_type":"data_cleanup"}`))
+	if !errors.Is(err, errEventRejected) || !strings.Contains(err.Error(), "InternalFailure") {
+		t.Errorf("expected the failed entry returned with its error code, got %v", err)
+	}
+	if rejected := rejectedEvents.Load(); rejected != 1 {
+		t.Errorf("expected 1 rejected event counted, got %d", rejected)
+	}
+}
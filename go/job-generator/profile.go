@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// scheduleStep is the resolution send times are computed at
+const scheduleStep = 10 * time.Millisecond
+
+// loadProfile describes a benchmark run: ramp from 0 to PeakRate messages
+// per second over RampUp, hold for Hold, then ramp back to 0 over RampDown.
+// Durations are Go duration strings, e.g. "30s".
+type loadProfile struct {
+	PeakRate float64 `json:"peak_rate"`
+	RampUp   string  `json:"ramp_up"`
+	Hold     string  `json:"hold"`
+	RampDown string  `json:"ramp_down"`
+
+	rampUp, hold, rampDown time.Duration
+}
+
+// readLoadProfile reads and validates a load profile from a JSON file.
+func readLoadProfile(filename string) (loadProfile, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return loadProfile{}, err
+	}
+	var profile loadProfile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return loadProfile{}, fmt.Errorf("invalid load profile: %w", err)
+	}
+	return profile, profile.parse()
+}
+
+// parse validates the profile and parses its durations.
+func (p *loadProfile) parse() error {
+	if p.PeakRate <= 0 {
+		return errors.New("peak_rate must be greater than 0")
+	}
+	for _, d := range []struct {
+		name  string
+		value string
+		into  *time.Duration
+	}{
+		{"ramp_up", p.RampUp, &p.rampUp},
+		{"hold", p.Hold, &p.hold},
+		{"ramp_down", p.RampDown, &p.rampDown},
+	} {
+		if d.value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil || parsed < 0 {
+			return fmt.Errorf("invalid %s %q", d.name, d.value)
+		}
+		*d.into = parsed
+	}
+	if p.Duration() == 0 {
+		return errors.New("load profile has no duration")
+	}
+	return nil
+}
+
+// Duration is the length of the whole profile.
+func (p loadProfile) Duration() time.Duration {
+	return p.rampUp + p.hold + p.rampDown
+}
+
+// rateAt returns the target messages per second at elapsed into the profile.
+func (p loadProfile) rateAt(elapsed time.Duration) float64 {
+	switch {
+	case elapsed < 0 || elapsed >= p.Duration():
+		return 0
+	case elapsed < p.rampUp:
+		return p.PeakRate * float64(elapsed) / float64(p.rampUp)
+	case elapsed < p.rampUp+p.hold:
+		return p.PeakRate
+	default:
+		remaining := p.Duration() - elapsed
+		return p.PeakRate * float64(remaining) / float64(p.rampDown)
+	}
+}
+
+// sendTimes returns the offset from the start of the run at which each
+// message is sent, so the send rate follows rateAt.
+func (p loadProfile) sendTimes() []time.Duration {
+	var times []time.Duration
+	owed := 0.0
+	for elapsed := time.Duration(0); elapsed < p.Duration(); elapsed += scheduleStep {
+		// Rate at the middle of the step so ramps integrate exactly
+		owed += p.rateAt(elapsed+scheduleStep/2) * scheduleStep.Seconds()
+		for ; owed >= 1; owed-- {
+			times = append(times, elapsed)
+		}
+	}
+	return times
+}
+
+// runLoadProfile sends messages, cycling through eventJSONs, on the
+// profile's schedule, stopping early if ctx ends. Each message sent is passed
+// to record as good.
+func runLoadProfile(ctx context.Context, client eventPutter, eventJSONs [][]byte, profile loadProfile, record func(good bool)) {
+	times := profile.sendTimes()
+	log.Printf("Running load profile: %d messages over %s peaking at %.2f msg/s", len(times), profile.Duration(), profile.PeakRate)
+	start := time.Now()
+	for i, offset := range times {
+		select {
+		case <-ctx.Done():
+			log.Printf("%s during the load profile, sent %d of %d messages.", stopReason(ctx), i, len(times))
+			return
+		case <-time.After(time.Until(start.Add(offset))):
+		}
+		if err := sendToEventBridge(context.WithoutCancel(ctx), client, eventJSONs[i%len(eventJSONs)]); err != nil {
+			log.Printf("failed to send job message to EventBridge: %v", err)
+		} else {
+			record(true)
+		}
+	}
+	log.Printf("Load profile completed in %s", time.Since(start).Round(time.Millisecond))
+}
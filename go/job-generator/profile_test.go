@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testProfile(t *testing.T, peakRate float64, rampUp, hold, rampDown string) loadProfile {
+	t.Helper()
+	profile := loadProfile{PeakRate: peakRate, RampUp: rampUp, Hold: hold, RampDown: rampDown}
+	if err := profile.parse(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return profile
+}
+
+func TestLoadProfileRateAt(t *testing.T) {
+	profile := testProfile(t, 10, "10s", "20s", "5s")
+
+	tests := []struct {
+		elapsed  time.Duration
+		expected float64
+	}{
+		{elapsed: 0, expected: 0},
+		{elapsed: 5 * time.Second, expected: 5},
+		{elapsed: 10 * time.Second, expected: 10},
+		{elapsed: 25 * time.Second, expected: 10},
+		{elapsed: 30 * time.Second, expected: 10},
+		{elapsed: 32500 * time.Millisecond, expected: 5},
+		{elapsed: 35 * time.Second, expected: 0},
+		{elapsed: time.Minute, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.elapsed.String(), func(t *testing.T) {
+			if actual := profile.rateAt(tt.elapsed); math.Abs(actual-tt.expected) > 1e-9 {
+				t.Errorf("expected %.2f msg/s, got %.2f", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestLoadProfileSendTimes(t *testing.T) {
+	tests := []struct {
+		name     string
+		profile  loadProfile
+		expected int
+	}{
+		// Ramps send half the peak rate on average
+		{name: "Ramp, hold and ramp down", profile: testProfile(t, 10, "10s", "10s", "10s"), expected: 200},
+		{name: "Hold only", profile: testProfile(t, 4, "", "30s", ""), expected: 120},
+		{name: "Ramp up only", profile: testProfile(t, 2, "1m", "", ""), expected: 60},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			times := tt.profile.sendTimes()
+			if len(times) != tt.expected {
+				t.Fatalf("expected %d messages, got %d", tt.expected, len(times))
+			}
+			for i := 1; i < len(times); i++ {
+				if times[i] < times[i-1] {
+					t.Fatalf("expected send times in order, got %s after %s", times[i], times[i-1])
+				}
+			}
+			if last := times[len(times)-1]; last >= tt.profile.Duration() {
+				t.Errorf("expected every send within %s, got %s", tt.profile.Duration(), last)
+			}
+		})
+	}
+
+	// Sends thin out towards the start of the ramp
+	times := testProfile(t, 10, "10s", "", "").sendTimes()
+	firstHalf := 0
+	for _, offset := range times {
+		if offset < 5*time.Second {
+			firstHalf++
+		}
+	}
+	if firstHalf != 12 {
+		t.Errorf("expected a quarter of the ramp's 50 messages in its first half, got %d", firstHalf)
+	}
+}
+
+func TestReadLoadProfile(t *testing.T) {
+	tests := []struct {
+		name        string
+		contents    string
+		expectError bool
+	}{
+		{name: "Valid", contents: `{"peak_rate": 20, "ramp_up": "2m", "hold": "5m", "ramp_down": "2m"}`},
+		{name: "No rate", contents: `{"hold": "5m"}`, expectError: true},
+		{name: "Bad duration", contents: `{"peak_rate": 20, "hold": "five minutes"}`, expectError: true},
+		{name: "No duration", contents: `{"peak_rate": 20}`, expectError: true},
+		{name: "Not JSON", contents: `peak_rate: 20`, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "profile.json")
+			if err := os.WriteFile(filename, []byte(tt.contents), 0o644); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			profile, err := readLoadProfile(filename)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if profile.Duration() != 9*time.Minute {
+				t.Errorf("expected a 9m profile, got %s", profile.Duration())
+			}
+		})
+	}
+}
+
+func TestSampleLoadProfile(t *testing.T) {
+	if _, err := readLoadProfile("load_profile.json"); err != nil {
+		t.Errorf("expected the sample profile to be valid, got %v", err)
+	}
+}
+
+func TestRunLoadProfileStopped(t *testing.T) {
+	bus := &fakeBus{}
+	eventJSONs := [][]byte{[]byte(`{"job_type":"report_generation","message":{"report_name":"Report","filters":"region=US"}}`)}
+	profile := testProfile(t, 10, "0s", "1h", "0s")
+
+	ctx, stop := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer stop()
+	done := make(chan struct{})
+	go func() {
+		runLoadProfile(ctx, bus, eventJSONs, profile, func(bool) {})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the load profile to stop when its context ended")
+	}
+	if sent := len(bus.details); sent == 0 || sent > 5 {
+		t.Errorf("expected the messages due before the stop to be sent, got %d", sent)
+	}
+}
+
+func TestStopReason(t *testing.T) {
+	expired, cancel := context.WithDeadline(context.Background(), time.Now())
+	defer cancel()
+	<-expired.Done()
+	if reason := stopReason(expired); reason != "Job generator has completed its runtime" {
+		t.Errorf("unexpected reason for the runtime ending: %q", reason)
+	}
+
+	stopped, stop := context.WithCancel(context.Background())
+	stop()
+	if reason := stopReason(stopped); reason != "Job generator was stopped" {
+		t.Errorf("unexpected reason for a signal: %q", reason)
+	}
+}
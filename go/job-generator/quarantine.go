@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// releaseVisibilityTimeout hides messages that fail validation for the rest
+// of a release run so each is only checked once.
+const releaseVisibilityTimeout = 60
+
+// sqsReleaser is the subset of the SQS client used to release quarantined messages
+type sqsReleaser interface {
+	sqsPeeker
+	SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
+}
+
+// releaseQuarantine moves every quarantined message that now validates back
+// onto jobsTodoURL, returning how many were released and how many were kept
+// because they are still invalid.
+func releaseQuarantine(ctx context.Context, queues sqsReleaser, quarantineURL, jobsTodoURL string) (int, int, error) {
+	released, kept := 0, 0
+	for {
+		output, err := queues.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:              aws.String(quarantineURL),
+			MaxNumberOfMessages:   10,
+			VisibilityTimeout:     releaseVisibilityTimeout,
+			MessageAttributeNames: []string{"All"},
+		})
+		if err != nil {
+			return released, kept, fmt.Errorf("failed to receive from %s: %w", quarantineURL, err)
+		}
+		if len(output.Messages) == 0 {
+			return released, kept, nil
+		}
+
+		for _, message := range output.Messages {
+			if err := joblib.ValidateQuarantined([]byte(aws.ToString(message.Body))); err != nil {
+				log.Printf("keeping quarantined message %s: %v", aws.ToString(message.MessageId), err)
+				kept++
+				continue
+			}
+
+			if _, err := queues.SendMessage(ctx, &sqs.SendMessageInput{
+				QueueUrl:          aws.String(jobsTodoURL),
+				MessageBody:       message.Body,
+				MessageAttributes: releasedAttributes(message.MessageAttributes),
+			}); err != nil {
+				return released, kept, fmt.Errorf("failed to release message %s: %w", aws.ToString(message.MessageId), err)
+			}
+			if _, err := queues.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+				QueueUrl:      aws.String(quarantineURL),
+				ReceiptHandle: message.ReceiptHandle,
+			}); err != nil {
+				log.Printf("released message %s but failed to delete it from quarantine: %v", aws.ToString(message.MessageId), err)
+			}
+			log.Printf("Released quarantined message %s", aws.ToString(message.MessageId))
+			released++
+		}
+	}
+}
+
+// releasedAttributes drops the quarantine bookkeeping from a message's
+// attributes, keeping the rest such as the traceparent.
+func releasedAttributes(attributes map[string]types.MessageAttributeValue) map[string]types.MessageAttributeValue {
+	kept := map[string]types.MessageAttributeValue{}
+	for name, value := range attributes {
+		if !strings.HasPrefix(name, "quarantine_") {
+			kept[name] = value
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return kept
+}
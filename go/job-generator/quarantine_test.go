@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// fakeQuarantine holds quarantined messages, hiding each once received as a
+// visibility timeout would
+type fakeQuarantine struct {
+	messages []sqstypes.Message
+	received map[string]bool
+	deleted  map[string]bool
+	sent     []*sqs.SendMessageInput
+}
+
+func (f *fakeQuarantine) ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+	var visible []sqstypes.Message
+	for _, message := range f.messages {
+		handle := aws.ToString(message.ReceiptHandle)
+		if !f.received[handle] && !f.deleted[handle] && len(visible) < int(params.MaxNumberOfMessages) {
+			f.received[handle] = true
+			visible = append(visible, message)
+		}
+	}
+	return &sqs.ReceiveMessageOutput{Messages: visible}, nil
+}
+
+func (f *fakeQuarantine) DeleteMessage(ctx context.Context, params *sqs.DeleteMessageInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error) {
+	f.deleted[aws.ToString(params.ReceiptHandle)] = true
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+func (f *fakeQuarantine) SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	f.sent = append(f.sent, params)
+	return &sqs.SendMessageOutput{MessageId: aws.String("released")}, nil
+}
+
+func TestReleaseQuarantine(t *testing.T) {
+	const (
+		quarantine = "http://localhost:4566/000000000000/jobs-quarantine"
+		jobsTodo   = "http://localhost:4566/000000000000/jobs-todo"
+		valid      = `{"originalmessage": {"job_type": "data_cleanup", "message": {"target_table": "users", "retention": 30}}, "id": "%d", "status": "NEW"}`
+		invalid    = `{"originalmessage": {"job_type": "data_cleanup", "message": {"retention": 30}}, "id": "%d", "status": "NEW"}`
+	)
+
+	queue := &fakeQuarantine{received: map[string]bool{}, deleted: map[string]bool{}}
+	for i := 0; i < 15; i++ {
+		body := fmt.Sprintf(valid, i)
+		if i%5 == 0 {
+			body = fmt.Sprintf(invalid, i)
+		}
+		queue.messages = append(queue.messages, sqstypes.Message{
+			MessageId:     aws.String(fmt.Sprintf("m-%d", i)),
+			ReceiptHandle: aws.String(fmt.Sprintf("h-%d", i)),
+			Body:          aws.String(body),
+			MessageAttributes: map[string]sqstypes.MessageAttributeValue{
+				joblib.QuarantineReasonAttribute: {DataType: aws.String("String"), StringValue: aws.String("received 6 times (max 5)")},
+				joblib.TraceparentAttribute:      {DataType: aws.String("String"), StringValue: aws.String("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")},
+			},
+		})
+	}
+
+	released, kept, err := releaseQuarantine(context.Background(), queue, quarantine, jobsTodo)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if released != 12 || kept != 3 {
+		t.Errorf("expected 12 released and 3 kept, got %d and %d", released, kept)
+	}
+	if len(queue.sent) != 12 || len(queue.deleted) != 12 {
+		t.Errorf("expected 12 messages moved, got %d sent and %d deleted", len(queue.sent), len(queue.deleted))
+	}
+	for _, handle := range []string{"h-0", "h-5", "h-10"} {
+		if queue.deleted[handle] {
+			t.Errorf("expected invalid message %s to stay quarantined", handle)
+		}
+	}
+	for _, input := range queue.sent {
+		if aws.ToString(input.QueueUrl) != jobsTodo {
+			t.Errorf("expected release to %s, got %s", jobsTodo, aws.ToString(input.QueueUrl))
+		}
+		if _, ok := input.MessageAttributes[joblib.QuarantineReasonAttribute]; ok {
+			t.Errorf("expected quarantine attributes to be dropped, got %v", input.MessageAttributes)
+		}
+		if _, ok := input.MessageAttributes[joblib.TraceparentAttribute]; !ok {
+			t.Errorf("expected the traceparent attribute to be kept, got %v", input.MessageAttributes)
+		}
+	}
+}
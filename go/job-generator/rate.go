@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// rateSummary describes the generator's output since the run started, and
+// its rate since the previous summary.
+type rateSummary struct {
+	Sent int
+	Good int
+	Bad  int
+	Rate float64 // messages per second since the previous summary
+}
+
+func (s rateSummary) String() string {
+	return fmt.Sprintf("Sent %d messages (%d good, %d bad), currently %.2f messages/s", s.Sent, s.Good, s.Bad, s.Rate)
+}
+
+// rateReporter accumulates the messages sent for periodic summaries.
+type rateReporter struct {
+	mu         sync.Mutex
+	clock      joblib.Clock
+	good, bad  int
+	sinceLast  int
+	lastReport time.Time
+}
+
+func newRateReporter(clock joblib.Clock) *rateReporter {
+	return &rateReporter{clock: clock, lastReport: clock.Now()}
+}
+
+// record counts a message sent to EventBridge.
+func (r *rateReporter) record(good bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if good {
+		r.good++
+	} else {
+		r.bad++
+	}
+	r.sinceLast++
+}
+
+// totals returns the good and bad messages sent so far, without starting a
+// new rate interval.
+func (r *rateReporter) totals() (good, bad int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.good, r.bad
+}
+
+// summary returns the totals so far and the rate since the previous summary,
+// starting a new rate interval.
+func (r *rateReporter) summary() rateSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.clock.Now()
+	summary := rateSummary{Sent: r.good + r.bad, Good: r.good, Bad: r.bad}
+	if elapsed := now.Sub(r.lastReport); elapsed > 0 {
+		summary.Rate = float64(r.sinceLast) / elapsed.Seconds()
+	}
+	r.sinceLast, r.lastReport = 0, now
+	return summary
+}
+
+// reportRates calls report with a summary on every tick until ctx is
+// cancelled.
+func reportRates(ctx context.Context, reporter *rateReporter, ticks <-chan time.Time, report func(rateSummary)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticks:
+			report(reporter.summary())
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// movableClock returns whatever time the test last set
+type movableClock struct{ now time.Time }
+
+func (c *movableClock) Now() time.Time { return c.now }
+
+func TestRateReporterSummary(t *testing.T) {
+	clock := &movableClock{now: time.Date(2025, 8, 30, 12, 0, 0, 0, time.UTC)}
+	reporter := newRateReporter(clock)
+
+	for i := 0; i < 8; i++ {
+		reporter.record(i%4 != 0)
+	}
+	clock.now = clock.now.Add(4 * time.Second)
+	expected := rateSummary{Sent: 8, Good: 6, Bad: 2, Rate: 2}
+	if summary := reporter.summary(); summary != expected {
+		t.Errorf("expected %+v, got %+v", expected, summary)
+	}
+
+	// The rate covers only the interval since the previous summary
+	reporter.record(true)
+	clock.now = clock.now.Add(10 * time.Second)
+	expected = rateSummary{Sent: 9, Good: 7, Bad: 2, Rate: 0.1}
+	if summary := reporter.summary(); summary != expected {
+		t.Errorf("expected %+v, got %+v", expected, summary)
+	}
+
+	if summary := reporter.summary(); summary.Rate != 0 {
+		t.Errorf("expected no rate over an empty interval, got %v", summary.Rate)
+	}
+}
+
+func TestReportRates(t *testing.T) {
+	clock := &movableClock{now: time.Date(2025, 8, 30, 12, 0, 0, 0, time.UTC)}
+	reporter := newRateReporter(clock)
+	ticks := make(chan time.Time)
+	reports := make(chan rateSummary)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		reportRates(ctx, reporter, ticks, func(summary rateSummary) { reports <- summary })
+		close(done)
+	}()
+
+	for i := 1; i <= 3; i++ {
+		reporter.record(true)
+		clock.now = clock.now.Add(time.Second)
+		ticks <- clock.now
+		if summary := <-reports; summary.Sent != i || summary.Rate != 1 {
+			t.Errorf("tick %d: expected %d sent at 1 message/s, got %+v", i, i, summary)
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("expected reporting to stop once cancelled")
+	}
+}
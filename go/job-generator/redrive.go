@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// redriveResult counts what a redrive run did with each dead letter.
+type redriveResult struct {
+	Redriven int // sent back to jobs-todo
+	Expired  int // past their TTL, skipped or purged
+	Kept     int // not an envelope, left for an operator to inspect
+}
+
+// redriveDeadLetters sends the original body of every dead-letter envelope
+// back onto jobsTodoURL. Envelopes past their ExpiresAt are skipped, and
+// deleted when purgeExpired is set, because the failures are too stale to
+// retry.
+func redriveDeadLetters(ctx context.Context, queues sqsReleaser, deadLetterURL, jobsTodoURL string, clock joblib.Clock, purgeExpired bool) (redriveResult, error) {
+	var result redriveResult
+	for {
+		output, err := queues.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(deadLetterURL),
+			MaxNumberOfMessages: 10,
+			VisibilityTimeout:   releaseVisibilityTimeout,
+		})
+		if err != nil {
+			return result, fmt.Errorf("failed to receive from %s: %w", deadLetterURL, err)
+		}
+		if len(output.Messages) == 0 {
+			return result, nil
+		}
+
+		for _, message := range output.Messages {
+			id := aws.ToString(message.MessageId)
+			envelope, err := joblib.ParseDeadLetterEnvelope([]byte(aws.ToString(message.Body)))
+			if err != nil {
+				log.Printf("keeping dead letter %s: %v", id, err)
+				result.Kept++
+				continue
+			}
+
+			if envelope.Expired(clock.Now()) {
+				result.Expired++
+				if !purgeExpired {
+					log.Printf("skipping dead letter %s, expired at %s", id, envelope.ExpiresAt)
+					continue
+				}
+				if _, err := queues.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+					QueueUrl:      aws.String(deadLetterURL),
+					ReceiptHandle: message.ReceiptHandle,
+				}); err != nil {
+					log.Printf("failed to purge expired dead letter %s: %v", id, err)
+					continue
+				}
+				log.Printf("Purged dead letter %s, expired at %s", id, envelope.ExpiresAt)
+				continue
+			}
+
+			original, err := envelope.RecoverOriginal()
+			if err != nil {
+				log.Printf("keeping dead letter %s: %v", id, err)
+				result.Kept++
+				continue
+			}
+			if _, err := queues.SendMessage(ctx, &sqs.SendMessageInput{
+				QueueUrl:    aws.String(jobsTodoURL),
+				MessageBody: aws.String(string(original)),
+			}); err != nil {
+				return result, fmt.Errorf("failed to redrive dead letter %s: %w", id, err)
+			}
+			if _, err := queues.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+				QueueUrl:      aws.String(deadLetterURL),
+				ReceiptHandle: message.ReceiptHandle,
+			}); err != nil {
+				log.Printf("redrove dead letter %s but failed to delete it: %v", id, err)
+			}
+			log.Printf("Redrove dead letter %s", id)
+			result.Redriven++
+		}
+	}
+}
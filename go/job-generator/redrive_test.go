@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+func TestRedriveDeadLetters(t *testing.T) {
+	const (
+		deadLetters = "http://localhost:4566/000000000000/dead-letter-queue"
+		jobsTodo    = "http://localhost:4566/000000000000/jobs-todo"
+		original    = `{"job_type":"report_generation","message":{"report_name":"Sales Report","filters":"region=US"}}`
+	)
+	now := time.Date(2025, 8, 30, 12, 0, 0, 0, time.UTC)
+	ttls := map[joblib.JobType]time.Duration{joblib.ReportGeneration: time.Hour}
+
+	envelope := func(t *testing.T, failedAt time.Time, jobType joblib.JobType) string {
+		e := joblib.DeadLetterEnvelope{
+			OriginalBody: original,
+			Reason:       "report service unavailable",
+			Stage:        joblib.StageExecute,
+			Timestamp:    failedAt.Format(time.RFC3339),
+		}
+		data, err := json.Marshal(e.WithExpiry(jobType, ttls))
+		if err != nil {
+			t.Fatalf("failed to marshal envelope: %v", err)
+		}
+		return string(data)
+	}
+
+	tests := []struct {
+		purge bool
+	}{
+		{purge: false},
+		{purge: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("purge %v", tt.purge), func(t *testing.T) {
+			bodies := map[string]string{
+				"fresh":   envelope(t, now.Add(-30*time.Minute), joblib.ReportGeneration),
+				"expired": envelope(t, now.Add(-2*time.Hour), joblib.ReportGeneration),
+				"no-ttl":  envelope(t, now.Add(-48*time.Hour), joblib.DataCleanup),
+				"raw":     original,
+			}
+			queue := &fakeQuarantine{received: map[string]bool{}, deleted: map[string]bool{}}
+			for name, body := range bodies {
+				queue.messages = append(queue.messages, sqstypes.Message{
+					MessageId:     aws.String(name),
+					ReceiptHandle: aws.String(name),
+					Body:          aws.String(body),
+				})
+			}
+
+			result, err := redriveDeadLetters(context.Background(), queue, deadLetters, jobsTodo, fixedClock{now: now}, tt.purge)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expected := redriveResult{Redriven: 2, Expired: 1, Kept: 1}
+			if result != expected {
+				t.Errorf("expected %+v, got %+v", expected, result)
+			}
+			if len(queue.sent) != 2 {
+				t.Fatalf("expected 2 messages redriven, got %d", len(queue.sent))
+			}
+			for _, input := range queue.sent {
+				if aws.ToString(input.QueueUrl) != jobsTodo || aws.ToString(input.MessageBody) != original {
+					t.Errorf("expected the original body redriven to %s, got %s to %s", jobsTodo, aws.ToString(input.MessageBody), aws.ToString(input.QueueUrl))
+				}
+			}
+			if !queue.deleted["fresh"] || !queue.deleted["no-ttl"] || queue.deleted["raw"] {
+				t.Errorf("expected only redriven messages and purged ones deleted, got %v", queue.deleted)
+			}
+			if queue.deleted["expired"] != tt.purge {
+				t.Errorf("expected expired envelope deleted to be %v, got %v", tt.purge, queue.deleted["expired"])
+			}
+		})
+	}
+}
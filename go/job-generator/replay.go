@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// replayInput returns the job message to replay, read from stdin when arg
+// is "-" and taken from arg itself otherwise.
+func replayInput(arg string, stdin io.Reader) ([]byte, error) {
+	if arg == "-" {
+		return io.ReadAll(stdin)
+	}
+	return []byte(strings.TrimSpace(arg)), nil
+}
+
+// replayEvent runs a single JobMessage through the ingester's parse and
+// enrich steps without AWS and returns the indented enriched payload.
+func replayEvent(input []byte, clock joblib.Clock) ([]byte, error) {
+	if _, _, _, err := joblib.ParseJob(input); err != nil {
+		return nil, fmt.Errorf("failed to parse or validate job: %w", err)
+	}
+	payload, err := joblib.Enrich(input, "local-replay", clock, trace.SpanFromContext(context.Background()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to enrich job: %w", err)
+	}
+	payload.CorrelationID = joblib.MessageCorrelationID(input)
+	return json.MarshalIndent(payload, "", "  ")
+}
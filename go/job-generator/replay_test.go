@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// fixedClock always returns the same time
+type fixedClock struct{ now time.Time }
+
+func (c fixedClock) Now() time.Time { return c.now }
+
+func TestReplayEvent(t *testing.T) {
+	clock := fixedClock{now: time.Date(2025, 8, 30, 12, 0, 0, 0, time.UTC)}
+
+	tests := []struct {
+		name        string
+		input       string
+		expectError bool
+	}{
+		{name: "Valid job", input: `{"job_type":"data_cleanup","message":{"target_table":"users","retention":30}}`},
+		{name: "Invalid job", input: `{"job_type":"data_cleanup","message":{"retention":30}}`, expectError: true},
+		{name: "Unknown job type", input: `{"job_type":"unknown","message":{}}`, expectError: true},
+		{name: "Not JSON", input: `not json`, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := replayEvent([]byte(tt.input), clock)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var payload joblib.EnrichedPayload
+			if err := json.Unmarshal(output, &payload); err != nil {
+				t.Fatalf("failed to unmarshal payload: %v", err)
+			}
+			if payload.Status != joblib.StatusNew || payload.ID != "local-replay" || payload.Timestamp != "2025-08-30T12:00:00Z" {
+				t.Errorf("unexpected enriched payload %+v", payload)
+			}
+			if payload.TraceContext != "" {
+				t.Errorf("expected no trace context, got %s", payload.TraceContext)
+			}
+		})
+	}
+}
+
+func TestReplayInput(t *testing.T) {
+	const job = `{"job_type":"data_cleanup","message":{"target_table":"users","retention":30}}`
+
+	fromArg, err := replayInput(" "+job+"\n", strings.NewReader("ignored"))
+	if err != nil || string(fromArg) != job {
+		t.Errorf("expected %s from the argument, got %s, %v", job, fromArg, err)
+	}
+	fromStdin, err := replayInput("-", strings.NewReader(job))
+	if err != nil || string(fromStdin) != job {
+		t.Errorf("expected %s from stdin, got %s, %v", job, fromStdin, err)
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// maxEventDetailBytes is EventBridge's limit on the size of an event entry
+const maxEventDetailBytes = 256 * 1024
+
+// errEventTooLarge is returned for events over maxEventDetailBytes, which are
+// skipped rather than sent for EventBridge to reject.
+var errEventTooLarge = errors.New("event exceeds the EventBridge size limit")
+
+// checkEventSize rejects an event detail too large for EventBridge.
+func checkEventSize(eventJSON []byte) error {
+	if len(eventJSON) > maxEventDetailBytes {
+		return fmt.Errorf("%w: %d bytes, maximum %d", errEventTooLarge, len(eventJSON), maxEventDetailBytes)
+	}
+	return nil
+}
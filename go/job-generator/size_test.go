@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+func TestSendOversizedEvent(t *testing.T) {
+	eventFor := func(t *testing.T, reportNameBytes int) []byte {
+		message, err := json.Marshal(map[string]string{
+			"report_name": strings.Repeat("x", reportNameBytes),
+			"filters":     "region=US",
+		})
+		if err != nil {
+			t.Fatalf("failed to marshal message: %v", err)
+		}
+		eventJSON, err := json.Marshal(joblib.JobMessage{JobType: string(joblib.ReportGeneration), Message: message})
+		if err != nil {
+			t.Fatalf("failed to marshal job message: %v", err)
+		}
+		return eventJSON
+	}
+
+	tests := []struct {
+		name            string
+		reportNameBytes int
+		expectSkipped   bool
+	}{
+		{name: "Typical message", reportNameBytes: 12},
+		{name: "Just under the limit", reportNameBytes: maxEventDetailBytes - 200},
+		{name: "Oversized message", reportNameBytes: maxEventDetailBytes, expectSkipped: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := &fakePipeline{}
+			err := sendToEventBridge(context.Background(), bus, eventFor(t, tt.reportNameBytes))
+			if tt.expectSkipped {
+				if !errors.Is(err, errEventTooLarge) {
+					t.Errorf("expected an oversized event error, got %v", err)
+				}
+				if bus.detail != "" {
+					t.Errorf("expected the oversized event not to be sent")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if bus.detail == "" {
+				t.Errorf("expected the event to be sent")
+			}
+		})
+	}
+}
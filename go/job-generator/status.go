@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// generatorStats is what /stats reports about the run so far.
+type generatorStats struct {
+	Sent          int     `json:"sent"`
+	Good          int     `json:"good"`
+	Bad           int     `json:"bad"`
+	UptimeSeconds float64 `json:"uptime_seconds"`
+}
+
+// statusServer answers health checks and reports what a long-running
+// generator has sent, for orchestrators and operators.
+type statusServer struct {
+	rates   *rateReporter
+	clock   joblib.Clock
+	started time.Time
+	running atomic.Bool // whether the send loop is running
+}
+
+func newStatusServer(rates *rateReporter, clock joblib.Clock) *statusServer {
+	return &statusServer{rates: rates, clock: clock, started: clock.Now()}
+}
+
+// handler serves /healthz, 200 while the send loop is running and 503
+// otherwise, and /stats as JSON.
+func (s *statusServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		if !s.running.Load() {
+			http.Error(w, "not sending", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("GET /stats", func(w http.ResponseWriter, r *http.Request) {
+		good, bad := s.rates.totals()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(generatorStats{
+			Sent:          good + bad,
+			Good:          good,
+			Bad:           bad,
+			UptimeSeconds: s.clock.Now().Sub(s.started).Seconds(),
+		})
+	})
+	return mux
+}
+
+// serveStatus serves status on addr, e.g. ":8080", in the background,
+// returning the address it listens on and a func that shuts the server down,
+// letting requests in progress finish.
+func serveStatus(addr string, status *statusServer) (net.Addr, func(), error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	server := &http.Server{Handler: status.handler(), ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("status server stopped: %v", err)
+		}
+	}()
+
+	shutdown := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			log.Printf("failed to shut down the status server: %v", err)
+		}
+	}
+	return listener.Addr(), shutdown, nil
+}
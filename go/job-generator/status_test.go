@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStatusServer(t *testing.T) {
+	clock := &movableClock{now: time.Date(2025, 8, 30, 12, 0, 0, 0, time.UTC)}
+	rates := newRateReporter(clock)
+	status := newStatusServer(rates, clock)
+	handler := status.handler()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+
+	if code := get("/healthz").Code; code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 before the send loop starts, got %d", code)
+	}
+	status.running.Store(true)
+	if code := get("/healthz").Code; code != http.StatusOK {
+		t.Errorf("expected 200 while the send loop runs, got %d", code)
+	}
+
+	for i := 0; i < 5; i++ {
+		rates.record(i != 2)
+	}
+	clock.now = clock.now.Add(90 * time.Second)
+	response := get("/stats")
+	var stats generatorStats
+	if err := json.Unmarshal(response.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("expected JSON stats, got %s: %v", response.Body, err)
+	}
+	expected := generatorStats{Sent: 5, Good: 4, Bad: 1, UptimeSeconds: 90}
+	if stats != expected {
+		t.Errorf("expected %+v, got %+v", expected, stats)
+	}
+	// Reading the stats doesn't disturb the rate interval
+	if summary := rates.summary(); summary.Rate != 5.0/90 {
+		t.Errorf("expected the rate over the whole run, got %v", summary.Rate)
+	}
+
+	status.running.Store(false)
+	if code := get("/healthz").Code; code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 once the send loop stops, got %d", code)
+	}
+}
+
+func TestServeStatus(t *testing.T) {
+	status := newStatusServer(newRateReporter(&movableClock{}), &movableClock{})
+	status.running.Store(true)
+	addr, shutdown, err := serveStatus("127.0.0.1:0", status)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	response, err := http.Get("http://" + addr.String() + "/healthz")
+	if err != nil {
+		t.Fatalf("expected the status server to answer, got %v", err)
+	}
+	body, _ := io.ReadAll(response.Body)
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK || string(body) != "ok\n" {
+		t.Errorf("expected a healthy generator, got %d %q", response.StatusCode, body)
+	}
+
+	shutdown()
+	if _, err := http.Get("http://" + addr.String() + "/healthz"); err == nil {
+		t.Errorf("expected the status server to stop listening once shut down")
+	}
+}
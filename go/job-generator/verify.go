@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// sqsPeeker is the subset of the SQS client used to look for the probe
+type sqsPeeker interface {
+	ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error)
+	DeleteMessage(ctx context.Context, params *sqs.DeleteMessageInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error)
+}
+
+// probeMessage builds a valid job tagged with marker so it can be recognised
+// wherever it ends up.
+func probeMessage(marker string) joblib.JobMessage {
+	return joblib.JobMessage{
+		JobType: string(joblib.DataCleanup),
+		Message: json.RawMessage(`{"target_table":"wiring_probe","retention":1}`),
+		Fixture: marker,
+	}
+}
+
+// verifyWiring sends a probe event to EventBridge and polls queueURLs until
+// one of them holds the probe, returning that queue's URL. The probe is
+// deleted once found. Other messages are left visible for their consumers.
+func verifyWiring(ctx context.Context, events eventPutter, queues sqsPeeker, queueURLs []string, timeout, pollInterval time.Duration) (string, error) {
+	marker := fmt.Sprintf("wiring-probe-%d", time.Now().UnixNano())
+	eventJSON, err := json.Marshal(probeMessage(marker))
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal probe: %w", err)
+	}
+	if err := sendToEventBridge(ctx, events, eventJSON); err != nil {
+		return "", fmt.Errorf("failed to send probe to EventBridge: %w", err)
+	}
+	log.Printf("Sent wiring probe %s, waiting up to %s for it to arrive", marker, timeout)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		for _, queueURL := range queueURLs {
+			found, err := findProbe(ctx, queues, queueURL, marker)
+			if err != nil {
+				log.Printf("failed to poll %s: %v", queueURL, err)
+				continue
+			}
+			if found {
+				return queueURL, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("probe %s did not reach %s within %s", marker, strings.Join(queueURLs, " or "), timeout)
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// findProbe reports whether the probe is on queueURL, deleting it if so.
+func findProbe(ctx context.Context, queues sqsPeeker, queueURL, marker string) (bool, error) {
+	output, err := queues.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(queueURL),
+		MaxNumberOfMessages: 10,
+		VisibilityTimeout:   0, // leave other messages visible to the services
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, message := range output.Messages {
+		if !strings.Contains(aws.ToString(message.Body), marker) {
+			continue
+		}
+		if _, err := queues.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+			QueueUrl:      aws.String(queueURL),
+			ReceiptHandle: message.ReceiptHandle,
+		}); err != nil {
+			log.Printf("failed to delete probe from %s: %v", queueURL, err)
+		}
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// fakePipeline delivers events put on EventBridge to a queue after a number
+// of polls, standing in for the rule, ingester and queues
+type fakePipeline struct {
+	deliverTo  string // queue the probe arrives on, empty if the rule is miswired
+	afterPolls int    // receives before the probe shows up
+
+	detail  string
+	polls   int
+	deleted []string
+}
+
+func (f *fakePipeline) PutEvents(ctx context.Context, params *eventbridge.PutEventsInput, optFns ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error) {
+	f.detail = aws.ToString(params.Entries[0].Detail)
+	return &eventbridge.PutEventsOutput{}, nil
+}
+
+func (f *fakePipeline) ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+	queueURL := aws.ToString(params.QueueUrl)
+	f.polls++
+	messages := []sqstypes.Message{{Body: aws.String(`{"id":"other"}`), ReceiptHandle: aws.String("other")}}
+	if queueURL == f.deliverTo && f.polls > f.afterPolls {
+		messages = append(messages, sqstypes.Message{Body: aws.String(f.detail), ReceiptHandle: aws.String("probe")})
+	}
+	return &sqs.ReceiveMessageOutput{Messages: messages}, nil
+}
+
+func (f *fakePipeline) DeleteMessage(ctx context.Context, params *sqs.DeleteMessageInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error) {
+	f.deleted = append(f.deleted, aws.ToString(params.ReceiptHandle))
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+func TestVerifyWiring(t *testing.T) {
+	const (
+		jobsTodo   = "http://localhost:4566/000000000000/jobs-todo"
+		deadLetter = "http://localhost:4566/000000000000/dead-letter-queue"
+	)
+
+	tests := []struct {
+		name        string
+		deliverTo   string
+		afterPolls  int
+		expectQueue string
+		expectError bool
+	}{
+		{name: "Probe reaches jobs-todo", deliverTo: jobsTodo, afterPolls: 3, expectQueue: jobsTodo},
+		{name: "Probe dead-lettered", deliverTo: deadLetter, expectQueue: deadLetter},
+		{name: "Rule miswired", deliverTo: "", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipeline := &fakePipeline{deliverTo: tt.deliverTo, afterPolls: tt.afterPolls}
+
+			queueURL, err := verifyWiring(context.Background(), pipeline, pipeline, []string{jobsTodo, deadLetter}, 200*time.Millisecond, 5*time.Millisecond)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected an error but got none")
+				}
+				if len(pipeline.deleted) != 0 {
+					t.Errorf("expected no messages deleted, got %v", pipeline.deleted)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if queueURL != tt.expectQueue {
+				t.Errorf("expected probe on %s, got %s", tt.expectQueue, queueURL)
+			}
+			if len(pipeline.deleted) != 1 || pipeline.deleted[0] != "probe" {
+				t.Errorf("expected only the probe to be deleted, got %v", pipeline.deleted)
+			}
+		})
+	}
+}
+
+func TestProbeMessage(t *testing.T) {
+	probe := probeMessage("wiring-probe-1")
+	eventJSON, err := json.Marshal(probe)
+	if err != nil {
+		t.Fatalf("failed to marshal probe: %v", err)
+	}
+	if _, _, _, err := joblib.ParseJob(eventJSON); err != nil {
+		t.Errorf("expected the probe to be a valid job: %v", err)
+	}
+	if !strings.Contains(string(eventJSON), "wiring-probe-1") {
+		t.Errorf("expected the marker in the probe, got %s", eventJSON)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// jobWeights picks good messages so each job type is sent in proportion to
+// its weight, e.g. {"report_generation": 70, "data_cleanup": 20}. Job types
+// without a weight are never sent.
+type jobWeights struct {
+	types      []string
+	cumulative []float64 // running total of the weights, in types order
+	byType     map[string][]int
+}
+
+// readJobWeights reads a job type to weight mapping from a JSON file and
+// resolves it against the good messages.
+func readJobWeights(filename string, messages []joblib.JobMessage) (*jobWeights, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var weights map[string]float64
+	if err := json.Unmarshal(data, &weights); err != nil {
+		return nil, fmt.Errorf("invalid job weights: %w", err)
+	}
+	return newJobWeights(weights, messages)
+}
+
+// newJobWeights checks every weighted job type has a message to send and
+// the weights add up to more than zero.
+func newJobWeights(weights map[string]float64, messages []joblib.JobMessage) (*jobWeights, error) {
+	w := &jobWeights{byType: map[string][]int{}}
+	for i, message := range messages {
+		w.byType[message.JobType] = append(w.byType[message.JobType], i)
+	}
+
+	for jobType := range weights {
+		w.types = append(w.types, jobType)
+	}
+	sort.Strings(w.types) // a stable order so seeded draws are reproducible
+
+	total := 0.0
+	for _, jobType := range w.types {
+		weight := weights[jobType]
+		if weight < 0 {
+			return nil, fmt.Errorf("weight for %s must not be negative, got %v", jobType, weight)
+		}
+		if weight > 0 && len(w.byType[jobType]) == 0 {
+			return nil, fmt.Errorf("no good messages of job type %s to send", jobType)
+		}
+		total += weight
+		w.cumulative = append(w.cumulative, total)
+	}
+	if total <= 0 {
+		return nil, fmt.Errorf("job weights must add up to more than 0")
+	}
+	return w, nil
+}
+
+// pick returns the index of the next good message to send: a job type drawn
+// by weight, then one of that type's messages at random.
+func (w *jobWeights) pick(float64n func() float64, intn func(int) int) int {
+	total := w.cumulative[len(w.cumulative)-1]
+	draw := float64n() * total
+	chosen := sort.Search(len(w.cumulative), func(i int) bool { return w.cumulative[i] > draw })
+	if chosen == len(w.cumulative) {
+		chosen--
+	}
+	indexes := w.byType[w.types[chosen]]
+	return indexes[intn(len(indexes))]
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+func TestJobWeightsPick(t *testing.T) {
+	messages := []joblib.JobMessage{
+		{JobType: "report_generation"},
+		{JobType: "data_cleanup"},
+		{JobType: "report_generation"},
+		{JobType: "long_running_job"},
+		{JobType: "user_onboarding"},
+	}
+	weights, err := newJobWeights(map[string]float64{"report_generation": 70, "data_cleanup": 20, "long_running_job": 10}, messages)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	random := rand.New(rand.NewSource(1))
+	const draws = 10000
+	counts := map[string]int{}
+	picked := map[int]bool{}
+	for i := 0; i < draws; i++ {
+		index := weights.pick(random.Float64, random.Intn)
+		counts[messages[index].JobType]++
+		picked[index] = true
+	}
+
+	for jobType, expected := range map[string]float64{"report_generation": 0.7, "data_cleanup": 0.2, "long_running_job": 0.1, "user_onboarding": 0} {
+		if actual := float64(counts[jobType]) / draws; math.Abs(actual-expected) > 0.02 {
+			t.Errorf("expected %s %.0f%% of the time, got %.1f%%", jobType, expected*100, actual*100)
+		}
+	}
+	if !picked[0] || !picked[2] {
+		t.Errorf("expected both report_generation messages to be sent, got %v", picked)
+	}
+}
+
+func TestJobWeightsInvalid(t *testing.T) {
+	messages := []joblib.JobMessage{{JobType: "report_generation"}}
+
+	tests := []struct {
+		name     string
+		weights  map[string]float64
+		expected string
+	}{
+		{name: "Negative", weights: map[string]float64{"report_generation": -1}, expected: "must not be negative"},
+		{name: "No messages of a weighted type", weights: map[string]float64{"report_generation": 1, "data_cleanup": 1}, expected: "no good messages of job type data_cleanup"},
+		{name: "All zero", weights: map[string]float64{"report_generation": 0}, expected: "add up to more than 0"},
+		{name: "Empty", weights: map[string]float64{}, expected: "add up to more than 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newJobWeights(tt.weights, messages); err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected an error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestReadJobWeightsExample(t *testing.T) {
+	messages, err := readMessages("good_jobs.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := readJobWeights("job_weights.json", messages); err != nil {
+		t.Errorf("expected the example weights to match good_jobs.json, got %v", err)
+	}
+}
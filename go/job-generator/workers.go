@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// messageSource builds the generator's next event: the good messages in
+// turn, or drawn by weight, with bad messages mixed in at badRate.
+type messageSource struct {
+	goodMessages, badMessages []joblib.JobMessage
+	goodJobs, badJobs         string // fixture names, for tagging
+	tagFixtures, allowBad     bool
+	badRate                   float64
+	weights                   *jobWeights
+	index                     int
+}
+
+// next returns the JSON of the next event to send and whether it is a bad message.
+func (s *messageSource) next() ([]byte, bool, error) {
+	i := s.index % len(s.goodMessages)
+	s.index++
+	if s.weights != nil {
+		i = s.weights.pick(rng.Float64, rng.Intn)
+	}
+	jobMessage := s.goodMessages[i]
+
+	randomiseMessageParameters(&jobMessage)
+	if s.tagFixtures {
+		tagFixture(&jobMessage, s.goodJobs, i)
+	}
+
+	// Randomly pick a good or bad message
+	randomIndex, bad := pickBadMessage(s.badMessages, s.allowBad, s.badRate, rng.Float64, rng.Intn)
+	if bad {
+		badMessage := s.badMessages[randomIndex]
+		if s.tagFixtures {
+			tagFixture(&badMessage, s.badJobs, randomIndex)
+		}
+		log.Printf("Sending a bad message: %v", badMessage)
+		eventJSON, err := json.Marshal(badMessage)
+		return eventJSON, true, err
+	}
+	log.Printf("Sending a good message: %v", jobMessage)
+	eventJSON, err := json.Marshal(jobMessage)
+	return eventJSON, false, err
+}
+
+// validateWorkers checks the -workers and -rate flags. A rate is needed to
+// pace more than one worker, otherwise the generator sleeps between sends.
+func validateWorkers(workers int, rate float64) error {
+	if workers < 1 {
+		return fmt.Errorf("-workers must be at least 1, got %d", workers)
+	}
+	if rate < 0 {
+		return fmt.Errorf("-rate must not be negative, got %v", rate)
+	}
+	if workers > 1 && rate == 0 {
+		return errors.New("-workers above 1 needs a -rate to pace them")
+	}
+	return nil
+}
+
+// generatedEvent is an event handed from the pacing loop to a worker.
+type generatedEvent struct {
+	json []byte
+	bad  bool
+}
+
+// sendConcurrently builds an event each tick and hands them in batches of
+// batchSize to one of workers goroutines sending to EventBridge, until ctx
+// ends. A batch is handed out early at each flush, or when another event
+// would take it past the PutEvents size limit. Once ctx ends it stops
+// building events, hands out the batch built so far and waits for the
+// workers to send those already handed out, returning how many were sent.
+func sendConcurrently(ctx context.Context, client eventPutter, next func() ([]byte, bool, error), workers int, ticks <-chan time.Time, batchSize int, flushes <-chan time.Time, record func(good bool)) int {
+	// Sends in progress when ctx ends are finished rather than cut off
+	sendCtx := context.WithoutCancel(ctx)
+	batches := make(chan []generatedEvent)
+	var sent int
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				eventJSONs := make([][]byte, len(batch))
+				for i, event := range batch {
+					eventJSONs[i] = event.json
+				}
+				for i, err := range putEvents(sendCtx, client, eventJSONs) {
+					switch {
+					case errors.Is(err, errEventTooLarge):
+						log.Printf("skipping oversized job message: %v", err)
+					case errors.Is(err, errEventRejected):
+						log.Printf("WARNING: %v", err)
+					case err != nil:
+						log.Printf("failed to send job message to EventBridge: %v", err)
+					default:
+						record(!batch[i].bad)
+						mu.Lock()
+						sent++
+						mu.Unlock()
+					}
+				}
+			}
+		}()
+	}
+
+	var batch []generatedEvent
+	batchBytes := 0
+	// handOut gives the batch to a worker, reporting false if ctx ended first
+	handOut := func() bool {
+		if len(batch) == 0 {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case batches <- batch:
+			batch, batchBytes = nil, 0
+			return true
+		}
+	}
+
+pace:
+	for {
+		select {
+		case <-ctx.Done():
+			break pace
+		case <-flushes:
+			if !handOut() {
+				break pace
+			}
+			continue
+		case <-ticks:
+		}
+		eventJSON, bad, err := next()
+		if err != nil {
+			log.Printf("failed to marshal job message: %v", err)
+			continue
+		}
+		if batchBytes+entrySize(eventJSON) > maxEventDetailBytes && !handOut() {
+			break pace
+		}
+		batch = append(batch, generatedEvent{json: eventJSON, bad: bad})
+		batchBytes += entrySize(eventJSON)
+		if len(batch) >= batchSize && !handOut() {
+			break pace
+		}
+	}
+
+	// Drain: send the events batched so far and let the workers finish the
+	// batches they already hold
+	if len(batch) > 0 {
+		batches <- batch
+	}
+	close(batches)
+	wg.Wait()
+	return sent
+}
+
+// sendWithPauses sends events to EventBridge one at a time, sleeping for
+// pause after each, until ctx ends, returning how many were sent. ctx is
+// checked before every send and cuts a sleep short, so a run stops at its
+// deadline rather than after the messages it would have sent past it.
+func sendWithPauses(ctx context.Context, client eventPutter, next func() ([]byte, bool, error), pause func() time.Duration, record func(good bool)) int {
+	sent := 0
+	for ctx.Err() == nil {
+		eventJSON, bad, err := next()
+		if err != nil {
+			log.Printf("failed to marshal job message: %v", err)
+			continue
+		}
+
+		// Finish the send even if the generator is stopping
+		err = sendToEventBridge(context.WithoutCancel(ctx), client, eventJSON)
+		switch {
+		case errors.Is(err, errEventTooLarge):
+			log.Printf("skipping oversized job message: %v", err)
+		case errors.Is(err, errEventRejected):
+			log.Printf("WARNING: %v", err)
+		case err != nil:
+			log.Printf("failed to send job message to EventBridge: %v", err)
+		default:
+			record(!bad)
+			sent++
+		}
+
+		sleepDuration := pause()
+		log.Printf("Sleeping for %v before sending the next message...", sleepDuration)
+		select {
+		case <-ctx.Done():
+		case <-time.After(sleepDuration):
+		}
+	}
+	return sent
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// fakeBus records the events it is sent, holding each send until release is
+// closed so in-flight sends can be observed
+type fakeBus struct {
+	mu       sync.Mutex
+	details  []string
+	inFlight int32
+	maxPeak  int32
+	release  chan struct{}
+}
+
+func (f *fakeBus) PutEvents(ctx context.Context, params *eventbridge.PutEventsInput, optFns ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error) {
+	n := atomic.AddInt32(&f.inFlight, 1)
+	for {
+		peak := atomic.LoadInt32(&f.maxPeak)
+		if n <= peak || atomic.CompareAndSwapInt32(&f.maxPeak, peak, n) {
+			break
+		}
+	}
+	if f.release != nil {
+		<-f.release
+	}
+	atomic.AddInt32(&f.inFlight, -1)
+	f.mu.Lock()
+	f.details = append(f.details, aws.ToString(params.Entries[0].Detail))
+	f.mu.Unlock()
+	return &eventbridge.PutEventsOutput{}, nil
+}
+
+// countingSource numbers the events it builds
+func countingSource() func() ([]byte, bool, error) {
+	n := 0
+	return func() ([]byte, bool, error) {
+		n++
+		return []byte(fmt.Sprintf(`{"job_type":"report_generation","message":{"report_name":"Report %d","filters":"region=US"}}`, n)), n%4 == 0, nil
+	}
+}
+
+func TestSendConcurrently(t *testing.T) {
+	for _, workers := range []int{1, 3} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			bus := &fakeBus{release: make(chan struct{})}
+			ticks := make(chan time.Time)
+			ctx, stop := context.WithCancel(context.Background())
+			var good, bad int32
+			record := func(isGood bool) {
+				if isGood {
+					atomic.AddInt32(&good, 1)
+				} else {
+					atomic.AddInt32(&bad, 1)
+				}
+			}
+
+			done := make(chan int)
+			go func() { done <- sendConcurrently(ctx, bus, countingSource(), workers, ticks, 1, nil, record) }()
+
+			// Each tick hands an event to a worker, blocking once they are all busy
+			for i := 0; i < workers; i++ {
+				ticks <- time.Now()
+			}
+			for atomic.LoadInt32(&bus.inFlight) < int32(workers) {
+				time.Sleep(time.Millisecond)
+			}
+
+			// Stopping waits for the in-flight sends to drain
+			stop()
+			select {
+			case sent := <-done:
+				t.Fatalf("expected the generator to wait for in-flight sends, returned after %d", sent)
+			case <-time.After(20 * time.Millisecond):
+			}
+			close(bus.release)
+			if sent := <-done; sent != workers {
+				t.Errorf("expected %d messages sent, got %d", workers, sent)
+			}
+
+			if len(bus.details) != workers || bus.maxPeak != int32(workers) {
+				t.Errorf("expected %d concurrent sends, got %d peaking at %d", workers, len(bus.details), bus.maxPeak)
+			}
+			if good+bad != int32(workers) {
+				t.Errorf("expected %d messages recorded, got %d good and %d bad", workers, good, bad)
+			}
+		})
+	}
+}
+
+func TestSendConcurrentlyPacedByTicks(t *testing.T) {
+	bus := &fakeBus{}
+	ticks := make(chan time.Time)
+	ctx, stop := context.WithCancel(context.Background())
+	done := make(chan int)
+	go func() { done <- sendConcurrently(ctx, bus, countingSource(), 4, ticks, 1, nil, func(bool) {}) }()
+
+	for i := 0; i < 10; i++ {
+		ticks <- time.Now()
+	}
+	stop()
+	if sent := <-done; sent != 10 {
+		t.Errorf("expected one message per tick, got %d", sent)
+	}
+}
+
+func TestMessageSourceInTurn(t *testing.T) {
+	goodMessages, err := readMessages("good_jobs.json")
+	if err != nil {
+		t.Fatalf("failed to read fixtures: %v", err)
+	}
+	source := &messageSource{goodMessages: goodMessages, goodJobs: "good_jobs.json", tagFixtures: true}
+
+	for round := 0; round < 2; round++ {
+		for i, expected := range goodMessages {
+			eventJSON, bad, err := source.next()
+			if err != nil || bad {
+				t.Fatalf("expected a good message, got bad %v, err %v", bad, err)
+			}
+			var sent joblib.JobMessage
+			if err := json.Unmarshal(eventJSON, &sent); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if sent.JobType != expected.JobType || sent.Fixture != fmt.Sprintf("good_jobs.json#%d", i) {
+				t.Errorf("expected fixture %d (%s) in turn, got %s from %s", i, expected.JobType, sent.JobType, sent.Fixture)
+			}
+		}
+	}
+}
+
+func TestValidateWorkers(t *testing.T) {
+	tests := []struct {
+		workers     int
+		rate        float64
+		expectError bool
+	}{
+		{workers: 1, rate: 0},
+		{workers: 1, rate: 5},
+		{workers: 8, rate: 50},
+		{workers: 0, rate: 5, expectError: true},
+		{workers: 4, rate: 0, expectError: true},
+		{workers: 1, rate: -1, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d workers at %v/s", tt.workers, tt.rate), func(t *testing.T) {
+			if err := validateWorkers(tt.workers, tt.rate); (err != nil) != tt.expectError {
+				t.Errorf("expected error %v, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
+
+func TestSendWithPausesStopsMidSleep(t *testing.T) {
+	bus := &fakeBus{}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	sent := sendWithPauses(ctx, bus, countingSource(), func() time.Duration { return 10 * time.Second }, func(bool) {})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the deadline to cut the sleep short, ran for %s", elapsed)
+	}
+	if sent != 1 || len(bus.details) != 1 {
+		t.Errorf("expected only the message before the deadline sent, got %d", len(bus.details))
+	}
+}
+
+func TestSendWithPausesChecksBeforeSending(t *testing.T) {
+	bus := &fakeBus{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if sent := sendWithPauses(ctx, bus, countingSource(), func() time.Duration { return 0 }, func(bool) {}); sent != 0 || len(bus.details) != 0 {
+		t.Errorf("expected nothing sent once the run has ended, got %d", len(bus.details))
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// ageRoute sends messages at least MinAge old to QueueURL, e.g. a cold path
+// batch processor, while fresher messages keep their usual queue.
+type ageRoute struct {
+	MinAge   time.Duration
+	QueueURL string
+}
+
+// ageRoutes are the configured age routes, oldest threshold first
+var ageRoutes []ageRoute
+
+// parseAgeRoutes parses a comma separated list of min_age=queue_url pairs,
+// e.g. "5m=http://.../warm,1h=http://.../cold". Malformed entries are
+// logged and skipped.
+func parseAgeRoutes(routes string) []ageRoute {
+	var parsed []ageRoute
+	for _, route := range strings.Split(routes, ",") {
+		route = strings.TrimSpace(route)
+		if route == "" {
+			continue
+		}
+		minAge, queueURL, ok := strings.Cut(route, "=")
+		age, err := time.ParseDuration(strings.TrimSpace(minAge))
+		queueURL = strings.TrimSpace(queueURL)
+		if !ok || err != nil || age <= 0 || queueURL == "" {
+			log.Printf("ignoring malformed age route: %q", route)
+			continue
+		}
+		parsed = append(parsed, ageRoute{MinAge: age, QueueURL: queueURL})
+	}
+	sort.Slice(parsed, func(i, j int) bool { return parsed[i].MinAge > parsed[j].MinAge })
+	return parsed
+}
+
+// messageAge returns how long ago a message was produced, from the
+// EventBridge event time or failing that the SQS sent timestamp.
+func messageAge(eventTime string, attributes map[string]string, now time.Time) (time.Duration, bool) {
+	if produced, err := time.Parse(time.RFC3339, eventTime); err == nil {
+		return now.Sub(produced), true
+	}
+	if value, ok := attributes[joblib.SQSSentTimestamp]; ok {
+		if sentMillis, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return now.Sub(time.UnixMilli(sentMillis)), true
+		}
+	}
+	return 0, false
+}
+
+// queueURLForAge returns the queue of the oldest age route a message of age
+// qualifies for, or false when it is fresher than every threshold.
+func queueURLForAge(age time.Duration) (string, bool) {
+	for _, route := range ageRoutes {
+		if age >= route.MinAge {
+			return route.QueueURL, true
+		}
+	}
+	return "", false
+}
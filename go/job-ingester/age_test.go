@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestParseAgeRoutes(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []ageRoute
+	}{
+		{name: "Empty", input: ""},
+		{
+			name:  "Sorted oldest first",
+			input: "5m=http://localstack:4566/000000000000/warm, 1h=http://localstack:4566/000000000000/cold",
+			expected: []ageRoute{
+				{MinAge: time.Hour, QueueURL: "http://localstack:4566/000000000000/cold"},
+				{MinAge: 5 * time.Minute, QueueURL: "http://localstack:4566/000000000000/warm"},
+			},
+		},
+		{
+			name:  "Malformed entries are skipped",
+			input: "soon=http://localstack:4566/000000000000/x,5m=,-1m=http://localstack:4566/000000000000/y,10m=http://localstack:4566/000000000000/cold",
+			expected: []ageRoute{
+				{MinAge: 10 * time.Minute, QueueURL: "http://localstack:4566/000000000000/cold"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := parseAgeRoutes(tt.input); !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestMessageAge(t *testing.T) {
+	now := time.Date(2025, 8, 30, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		eventTime  string
+		attributes map[string]string
+		expected   time.Duration
+		expectOK   bool
+	}{
+		{name: "Event time", eventTime: "2025-08-30T11:50:00Z", expected: 10 * time.Minute, expectOK: true},
+		{name: "SQS sent timestamp", attributes: map[string]string{"SentTimestamp": "1756554900000"}, expected: 5 * time.Minute, expectOK: true},
+		{name: "Event time preferred", eventTime: "2025-08-30T11:59:00Z", attributes: map[string]string{"SentTimestamp": "1756554900000"}, expected: time.Minute, expectOK: true},
+		{name: "Unknown", eventTime: "yesterday"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			age, ok := messageAge(tt.eventTime, tt.attributes, now)
+			if ok != tt.expectOK || age != tt.expected {
+				t.Errorf("expected %s, %v, got %s, %v", tt.expected, tt.expectOK, age, ok)
+			}
+		})
+	}
+}
+
+func TestAgeBasedRouting(t *testing.T) {
+	const (
+		warm = "http://localstack:4566/000000000000/warm"
+		cold = "http://localstack:4566/000000000000/cold"
+	)
+	previous := ageRoutes
+	ageRoutes = parseAgeRoutes("5m=" + warm + ",1h=" + cold)
+	defer func() { ageRoutes = previous }()
+
+	tests := []struct {
+		name        string
+		age         time.Duration
+		expectQueue string
+	}{
+		{name: "Fresh", age: time.Second, expectQueue: jobsTodoURL},
+		{name: "Warm", age: 10 * time.Minute, expectQueue: warm},
+		{name: "Cold", age: 2 * time.Hour, expectQueue: cold},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, fakeQueue, _, _ := withFakes(t)
+			eventTime := time.Now().Add(-tt.age).UTC().Format(time.RFC3339)
+			in.processMessage(context.Background(), events.SQSMessage{
+				MessageId: "sqs-1",
+				Body:      `{"version":"0","id":"eb-1","detail-type":"JobEvent","source":"jobs","time":"` + eventTime + `","detail":` + validJob + `}`,
+			})
+
+			if len(fakeQueue.sentTo(tt.expectQueue)) != 1 {
+				t.Errorf("expected the job on %s, got %v", tt.expectQueue, fakeQueue.sent)
+			}
+		})
+	}
+}
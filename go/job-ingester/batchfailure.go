@@ -0,0 +1,20 @@
+package main
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// reportBatchItemFailures returns failed messages to SQS in a partial batch
+// response so only they are redelivered. Pair it with FAILURE_SINK=sns to
+// leave dead-lettering to the queue's redrive policy, otherwise a failed
+// message is dead-lettered by the ingester and redelivered as well.
+var reportBatchItemFailures bool
+
+// appendBatchItemFailure adds a failed message to a partial batch response
+// when reporting batch item failures is enabled.
+func appendBatchItemFailure(failures []events.SQSBatchItemFailure, messageID string) []events.SQSBatchItemFailure {
+	if !reportBatchItemFailures {
+		return failures
+	}
+	return append(failures, events.SQSBatchItemFailure{ItemIdentifier: messageID})
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	"github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job/jobtest"
+)
+
+func TestBatchItemFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		report   bool
+		expected []events.SQSBatchItemFailure
+	}{
+		{name: "Reported", report: true, expected: []events.SQSBatchItemFailure{{ItemIdentifier: "sqs-2"}}},
+		{name: "Not reported when disabled", report: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, fakeQueue, _, _ := withFakes(t)
+			previous := reportBatchItemFailures
+			reportBatchItemFailures = tt.report
+			defer func() { reportBatchItemFailures = previous }()
+
+			var records []events.SQSMessage
+			for i, detail := range []string{validJob, `{"job_type":"unknown_job","message":{}}`, validJob} {
+				record := eventBridgeRecord(detail)
+				record.MessageId = []string{"sqs-1", "sqs-2", "sqs-3"}[i]
+				records = append(records, record)
+			}
+			response, err := in.handler(context.Background(), events.SQSEvent{Records: records})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(response.BatchItemFailures, tt.expected) {
+				t.Errorf("expected batch item failures %v, got %v", tt.expected, response.BatchItemFailures)
+			}
+			if sent := fakeQueue.sentTo(jobsTodoURL); len(sent) != 2 {
+				t.Errorf("expected the other jobs sent to jobs-todo, got %d", len(sent))
+			}
+		})
+	}
+}
+
+func TestBatchItemFailuresFromJobs(t *testing.T) {
+	in, fakeQueue, _, _ := withFakes(t)
+	previous := reportBatchItemFailures
+	reportBatchItemFailures = true
+	defer func() { reportBatchItemFailures = previous }()
+
+	valid := joblib.JobMessage{JobType: string(joblib.DataCleanup), Message: json.RawMessage(`{"target_table":"users","retention":30}`)}
+	unknown := joblib.JobMessage{JobType: "unknown_job", Message: json.RawMessage(`{}`)}
+	response, err := in.handler(context.Background(), jobtest.NewSQSEventFromJobs(valid, unknown, valid))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expected := []events.SQSBatchItemFailure{{ItemIdentifier: "sqs-2"}}; !reflect.DeepEqual(response.BatchItemFailures, expected) {
+		t.Errorf("expected batch item failures %v, got %v", expected, response.BatchItemFailures)
+	}
+	var eventIDs []string
+	for _, body := range fakeQueue.sentTo(jobsTodoURL) {
+		var payload joblib.EnrichedPayload
+		if err := json.Unmarshal([]byte(body), &payload); err != nil {
+			t.Fatalf("failed to parse enriched payload: %v", err)
+		}
+		eventIDs = append(eventIDs, payload.EventBridgeID)
+	}
+	if !reflect.DeepEqual(eventIDs, []string{"eb-1", "eb-3"}) {
+		t.Errorf("expected the valid jobs' events enriched, got %v", eventIDs)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// compressBody gzips and base64 encodes a body the way producers of large payloads do
+func compressBody(t *testing.T, body string) string {
+	t.Helper()
+	compressed, err := joblib.CompressDeadLetter(body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return strings.TrimPrefix(compressed, "gzip:")
+}
+
+func TestCompressedBody(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectQueued bool
+	}{
+		{name: "Plain JSON", body: eventBridgeRecord(validJob).Body, expectQueued: true},
+		{name: "Gzipped and base64 encoded", body: compressBody(t, eventBridgeRecord(validJob).Body), expectQueued: true},
+		{name: "Corrupt", body: compressBody(t, eventBridgeRecord(validJob).Body)[:24]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, fakeQueue, _, _ := withFakes(t)
+
+			err := in.processMessage(context.Background(), events.SQSMessage{MessageId: "sqs-1", Body: tt.body})
+			if queued := len(fakeQueue.sentTo(jobsTodoURL)) == 1; queued != tt.expectQueued {
+				t.Errorf("expected queued %v, got %v: %v", tt.expectQueued, queued, err)
+			}
+			if !tt.expectQueued {
+				if dlq := fakeQueue.deadLetters(t); len(dlq) != 1 || dlq[0].OriginalBody != tt.body {
+					t.Errorf("expected the original body dead-lettered, got %v", dlq)
+				}
+			}
+		})
+	}
+}
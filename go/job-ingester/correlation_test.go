@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+func TestCorrelationIDPropagated(t *testing.T) {
+	tests := []struct {
+		name           string
+		detail         string
+		expectedID     string
+		expectGenerate bool
+	}{
+		{name: "Caller's correlation ID", detail: `{"job_type":"data_cleanup","message":{"target_table":"users","retention":30},"correlation_id":"req-42"}`, expectedID: "req-42"},
+		{name: "Generated when absent", detail: validJob, expectGenerate: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, fakeQueue, _, recorder := withFakes(t)
+			logs := captureLogs(t)
+			if err := in.processMessage(context.Background(), eventBridgeRecord(tt.detail)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			queued := fakeQueue.sentTo(jobsTodoURL)
+			if len(queued) != 1 {
+				t.Fatalf("expected the job queued, got %v", queued)
+			}
+			var payload joblib.EnrichedPayload
+			if err := json.Unmarshal([]byte(queued[0]), &payload); err != nil {
+				t.Fatalf("expected an enriched payload, got %s: %v", queued[0], err)
+			}
+			if tt.expectGenerate {
+				if _, err := uuid.Parse(payload.CorrelationID); err != nil {
+					t.Fatalf("expected a generated UUID, got %q", payload.CorrelationID)
+				}
+			} else if payload.CorrelationID != tt.expectedID {
+				t.Errorf("expected correlation ID %q, got %q", tt.expectedID, payload.CorrelationID)
+			}
+
+			if spans := recorder.Ended(); len(spans) != 1 || spanAttributes(spans[0])["correlation.id"].AsString() != payload.CorrelationID {
+				t.Errorf("expected the span tagged with correlation.id %s", payload.CorrelationID)
+			}
+			if !strings.Contains(logs.String(), `"correlation_id":"`+payload.CorrelationID+`"`) {
+				t.Errorf("expected log lines to carry the correlation ID, got %s", logs.String())
+			}
+		})
+	}
+}
+
+func TestCorrelationIDInRejection(t *testing.T) {
+	in, _, fakeTopic, _ := withFakes(t)
+	in.processMessage(context.Background(), eventBridgeRecord(`{"job_type":"data_cleanup","message":{"retention":0},"correlation_id":"req-42"}`))
+
+	if len(fakeTopic.messages) != 1 {
+		t.Fatalf("expected 1 SNS message, got %v", fakeTopic.messages)
+	}
+	var event joblib.JobEndStateEvent
+	if err := json.Unmarshal([]byte(fakeTopic.messages[0]), &event); err != nil || event.CorrelationID != "req-42" {
+		t.Errorf("expected the rejection to echo req-42, got %s", fakeTopic.messages[0])
+	}
+}
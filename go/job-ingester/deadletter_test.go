@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestDeadLetterEnvelope(t *testing.T) {
+	tests := []struct {
+		name         string
+		detail       string
+		expectStage  string
+		expectReason string
+		expectFields string
+	}{
+		{
+			name:         "Unknown job type",
+			detail:       `{"job_type":"does_not_exist","message":{}}`,
+			expectStage:  joblib.StageParse,
+			expectReason: "failed to parse or validate job",
+		},
+		{
+			name:         "Failed validation",
+			detail:       `{"job_type":"data_cleanup","message":{"target_table":"users","retention":0}}`,
+			expectStage:  joblib.StageValidate,
+			expectReason: "failed to parse or validate job",
+			expectFields: `fields: retention was 0, target_table was "users"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, fakeQueue, _, recorder := withFakes(t)
+			previous := failureSink
+			failureSink = joblib.FailureSinkDLQ
+			defer func() { failureSink = previous }()
+
+			in.processMessage(context.Background(), eventBridgeRecord(tt.detail))
+
+			dlq := fakeQueue.deadLetters(t)
+			if len(dlq) != 1 {
+				t.Fatalf("expected 1 dead letter, got %d", len(dlq))
+			}
+			envelope := dlq[0]
+			if envelope.OriginalBody != tt.detail || envelope.Stage != tt.expectStage || !strings.HasPrefix(envelope.Reason, tt.expectReason) {
+				t.Errorf("expected the %s failure of %s, got %+v", tt.expectStage, tt.detail, envelope)
+			}
+			if tt.expectFields != "" && !strings.Contains(envelope.Reason, tt.expectFields) {
+				t.Errorf("expected the reason to say %s, got %q", tt.expectFields, envelope.Reason)
+			} else if tt.expectFields == "" && strings.Contains(envelope.Reason, "fields:") {
+				t.Errorf("expected no fields for a job that didn't parse, got %q", envelope.Reason)
+			}
+			if spans := recorder.Ended(); len(spans) != 1 || envelope.TraceID != spans[0].SpanContext().TraceID().String() {
+				t.Errorf("expected the envelope tagged with the message's trace, got %q", envelope.TraceID)
+			}
+
+			attributes := fakeQueue.sent[0].MessageAttributes
+			if stage := aws.ToString(attributes[joblib.DeadLetterStageAttribute].StringValue); stage != tt.expectStage {
+				t.Errorf("expected the %s attribute %q, got %q", joblib.DeadLetterStageAttribute, tt.expectStage, stage)
+			}
+			if reason := aws.ToString(attributes[joblib.DeadLetterReasonAttribute].StringValue); reason != envelope.ReasonAttribute() {
+				t.Errorf("expected the %s attribute %q, got %q", joblib.DeadLetterReasonAttribute, envelope.ReasonAttribute(), reason)
+			}
+		})
+	}
+}
+
+// deadLetterCounts returns jobs_deadlettered_total collected from reader, by stage
+func deadLetterCounts(t *testing.T, reader *sdkmetric.ManualReader) map[string]int64 {
+	t.Helper()
+	var metrics metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &metrics); err != nil {
+		t.Fatalf("failed to collect metrics: %v", err)
+	}
+	counts := map[string]int64{}
+	for _, scope := range metrics.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if m.Name != "jobs_deadlettered_total" || !ok {
+				continue
+			}
+			for _, point := range sum.DataPoints {
+				stage, _ := point.Attributes.Value("stage")
+				counts[stage.AsString()] += point.Value
+			}
+		}
+	}
+	return counts
+}
+
+// withFailureMetrics records dead letters and failed SNS publishes on the returned reader
+func withFailureMetrics(t *testing.T) *sdkmetric.ManualReader {
+	t.Helper()
+	reader := sdkmetric.NewManualReader()
+	previous := failureMetrics
+	metrics, err := joblib.NewFailureMetrics(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test"))
+	if err != nil {
+		t.Fatalf("failed to create failure metrics: %v", err)
+	}
+	failureMetrics = metrics
+	t.Cleanup(func() { failureMetrics = previous })
+	return reader
+}
+
+func TestDeadLettersCounted(t *testing.T) {
+	in, _, _, _ := withFakes(t)
+	reader := withFailureMetrics(t)
+
+	in.processMessage(context.Background(), eventBridgeRecord(`{"job_type":"unknown_job","message":{}}`))
+	in.processMessage(context.Background(), eventBridgeRecord(`{"job_type":"data_cleanup","message":{"retention":30}}`))
+	in.processMessage(context.Background(), eventBridgeRecord(validJob))
+
+	if counts := deadLetterCounts(t, reader); fmt.Sprint(counts) != "map[parse:1 validate:1]" {
+		t.Errorf("expected one parse and one validate dead letter counted, got %v", counts)
+	}
+}
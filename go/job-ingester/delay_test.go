@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDelayPassedToSQS(t *testing.T) {
+	tests := []struct {
+		name         string
+		detail       string
+		expectDelay  int32
+		expectReject bool
+	}{
+		{name: "No delay", detail: validJob},
+		{
+			name:        "Delayed",
+			detail:      `{"job_type":"user_onboarding","message":{"user_id":"user-001","user_name":"John Doe"},"delay":300}`,
+			expectDelay: 300,
+		},
+		{
+			name:         "Over the SQS limit",
+			detail:       `{"job_type":"user_onboarding","message":{"user_id":"user-001","user_name":"John Doe"},"delay":3600}`,
+			expectReject: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, fakeQueue, _, recorder := withFakes(t)
+
+			err := in.processMessage(context.Background(), eventBridgeRecord(tt.detail))
+			if tt.expectReject {
+				if err == nil || len(fakeQueue.sentTo(jobsTodoURL)) != 0 || len(fakeQueue.deadLetters(t)) != 1 {
+					t.Errorf("expected the job to be dead-lettered, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for _, input := range fakeQueue.sent {
+				if input.DelaySeconds != tt.expectDelay {
+					t.Errorf("expected a %d second delay, got %d", tt.expectDelay, input.DelaySeconds)
+				}
+			}
+			delay, ok := spanAttributes(recorder.Ended()[0])["sqs.delay_seconds"]
+			if ok != (tt.expectDelay > 0) || delay.AsInt64() != int64(tt.expectDelay) {
+				t.Errorf("expected sqs.delay_seconds %d on the span, got %v", tt.expectDelay, delay.AsInt64())
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+func TestMissingDetail(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "Absent", body: `{"version":"0","id":"eb-1","detail-type":"JobEvent","source":"jobs"}`},
+		{name: "Null", body: eventBridgeRecord(`null`).Body},
+		{name: "Empty object", body: eventBridgeRecord(`{}`).Body},
+		{name: "Empty object with whitespace", body: eventBridgeRecord(`{ }`).Body},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, fakeQueue, fakeTopic, recorder := withFakes(t)
+			in.processMessage(context.Background(), events.SQSMessage{MessageId: "sqs-1", Body: tt.body})
+
+			dlq := fakeQueue.deadLetters(t)
+			if len(dlq) != 1 || dlq[0].OriginalBody != tt.body {
+				t.Errorf("expected the whole event dead-lettered, got %v", dlq)
+			}
+			if len(fakeQueue.sentTo(jobsTodoURL)) != 0 {
+				t.Errorf("expected nothing sent to the jobs queue")
+			}
+			if len(fakeTopic.messages) != 1 || !strings.Contains(fakeTopic.messages[0], "missing detail") {
+				t.Errorf("expected a missing detail notification, got %v", fakeTopic.messages)
+			}
+
+			spans := recorder.Ended()
+			if len(spans) != 1 || len(spans[0].Events()) == 0 || spans[0].Events()[0].Name != "exception" {
+				t.Errorf("expected the missing detail recorded on the span")
+			}
+		})
+	}
+}
+
+func TestDetailPresent(t *testing.T) {
+	in, fakeQueue, _, _ := withFakes(t)
+	in.processMessage(context.Background(), eventBridgeRecord(validJob))
+
+	if dlq := fakeQueue.sentTo(deadletterURL); len(dlq) != 0 {
+		t.Errorf("expected a job with detail not to be dead-lettered, got %v", dlq)
+	}
+}
+
+func TestUnwrappedJob(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		expectQueue bool
+		expectError string
+	}{
+		{name: "Wrapped in an EventBridge event", body: eventBridgeRecord(validJob).Body, expectQueue: true},
+		{name: "Sent straight to the queue", body: validJob, expectQueue: true},
+		{name: "Invalid job sent straight to the queue", body: `{"job_type":"data_cleanup","message":{"retention":0}}`, expectError: "target_table is required"},
+		{name: "Neither an event nor a job", body: `{"task_name":"Data Migration"}`, expectError: "the body has no job_type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, fakeQueue, _, _ := withFakes(t)
+			err := in.processMessage(context.Background(), events.SQSMessage{MessageId: "sqs-1", Body: tt.body})
+
+			queued := fakeQueue.sentTo(jobsTodoURL)
+			if !tt.expectQueue {
+				dlq := fakeQueue.deadLetters(t)
+				if err == nil || len(queued) != 0 || len(dlq) != 1 || !strings.Contains(dlq[0].Reason, tt.expectError) {
+					t.Errorf("expected the body dead-lettered with %q, got %v", tt.expectError, dlq)
+				}
+				return
+			}
+			if err != nil || len(queued) != 1 {
+				t.Fatalf("expected the job queued, got %v and %v", err, queued)
+			}
+			var payload joblib.EnrichedPayload
+			if err := json.Unmarshal([]byte(queued[0]), &payload); err != nil {
+				t.Fatalf("expected an enriched payload, got %s: %v", queued[0], err)
+			}
+			var job joblib.JobMessage
+			if err := json.Unmarshal(payload.OriginalMessage, &job); err != nil || job.JobType != "data_cleanup" || payload.ID != "sqs-1" {
+				t.Errorf("expected data_cleanup job sqs-1 queued, got %s", queued[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"context"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// rejected describes a message the ingester turned away for the
+// notifications topic. jobType is empty when the job never parsed.
+func rejected(ctx context.Context, jobID, jobType, notification string) joblib.JobEndStateEvent {
+	return joblib.NewJobEndStateEvent(ctx, jobID, jobType, joblib.StatusRejected, notification)
+}
+
+// publishEndState publishes a job's end state to the notifications topic as
+// JSON, with its job type and status as message attributes.
+func (in *ingester) publishEndState(ctx context.Context, event joblib.JobEndStateEvent) error {
+	message, err := marshalJSON(event)
+	if err != nil {
+		return err
+	}
+	return publishToSNS(ctx, in.snsClient, snsTopicArn, string(message), joblib.FilterAttributes(event.JobType, event.Status))
+}
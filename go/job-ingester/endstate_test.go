@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+func TestRejectedEndState(t *testing.T) {
+	tests := []struct {
+		name          string
+		detail        string
+		expectJobType string
+		expectStatus  joblib.Status
+		expectError   string
+	}{
+		{name: "Invalid job", detail: `{"job_type":"data_cleanup","message":{"retention":30}}`, expectJobType: "data_cleanup", expectStatus: joblib.StatusValidationFailed, expectError: "failed to parse or validate job"},
+		{name: "Every violation listed", detail: `{"job_type":"data_cleanup","message":{"retention":0}}`, expectJobType: "data_cleanup", expectStatus: joblib.StatusValidationFailed, expectError: "target_table is required\nretention must be greater than 0"},
+		{name: "Unknown job type", detail: `{"job_type":"unknown_job","message":{}}`, expectStatus: joblib.StatusRejected, expectError: "failed to parse or validate job"},
+		{name: "Missing detail", detail: `null`, expectStatus: joblib.StatusRejected, expectError: "missing detail in EventBridge message"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, _, fakeTopic, recorder := withFakes(t)
+
+			in.processMessage(context.Background(), eventBridgeRecord(tt.detail))
+
+			if len(fakeTopic.messages) != 1 {
+				t.Fatalf("expected 1 SNS message, got %d", len(fakeTopic.messages))
+			}
+			var event joblib.JobEndStateEvent
+			if err := json.Unmarshal([]byte(fakeTopic.messages[0]), &event); err != nil {
+				t.Fatalf("expected a JSON end-state event, got %s: %v", fakeTopic.messages[0], err)
+			}
+			if event.JobID != "sqs-1" || event.JobType != tt.expectJobType || event.Status != tt.expectStatus {
+				t.Errorf("expected a %s %q job sqs-1, got %+v", tt.expectStatus, tt.expectJobType, event)
+			}
+			if expected := joblib.FilterAttributes(tt.expectJobType, tt.expectStatus); fmt.Sprint(fakeTopic.attributes[0]) != fmt.Sprint(expected) {
+				t.Errorf("expected message attributes %v, got %v", expected, fakeTopic.attributes[0])
+			}
+			if !strings.Contains(event.Error, tt.expectError) {
+				t.Errorf("expected error to contain %q, got %q", tt.expectError, event.Error)
+			}
+			if spans := recorder.Ended(); len(spans) != 1 || event.TraceID != spans[0].SpanContext().TraceID().String() {
+				t.Errorf("expected the event to carry the span's trace ID, got %q", event.TraceID)
+			}
+		})
+	}
+}
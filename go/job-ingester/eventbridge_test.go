@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+func TestEventBridgeIDRecorded(t *testing.T) {
+	tests := []struct {
+		name       string
+		message    events.SQSMessage
+		expectedID string
+	}{
+		{name: "EventBridge event", message: eventBridgeRecord(validJob), expectedID: "eb-1"},
+		{name: "Job sent straight to the queue", message: events.SQSMessage{MessageId: "sqs-1", Body: validJob}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, fakeQueue, _, recorder := withFakes(t)
+			if err := in.processMessage(context.Background(), tt.message); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			queued := fakeQueue.sentTo(jobsTodoURL)
+			if len(queued) != 1 {
+				t.Fatalf("expected the job queued, got %v", queued)
+			}
+			var payload joblib.EnrichedPayload
+			if err := json.Unmarshal([]byte(queued[0]), &payload); err != nil {
+				t.Fatalf("expected an enriched payload, got %s: %v", queued[0], err)
+			}
+			if payload.EventBridgeID != tt.expectedID {
+				t.Errorf("expected EventBridge ID %q, got %q", tt.expectedID, payload.EventBridgeID)
+			}
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 span, got %d", len(spans))
+			}
+			if id, ok := spanAttributes(spans[0])["eventbridge.event_id"]; ok != (tt.expectedID != "") || id.AsString() != tt.expectedID {
+				t.Errorf("expected the span tagged with eventbridge.event_id %q, got %q", tt.expectedID, id.AsString())
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"time"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// useEventTime timestamps enriched payloads with the EventBridge event time
+// rather than when the ingester got to them, so latency is measured from
+// when the job was produced
+var useEventTime bool
+
+// eventClock always returns the time an event was produced
+type eventClock time.Time
+
+func (c eventClock) Now() time.Time { return time.Time(c) }
+
+// enrichClock returns the clock to timestamp a job with: its event time when
+// useEventTime is enabled and the event has a valid one, otherwise now.
+func enrichClock(eventTime string) joblib.Clock {
+	if useEventTime {
+		if produced, err := time.Parse(time.RFC3339, eventTime); err == nil {
+			return eventClock(produced)
+		}
+	}
+	return joblib.SystemClock{}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+func TestEnrichWithEventTime(t *testing.T) {
+	const eventTime = "2025-08-30T12:00:00Z"
+
+	tests := []struct {
+		name        string
+		enabled     bool
+		time        string
+		expectEvent bool
+	}{
+		{name: "Event time present", enabled: true, time: eventTime, expectEvent: true},
+		{name: "Event time absent", enabled: true},
+		{name: "Event time malformed", enabled: true, time: "yesterday"},
+		{name: "Disabled", enabled: false, time: eventTime},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, fakeQueue, _, _ := withFakes(t)
+			previous := useEventTime
+			useEventTime = tt.enabled
+			defer func() { useEventTime = previous }()
+
+			body := eventBridgeRecord(validJob).Body
+			if tt.time != "" {
+				body = strings.Replace(body, `"source":"jobs",`, `"source":"jobs","time":"`+tt.time+`",`, 1)
+			}
+			before := time.Now().Add(-time.Second)
+			in.processMessage(context.Background(), events.SQSMessage{MessageId: "sqs-1", Body: body})
+
+			queued := fakeQueue.sentTo(jobsTodoURL)
+			if len(queued) != 1 {
+				t.Fatalf("expected 1 message queued, got %d", len(queued))
+			}
+			_, payload, _, err := joblib.ParseEnrichedPayload([]byte(queued[0]))
+			if err != nil {
+				t.Fatalf("failed to parse queued payload: %v", err)
+			}
+			if tt.expectEvent {
+				if payload.Timestamp != eventTime {
+					t.Errorf("expected the event time %s, got %s", eventTime, payload.Timestamp)
+				}
+				return
+			}
+			stamped, err := time.Parse(time.RFC3339, payload.Timestamp)
+			if err != nil || stamped.Before(before) {
+				t.Errorf("expected the payload timestamped now, got %s", payload.Timestamp)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// isFIFOQueue reports whether queueURL is an SQS FIFO queue, whose names
+// always end in .fifo.
+func isFIFOQueue(queueURL string) bool {
+	return strings.HasSuffix(queueURL, ".fifo")
+}
+
+// sendMessageInput builds the SendMessage request queueing an enriched
+// payload. FIFO queues order the job within groupID and drop redeliveries of
+// the same jobID, but refuse a per-message delay, so it is left to the
+// queue's own. Standard queues get the delay and no group or deduplication
+// ID.
+func sendMessageInput(queueURL, body string, attributes map[string]types.MessageAttributeValue, delay int32, groupID, jobID string) *sqs.SendMessageInput {
+	input := &sqs.SendMessageInput{
+		QueueUrl:          aws.String(queueURL),
+		MessageBody:       aws.String(body),
+		MessageAttributes: attributes,
+	}
+	if isFIFOQueue(queueURL) {
+		input.MessageGroupId = aws.String(groupID)
+		input.MessageDeduplicationId = aws.String(jobID)
+		return input
+	}
+	input.DelaySeconds = delay
+	return input
+}
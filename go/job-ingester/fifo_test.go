@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+func TestSendMessageInput(t *testing.T) {
+	tests := []struct {
+		name          string
+		queueURL      string
+		expectGroup   string
+		expectDedupID string
+		expectDelay   int32
+	}{
+		{name: "Standard queue", queueURL: "http://localstack:4566/000000000000/jobs-todo", expectDelay: 30},
+		{name: "FIFO queue", queueURL: "http://localstack:4566/000000000000/jobs-todo.fifo", expectGroup: "data_cleanup", expectDedupID: "sqs-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := sendMessageInput(tt.queueURL, validJob, nil, 30, "data_cleanup", "sqs-1")
+			if aws.ToString(input.QueueUrl) != tt.queueURL || aws.ToString(input.MessageBody) != validJob {
+				t.Errorf("expected the payload sent to %s, got %s to %s", tt.queueURL, aws.ToString(input.MessageBody), aws.ToString(input.QueueUrl))
+			}
+			if group := aws.ToString(input.MessageGroupId); group != tt.expectGroup {
+				t.Errorf("expected message group %q, got %q", tt.expectGroup, group)
+			}
+			if dedupID := aws.ToString(input.MessageDeduplicationId); dedupID != tt.expectDedupID {
+				t.Errorf("expected deduplication ID %q, got %q", tt.expectDedupID, dedupID)
+			}
+			if input.DelaySeconds != tt.expectDelay {
+				t.Errorf("expected delay %d, got %d", tt.expectDelay, input.DelaySeconds)
+			}
+		})
+	}
+}
+
+func TestJobQueuedToFIFOQueue(t *testing.T) {
+	tests := []struct {
+		name        string
+		detail      string
+		expectGroup string
+	}{
+		{name: "Grouped by job type", detail: validJob, expectGroup: "data_cleanup"},
+		{name: "Explicit group", detail: `{"job_type":"data_cleanup","message":{"target_table":"logs","retention":30},"group_id":"acme"}`, expectGroup: "acme"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, fakeQueue, _, _ := withFakes(t)
+			previous := jobsTodoURL
+			jobsTodoURL = "http://localstack:4566/000000000000/jobs-todo.fifo"
+			defer func() { jobsTodoURL = previous }()
+
+			if err := in.processMessage(context.Background(), eventBridgeRecord(tt.detail)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(fakeQueue.sent) != 1 {
+				t.Fatalf("expected 1 message sent, got %d", len(fakeQueue.sent))
+			}
+			input := fakeQueue.sent[0]
+			var payload joblib.EnrichedPayload
+			if err := json.Unmarshal([]byte(aws.ToString(input.MessageBody)), &payload); err != nil {
+				t.Fatalf("failed to unmarshal payload: %v", err)
+			}
+			if aws.ToString(input.MessageGroupId) != tt.expectGroup || aws.ToString(input.MessageDeduplicationId) != payload.ID {
+				t.Errorf("expected group %q deduplicated by job ID %s, got %q and %q", tt.expectGroup, payload.ID, aws.ToString(input.MessageGroupId), aws.ToString(input.MessageDeduplicationId))
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestLogsCarryTraceContext(t *testing.T) {
+	in, _, _, recorder := withFakes(t)
+	logs := captureLogs(t)
+
+	if err := in.processMessage(context.Background(), eventBridgeRecord(validJob)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	var processed map[string]any
+	for _, line := range bytes.Split(bytes.TrimSpace(logs.Bytes()), []byte("\n")) {
+		var fields map[string]any
+		if err := json.Unmarshal(line, &fields); err != nil {
+			t.Fatalf("expected a JSON log line, got %s: %v", line, err)
+		}
+		if fields["msg"] == "successfully processed job" {
+			processed = fields
+		}
+	}
+	if processed == nil {
+		t.Fatalf("expected the job to be logged, got %s", logs.String())
+	}
+	sc := spans[0].SpanContext()
+	if processed["trace_id"] != sc.TraceID().String() || processed["span_id"] != sc.SpanID().String() {
+		t.Errorf("expected the ProcessMessage span's trace_id and span_id, got %v", processed)
+	}
+}
@@ -1,49 +1,102 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/trace"
 )
 
+// sqsSender is the subset of the SQS client used to queue messages
+type sqsSender interface {
+	SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
+}
+
+// snsPublisher is the subset of the SNS client used to publish failures
+type snsPublisher interface {
+	Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error)
+}
+
+// ingester enriches the jobs in SQS batches, sending them on and routing
+// failures through the clients it holds.
+type ingester struct {
+	sqsClient sqsSender
+	snsClient snsPublisher
+}
+
+// newIngester creates an ingester with the SQS and SNS clients for cfg,
+// giving each call its own span when TRACE_AWS_CALLS is set.
+func newIngester(cfg aws.Config) *ingester {
+	in := &ingester{sqsClient: sqs.NewFromConfig(cfg), snsClient: sns.NewFromConfig(cfg)}
+
+	// Optionally give each SQS and SNS call its own span for finer latency attribution
+	if envBool("TRACE_AWS_CALLS", false) {
+		in.sqsClient = joblib.TracedSQS{SQSSender: in.sqsClient, Tracer: otel.Tracer("jobs")}
+		in.snsClient = joblib.TracedSNS{SNSPublisher: in.snsClient, Tracer: otel.Tracer("jobs")}
+	}
+	return in
+}
+
 var (
 	tracer        trace.Tracer
-	sqsClient     *sqs.Client
 	jobsTodoURL   string
 	deadletterURL string
-	snsClient     *sns.Client
 	snsTopicArn   string
+	failureSink   string            // where failures are routed: dlq, sns or both
+	traceCarrier  string            // where trace context is propagated: body, attributes or both
+	spanStatus    joblib.SpanStatus // sets an Ok or Error status on spans by outcome
+	jobQueueURLs  map[string]string // job type -> dedicated queue URL, falls back to jobsTodoURL
+
+	recordSQSAttributes bool
+	recordReceived      bool                      // add a "received" span event describing the raw body
+	bodyPreviewBytes    int                       // how much of the raw body the "received" event previews
+	invocations         joblib.InvocationRecorder // tag spans and logs with the Lambda request ID
+	jsonIndent          bool                      // indent outgoing JSON rather than marshalling it compactly
+	shardKeyField       string                    // job message field hashed into the shard key, empty disables sharding
+	shardCount          int                       // number of shard buckets
+	signingKey          []byte                    // HMAC key signing enriched payloads, empty disables signing
+	fieldCipher         joblib.FieldCipher        // encrypts sensitive fields before queueing, nil disables encryption
+	encryptFields       []string                  // job message fields encrypted by fieldCipher
+	recordFingerprint   bool                      // tag enriched payloads with the job's schema fingerprint
+
+	sqsSendDuration metric.Float64Histogram
+	failureMetrics  *joblib.FailureMetrics // dead letters and failed SNS publishes
 )
 
 func initTracer() func() {
-	// Create OTLP HTTP exporter
-	exporter, err := otlptracehttp.New(context.Background(),
-		otlptracehttp.WithEndpoint("otel_collector:4318"),
-		otlptracehttp.WithInsecure(),
-	)
+	// Create the span exporter, OTLP unless OTEL_TRACES_EXPORTER=stdout
+	exporter, err := joblib.NewTraceExporter(context.Background(), joblib.LoadTelemetryConfig(os.LookupEnv))
 	if err != nil {
-		log.Fatalf("failed to create OTLP exporter: %v", err)
+		log.Fatalf("failed to create trace exporter: %v", err)
 	}
 
-	// Create trace provider
+	// Create trace provider, sampling OTEL_TRACES_SAMPLER_ARG of new traces
 	tp := sdktrace.NewTracerProvider(
 		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(joblib.LoadTelemetryConfig(os.LookupEnv).Sampler()),
 		sdktrace.WithResource(resource.NewSchemaless(
 			attribute.String("service.name", "job-ingester"),
 		)),
@@ -59,88 +112,422 @@ func initTracer() func() {
 	}
 }
 
+// initMeter exports metrics such as the SQS send duration over OTLP to
+// the trace collector, or serves them on /metrics at METRICS_ADDR, returning
+// a function that flushes and shuts it down.
+func initMeter() func() {
+	reader, stopServing, err := joblib.NewMetricReader(context.Background(), joblib.LoadTelemetryConfig(os.LookupEnv))
+	if err != nil {
+		log.Fatalf("failed to create metric exporter: %v", err)
+	}
+
+	mp := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(reader),
+		sdkmetric.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", "job-ingester"),
+		)),
+	)
+	otel.SetMeterProvider(mp)
+
+	return func() {
+		if err := mp.Shutdown(context.Background()); err != nil {
+			log.Printf("failed to shut down meter provider: %v", err)
+		}
+		stopServing()
+	}
+}
+
 func init() {
-	// Load AWS configuration
-	cfg, err := config.LoadDefaultConfig(context.TODO(),
-		config.WithRegion("us-east-1"),
-		config.WithEndpointResolver(aws.EndpointResolverFunc(func(service, region string) (aws.Endpoint, error) {
-			if service == sqs.ServiceID {
-				return aws.Endpoint{URL: "http://localstack:4566"}, nil // LocalStack endpoint
-			}
-			return aws.Endpoint{}, &aws.EndpointNotFoundError{}
-		})),
+	// Log JSON lines at LOG_LEVEL (info by default) and above
+	slog.SetDefault(joblib.NewLogger(os.Stderr, joblib.ParseLogLevel(os.Getenv("LOG_LEVEL"), slog.LevelInfo)))
+
+	var err error
+	sqsSendDuration, err = otel.Meter("job-ingester").Float64Histogram("sqs_send_duration_ms",
+		metric.WithDescription("Time taken to send enriched payloads to SQS"),
+		metric.WithUnit("ms"),
 	)
 	if err != nil {
-		log.Fatalf("unable to load AWS SDK config: %v", err)
+		log.Printf("failed to create SQS send duration histogram: %v", err)
+	}
+	failureMetrics, err = joblib.NewFailureMetrics(otel.Meter("job-ingester"))
+	if err != nil {
+		log.Printf("failed to create failure counters: %v", err)
 	}
 
-	// Create SQS client
-	sqsClient = sqs.NewFromConfig(cfg)
+	// Queues, topic, region and endpoint come from the environment, defaulting to LocalStack
+	serviceConfig := joblib.LoadServiceConfig(os.LookupEnv)
 
 	// Set the jobs-todo queue URL
-	jobsTodoURL = "http://localstack:4566/000000000000/jobs-todo"
+	jobsTodoURL = serviceConfig.JobsTodoURL
 
 	// Dead letter for post mortem analysis
-	deadletterURL = "http://localstack:4566/000000000000/dead-letter-queue"
+	deadletterURL = serviceConfig.DeadLetterURL
+
+	// Set the SNS topic ARN
+	snsTopicArn = serviceConfig.SNSTopicArn
+
+	// Route failures to the dead-letter queue, SNS or both (the default)
+	failureSink = joblib.ParseFailureSink(os.Getenv("FAILURE_SINK"), joblib.FailureSinkBoth)
+
+	// Report failed messages back to SQS so only they are redelivered
+	reportBatchItemFailures = envBool("REPORT_BATCH_ITEM_FAILURES", false)
+
+	// Propagate trace context in the payload body (the default), SQS message attributes or both
+	traceCarrier = joblib.ParsePropagation(os.Getenv("TRACE_PROPAGATION"), joblib.PropagationBody)
+
+	// Optionally route job types to dedicated queues, e.g.
+	// JOB_QUEUE_ROUTES=report_generation=http://localstack:4566/000000000000/reports,data_cleanup=...
+	jobQueueURLs = parseQueueRoutes(os.Getenv("JOB_QUEUE_ROUTES"))
+
+	// Optionally route old messages to cold path queues by age, e.g.
+	// AGE_ROUTES=5m=http://localstack:4566/000000000000/jobs-cold
+	ageRoutes = parseAgeRoutes(os.Getenv("AGE_ROUTES"))
+
+	// Record SQS system attributes such as the receive count on spans
+	recordSQSAttributes = envBool("RECORD_SQS_ATTRIBUTES", true)
+
+	// Record the Lambda request ID on spans and logs
+	invocations.Record = envBool("RECORD_INVOCATION_ID", true)
+
+	// Accept job types in any casing, e.g. REPORT_GENERATION
+	joblib.NormalizeJobType = envBool("NORMALIZE_JOB_TYPE", false)
 
-	// Initialize SNS client
-	snsClient = sns.NewFromConfig(cfg)
+	// Require report filters to be key=value pairs
+	joblib.StrictFilters = envBool("STRICT_FILTERS", false)
 
-	// Set the SNS topic ARN for LocalStack
-	snsTopicArn = "arn:aws:sns:us-east-1:000000000000:job-end-state-topic"
+	// Reject cleanups below a table's minimum retention, e.g. MIN_RETENTION_DAYS=users=90,audit_log=365
+	joblib.MinRetentionDays = joblib.ParseMinRetention(os.Getenv("MIN_RETENTION_DAYS"))
+
+	// Cap long running timeouts by priority, e.g. PRIORITY_TIMEOUTS=0=60,1=300,2=900
+	if limits := joblib.ParsePriorityTimeouts(os.Getenv("PRIORITY_TIMEOUTS")); len(limits) > 0 {
+		joblib.RegisterCrossFieldValidator(joblib.LongRunning, joblib.PriorityTimeouts(limits))
+	}
+
+	// Reject batches with too many children, e.g. MAX_BATCH_CHILDREN=100
+	joblib.MaxBatchChildren = envInt("MAX_BATCH_CHILDREN", 0)
+	// Run up to BATCH_CONCURRENCY children of a batch job at once, 1 runs them in order
+	joblib.BatchConcurrency = envInt("BATCH_CONCURRENCY", 1)
+
+	// Optionally enforce a user_id format, e.g. USER_ID_PATTERN=^user_[0-9]+$
+	joblib.UserIDPattern = joblib.ParseUserIDPattern(os.Getenv("USER_ID_PATTERN"))
+
+	// Validate some job types leniently, e.g. LENIENT_JOB_TYPES=report_generation
+	joblib.LenientJobTypes = joblib.ParseJobTypes(os.Getenv("LENIENT_JOB_TYPES"))
+
+	// Ignore, warn about or reject fields a job type doesn't define
+	joblib.UnknownFields = joblib.ParseUnknownFields(os.Getenv("UNKNOWN_FIELDS"), joblib.UnknownFieldsIgnore)
+
+	// Optionally validate job messages against JSON schemas in JOB_SCHEMA_DIR
+	if err := joblib.LoadSchemasFromEnv(); err != nil {
+		log.Fatalf("unable to load job schemas: %v", err)
+	}
+
+	// Fail fast if a job type is registered without working validation
+	if err := joblib.VerifyRegistry(); err != nil {
+		log.Fatalf("job type registry is misconfigured: %v", err)
+	}
+
+	// Optionally separate parse failures from other failures for triage
+	parseErrorQueueURL = os.Getenv("PARSE_ERROR_QUEUE_URL")
+
+	// Only accept events from trusted sources, e.g. ALLOWED_SOURCES=jobs
+	allowedSources = parseAllowedSources(os.Getenv("ALLOWED_SOURCES"))
+
+	// Timestamp jobs from the EventBridge event time rather than now
+	useEventTime = envBool("USE_EVENT_TIME", false)
+
+	// Pretty-print the enriched payload and SNS messages for readability
+	jsonIndent = envBool("JSON_INDENT", false)
+
+	// Optionally shard jobs for downstream partitioning, e.g. SHARD_KEY_FIELD=user_id SHARD_COUNT=16
+	shardKeyField = os.Getenv("SHARD_KEY_FIELD")
+	shardCount = envInt("SHARD_COUNT", 16)
+
+	// Optionally record each raw body's size and a preview, for debugging deserialization
+	recordReceived = envBool("RECORD_RECEIVED_EVENT", false)
+	bodyPreviewBytes = envInt("BODY_PREVIEW_BYTES", 256)
+
+	// Set an Ok or Error span status by outcome
+	spanStatus.Record = envBool("RECORD_SPAN_STATUS", true)
+
+	// Tag payloads with the job's schema fingerprint so the processor can detect drift
+	recordFingerprint = envBool("RECORD_SCHEMA_FINGERPRINT", false)
+
+	// Optionally sign enriched payloads so the processor can detect tampering
+	signingKey = []byte(os.Getenv("HMAC_SIGNING_KEY"))
+
+	// Optionally encrypt sensitive fields at rest in the queue, e.g. ENCRYPT_FIELDS=user_id,user_name
+	fieldCipher, encryptFields, err = joblib.FieldEncryptionFromEnv()
+	if err != nil {
+		log.Fatalf("unable to configure field encryption: %v", err)
+	}
 }
 
-func handler(ctx context.Context, sqsEvent events.SQSEvent) error {
+// envBool reads a boolean environment variable, returning fallback when it is
+// unset or not a valid boolean.
+func envBool(name string, fallback bool) bool {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return fallback
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("ignoring invalid %s %q: %v", name, value, err)
+		return fallback
+	}
+	return parsed
+}
 
-	for _, message := range sqsEvent.Records {
-		processMessage(ctx, message)
+// envInt reads an integer environment variable, returning fallback when it is
+// unset or not a valid integer.
+func envInt(name string, fallback int) int {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("ignoring invalid %s %q: %v", name, value, err)
+		return fallback
 	}
+	return parsed
+}
 
-	return nil
+// marshalJSON marshals v compactly, or indented when JSON_INDENT is set.
+func marshalJSON(v any) ([]byte, error) {
+	if jsonIndent {
+		return json.MarshalIndent(v, "", "  ")
+	}
+	return json.Marshal(v)
 }
 
-func sendToDeadLetterQueue(ctx context.Context, messageBody string) {
-	_, err := sqsClient.SendMessage(ctx, &sqs.SendMessageInput{
-		QueueUrl:    aws.String(deadletterURL),
-		MessageBody: aws.String(messageBody),
-	})
+// formatJSON reformats raw JSON to match JSON_INDENT, returning it unchanged
+// when it is not valid JSON.
+func formatJSON(raw []byte) string {
+	var buf bytes.Buffer
+	var err error
+	if jsonIndent {
+		err = json.Indent(&buf, raw, "", "  ")
+	} else {
+		err = json.Compact(&buf, raw)
+	}
 	if err != nil {
-		log.Printf("failed to send message to dead-letter queue: %v", err)
+		return string(raw)
 	}
+	return buf.String()
 }
 
-func processMessage(ctx context.Context, message events.SQSMessage) {
+// describeFields lists the fields job parsed with by name, such as
+// `retention was -5, target_table was "users"`.
+func describeFields(job joblib.Job) string {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return ""
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return ""
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	described := make([]string, len(names))
+	for i, name := range names {
+		described[i] = fmt.Sprintf("%s was %s", name, fields[name])
+	}
+	return strings.Join(described, ", ")
+}
+
+// parseQueueRoutes parses a comma separated list of job_type=queue_url pairs.
+// Malformed entries are logged and skipped.
+func parseQueueRoutes(routes string) map[string]string {
+	queueURLs := map[string]string{}
+	for _, route := range strings.Split(routes, ",") {
+		route = strings.TrimSpace(route)
+		if route == "" {
+			continue
+		}
+		jobType, queueURL, ok := strings.Cut(route, "=")
+		jobType, queueURL = strings.TrimSpace(jobType), strings.TrimSpace(queueURL)
+		if !ok || jobType == "" || queueURL == "" {
+			log.Printf("ignoring malformed job queue route: %q", route)
+			continue
+		}
+		queueURLs[jobType] = queueURL
+	}
+	return queueURLs
+}
+
+// queueURLForJobType returns the queue a job type is routed to, falling back
+// to jobs-todo when the type has no dedicated queue.
+func queueURLForJobType(jobType string) string {
+	if queueURL, ok := jobQueueURLs[jobType]; ok {
+		return queueURL
+	}
+	return jobsTodoURL
+}
+
+func (in *ingester) handler(ctx context.Context, sqsEvent events.SQSEvent) (events.SQSEventResponse, error) {
+	var response events.SQSEventResponse
+	for _, message := range sqsEvent.Records {
+		if err := in.processMessage(ctx, message); err != nil {
+			response.BatchItemFailures = appendBatchItemFailure(response.BatchItemFailures, message.MessageId)
+		}
+	}
+
+	return response, nil
+}
+
+// logger returns the default logger tagged with the trace and span of ctx,
+// and the Lambda request ID to correlate lines with CloudWatch.
+func logger(ctx context.Context) *slog.Logger {
+	l := joblib.LoggerWithTrace(ctx, slog.Default())
+	if id, ok := invocations.ID(ctx); ok {
+		l = l.With(slog.String("request_id", id))
+	}
+	if id := joblib.CorrelationIDFromContext(ctx); id != "" {
+		l = l.With(slog.String("correlation_id", id))
+	}
+	return l
+}
+
+// reportFailure routes a message that failed at stage to the configured
+// failure sinks: an end-state event on the SNS topic and/or the original body,
+// wrapped with the failure, on the dead-letter queue.
+func (in *ingester) reportFailure(ctx context.Context, stage string, event joblib.JobEndStateEvent, messageBody string) {
+	if err := in.failureRouter().Report(ctx, stage, event, messageBody); err != nil {
+		logger(ctx).Error("failed to report failure", "error", err)
+	}
+}
+
+// failureRouter routes failures to the sinks FAILURE_SINK selects.
+func (in *ingester) failureRouter() joblib.FailureRouter {
+	return joblib.FailureRouter{
+		Sink:             failureSink,
+		Publish:          in.publishEndState,
+		DeadLetters:      joblib.DeadLetterQueue{Client: in.sqsClient, URL: deadletterURL, Metrics: failureMetrics},
+		ParseErrorURL:    parseErrorQueueURL,
+		ParseErrorClient: in.sqsClient,
+	}
+}
+
+// missingDetail reports whether an EventBridge detail is absent, null or an
+// empty object.
+func missingDetail(detail json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(detail)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return true
+	}
+	var fields map[string]json.RawMessage
+	return json.Unmarshal(trimmed, &fields) == nil && len(fields) == 0
+}
+
+// unwrappedJob reports whether body, which has no EventBridge detail, is
+// itself a job, as when job JSON is sent straight to the queue.
+func unwrappedJob(body []byte) bool {
+	var job joblib.JobMessage
+	return json.Unmarshal(body, &job) == nil && job.JobType != ""
+}
+
+func (in *ingester) processMessage(ctx context.Context, message events.SQSMessage) error {
 	ctx, span := tracer.Start(ctx, "ProcessMessage", trace.WithAttributes(
 		attribute.String("sqs.message.id", message.MessageId),
 	))
 	defer span.End()
+	span.SetAttributes(invocations.Attributes(ctx)...)
+	if recordSQSAttributes {
+		span.SetAttributes(joblib.SQSAttributes(message.Attributes)...)
+	}
+	if recordReceived {
+		span.AddEvent("received", trace.WithAttributes(joblib.ReceivedAttributes(message.Body, bodyPreviewBytes)...))
+	}
+
+	// Producers may gzip and base64 encode large payloads
+	body, err := joblib.DecodeBody([]byte(message.Body))
+	if err != nil {
+		spanStatus.Fail(span, err)
+		logger(ctx).Error("failed to decode message body", "error", err)
+		in.reportParseFailure(ctx, joblib.StageParse, rejected(ctx, message.MessageId, "", fmt.Sprintf("failed to decode message body: %v", err)), message.Body)
+		return err
+	}
 
 	// Parse the EventBridge message
 	var eventBridgeMessage struct {
+		ID     string          `json:"id"`
+		Time   string          `json:"time"`
+		Source string          `json:"source"`
 		Detail json.RawMessage `json:"detail"`
 	}
-	if err := json.Unmarshal([]byte(message.Body), &eventBridgeMessage); err != nil {
-		span.RecordError(err)
-		log.Printf("failed to parse EventBridge message: %v", err)
-		publishToSNS(snsClient, snsTopicArn, fmt.Sprintf("failed to parse EventBridge message: %s", string(eventBridgeMessage.Detail)))
-		sendToDeadLetterQueue(ctx, string(eventBridgeMessage.Detail))
-		return
+	if err := json.Unmarshal(body, &eventBridgeMessage); err != nil {
+		spanStatus.Fail(span, err)
+		logger(ctx).Error("failed to parse EventBridge message", "error", err)
+		in.reportParseFailure(ctx, joblib.StageParse, rejected(ctx, message.MessageId, "", fmt.Sprintf("failed to parse EventBridge message: %s", formatJSON(eventBridgeMessage.Detail))), string(eventBridgeMessage.Detail))
+		return err
+	}
+
+	// Events from unexpected sources are dead-lettered whole without being parsed as jobs
+	if !sourceAllowed(eventBridgeMessage.Source) {
+		err := fmt.Errorf("EventBridge source %q is not allowed", eventBridgeMessage.Source)
+		span.SetAttributes(attribute.String("event.source", eventBridgeMessage.Source))
+		spanStatus.Fail(span, err)
+		logger(ctx).Error("EventBridge source is not allowed", "source", eventBridgeMessage.Source, "body", message.Body)
+		in.reportFailure(ctx, joblib.StageValidate, rejected(ctx, message.MessageId, "", fmt.Sprintf("disallowed source %q in EventBridge message: %s", eventBridgeMessage.Source, formatJSON(body))), message.Body)
+		return err
 	}
 
+	// A body without a detail may be a job sent straight to the queue rather
+	// than through EventBridge, otherwise there is no job to parse and the whole
+	// event is dead-lettered
+	if missingDetail(eventBridgeMessage.Detail) {
+		if !unwrappedJob(body) {
+			err := errors.New("EventBridge event is missing detail and the body is not a job")
+			spanStatus.Fail(span, err)
+			logger(ctx).Error("EventBridge event is missing detail and the body is not a job", "body", message.Body)
+			in.reportFailure(ctx, joblib.StageValidate, rejected(ctx, message.MessageId, "", fmt.Sprintf("missing detail in EventBridge message and the body has no job_type: %s", formatJSON(body))), message.Body)
+			return err
+		}
+		span.AddEvent("unwrapped job")
+		logger(ctx).Warn("message is not an EventBridge event, parsing the whole body as the job")
+		eventBridgeMessage.ID, eventBridgeMessage.Detail = "", body
+	}
+
+	// Link the job back to the PutEvents call that produced it
+	span.SetAttributes(joblib.EventBridgeAttributes(eventBridgeMessage.ID)...)
+
+	// Every job gets a correlation ID support can search logs and end states
+	// for, the caller's if it passed one
+	correlationID := joblib.MessageCorrelationID(eventBridgeMessage.Detail)
+	if correlationID == "" {
+		correlationID = joblib.NewCorrelationID()
+	}
+	ctx = joblib.ContextWithCorrelationID(ctx, correlationID)
+	span.SetAttributes(joblib.CorrelationAttributes(ctx)...)
+
+	// Tag the span with the generator fixture in demo runs
+	span.SetAttributes(joblib.FixtureAttributes(eventBridgeMessage.Detail)...)
+
 	// Parse the message into a Job
 	job, _, jobType, err := joblib.ParseJob(eventBridgeMessage.Detail)
 	if err != nil {
 		// Add job type now if we know it
+		failedType := ""
 		if jobType != nil {
+			failedType = *jobType
 			span.SetAttributes(
 				attribute.String("job.type", *jobType),
 			)
 		}
-		span.RecordError(err)
-		log.Printf("failed to parse or validate job: %v", err)
-		publishToSNS(snsClient, snsTopicArn, fmt.Sprintf("failed to parse or validate job: %s", string(eventBridgeMessage.Detail)))
-		sendToDeadLetterQueue(ctx, string(eventBridgeMessage.Detail))
-		return
+		spanStatus.Fail(span, err)
+		logger(ctx).Error("failed to parse or validate job", "error", err)
+		reason := fmt.Sprintf("failed to parse or validate job: %s, err: %v", formatJSON(eventBridgeMessage.Detail), err)
+		// A job that failed validation still parsed, so say what its fields were
+		if job != nil {
+			reason += ", fields: " + describeFields(job)
+		}
+		in.reportParseFailure(ctx, joblib.ParseStage(err), joblib.NewJobEndStateEvent(ctx, message.MessageId, failedType, joblib.RejectedStatus(err), reason), string(eventBridgeMessage.Detail))
+		return err
 	}
 
 	// Add job type now we know it
@@ -148,54 +535,149 @@ func processMessage(ctx context.Context, message events.SQSMessage) {
 		attribute.String("job.type", *jobType),
 	)
 
-	enrichedPayload := joblib.EnrichedPayload{
-		OriginalMessage: []byte(eventBridgeMessage.Detail),
-		ID:              message.MessageId, // propogate the SQS message ID in case we need it (tracing propogation should mean we don't)
-		Timestamp:       time.Now().Format(time.RFC3339),
-		Status:          joblib.StatusNew,
-		TraceContext:    fmt.Sprintf("00-%s-%s-01", span.SpanContext().TraceID(), span.SpanContext().SpanID()),
+	// propogate the SQS message ID in case we need it (tracing propogation should mean we don't)
+	enrichedPayload, err := joblib.Enrich(eventBridgeMessage.Detail, message.MessageId, enrichClock(eventBridgeMessage.Time), span)
+	if err != nil {
+		spanStatus.Fail(span, err)
+		logger(ctx).Error("failed to enrich job", "error", err)
+		in.reportFailure(ctx, joblib.StageParse, rejected(ctx, message.MessageId, *jobType, fmt.Sprintf("failed to enrich job: %s", formatJSON(eventBridgeMessage.Detail))), string(eventBridgeMessage.Detail))
+		return err
+	}
+	enrichedPayload.CorrelationID = correlationID
+	enrichedPayload.EventBridgeID = eventBridgeMessage.ID
+	if shardKeyField != "" {
+		enrichedPayload.ShardKey = joblib.ShardKey(eventBridgeMessage.Detail, shardKeyField, shardCount)
+		span.SetAttributes(attribute.Int("job.shard_key", enrichedPayload.ShardKey))
+	}
+	if recordFingerprint {
+		enrichedPayload.SchemaFingerprint = joblib.SchemaFingerprint(job)
+	}
+
+	// Carry the job's tenant, if it has one, to the processor as baggage
+	if tenant := joblib.MessageTenant(eventBridgeMessage.Detail); tenant != "" {
+		ctx = joblib.ContextWithTenant(ctx, tenant)
+		enrichedPayload.BaggageContext = joblib.InjectBaggage(ctx)
+		span.SetAttributes(joblib.TenantAttributes(ctx)...)
+		logger(ctx).Info("job belongs to tenant", "job_id", enrichedPayload.ID, "tenant_id", tenant)
+	}
+	traceparent, tracestate := enrichedPayload.TraceContext, enrichedPayload.TraceState
+	if traceCarrier == joblib.PropagationAttributes {
+		enrichedPayload.TraceContext = ""
+		enrichedPayload.TraceState = ""
+	}
+	if fieldCipher != nil {
+		enrichedPayload.OriginalMessage, err = joblib.EncryptFields(enrichedPayload.OriginalMessage, encryptFields, fieldCipher)
+		if err != nil {
+			spanStatus.Fail(span, err)
+			logger(ctx).Error("failed to encrypt job fields", "error", err)
+			in.reportFailure(ctx, joblib.StageMarshal, rejected(ctx, message.MessageId, *jobType, fmt.Sprintf("failed to encrypt job fields: %s", formatJSON(eventBridgeMessage.Detail))), string(eventBridgeMessage.Detail))
+			return err
+		}
+	}
+	if len(signingKey) > 0 {
+		signature, err := joblib.SignPayload(enrichedPayload, signingKey)
+		if err != nil {
+			spanStatus.Fail(span, err)
+			logger(ctx).Error("failed to sign enriched payload", "error", err)
+			in.reportFailure(ctx, joblib.StageMarshal, rejected(ctx, message.MessageId, *jobType, fmt.Sprintf("failed to sign enriched payload: %s", formatJSON(eventBridgeMessage.Detail))), string(eventBridgeMessage.Detail))
+			return err
+		}
+		enrichedPayload.Signature = signature
 	}
 
 	// Marshal the enriched payload to JSON
-	enrichedPayloadJSON, err := json.Marshal(enrichedPayload)
+	enrichedPayloadJSON, err := marshalJSON(enrichedPayload)
 	if err != nil {
-		span.RecordError(err)
-		log.Printf("failed to marshal enriched payload: %v", err)
-		publishToSNS(snsClient, snsTopicArn, fmt.Sprintf("failed to marshal enriched payload: %s", string(eventBridgeMessage.Detail)))
-		sendToDeadLetterQueue(ctx, string(eventBridgeMessage.Detail))
-		return
+		spanStatus.Fail(span, err)
+		logger(ctx).Error("failed to marshal enriched payload", "error", err)
+		in.reportFailure(ctx, joblib.StageMarshal, rejected(ctx, message.MessageId, *jobType, fmt.Sprintf("failed to marshal enriched payload: %s", formatJSON(eventBridgeMessage.Detail))), string(eventBridgeMessage.Detail))
+		return err
 	}
 
-	// Send the enriched payload to the jobs-todo SQS queue
-	_, err = sqsClient.SendMessage(ctx, &sqs.SendMessageInput{
-		QueueUrl:    aws.String(jobsTodoURL),
-		MessageBody: aws.String(string(enrichedPayloadJSON)),
-	})
+	// Send the enriched payload to the queue for this job type (jobs-todo by default)
+	queueURL := queueURLForJobType(*jobType)
+	if age, ok := messageAge(eventBridgeMessage.Time, message.Attributes, time.Now()); ok && len(ageRoutes) > 0 {
+		span.SetAttributes(attribute.Float64("message.age_seconds", age.Seconds()))
+		if ageQueueURL, routed := queueURLForAge(age); routed {
+			queueURL = ageQueueURL
+		}
+	}
+	// Hold delayed jobs on the queue until they are due
+	delay := joblib.MessageDelay(eventBridgeMessage.Detail)
+	if delay > 0 {
+		span.SetAttributes(attribute.Int("sqs.delay_seconds", int(delay)))
+	}
+	// SQS would refuse an oversized payload, so dead-letter the job saying why
+	messageAttributes := joblib.TraceMessageAttributes(traceCarrier, traceparent, tracestate)
+	if err := checkMessageSize(enrichedPayloadJSON, messageAttributes); err != nil {
+		spanStatus.Fail(span, err)
+		logger(ctx).Error("enriched payload is too large to queue", "job_id", enrichedPayload.ID, "job_type", *jobType, "bytes", len(enrichedPayloadJSON), "max_bytes", maxMessageBytes)
+		in.reportFailure(ctx, joblib.StageSend, rejected(ctx, message.MessageId, *jobType, err.Error()), string(eventBridgeMessage.Detail))
+		return err
+	}
+	// FIFO queues keep each group's jobs in order, deduplicated by job ID
+	groupID := joblib.MessageGroupID(eventBridgeMessage.Detail)
+	if isFIFOQueue(queueURL) {
+		span.SetAttributes(attribute.String("sqs.message_group_id", groupID))
+		if delay > 0 {
+			logger(ctx).Warn("FIFO queues don't delay individual messages, queueing the job without its delay", "job_id", enrichedPayload.ID, "queue_url", queueURL, "delay", delay)
+		}
+	}
+	sendStart := time.Now()
+	_, err = in.sqsClient.SendMessage(ctx, sendMessageInput(queueURL, string(enrichedPayloadJSON), messageAttributes, delay, groupID, enrichedPayload.ID))
+	sendDurationMs := recordSendDuration(ctx, span, queueURL, time.Since(sendStart), err)
 	if err != nil {
 
-		span.RecordError(err)
-		log.Printf("failed to send message to jobs-todo queue: %v, message was %s", err, string(enrichedPayloadJSON))
-		publishToSNS(snsClient, snsTopicArn, fmt.Sprintf("failed to send message to jobs-todo queue: %v, message was %s", err, string(enrichedPayloadJSON)))
-		sendToDeadLetterQueue(ctx, string(eventBridgeMessage.Detail))
-		return
+		spanStatus.Fail(span, err)
+		logger(ctx).Error("failed to send message to queue", "queue_url", queueURL, "error", err, "message", string(enrichedPayloadJSON))
+		in.reportFailure(ctx, joblib.StageSend, rejected(ctx, message.MessageId, *jobType, fmt.Sprintf("failed to send message to queue %s: %v, message was %s", queueURL, err, string(enrichedPayloadJSON))), string(eventBridgeMessage.Detail))
+		return err
 	}
 
 	span.AddEvent("Message sent to jobs-todo queue", trace.WithAttributes(
 		attribute.String("message.id", enrichedPayload.ID),
 		attribute.String("message.timestamp", enrichedPayload.Timestamp),
+		attribute.String("sqs.queue.url", queueURL),
+		attribute.Float64("sqs.send_duration_ms", sendDurationMs),
 	))
 
 	// Log the enriched payload
-	log.Printf("Successfully processed job: %+v", job)
-	log.Printf("Enriched Payload: %+v", enrichedPayload)
+	spanStatus.Succeed(span)
+	logger(ctx).Info("successfully processed job", "job", job, "enriched_payload", enrichedPayload)
+	return nil
+}
+
+// recordSendDuration records how long an SQS send took on the span and the
+// send duration histogram, returning the duration in milliseconds.
+func recordSendDuration(ctx context.Context, span trace.Span, queueURL string, duration time.Duration, sendErr error) float64 {
+	durationMs := float64(duration.Microseconds()) / 1000
+	span.SetAttributes(attribute.Float64("sqs.send_duration_ms", durationMs))
+	if sqsSendDuration != nil {
+		sqsSendDuration.Record(ctx, durationMs, metric.WithAttributes(
+			attribute.String("sqs.queue.url", queueURL),
+			attribute.Bool("success", sendErr == nil),
+		))
+	}
+	return durationMs
 }
 
-func publishToSNS(snsClient *sns.Client, topicArn string, message string) error {
+// publishToSNS publishes message to topicArn with the given string message
+// attributes, which subscription filter policies can match on.
+func publishToSNS(ctx context.Context, snsClient snsPublisher, topicArn string, message string, attributes map[string]string) error {
 	input := &sns.PublishInput{
 		Message:  aws.String(message),
 		TopicArn: aws.String(topicArn),
 	}
-	_, err := snsClient.Publish(context.TODO(), input)
+	for name, value := range attributes {
+		if input.MessageAttributes == nil {
+			input.MessageAttributes = map[string]snstypes.MessageAttributeValue{}
+		}
+		input.MessageAttributes[name] = snstypes.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(value)}
+	}
+	_, err := snsClient.Publish(ctx, input)
+	if err != nil {
+		failureMetrics.SNSPublishFailed(ctx, topicArn)
+	}
 	return err
 }
 
@@ -204,6 +686,16 @@ func main() {
 	shutdown := initTracer()
 	defer shutdown()
 
+	// Export metrics alongside the traces
+	shutdownMeter := initMeter()
+	defer shutdownMeter()
+
+	// Load AWS configuration, retrying with backoff if AWS_INIT_ATTEMPTS allows
+	cfg, err := joblib.LoadAWSConfigWithRetry(context.TODO(), os.LookupEnv, joblib.LoadAWSConfig)
+	if err != nil {
+		log.Fatalf("unable to load AWS SDK config: %v", err)
+	}
+
 	// Start the Lambda handler
-	lambda.Start(handler)
+	lambda.Start(newIngester(cfg).handler)
 }
@@ -0,0 +1,606 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fakeSQS records messages instead of sending them
+type fakeSQS struct {
+	sent []*sqs.SendMessageInput
+}
+
+func (f *fakeSQS) SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	f.sent = append(f.sent, params)
+	return &sqs.SendMessageOutput{MessageId: aws.String("fake")}, nil
+}
+
+// sentTo returns the bodies sent to queueURL
+func (f *fakeSQS) sentTo(queueURL string) []string {
+	var bodies []string
+	for _, input := range f.sent {
+		if aws.ToString(input.QueueUrl) == queueURL {
+			bodies = append(bodies, aws.ToString(input.MessageBody))
+		}
+	}
+	return bodies
+}
+
+// deadLetters returns the dead-letter envelopes sent to the dead-letter queue
+func (f *fakeSQS) deadLetters(t *testing.T) []joblib.DeadLetterEnvelope {
+	t.Helper()
+	var envelopes []joblib.DeadLetterEnvelope
+	for _, body := range f.sentTo(deadletterURL) {
+		envelope, err := joblib.ParseDeadLetterEnvelope([]byte(body))
+		if err != nil {
+			t.Fatalf("expected a dead-letter envelope, got %s: %v", body, err)
+		}
+		envelopes = append(envelopes, *envelope)
+	}
+	return envelopes
+}
+
+// fakeSNS records published messages instead of publishing them
+type fakeSNS struct {
+	messages   []string
+	attributes []map[string]string // the string message attributes of each message
+}
+
+func (f *fakeSNS) Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error) {
+	f.messages = append(f.messages, aws.ToString(params.Message))
+	f.attributes = append(f.attributes, stringAttributes(params.MessageAttributes))
+	return &sns.PublishOutput{MessageId: aws.String("fake")}, nil
+}
+
+// stringAttributes returns the values of SNS message attributes
+func stringAttributes(attributes map[string]snstypes.MessageAttributeValue) map[string]string {
+	values := map[string]string{}
+	for name, attribute := range attributes {
+		values[name] = aws.ToString(attribute.StringValue)
+	}
+	return values
+}
+
+// withFakes swaps the AWS clients for fakes and the tracer for one backed by
+// a span recorder for the duration of a test
+func withFakes(t *testing.T) (*ingester, *fakeSQS, *fakeSNS, *tracetest.SpanRecorder) {
+	t.Helper()
+	previousTracer := tracer
+	fakeQueue, fakeTopic := &fakeSQS{}, &fakeSNS{}
+	recorder := tracetest.NewSpanRecorder()
+	tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	t.Cleanup(func() { tracer = previousTracer })
+	return &ingester{sqsClient: fakeQueue, snsClient: fakeTopic}, fakeQueue, fakeTopic, recorder
+}
+
+// eventBridgeRecord wraps a job message in the EventBridge envelope as
+// delivered to the ingester queue
+func eventBridgeRecord(detail string) events.SQSMessage {
+	return events.SQSMessage{
+		MessageId: "sqs-1",
+		Body:      `{"version":"0","id":"eb-1","detail-type":"JobEvent","source":"jobs","detail":` + detail + `}`,
+	}
+}
+
+// spanAttributes flattens a span's attributes into a map
+// captureLogs sends log lines to the returned buffer for the rest of the test
+func captureLogs(t *testing.T) *bytes.Buffer {
+	var logs bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(joblib.NewLogger(&logs, slog.LevelDebug))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &logs
+}
+
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+const validJob = `{"job_type":"data_cleanup","message":{"target_table":"users","retention":30}}`
+
+func TestParseQueueRoutes(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]string
+	}{
+		{
+			name:     "Empty",
+			input:    "",
+			expected: map[string]string{},
+		},
+		{
+			name:  "Multiple routes",
+			input: "report_generation=http://localstack:4566/000000000000/reports, data_cleanup=http://localstack:4566/000000000000/cleanup",
+			expected: map[string]string{
+				"report_generation": "http://localstack:4566/000000000000/reports",
+				"data_cleanup":      "http://localstack:4566/000000000000/cleanup",
+			},
+		},
+		{
+			name:  "Malformed entries are skipped",
+			input: "report_generation,=http://localstack:4566/000000000000/x,data_cleanup=,user_onboarding=http://localstack:4566/000000000000/users",
+			expected: map[string]string{
+				"user_onboarding": "http://localstack:4566/000000000000/users",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := parseQueueRoutes(tt.input)
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestQueueURLForJobType(t *testing.T) {
+	previous := jobQueueURLs
+	jobQueueURLs = map[string]string{
+		"report_generation": "http://localstack:4566/000000000000/reports",
+	}
+	defer func() { jobQueueURLs = previous }()
+
+	tests := []struct {
+		name     string
+		jobType  string
+		expected string
+	}{
+		{
+			name:     "Mapped job type",
+			jobType:  "report_generation",
+			expected: "http://localstack:4566/000000000000/reports",
+		},
+		{
+			name:     "Unmapped job type falls back to jobs-todo",
+			jobType:  "data_cleanup",
+			expected: jobsTodoURL,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := queueURLForJobType(tt.jobType); actual != tt.expected {
+				t.Errorf("expected queue %s, got %s", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	payload := joblib.EnrichedPayload{
+		OriginalMessage: json.RawMessage(`{"job_type":"data_cleanup","message":{"target_table":"users","retention":30}}`),
+		ID:              "12345",
+		Timestamp:       "2025-08-30T12:00:00Z",
+		Status:          joblib.StatusNew,
+	}
+
+	tests := []struct {
+		name         string
+		indent       bool
+		expectIndent bool
+	}{
+		{name: "Compact by default", indent: false, expectIndent: false},
+		{name: "Indented when enabled", indent: true, expectIndent: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := jsonIndent
+			jsonIndent = tt.indent
+			defer func() { jsonIndent = previous }()
+
+			data, err := marshalJSON(payload)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if indented := strings.Contains(string(data), "\n  \""); indented != tt.expectIndent {
+				t.Errorf("expected indented %v, got %s", tt.expectIndent, data)
+			}
+
+			var roundTripped joblib.EnrichedPayload
+			if err := json.Unmarshal(data, &roundTripped); err != nil {
+				t.Fatalf("failed to unmarshal output: %v", err)
+			}
+			if roundTripped.ID != payload.ID {
+				t.Errorf("expected ID %s, got %s", payload.ID, roundTripped.ID)
+			}
+		})
+	}
+}
+
+func TestFormatJSON(t *testing.T) {
+	raw := []byte(`{
+		"job_type": "data_cleanup",
+		"message": {"target_table": "users", "retention": 30}
+	}`)
+
+	tests := []struct {
+		name     string
+		indent   bool
+		input    []byte
+		expected string
+	}{
+		{
+			name:     "Compact",
+			indent:   false,
+			input:    raw,
+			expected: `{"job_type":"data_cleanup","message":{"target_table":"users","retention":30}}`,
+		},
+		{
+			name:     "Indented",
+			indent:   true,
+			input:    []byte(`{"job_type":"data_cleanup","message":{"retention":30}}`),
+			expected: "{\n  \"job_type\": \"data_cleanup\",\n  \"message\": {\n    \"retention\": 30\n  }\n}",
+		},
+		{
+			name:     "Invalid JSON is returned unchanged",
+			indent:   false,
+			input:    []byte(`not json`),
+			expected: `not json`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := jsonIndent
+			jsonIndent = tt.indent
+			defer func() { jsonIndent = previous }()
+
+			if actual := formatJSON(tt.input); actual != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSendDurationRecorded(t *testing.T) {
+	in, fakeQueue, _, recorder := withFakes(t)
+
+	in.processMessage(context.Background(), eventBridgeRecord(validJob))
+
+	if len(fakeQueue.sentTo(jobsTodoURL)) != 1 {
+		t.Fatalf("expected the job to be sent to jobs-todo")
+	}
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	duration, ok := spanAttributes(spans[0])["sqs.send_duration_ms"]
+	if !ok {
+		t.Fatalf("expected sqs.send_duration_ms attribute on span")
+	}
+	if duration.AsFloat64() < 0 {
+		t.Errorf("expected a non-negative duration, got %v", duration.AsFloat64())
+	}
+}
+
+func TestFailureSink(t *testing.T) {
+	tests := []struct {
+		sink      string
+		expectSNS int
+		expectDLQ int
+	}{
+		{sink: joblib.FailureSinkDLQ, expectSNS: 0, expectDLQ: 1},
+		{sink: joblib.FailureSinkSNS, expectSNS: 1, expectDLQ: 0},
+		{sink: joblib.FailureSinkBoth, expectSNS: 1, expectDLQ: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sink, func(t *testing.T) {
+			in, fakeQueue, fakeTopic, _ := withFakes(t)
+			previous := failureSink
+			failureSink = tt.sink
+			defer func() { failureSink = previous }()
+
+			in.processMessage(context.Background(), eventBridgeRecord(`{"job_type":"unknown_job","message":{}}`))
+
+			if len(fakeTopic.messages) != tt.expectSNS {
+				t.Errorf("expected %d SNS messages, got %d: %v", tt.expectSNS, len(fakeTopic.messages), fakeTopic.messages)
+			}
+			if dlq := fakeQueue.sentTo(deadletterURL); len(dlq) != tt.expectDLQ {
+				t.Errorf("expected %d dead-lettered messages, got %d: %v", tt.expectDLQ, len(dlq), dlq)
+			}
+		})
+	}
+}
+
+func TestShardKeyEnriched(t *testing.T) {
+	const onboardingJob = `{"job_type":"user_onboarding","message":{"user_id":"user-001","user_name":"John Doe"}}`
+
+	previousField, previousCount := shardKeyField, shardCount
+	shardKeyField, shardCount = "user_id", 8
+	defer func() { shardKeyField, shardCount = previousField, previousCount }()
+
+	expected := joblib.ShardKey([]byte(onboardingJob), "user_id", 8)
+	for i := 0; i < 3; i++ {
+		in, fakeQueue, _, _ := withFakes(t)
+		in.processMessage(context.Background(), eventBridgeRecord(onboardingJob))
+
+		sent := fakeQueue.sentTo(jobsTodoURL)
+		if len(sent) != 1 {
+			t.Fatalf("expected the job to be sent to jobs-todo, got %d messages", len(sent))
+		}
+		var payload joblib.EnrichedPayload
+		if err := json.Unmarshal([]byte(sent[0]), &payload); err != nil {
+			t.Fatalf("failed to unmarshal payload: %v", err)
+		}
+		if payload.ShardKey != expected {
+			t.Errorf("expected shard key %d, got %d", expected, payload.ShardKey)
+		}
+	}
+}
+
+func TestInvocationIDRecorded(t *testing.T) {
+	in, _, _, recorder := withFakes(t)
+
+	logs := captureLogs(t)
+
+	ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: "req-1"})
+	if _, err := in.handler(ctx, events.SQSEvent{Records: []events.SQSMessage{eventBridgeRecord(validJob)}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if id := spanAttributes(spans[0])["faas.invocation_id"]; id.AsString() != "req-1" {
+		t.Errorf("expected faas.invocation_id req-1, got %q", id.AsString())
+	}
+	if !strings.Contains(logs.String(), `"request_id":"req-1"`) {
+		t.Errorf("expected logs tagged with the request ID, got %s", logs.String())
+	}
+}
+
+func TestEnrichedPayloadSigned(t *testing.T) {
+	in, fakeQueue, _, _ := withFakes(t)
+	previous := signingKey
+	signingKey = []byte("demo-secret")
+	defer func() { signingKey = previous }()
+
+	in.processMessage(context.Background(), eventBridgeRecord(validJob))
+
+	sent := fakeQueue.sentTo(jobsTodoURL)
+	if len(sent) != 1 {
+		t.Fatalf("expected the job to be sent to jobs-todo, got %d messages", len(sent))
+	}
+	var payload joblib.EnrichedPayload
+	if err := json.Unmarshal([]byte(sent[0]), &payload); err != nil {
+		t.Fatalf("failed to unmarshal payload: %v", err)
+	}
+	if err := joblib.VerifySignature(payload, signingKey); err != nil {
+		t.Errorf("expected a valid signature, got %v", err)
+	}
+}
+
+func TestSensitiveFieldsEncrypted(t *testing.T) {
+	const onboardingJob = `{"job_type":"user_onboarding","message":{"user_id":"user-001","user_name":"John Doe"}}`
+
+	in, fakeQueue, _, _ := withFakes(t)
+	c, err := joblib.NewAESCipher([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	previousCipher, previousFields := fieldCipher, encryptFields
+	fieldCipher, encryptFields = c, []string{"user_id", "user_name"}
+	defer func() { fieldCipher, encryptFields = previousCipher, previousFields }()
+
+	in.processMessage(context.Background(), eventBridgeRecord(onboardingJob))
+
+	sent := fakeQueue.sentTo(jobsTodoURL)
+	if len(sent) != 1 {
+		t.Fatalf("expected the job to be sent to jobs-todo, got %d messages", len(sent))
+	}
+	if strings.Contains(sent[0], "user-001") || strings.Contains(sent[0], "John Doe") {
+		t.Errorf("expected sensitive fields to be encrypted, got %s", sent[0])
+	}
+
+	var payload joblib.EnrichedPayload
+	if err := json.Unmarshal([]byte(sent[0]), &payload); err != nil {
+		t.Fatalf("failed to unmarshal payload: %v", err)
+	}
+	decrypted, err := joblib.DecryptFields(payload.OriginalMessage, encryptFields, c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	job, _, _, err := joblib.ParseJob(decrypted)
+	if err != nil {
+		t.Fatalf("failed to parse decrypted message: %v", err)
+	}
+	if onboarding, ok := job.(joblib.UserOnboardingJob); !ok || onboarding.UserID != "user-001" || onboarding.UserName != "John Doe" {
+		t.Errorf("expected the original fields after decryption, got %+v", job)
+	}
+}
+
+func TestTraceContextAsMessageAttributes(t *testing.T) {
+	tests := []struct {
+		carrier         string
+		expectBody      bool
+		expectAttribute bool
+	}{
+		{carrier: joblib.PropagationBody, expectBody: true},
+		{carrier: joblib.PropagationAttributes, expectAttribute: true},
+		{carrier: joblib.PropagationBoth, expectBody: true, expectAttribute: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.carrier, func(t *testing.T) {
+			in, fakeQueue, _, recorder := withFakes(t)
+			previous := traceCarrier
+			traceCarrier = tt.carrier
+			defer func() { traceCarrier = previous }()
+
+			in.processMessage(context.Background(), eventBridgeRecord(validJob))
+
+			if len(fakeQueue.sent) != 1 {
+				t.Fatalf("expected 1 message sent, got %d", len(fakeQueue.sent))
+			}
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 span, got %d", len(spans))
+			}
+			expected := fmt.Sprintf("00-%s-%s-01", spans[0].SpanContext().TraceID(), spans[0].SpanContext().SpanID())
+
+			var payload joblib.EnrichedPayload
+			if err := json.Unmarshal([]byte(aws.ToString(fakeQueue.sent[0].MessageBody)), &payload); err != nil {
+				t.Fatalf("failed to unmarshal payload: %v", err)
+			}
+			if tt.expectBody != (payload.TraceContext == expected) {
+				t.Errorf("expected trace context in body %v, got %q", tt.expectBody, payload.TraceContext)
+			}
+			attribute, ok := fakeQueue.sent[0].MessageAttributes[joblib.TraceparentAttribute]
+			if tt.expectAttribute != (ok && aws.ToString(attribute.StringValue) == expected) {
+				t.Errorf("expected traceparent attribute %v, got %v", tt.expectAttribute, fakeQueue.sent[0].MessageAttributes)
+			}
+		})
+	}
+}
+
+func TestTracestatePropagated(t *testing.T) {
+	state, err := trace.ParseTraceState("vendor=opaque")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests := []struct {
+		name              string
+		carrier           string
+		state             trace.TraceState
+		expectedBody      string
+		expectedAttribute string
+	}{
+		{name: "Body with tracestate", carrier: joblib.PropagationBody, state: state, expectedBody: "vendor=opaque"},
+		{name: "Body without tracestate", carrier: joblib.PropagationBody},
+		{name: "Attributes with tracestate", carrier: joblib.PropagationAttributes, state: state, expectedAttribute: "vendor=opaque"},
+		{name: "Attributes without tracestate", carrier: joblib.PropagationAttributes},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, fakeQueue, _, _ := withFakes(t)
+			previous := traceCarrier
+			traceCarrier = tt.carrier
+			defer func() { traceCarrier = previous }()
+
+			// The ingester's span inherits the tracestate of the trace it continues
+			ctx := trace.ContextWithRemoteSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID:    trace.TraceID{0x4b, 0xf9},
+				SpanID:     trace.SpanID{0x00, 0xf0},
+				TraceFlags: trace.FlagsSampled,
+				TraceState: tt.state,
+				Remote:     true,
+			}))
+			in.processMessage(ctx, eventBridgeRecord(validJob))
+
+			if len(fakeQueue.sent) != 1 {
+				t.Fatalf("expected 1 message sent, got %d", len(fakeQueue.sent))
+			}
+			var payload joblib.EnrichedPayload
+			if err := json.Unmarshal([]byte(aws.ToString(fakeQueue.sent[0].MessageBody)), &payload); err != nil {
+				t.Fatalf("failed to unmarshal payload: %v", err)
+			}
+			if payload.TraceState != tt.expectedBody {
+				t.Errorf("expected tracestate %q in the body, got %q", tt.expectedBody, payload.TraceState)
+			}
+			attribute, ok := fakeQueue.sent[0].MessageAttributes[joblib.TracestateAttribute]
+			if ok != (tt.expectedAttribute != "") || aws.ToString(attribute.StringValue) != tt.expectedAttribute {
+				t.Errorf("expected tracestate attribute %q, got %v", tt.expectedAttribute, fakeQueue.sent[0].MessageAttributes)
+			}
+		})
+	}
+}
+
+func TestSchemaFingerprintRecorded(t *testing.T) {
+	tests := []struct {
+		name   string
+		record bool
+	}{
+		{name: "Enabled", record: true},
+		{name: "Disabled"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, fakeQueue, _, _ := withFakes(t)
+			previous := recordFingerprint
+			recordFingerprint = tt.record
+			defer func() { recordFingerprint = previous }()
+
+			in.processMessage(context.Background(), eventBridgeRecord(validJob))
+
+			sent := fakeQueue.sentTo(jobsTodoURL)
+			if len(sent) != 1 {
+				t.Fatalf("expected the job to be sent to jobs-todo, got %d messages", len(sent))
+			}
+			var payload joblib.EnrichedPayload
+			if err := json.Unmarshal([]byte(sent[0]), &payload); err != nil {
+				t.Fatalf("failed to unmarshal payload: %v", err)
+			}
+			expected := ""
+			if tt.record {
+				expected = joblib.SchemaFingerprint(joblib.DataCleanupJob{})
+			}
+			if payload.SchemaFingerprint != expected {
+				t.Errorf("expected fingerprint %q, got %q", expected, payload.SchemaFingerprint)
+			}
+		})
+	}
+}
+
+func TestHandlerRoutesMessages(t *testing.T) {
+	in, fakeQueue, fakeTopic, _ := withFakes(t)
+
+	invalid := `{"job_type":"data_cleanup","message":{"target_table":"users","retention":0}}`
+	valid, rejected := eventBridgeRecord(validJob), eventBridgeRecord(invalid)
+	rejected.MessageId = "sqs-2"
+	if _, err := in.handler(context.Background(), events.SQSEvent{Records: []events.SQSMessage{valid, rejected}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sent := fakeQueue.sentTo(jobsTodoURL)
+	if len(sent) != 1 {
+		t.Fatalf("expected 1 job sent to jobs-todo, got %d", len(sent))
+	}
+	var payload joblib.EnrichedPayload
+	if err := json.Unmarshal([]byte(sent[0]), &payload); err != nil {
+		t.Fatalf("failed to parse enriched payload: %v", err)
+	}
+	if payload.ID != "sqs-1" || payload.Status != joblib.StatusNew || payload.SchemaVersion != joblib.CurrentSchemaVersion || string(payload.OriginalMessage) != validJob {
+		t.Errorf("expected the valid job enriched, got %+v", payload)
+	}
+
+	if dlq := fakeQueue.deadLetters(t); len(dlq) != 1 || dlq[0].OriginalBody != invalid {
+		t.Errorf("expected the invalid job dead-lettered, got %v", dlq)
+	}
+	var event joblib.JobEndStateEvent
+	if len(fakeTopic.messages) != 1 || json.Unmarshal([]byte(fakeTopic.messages[0]), &event) != nil {
+		t.Fatalf("expected 1 end-state event published, got %v", fakeTopic.messages)
+	}
+	if event.JobID != "sqs-2" || event.Status != joblib.StatusValidationFailed {
+		t.Errorf("expected the invalid job to fail validation, got %+v", event)
+	}
+}
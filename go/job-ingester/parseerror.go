@@ -0,0 +1,21 @@
+package main
+
+import (
+	"context"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// parseErrorQueueURL receives events whose job failed to parse, so schema
+// problems can be triaged apart from the other failures on the dead-letter
+// queue. Empty sends them to the dead-letter queue with everything else.
+var parseErrorQueueURL string
+
+// reportParseFailure routes a job that failed to parse like reportFailure,
+// except that the raw body goes to the parse-error queue when one is
+// configured.
+func (in *ingester) reportParseFailure(ctx context.Context, stage string, event joblib.JobEndStateEvent, messageBody string) {
+	if err := in.failureRouter().ReportParseFailure(ctx, stage, event, messageBody); err != nil {
+		logger(ctx).Error("failed to report parse failure", "error", err)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestParseErrorQueue(t *testing.T) {
+	const parseErrors = "http://localhost:4566/000000000000/jobs-parse-errors"
+	invalidJob := `{"job_type": "data_cleanup", "message": {"retention": 30}}`
+
+	tests := []struct {
+		name             string
+		queueURL         string
+		message          events.SQSMessage
+		expectParseError bool
+	}{
+		{name: "Invalid job", queueURL: parseErrors, message: eventBridgeRecord(invalidJob), expectParseError: true},
+		{name: "Invalid job without a parse-error queue", message: eventBridgeRecord(invalidJob)},
+		{name: "Missing detail", queueURL: parseErrors, message: eventBridgeRecord(`null`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, fakeQueue, _, _ := withFakes(t)
+			previous := parseErrorQueueURL
+			parseErrorQueueURL = tt.queueURL
+			defer func() { parseErrorQueueURL = previous }()
+
+			in.processMessage(context.Background(), tt.message)
+
+			parsed, dlq := fakeQueue.sentTo(parseErrors), fakeQueue.sentTo(deadletterURL)
+			if tt.expectParseError {
+				if len(parsed) != 1 || len(dlq) != 0 {
+					t.Errorf("expected the job on the parse-error queue only, got %v and dead letters %v", parsed, dlq)
+				}
+				return
+			}
+			if len(dlq) != 1 || len(parsed) != 0 {
+				t.Errorf("expected the job on the dead-letter queue only, got %v and parse errors %v", dlq, parsed)
+			}
+		})
+	}
+}
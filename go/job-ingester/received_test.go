@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestReceivedEvent(t *testing.T) {
+	in, _, _, recorder := withFakes(t)
+	previousRecord, previousPreview := recordReceived, bodyPreviewBytes
+	recordReceived, bodyPreviewBytes = true, 16
+	defer func() { recordReceived, bodyPreviewBytes = previousRecord, previousPreview }()
+
+	record := eventBridgeRecord(validJob)
+	in.processMessage(context.Background(), record)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	for _, event := range spans[0].Events() {
+		if event.Name != "received" {
+			continue
+		}
+		for _, kv := range event.Attributes {
+			switch kv.Key {
+			case "body.size_bytes":
+				if kv.Value.AsInt64() != int64(len(record.Body)) {
+					t.Errorf("expected body.size_bytes %d, got %d", len(record.Body), kv.Value.AsInt64())
+				}
+			case "body.preview":
+				if kv.Value.AsString() != record.Body[:16] {
+					t.Errorf("expected body.preview %q, got %q", record.Body[:16], kv.Value.AsString())
+				}
+			}
+		}
+		return
+	}
+	t.Errorf("expected a received span event")
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// maxMessageBytes is the largest message SQS accepts, counting its body and
+// message attributes.
+const maxMessageBytes = 256 * 1024
+
+// reasonPayloadTooLarge starts the dead-letter reason of a job whose enriched
+// payload is too large to queue.
+const reasonPayloadTooLarge = "payload_too_large"
+
+// checkMessageSize reports an error when body and attributes together are
+// over the SQS message size limit, so an oversized job is dead-lettered with
+// a clear reason rather than failing inside the SDK.
+func checkMessageSize(body []byte, attributes map[string]types.MessageAttributeValue) error {
+	size := len(body)
+	for name, value := range attributes {
+		size += len(name) + len(aws.ToString(value.DataType)) + len(aws.ToString(value.StringValue))
+	}
+	if size > maxMessageBytes {
+		return fmt.Errorf("%s: enriched payload is %d bytes, over the %d byte SQS limit", reasonPayloadTooLarge, size, maxMessageBytes)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+func TestCheckMessageSize(t *testing.T) {
+	traceparent := map[string]types.MessageAttributeValue{
+		"traceparent": {DataType: aws.String("String"), StringValue: aws.String("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")},
+	}
+	attributeBytes := len("traceparent") + len("String") + len("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	tests := []struct {
+		name       string
+		bodyBytes  int
+		attributes map[string]types.MessageAttributeValue
+		expectErr  bool
+	}{
+		{name: "Small", bodyBytes: 100},
+		{name: "At the limit", bodyBytes: maxMessageBytes},
+		{name: "One byte over", bodyBytes: maxMessageBytes + 1, expectErr: true},
+		{name: "Attributes count towards the limit", bodyBytes: maxMessageBytes - attributeBytes + 1, attributes: traceparent, expectErr: true},
+		{name: "Attributes within the limit", bodyBytes: maxMessageBytes - attributeBytes, attributes: traceparent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkMessageSize([]byte(strings.Repeat("x", tt.bodyBytes)), tt.attributes)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("expected error %v, got %v", tt.expectErr, err)
+			}
+			if err != nil && !strings.HasPrefix(err.Error(), reasonPayloadTooLarge) {
+				t.Errorf("expected a %s reason, got %v", reasonPayloadTooLarge, err)
+			}
+		})
+	}
+}
+
+func TestOversizedPayloadDeadLettered(t *testing.T) {
+	in, fakeQueue, _, _ := withFakes(t)
+	logs := captureLogs(t)
+
+	detail := fmt.Sprintf(`{"job_type":"report_generation","message":{"report_name":"Sales Report","filters":"%s"}}`, strings.Repeat("region=US;", maxMessageBytes/10))
+	if err := in.processMessage(context.Background(), eventBridgeRecord(detail)); err == nil {
+		t.Fatalf("expected an error for an oversized payload")
+	}
+
+	if sent := fakeQueue.sentTo(jobsTodoURL); len(sent) != 0 {
+		t.Errorf("expected nothing sent to jobs-todo, got %d messages", len(sent))
+	}
+	deadLetters := fakeQueue.deadLetters(t)
+	if len(deadLetters) != 1 {
+		t.Fatalf("expected 1 dead letter, got %d", len(deadLetters))
+	}
+	if !strings.HasPrefix(deadLetters[0].Reason, reasonPayloadTooLarge) || deadLetters[0].OriginalBody != detail {
+		t.Errorf("expected the original job dead-lettered as %s, got reason %q", reasonPayloadTooLarge, deadLetters[0].Reason)
+	}
+	if !strings.Contains(logs.String(), "enriched payload is too large to queue") {
+		t.Errorf("expected the oversized payload logged, got %s", logs.String())
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"strings"
+)
+
+// allowedSources are the EventBridge sources the ingester accepts, empty
+// accepts every source
+var allowedSources map[string]bool
+
+// parseAllowedSources parses a comma separated list of EventBridge sources,
+// e.g. "jobs,jobs.scheduler".
+func parseAllowedSources(sources string) map[string]bool {
+	parsed := map[string]bool{}
+	for _, source := range strings.Split(sources, ",") {
+		if source = strings.TrimSpace(source); source != "" {
+			parsed[source] = true
+		}
+	}
+	return parsed
+}
+
+// sourceAllowed reports whether events from source may be ingested.
+func sourceAllowed(source string) bool {
+	return len(allowedSources) == 0 || allowedSources[source]
+}
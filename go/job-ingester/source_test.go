@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestAllowedSources(t *testing.T) {
+	tests := []struct {
+		name        string
+		allowed     string
+		source      string
+		expectQueue bool
+	}{
+		{name: "No allowlist", source: "attacker", expectQueue: true},
+		{name: "Allowed source", allowed: "jobs.scheduler, jobs", source: "jobs", expectQueue: true},
+		{name: "Disallowed source", allowed: "jobs", source: "attacker"},
+		{name: "Missing source", allowed: "jobs", source: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, fakeQueue, fakeTopic, _ := withFakes(t)
+			previous := allowedSources
+			allowedSources = parseAllowedSources(tt.allowed)
+			defer func() { allowedSources = previous }()
+
+			body := strings.Replace(eventBridgeRecord(validJob).Body, `"source":"jobs"`, `"source":"`+tt.source+`"`, 1)
+			in.processMessage(context.Background(), events.SQSMessage{MessageId: "sqs-1", Body: body})
+
+			queued := len(fakeQueue.sentTo(jobsTodoURL)) == 1
+			if queued != tt.expectQueue {
+				t.Fatalf("expected queued to be %v, got %v", tt.expectQueue, queued)
+			}
+			if tt.expectQueue {
+				return
+			}
+			if dlq := fakeQueue.deadLetters(t); len(dlq) != 1 || dlq[0].OriginalBody != body {
+				t.Errorf("expected the whole event dead-lettered, got %v", dlq)
+			}
+			if len(fakeTopic.messages) != 1 || !strings.Contains(fakeTopic.messages[0], "disallowed source") {
+				t.Errorf("expected a disallowed source notification, got %v", fakeTopic.messages)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+)
+
+func TestProcessMessageSpanStatus(t *testing.T) {
+	tests := []struct {
+		name           string
+		detail         string
+		recordStatus   bool
+		expectedStatus codes.Code
+	}{
+		{name: "Valid job", detail: validJob, recordStatus: true, expectedStatus: codes.Ok},
+		{name: "Invalid job", detail: `{"job_type": "data_cleanup", "message": {"retention": 30}}`, recordStatus: true, expectedStatus: codes.Error},
+		{name: "Disabled", detail: validJob, expectedStatus: codes.Unset},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, _, _, recorder := withFakes(t)
+			previous := spanStatus.Record
+			spanStatus.Record = tt.recordStatus
+			defer func() { spanStatus.Record = previous }()
+
+			in.processMessage(context.Background(), eventBridgeRecord(tt.detail))
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 span, got %d", len(spans))
+			}
+			if status := spans[0].Status(); status.Code != tt.expectedStatus {
+				t.Errorf("expected status %s, got %s", tt.expectedStatus, status.Code)
+			}
+		})
+	}
+}
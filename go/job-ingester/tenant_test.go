@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+func TestTenantPropagatedAsBaggage(t *testing.T) {
+	tests := []struct {
+		name         string
+		detail       string
+		expectTenant string
+	}{
+		{
+			name:         "Tenant",
+			detail:       `{"job_type":"data_cleanup","message":{"target_table":"users","retention":30},"tenant_id":"acme"}`,
+			expectTenant: "acme",
+		},
+		{name: "No tenant", detail: validJob},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, fakeQueue, _, recorder := withFakes(t)
+
+			in.processMessage(context.Background(), eventBridgeRecord(tt.detail))
+
+			sent := fakeQueue.sentTo(jobsTodoURL)
+			if len(sent) != 1 {
+				t.Fatalf("expected the job to be sent to jobs-todo, got %d", len(sent))
+			}
+			var payload joblib.EnrichedPayload
+			if err := json.Unmarshal([]byte(sent[0]), &payload); err != nil {
+				t.Fatalf("failed to parse enriched payload: %v", err)
+			}
+			if tenant := joblib.TenantFromContext(joblib.ExtractBaggage(context.Background(), payload.BaggageContext)); tenant != tt.expectTenant {
+				t.Errorf("expected tenant %q in the baggage context, got %q", tt.expectTenant, payload.BaggageContext)
+			}
+
+			for _, span := range recorder.Ended() {
+				tenant, ok := spanAttributes(span)["tenant.id"]
+				if ok != (tt.expectTenant != "") || tenant.AsString() != tt.expectTenant {
+					t.Errorf("span %s: expected tenant.id %q, got %q", span.Name(), tt.expectTenant, tenant.AsString())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// s3Putter is the subset of the S3 client used to archive payloads
+type s3Putter interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+var (
+	archiveClient     s3Putter
+	archiveBucket     string
+	archivePrefix     string
+	archiveSampleRate float64 // fraction of completed payloads to archive, 0 disables archival
+
+	// archiveSampler decides from the payload ID, so retries of a job are
+	// archived consistently
+	archiveSampler = joblib.SampleDecision
+)
+
+// shouldArchive decides whether the completed payload id is sampled for archival.
+func shouldArchive(id string) bool {
+	if archiveClient == nil || archiveBucket == "" || archiveSampleRate <= 0 {
+		return false
+	}
+	return archiveSampler(id, archiveSampleRate)
+}
+
+// archiveKey builds a date partitioned object key for a payload, e.g.
+// completed/dt=2025-08-30/12345.json
+func archiveKey(prefix string, completed time.Time, id string) string {
+	return path.Join(prefix, fmt.Sprintf("dt=%s", completed.UTC().Format("2006-01-02")), id+".json")
+}
+
+// archivePayload writes a sample of completed payloads to S3. Failures are
+// logged but never fail the job.
+func archivePayload(ctx context.Context, span trace.Span, job joblib.EnrichedPayload) {
+	if !shouldArchive(job.ID) {
+		return
+	}
+
+	payloadJSON, err := json.Marshal(job)
+	if err != nil {
+		logger(ctx).Error("failed to marshal payload for archival", "job_id", job.ID, "error", err)
+		return
+	}
+
+	key := archiveKey(archivePrefix, time.Now(), job.ID)
+	_, err = archiveClient.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(archiveBucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(payloadJSON),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		span.RecordError(err)
+		logger(ctx).Error("failed to archive payload", "job_id", job.ID, "bucket", archiveBucket, "key", key, "error", err)
+		return
+	}
+
+	span.AddEvent("payload archived", trace.WithAttributes(
+		attribute.String("message.id", job.ID),
+		attribute.String("archive.key", key),
+	))
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// fakeS3 records objects instead of writing them
+type fakeS3 struct {
+	objects map[string][]byte
+}
+
+func (f *fakeS3) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	body, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.objects[aws.ToString(params.Key)] = body
+	return &s3.PutObjectOutput{}, nil
+}
+
+// withArchive configures archival to a fake S3 with a fixed sampler value
+func withArchive(t *testing.T, sampleRate, sample float64) *fakeS3 {
+	t.Helper()
+	previousClient, previousBucket, previousPrefix := archiveClient, archiveBucket, archivePrefix
+	previousRate, previousSampler := archiveSampleRate, archiveSampler
+	fake := &fakeS3{objects: map[string][]byte{}}
+	archiveClient, archiveBucket, archivePrefix = fake, "job-archive", "completed"
+	archiveSampleRate, archiveSampler = sampleRate, func(_ string, rate float64) bool { return sample < rate }
+	t.Cleanup(func() {
+		archiveClient, archiveBucket, archivePrefix = previousClient, previousBucket, previousPrefix
+		archiveSampleRate, archiveSampler = previousRate, previousSampler
+	})
+	return fake
+}
+
+func TestShouldArchive(t *testing.T) {
+	tests := []struct {
+		name       string
+		sampleRate float64
+		sample     float64
+		expected   bool
+	}{
+		{name: "Disabled", sampleRate: 0, sample: 0, expected: false},
+		{name: "Sampled in", sampleRate: 0.25, sample: 0.1, expected: true},
+		{name: "Sampled out", sampleRate: 0.25, sample: 0.5, expected: false},
+		{name: "Everything sampled", sampleRate: 1, sample: 0.99, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withArchive(t, tt.sampleRate, tt.sample)
+			if actual := shouldArchive("12345"); actual != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestArchiveKey(t *testing.T) {
+	completed := time.Date(2025, 8, 30, 23, 30, 0, 0, time.FixedZone("EST", -5*60*60))
+
+	tests := []struct {
+		name     string
+		prefix   string
+		expected string
+	}{
+		{name: "With prefix", prefix: "completed", expected: "completed/dt=2025-08-31/12345.json"},
+		{name: "Prefix with trailing slash", prefix: "completed/", expected: "completed/dt=2025-08-31/12345.json"},
+		{name: "Without prefix", prefix: "", expected: "dt=2025-08-31/12345.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := archiveKey(tt.prefix, completed, "12345"); actual != tt.expected {
+				t.Errorf("expected key %s, got %s", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCompletedPayloadArchived(t *testing.T) {
+	p, _, _ := withFakeClients(t)
+	fake := withArchive(t, 1, 0)
+
+	p.processMessage(context.Background(), eventsMessage(validEnrichedPayload))
+
+	key := archiveKey("completed", time.Now(), "12345")
+	body, ok := fake.objects[key]
+	if !ok {
+		t.Fatalf("expected payload archived at %s, got %v", key, fake.objects)
+	}
+	var payload joblib.EnrichedPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("failed to unmarshal archived payload: %v", err)
+	}
+	if payload.Status != joblib.StatusCompleted {
+		t.Errorf("expected archived status %s, got %s", joblib.StatusCompleted, payload.Status)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// splitArrayBodies processes each element of an SQS body holding a JSON array
+// of enriched payloads as a record of its own.
+var splitArrayBodies bool
+
+// expandArrayBodies replaces each record whose body is a JSON array of
+// payloads with one record per element, message IDs suffixed with the
+// element index. Other records, and arrays that are empty or don't parse,
+// are kept as they are so they fail as usual.
+func expandArrayBodies(records []events.SQSMessage) []events.SQSMessage {
+	var expanded []events.SQSMessage
+	for _, record := range records {
+		body := bytes.TrimSpace([]byte(record.Body))
+		if len(body) == 0 || body[0] != '[' {
+			expanded = append(expanded, record)
+			continue
+		}
+		var elements []json.RawMessage
+		if err := json.Unmarshal(body, &elements); err != nil || len(elements) == 0 {
+			expanded = append(expanded, record)
+			continue
+		}
+		log.Printf("splitting message %s into %d payloads", record.MessageId, len(elements))
+		for i, element := range elements {
+			split := record
+			split.MessageId = fmt.Sprintf("%s#%d", record.MessageId, i)
+			split.Body = string(element)
+			expanded = append(expanded, split)
+		}
+	}
+	return expanded
+}
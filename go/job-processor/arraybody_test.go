@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestArrayBodies(t *testing.T) {
+	tests := []struct {
+		name             string
+		split            bool
+		body             string
+		expectedMessages []string
+		expectDLQ        int
+	}{
+		{
+			name:             "Single object",
+			split:            true,
+			body:             payloadWithID(t, "job-a"),
+			expectedMessages: []string{"sqs-1"},
+		},
+		{
+			name:             "Array",
+			split:            true,
+			body:             "[" + payloadWithID(t, "job-a") + ", " + payloadWithID(t, "job-b") + "]",
+			expectedMessages: []string{"sqs-1#0", "sqs-1#1"},
+		},
+		{
+			name:             "Array with an invalid element",
+			split:            true,
+			body:             "[" + payloadWithID(t, "job-a") + `, {"id": 1}]`,
+			expectedMessages: []string{"sqs-1#0"},
+			expectDLQ:        1,
+		},
+		{
+			name:      "Array when disabled",
+			body:      "[" + payloadWithID(t, "job-a") + "]",
+			expectDLQ: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, fakeQueue, _ := withFakeClients(t)
+			recorder := tracetest.NewSpanRecorder()
+			previousTracer, previousSplit := tracer, splitArrayBodies
+			tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+			splitArrayBodies = tt.split
+			defer func() { tracer, splitArrayBodies = previousTracer, previousSplit }()
+
+			event := events.SQSEvent{Records: []events.SQSMessage{{MessageId: "sqs-1", Body: tt.body}}}
+			if _, err := p.handler(context.Background(), event); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var executed []string
+			for _, span := range recorder.Ended() {
+				if span.Name() != "ExecuteJob" {
+					continue
+				}
+				for _, kv := range span.Attributes() {
+					if kv.Key == "sqs.message.id" {
+						executed = append(executed, kv.Value.AsString())
+					}
+				}
+			}
+			sort.Strings(executed)
+			if strings.Join(executed, ",") != strings.Join(tt.expectedMessages, ",") {
+				t.Errorf("expected executions for %v, got %v", tt.expectedMessages, executed)
+			}
+			if dlq := fakeQueue.sentTo(deadletterURL); len(dlq) != tt.expectDLQ {
+				t.Errorf("expected %d dead letters, got %d", tt.expectDLQ, len(dlq))
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracedClientsCreateChildSpans(t *testing.T) {
+	p, fakeQueue, fakeTopic := withFakeClients(t)
+	recorder := tracetest.NewSpanRecorder()
+	previousTracer := tracer
+	tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	defer func() { tracer = previousTracer }()
+
+	// Route the processor's own calls through the traced clients
+	p.sqsClient = joblib.TracedSQS{SQSSender: fakeQueue, Tracer: tracer}
+	p.snsClient = joblib.TracedSNS{SNSPublisher: fakeTopic, Tracer: tracer}
+	p.processMessage(context.Background(), eventsMessage(`not json`))
+
+	spans := recorder.Ended()
+	names := map[string]bool{}
+	for _, span := range spans {
+		names[span.Name()] = true
+	}
+	if !names["SQS.SendMessage"] || !names["SNS.Publish"] {
+		t.Errorf("expected SQS.SendMessage and SNS.Publish spans, got %v", names)
+	}
+	if len(fakeQueue.sentTo(deadletterURL)) != 1 || len(fakeTopic.messages) != 1 {
+		t.Errorf("expected the calls to reach the wrapped clients")
+	}
+
+	// Calls made under a span become its children
+	ctx, parent := tracer.Start(context.Background(), "ExecuteJob")
+	if _, err := p.sqsClient.SendMessage(ctx, &sqs.SendMessageInput{QueueUrl: aws.String(jobsTodoURL), MessageBody: aws.String(validEnrichedPayload)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := p.snsClient.Publish(ctx, &sns.PublishInput{TopicArn: aws.String(snsTopicArn), Message: aws.String("done")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parent.End()
+	for _, span := range recorder.Ended()[len(spans):] {
+		if span.Name() != "ExecuteJob" && span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("expected %s to be a child of ExecuteJob", span.Name())
+		}
+	}
+}
+
+func TestTracedCompletionEvents(t *testing.T) {
+	p, _, _ := withFakeClients(t)
+	recorder := tracetest.NewSpanRecorder()
+	previousTracer, previousClient, previousBus := tracer, eventBridgeClient, completionEventBus
+	tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	fake := &fakeEventBridge{}
+	eventBridgeClient, completionEventBus = joblib.TracedEventBridge{EventPutter: fake, Tracer: tracer}, "jobs-bus"
+	defer func() { tracer, eventBridgeClient, completionEventBus = previousTracer, previousClient, previousBus }()
+
+	p.processMessage(context.Background(), eventsMessage(validEnrichedPayload))
+
+	var executeSpan, putSpan sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		switch span.Name() {
+		case "ExecuteJob":
+			executeSpan = span
+		case "EventBridge.PutEvents":
+			putSpan = span
+		}
+	}
+	if executeSpan == nil || putSpan == nil {
+		t.Fatalf("expected ExecuteJob and EventBridge.PutEvents spans")
+	}
+	if putSpan.Parent().SpanID() != executeSpan.SpanContext().SpanID() {
+		t.Errorf("expected EventBridge.PutEvents to be a child of ExecuteJob")
+	}
+	if len(fake.entries) != 1 {
+		t.Errorf("expected the event to reach the wrapped client, got %d entries", len(fake.entries))
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// reportBatchItemFailures returns failed messages to SQS in a partial batch
+// response so only they are redelivered. Pair it with FAILURE_SINK=sns to
+// leave dead-lettering to the queue's redrive policy, otherwise a failed
+// message is dead-lettered by the processor and redelivered as well.
+var reportBatchItemFailures bool
+
+// appendBatchItemFailure adds a failed message to a partial batch response
+// when reporting batch item failures is enabled. Records split out of an
+// array body are reported as the SQS message they came from, once.
+func appendBatchItemFailure(failures []events.SQSBatchItemFailure, messageID string) []events.SQSBatchItemFailure {
+	if !reportBatchItemFailures {
+		return failures
+	}
+	messageID, _, _ = strings.Cut(messageID, "#")
+	for _, failure := range failures {
+		if failure.ItemIdentifier == messageID {
+			return failures
+		}
+	}
+	return append(failures, events.SQSBatchItemFailure{ItemIdentifier: messageID})
+}
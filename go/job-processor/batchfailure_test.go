@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	"github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job/jobtest"
+)
+
+func TestBatchItemFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		report   bool
+		split    bool
+		records  []events.SQSMessage
+		expected []events.SQSBatchItemFailure
+	}{
+		{
+			name:   "Only the failed message reported",
+			report: true,
+			records: []events.SQSMessage{
+				{MessageId: "sqs-1", Body: validEnrichedPayload},
+				{MessageId: "sqs-2", Body: "not json"},
+				{MessageId: "sqs-3", Body: validEnrichedPayload},
+			},
+			expected: []events.SQSBatchItemFailure{{ItemIdentifier: "sqs-2"}},
+		},
+		{
+			name:   "Split array body reported once as its message",
+			report: true,
+			split:  true,
+			records: []events.SQSMessage{
+				{MessageId: "sqs-1", Body: `[{"id": "a"}, {"id": "b"}, ` + validEnrichedPayload + `]`},
+			},
+			expected: []events.SQSBatchItemFailure{{ItemIdentifier: "sqs-1"}},
+		},
+		{
+			name:    "Not reported when disabled",
+			records: []events.SQSMessage{{MessageId: "sqs-2", Body: "not json"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _, _ := withFakeClients(t)
+			previousReport, previousSplit := reportBatchItemFailures, splitArrayBodies
+			reportBatchItemFailures, splitArrayBodies = tt.report, tt.split
+			defer func() { reportBatchItemFailures, splitArrayBodies = previousReport, previousSplit }()
+
+			response, err := p.handler(context.Background(), events.SQSEvent{Records: tt.records})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(response.BatchItemFailures, tt.expected) {
+				t.Errorf("expected batch item failures %v, got %v", tt.expected, response.BatchItemFailures)
+			}
+		})
+	}
+}
+
+func TestBatchItemFailuresFromJobs(t *testing.T) {
+	p, _, fakeTopic := withFakeClients(t)
+	previous := reportBatchItemFailures
+	reportBatchItemFailures = true
+	defer func() { reportBatchItemFailures = previous }()
+
+	valid := joblib.JobMessage{JobType: string(joblib.ReportGeneration), Message: json.RawMessage(`{"report_name":"Sales Report","filters":"region=US"}`)}
+	invalid := joblib.JobMessage{JobType: string(joblib.DataCleanup), Message: json.RawMessage(`{"retention":30}`)}
+	response, err := p.handler(context.Background(), jobtest.NewEnrichedSQSEvent(valid, invalid, valid))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expected := []events.SQSBatchItemFailure{{ItemIdentifier: "sqs-2"}}; !reflect.DeepEqual(response.BatchItemFailures, expected) {
+		t.Errorf("expected batch item failures %v, got %v", expected, response.BatchItemFailures)
+	}
+	for id, expected := range map[string]joblib.Status{"sqs-1": joblib.StatusCompleted, "sqs-2": joblib.StatusValidationFailed, "sqs-3": joblib.StatusCompleted} {
+		if states := publishedFor(fakeTopic.messages, id); len(states) != 1 || states[0].Status != expected {
+			t.Errorf("expected %s to end %s, got %+v", id, expected, states)
+		}
+	}
+}
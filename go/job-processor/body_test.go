@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+func TestCompressedBody(t *testing.T) {
+	compressed, err := joblib.CompressDeadLetter(validEnrichedPayload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	compressed = strings.TrimPrefix(compressed, "gzip:")
+
+	tests := []struct {
+		name          string
+		body          string
+		expectedState joblib.Status
+	}{
+		{name: "Plain JSON", body: validEnrichedPayload, expectedState: joblib.StatusCompleted},
+		{name: "Gzipped and base64 encoded", body: compressed, expectedState: joblib.StatusCompleted},
+		{name: "Corrupt", body: compressed[:24], expectedState: joblib.StatusRejected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, fakeQueue, fakeTopic := withFakeClients(t)
+
+			p.processMessage(context.Background(), eventsMessage(tt.body))
+			states := endStates(fakeTopic.messages)
+			if len(states) != 1 || endState(states[0]).Status != tt.expectedState {
+				t.Fatalf("expected a %s end state, got %v", tt.expectedState, states)
+			}
+			if tt.expectedState == joblib.StatusRejected {
+				if dlq := fakeQueue.deadLetters(t); len(dlq) != 1 || dlq[0].OriginalBody != tt.body {
+					t.Errorf("expected the original body dead-lettered, got %v", dlq)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// runningJobs are the jobs this processor is executing, so a cancel_job can
+// stop one of them.
+var runningJobs = newCancelRegistry()
+
+// cancelRegistry holds the cancel functions of running jobs by job ID. It is
+// safe for concurrent use.
+type cancelRegistry struct {
+	mu      sync.Mutex
+	cancels map[string]*runningJob
+}
+
+type runningJob struct {
+	cancel context.CancelCauseFunc
+}
+
+func newCancelRegistry() *cancelRegistry {
+	return &cancelRegistry{cancels: map[string]*runningJob{}}
+}
+
+// start registers the job with id as running, returning the context to
+// execute it with and a func to call with its Execute error once it has
+// finished. The func unregisters the job and, if a cancel_job stopped it,
+// returns the error wrapped with joblib.ErrCancelRequested.
+func (r *cancelRegistry) start(ctx context.Context, id string) (context.Context, func(error) error) {
+	runCtx, cancel := context.WithCancelCause(ctx)
+	running := &runningJob{cancel: cancel}
+
+	r.mu.Lock()
+	r.cancels[id] = running
+	r.mu.Unlock()
+
+	finish := func(err error) error {
+		r.mu.Lock()
+		// A redelivery of the same job may have registered since
+		if r.cancels[id] == running {
+			delete(r.cancels, id)
+		}
+		r.mu.Unlock()
+
+		cancelled := errors.Is(context.Cause(runCtx), joblib.ErrCancelRequested)
+		cancel(nil)
+		if err != nil && cancelled {
+			return fmt.Errorf("%w: %w", joblib.ErrCancelRequested, err)
+		}
+		return err
+	}
+	return joblib.ContextWithCanceller(runCtx, r.cancel), finish
+}
+
+// cancel stops the running job with id, reporting whether it was running.
+func (r *cancelRegistry) cancel(id string) bool {
+	r.mu.Lock()
+	running, ok := r.cancels[id]
+	r.mu.Unlock()
+	if ok {
+		running.cancel(joblib.ErrCancelRequested)
+	}
+	return ok
+}
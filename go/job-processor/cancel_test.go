@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// cancelPayload is a cancel_job with id targeting targetID
+func cancelPayload(id, targetID string) string {
+	return `{
+		"originalmessage": {"job_type": "cancel_job", "message": {"target_id": "` + targetID + `"}},
+		"id": "` + id + `",
+		"timestamp": "2025-08-30T12:00:00Z",
+		"status": "NEW"
+	}`
+}
+
+// publishedFor returns the end states published for jobID
+func publishedFor(messages []string, jobID string) []joblib.JobEndStateEvent {
+	var states []joblib.JobEndStateEvent
+	for _, message := range endStates(messages) {
+		if state := endState(message); state.JobID == jobID {
+			states = append(states, state)
+		}
+	}
+	return states
+}
+
+func TestCancelRunningJob(t *testing.T) {
+	p, fakeQueue, fakeTopic := withFakeClients(t)
+	previous := maxRetries
+	maxRetries = 2
+	defer func() { maxRetries = previous }()
+
+	done := make(chan error)
+	start := time.Now()
+	go func() {
+		done <- p.processMessage(context.Background(), eventsMessage(`{
+			"originalmessage": {"job_type": "long_running_job", "message": {"task_name": "Data Migration", "timeout": 60}},
+			"id": "long-1",
+			"timestamp": "2025-08-30T12:00:00Z",
+			"status": "NEW"
+		}`))
+	}()
+
+	deadline := time.After(time.Second)
+	for registered := false; !registered; {
+		select {
+		case <-deadline:
+			t.Fatalf("expected long-1 to be registered while executing")
+		case <-time.After(10 * time.Millisecond):
+			snapshot := inFlight.Snapshot()
+			registered = len(snapshot) == 1 && snapshot[0].ID == "long-1"
+		}
+	}
+	if err := p.processMessage(context.Background(), eventsMessage(cancelPayload("cancel-1", "long-1"))); err != nil {
+		t.Fatalf("expected the cancel to succeed, got %v", err)
+	}
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, joblib.ErrCancelRequested) {
+			t.Errorf("expected long-1 stopped by the cancel, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected long-1 to stop once cancelled, still running after %s", time.Since(start))
+	}
+
+	if states := publishedFor(fakeTopic.messages, "long-1"); len(states) != 1 || states[0].Status != joblib.StatusExecuteFailed || !strings.Contains(states[0].Error, "job_cancel_requested") {
+		t.Errorf("expected long-1 to end EXECUTE_FAILED as cancelled, got %+v", states)
+	}
+	if states := publishedFor(fakeTopic.messages, "cancel-1"); len(states) != 1 || states[0].Status != joblib.StatusCompleted || states[0].Output["cancelled"] != true {
+		t.Errorf("expected cancel-1 to complete having cancelled long-1, got %+v", states)
+	}
+	if retries := fakeQueue.sentTo(jobsTodoURL); len(retries) != 0 {
+		t.Errorf("expected a cancelled job not to be requeued, got %v", retries)
+	}
+}
+
+func TestCancelJobNotRunning(t *testing.T) {
+	p, _, fakeTopic := withFakeClients(t)
+	recorder := tracetest.NewSpanRecorder()
+	previousTracer := tracer
+	tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	defer func() { tracer = previousTracer }()
+
+	if err := p.processMessage(context.Background(), eventsMessage(cancelPayload("cancel-1", "long-1"))); err != nil {
+		t.Fatalf("expected cancelling a job that isn't running to be a no-op, got %v", err)
+	}
+	if states := publishedFor(fakeTopic.messages, "cancel-1"); len(states) != 1 || states[0].Status != joblib.StatusCompleted || states[0].Output["cancelled"] != false {
+		t.Errorf("expected cancel-1 to complete without cancelling anything, got %+v", states)
+	}
+
+	found := false
+	for _, span := range recorder.Ended() {
+		for _, event := range span.Events() {
+			if span.Name() == "ExecuteJob" && event.Name == "cancel target not running" {
+				found = true
+			}
+		}
+	}
+	if !found {
+		t.Errorf("expected a cancel target not running event on the ExecuteJob span")
+	}
+}
+
+func TestCancelRegistry(t *testing.T) {
+	registry := newCancelRegistry()
+	if registry.cancel("job-1") {
+		t.Errorf("expected nothing to cancel before job-1 starts")
+	}
+
+	first, finishFirst := registry.start(context.Background(), "job-1")
+	// A redelivery of job-1 starting alongside takes over its registration
+	second, finishSecond := registry.start(context.Background(), "job-1")
+	if err := finishFirst(nil); err != nil {
+		t.Errorf("expected a job that wasn't cancelled to keep its result, got %v", err)
+	}
+	if !registry.cancel("job-1") {
+		t.Fatalf("expected the redelivery of job-1 to still be registered")
+	}
+	if first.Err() == nil || second.Err() == nil {
+		t.Errorf("expected both contexts to be done once their jobs finished or were cancelled")
+	}
+	if err := finishSecond(context.Canceled); !errors.Is(err, joblib.ErrCancelRequested) || !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the cancelled job's error wrapped with ErrCancelRequested, got %v", err)
+	}
+	if registry.cancel("job-1") {
+		t.Errorf("expected nothing to cancel once job-1 finished")
+	}
+}
+
+func TestFailureReportedOnceInvocationIsDone(t *testing.T) {
+	p, fakeQueue, fakeTopic := withFakeClients(t)
+	// The fakes refuse calls on a done context as the SDK does, so the
+	// failure only reaches them if it is reported without the cancellation
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := p.processMessage(ctx, eventsMessage(`{
+		"originalmessage": {"job_type": "long_running_job", "message": {"task_name": "Data Migration", "timeout": 1}},
+		"id": "long-1",
+		"timestamp": "2025-08-30T12:00:00Z",
+		"status": "NEW"
+	}`))
+	if err == nil {
+		t.Fatalf("expected long-1 to fail once its invocation was cancelled")
+	}
+	if states := publishedFor(fakeTopic.messages, "long-1"); len(states) != 1 || states[0].Status != joblib.StatusExecuteFailed {
+		t.Errorf("expected long-1's failure published, got %+v", states)
+	}
+	if dlq := fakeQueue.deadLetters(t); len(dlq) != 1 {
+		t.Errorf("expected long-1 dead-lettered, got %d dead letters", len(dlq))
+	}
+}
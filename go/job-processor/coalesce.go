@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/aws/aws-lambda-go/events"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// coalesceDuplicates executes a job once when the same job ID appears more
+// than once in a batch, e.g. after producer retries.
+var coalesceDuplicates bool
+
+var (
+	detectDuplicateIDs  bool // warn about job IDs repeated within a batch, a sign of a producer bug
+	duplicateIDsInBatch metric.Int64Counter
+)
+
+func init() {
+	var err error
+	duplicateIDsInBatch, err = otel.Meter("job-processor").Int64Counter("duplicate_id_in_batch_total",
+		metric.WithDescription("Records whose job ID repeats an earlier record in the same batch"),
+	)
+	if err != nil {
+		log.Printf("failed to create duplicate ID counter: %v", err)
+	}
+}
+
+// coalescedDuplicate is a record skipped because an earlier record in the
+// batch carries the same job.
+type coalescedDuplicate struct {
+	MessageID string
+	JobID     string
+}
+
+// coalesceRecords keeps the first record for each job ID and returns the
+// rest as duplicates. Records whose payload can't be parsed are kept so
+// they fail as usual.
+func coalesceRecords(records []events.SQSMessage) ([]events.SQSMessage, []coalescedDuplicate) {
+	seen := map[string]bool{}
+	var unique []events.SQSMessage
+	var duplicates []coalescedDuplicate
+	for _, record := range records {
+		msg, err := newMessage(record)
+		if err != nil || msg.Payload.ID == "" {
+			unique = append(unique, record)
+			continue
+		}
+		if seen[msg.Payload.ID] {
+			log.Printf("coalescing message %s, a duplicate of job %s earlier in the batch", record.MessageId, msg.Payload.ID)
+			duplicates = append(duplicates, coalescedDuplicate{MessageID: record.MessageId, JobID: msg.Payload.ID})
+			continue
+		}
+		seen[msg.Payload.ID] = true
+		unique = append(unique, record)
+	}
+	return unique, duplicates
+}
+
+// warnDuplicateIDs logs and counts the records whose job ID repeats an
+// earlier record in the batch, returning how many there were. The records
+// are still processed.
+func warnDuplicateIDs(ctx context.Context, records []events.SQSMessage) int {
+	firstSeen := map[string]string{} // job ID -> first message ID
+	duplicates := 0
+	for _, record := range records {
+		msg, err := newMessage(record)
+		if err != nil || msg.Payload.ID == "" {
+			continue
+		}
+		first, ok := firstSeen[msg.Payload.ID]
+		if !ok {
+			firstSeen[msg.Payload.ID] = record.MessageId
+			continue
+		}
+		duplicates++
+		logger(ctx).Warn("message repeats a job ID in the same batch", "sqs_message_id", record.MessageId, "job_id", msg.Payload.ID, "first_sqs_message_id", first)
+	}
+	if duplicates > 0 && duplicateIDsInBatch != nil {
+		duplicateIDsInBatch.Add(ctx, int64(duplicates))
+	}
+	return duplicates
+}
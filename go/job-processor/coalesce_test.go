@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// payloadWithID returns validEnrichedPayload with its job ID replaced
+func payloadWithID(t *testing.T, id string) string {
+	t.Helper()
+	var payload joblib.EnrichedPayload
+	if err := json.Unmarshal([]byte(validEnrichedPayload), &payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	payload.ID = id
+	body, _ := json.Marshal(payload)
+	return string(body)
+}
+
+func TestCoalesceDuplicates(t *testing.T) {
+	records := []events.SQSMessage{
+		{MessageId: "sqs-1", Body: payloadWithID(t, "job-a")},
+		{MessageId: "sqs-2", Body: payloadWithID(t, "job-b")},
+		{MessageId: "sqs-3", Body: payloadWithID(t, "job-a")},
+		{MessageId: "sqs-4", Body: `not json`},
+		{MessageId: "sqs-5", Body: payloadWithID(t, "job-a")},
+	}
+
+	tests := []struct {
+		name             string
+		coalesce         bool
+		expectedExecuted int
+	}{
+		{name: "Coalesced", coalesce: true, expectedExecuted: 2},
+		{name: "Disabled", coalesce: false, expectedExecuted: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, fakeQueue, _ := withFakeClients(t)
+			recorder := tracetest.NewSpanRecorder()
+			previousTracer, previousCoalesce := tracer, coalesceDuplicates
+			tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+			coalesceDuplicates = tt.coalesce
+			defer func() { tracer, coalesceDuplicates = previousTracer, previousCoalesce }()
+
+			if _, err := p.handler(context.Background(), events.SQSEvent{Records: records}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			executed := 0
+			for _, span := range recorder.Ended() {
+				if span.Name() == "ExecuteJob" {
+					executed++
+				}
+			}
+			if executed != tt.expectedExecuted {
+				t.Errorf("expected %d executions, got %d", tt.expectedExecuted, executed)
+			}
+			// The unparseable record still fails as usual
+			if len(fakeQueue.sentTo(deadletterURL)) != 1 {
+				t.Errorf("expected the invalid record to be dead-lettered")
+			}
+		})
+	}
+}
+
+func TestCoalescedDuplicatesInBatchSummary(t *testing.T) {
+	p, _, fakeTopic := withFakeClients(t)
+	previousSummary, previousCoalesce := snsBatchSummary, coalesceDuplicates
+	snsBatchSummary, coalesceDuplicates = true, true
+	defer func() { snsBatchSummary, coalesceDuplicates = previousSummary, previousCoalesce }()
+
+	records := []events.SQSMessage{
+		{MessageId: "sqs-1", Body: payloadWithID(t, "job-a")},
+		{MessageId: "sqs-2", Body: payloadWithID(t, "job-a")},
+	}
+	if _, err := p.handler(context.Background(), events.SQSEvent{Records: records}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(fakeTopic.messages) != 1 {
+		t.Fatalf("expected 1 summary message, got %v", fakeTopic.messages)
+	}
+	var summary batchSummary
+	if err := json.Unmarshal([]byte(fakeTopic.messages[0]), &summary); err != nil {
+		t.Fatalf("failed to unmarshal summary: %v", err)
+	}
+	if summary.Records != 2 || summary.Succeeded != 2 || len(summary.Outcomes) != 2 {
+		t.Fatalf("expected 2 successful records, got %+v", &summary)
+	}
+	if duplicate := summary.Outcomes[1]; duplicate.MessageID != "sqs-2" || !strings.Contains(duplicate.Detail, "coalesced duplicate of job job-a") {
+		t.Errorf("expected sqs-2 acked as a duplicate, got %+v", duplicate)
+	}
+}
+
+func TestDuplicateIDsInBatch(t *testing.T) {
+	tests := []struct {
+		name              string
+		ids               []string
+		expectedDuplicate int64
+	}{
+		{name: "Unique IDs", ids: []string{"job-a", "job-b", "job-c"}},
+		{name: "Duplicate IDs", ids: []string{"job-a", "job-b", "job-a", "job-a"}, expectedDuplicate: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _, _ := withFakeClients(t)
+			reader := sdkmetric.NewManualReader()
+			recorder := tracetest.NewSpanRecorder()
+			previousCounter, previousDetect, previousTracer := duplicateIDsInBatch, detectDuplicateIDs, tracer
+			duplicateIDsInBatch, _ = sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test").Int64Counter("duplicate_id_in_batch_total")
+			detectDuplicateIDs = true
+			tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+			defer func() {
+				duplicateIDsInBatch, detectDuplicateIDs, tracer = previousCounter, previousDetect, previousTracer
+			}()
+
+			var records []events.SQSMessage
+			for i, id := range tt.ids {
+				records = append(records, events.SQSMessage{MessageId: fmt.Sprintf("sqs-%d", i), Body: payloadWithID(t, id)})
+			}
+			if _, err := p.handler(context.Background(), events.SQSEvent{Records: records}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var metrics metricdata.ResourceMetrics
+			if err := reader.Collect(context.Background(), &metrics); err != nil {
+				t.Fatalf("failed to collect metrics: %v", err)
+			}
+			var counted int64
+			for _, scope := range metrics.ScopeMetrics {
+				for _, m := range scope.Metrics {
+					for _, point := range m.Data.(metricdata.Sum[int64]).DataPoints {
+						counted += point.Value
+					}
+				}
+			}
+			if counted != tt.expectedDuplicate {
+				t.Errorf("expected %d duplicates counted, got %d", tt.expectedDuplicate, counted)
+			}
+
+			// Duplicates are only warned about, every record still executes
+			executed := 0
+			for _, span := range recorder.Ended() {
+				if span.Name() == "ExecuteJob" {
+					executed++
+				}
+			}
+			if executed != len(tt.ids) {
+				t.Errorf("expected %d executions, got %d", len(tt.ids), executed)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// eventPutter is the subset of the EventBridge client used to publish
+// completion events
+type eventPutter interface {
+	PutEvents(ctx context.Context, params *eventbridge.PutEventsInput, optFns ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error)
+}
+
+var (
+	eventBridgeClient eventPutter // publishes completion events, nil disables them
+
+	completionEventBus       string // event bus completion events are put on
+	completionEventSource    string // source of completion events, distinct from the jobs source the ingester consumes
+	completedEventDetailType string // detail-type of successful completions
+	failedEventDetailType    string // detail-type of failed executions
+)
+
+// completionEvent is the detail of a completion event.
+type completionEvent struct {
+	JobType string                 `json:"job_type"`
+	Error   string                 `json:"error,omitempty"`
+	Job     joblib.EnrichedPayload `json:"job"`
+}
+
+// emitCompletionEvent publishes a JobCompleted or JobFailed event to
+// EventBridge so other systems can react to the job's end state.
+func emitCompletionEvent(ctx context.Context, span trace.Span, job joblib.EnrichedPayload, jobType string, execErr error) {
+	if eventBridgeClient == nil {
+		return
+	}
+
+	detailType := completedEventDetailType
+	event := completionEvent{JobType: jobType, Job: job}
+	if execErr != nil {
+		detailType = failedEventDetailType
+		event.Error = execErr.Error()
+	}
+	detail, err := json.Marshal(event)
+	if err != nil {
+		span.RecordError(err)
+		logger(ctx).Error("failed to marshal completion event", "error", err)
+		return
+	}
+
+	output, err := eventBridgeClient.PutEvents(ctx, &eventbridge.PutEventsInput{
+		Entries: []types.PutEventsRequestEntry{
+			{
+				Source:       aws.String(completionEventSource),
+				DetailType:   aws.String(detailType),
+				Detail:       aws.String(string(detail)),
+				EventBusName: aws.String(completionEventBus),
+			},
+		},
+	})
+	if err == nil && output.FailedEntryCount > 0 && len(output.Entries) > 0 {
+		err = fmt.Errorf("%s: %s", aws.ToString(output.Entries[0].ErrorCode), aws.ToString(output.Entries[0].ErrorMessage))
+	}
+	if err != nil {
+		span.RecordError(err)
+		logger(ctx).Error("failed to put event to EventBridge", "detail_type", detailType, "error", err)
+		return
+	}
+	span.AddEvent("completion event emitted", trace.WithAttributes(
+		attribute.String("event.detail_type", detailType),
+		attribute.String("event.source", completionEventSource),
+	))
+}
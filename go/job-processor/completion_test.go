@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+)
+
+// fakeEventBridge records entries instead of putting them
+type fakeEventBridge struct {
+	entries []types.PutEventsRequestEntry
+}
+
+func (f *fakeEventBridge) PutEvents(ctx context.Context, params *eventbridge.PutEventsInput, optFns ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error) {
+	f.entries = append(f.entries, params.Entries...)
+	return &eventbridge.PutEventsOutput{}, nil
+}
+
+func TestEmitCompletionEvent(t *testing.T) {
+	tests := []struct {
+		name               string
+		body               string
+		cancelled          bool
+		expectedDetailType string
+		expectedID         string
+		expectError        bool
+	}{
+		{
+			name:               "Completed job",
+			body:               validEnrichedPayload,
+			expectedDetailType: "JobCompleted",
+			expectedID:         "12345",
+		},
+		{
+			name: "Failed job",
+			body: `{
+				"originalmessage": {"job_type": "long_running_job", "message": {"task_name": "Data Migration", "timeout": 1}},
+				"id": "67890",
+				"timestamp": "2025-08-30T12:00:00Z",
+				"status": "NEW"
+			}`,
+			cancelled:          true,
+			expectedDetailType: "JobFailed",
+			expectedID:         "67890",
+			expectError:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _, _ := withFakeClients(t)
+			fake := &fakeEventBridge{}
+			previousClient, previousBus, previousSource := eventBridgeClient, completionEventBus, completionEventSource
+			previousCompleted, previousFailed := completedEventDetailType, failedEventDetailType
+			eventBridgeClient, completionEventBus, completionEventSource = fake, "jobs-bus", "jobs.processor"
+			completedEventDetailType, failedEventDetailType = "JobCompleted", "JobFailed"
+			defer func() {
+				eventBridgeClient, completionEventBus, completionEventSource = previousClient, previousBus, previousSource
+				completedEventDetailType, failedEventDetailType = previousCompleted, previousFailed
+			}()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.cancelled {
+				cancel()
+			}
+			defer cancel()
+			p.processMessage(ctx, eventsMessage(tt.body))
+
+			if len(fake.entries) != 1 {
+				t.Fatalf("expected 1 completion event, got %d", len(fake.entries))
+			}
+			entry := fake.entries[0]
+			if aws.ToString(entry.DetailType) != tt.expectedDetailType {
+				t.Errorf("expected detail-type %s, got %s", tt.expectedDetailType, aws.ToString(entry.DetailType))
+			}
+			if aws.ToString(entry.Source) != "jobs.processor" {
+				t.Errorf("expected source jobs.processor, got %s", aws.ToString(entry.Source))
+			}
+			if aws.ToString(entry.EventBusName) != "jobs-bus" {
+				t.Errorf("expected bus jobs-bus, got %s", aws.ToString(entry.EventBusName))
+			}
+
+			var detail completionEvent
+			if err := json.Unmarshal([]byte(aws.ToString(entry.Detail)), &detail); err != nil {
+				t.Fatalf("failed to unmarshal detail: %v", err)
+			}
+			if detail.Job.ID != tt.expectedID {
+				t.Errorf("expected job ID %s, got %s", tt.expectedID, detail.Job.ID)
+			}
+			if (detail.Error != "") != tt.expectError {
+				t.Errorf("expected error %v, got %q", tt.expectError, detail.Error)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// maxConcurrency is how many records of a batch are processed at once, so a
+// batch of long-running jobs doesn't run them one after another.
+var maxConcurrency int
+
+type recordIDKey struct{}
+
+// withRecordID returns a context for processing the record messageID, so the
+// end states it reports can be attributed to it.
+func withRecordID(ctx context.Context, messageID string) context.Context {
+	return context.WithValue(ctx, recordIDKey{}, messageID)
+}
+
+// recordID returns the ID of the record ctx is processing, if any.
+func recordID(ctx context.Context) string {
+	id, _ := ctx.Value(recordIDKey{}).(string)
+	return id
+}
+
+// processRecords processes records on up to maxConcurrency goroutines,
+// reporting which of them failed by their index. Each record is processed
+// with its own context derived from ctx, and processMessage starts its spans
+// from that. SQS standard queues don't order a batch, so records may finish
+// in any order.
+func (p *processor) processRecords(ctx context.Context, records []events.SQSMessage) []bool {
+	failed := make([]bool, len(records))
+	workers := min(max(maxConcurrency, 1), len(records))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				failed[i] = p.processMessage(withRecordID(ctx, records[i].MessageId), records[i]) != nil
+			}
+		}()
+	}
+	for i := range records {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return failed
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// withMaxConcurrency processes up to n records of a batch at once
+func withMaxConcurrency(t *testing.T, n int) {
+	t.Helper()
+	previous := maxConcurrency
+	maxConcurrency = n
+	t.Cleanup(func() { maxConcurrency = previous })
+}
+
+func TestBatchProcessedConcurrently(t *testing.T) {
+	p, _, fakeTopic := withFakeClients(t)
+	withMaxConcurrency(t, 4)
+	recorder := tracetest.NewSpanRecorder()
+	previousTracer := tracer
+	tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	defer func() { tracer = previousTracer }()
+
+	// Each job takes a second, so run one after another they'd take four
+	var records []events.SQSMessage
+	for i := 0; i < 4; i++ {
+		records = append(records, events.SQSMessage{MessageId: fmt.Sprintf("sqs-%d", i), Body: fmt.Sprintf(`{
+			"originalmessage": {"job_type": "long_running_job", "message": {"task_name": "Data Migration", "timeout": 1}},
+			"id": "job-%d",
+			"timestamp": "2025-08-30T12:00:00Z",
+			"status": "NEW"
+		}`, i)})
+	}
+	start := time.Now()
+	response, err := p.handler(context.Background(), events.SQSEvent{Records: records})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("expected the jobs to run concurrently, took %s", elapsed)
+	}
+	if len(response.BatchItemFailures) != 0 {
+		t.Errorf("expected no failures, got %v", response.BatchItemFailures)
+	}
+	completed := 0
+	for _, message := range fakeTopic.messages {
+		if endState(message).Status == joblib.StatusCompleted {
+			completed++
+		}
+	}
+	if completed != 4 {
+		t.Errorf("expected 4 jobs completed, got %d", completed)
+	}
+
+	// Every record is traced in a span of its own
+	traces := map[string]bool{}
+	for _, span := range recorder.Ended() {
+		if span.Name() == "ExecuteJob" {
+			traces[span.SpanContext().TraceID().String()] = true
+		}
+	}
+	if len(traces) != 4 {
+		t.Errorf("expected 4 ExecuteJob spans in their own traces, got %d", len(traces))
+	}
+}
+
+func TestConcurrentBatchItemFailuresInBatchOrder(t *testing.T) {
+	for _, n := range []int{0, 1, 3, 20} {
+		t.Run(fmt.Sprintf("MAX_CONCURRENCY=%d", n), func(t *testing.T) {
+			p, _, _ := withFakeClients(t)
+			withMaxConcurrency(t, n)
+			previous := reportBatchItemFailures
+			reportBatchItemFailures = true
+			defer func() { reportBatchItemFailures = previous }()
+
+			var records []events.SQSMessage
+			for i := 0; i < 8; i++ {
+				body := payloadWithID(t, fmt.Sprintf("job-%d", i))
+				if i%3 == 1 {
+					body = "not json"
+				}
+				records = append(records, events.SQSMessage{MessageId: fmt.Sprintf("sqs-%d", i), Body: body})
+			}
+			response, err := p.handler(context.Background(), events.SQSEvent{Records: records})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var failed []string
+			for _, failure := range response.BatchItemFailures {
+				failed = append(failed, failure.ItemIdentifier)
+			}
+			if fmt.Sprint(failed) != "[sqs-1 sqs-4 sqs-7]" {
+				t.Errorf("expected sqs-1, sqs-4 and sqs-7 to fail, got %v", failed)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestCorrelationIDPropagated(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "From the payload", body: `{
+			"originalmessage": {"job_type": "report_generation", "message": {"report_name": "Sales Report", "filters": "region=US"}},
+			"id": "12345",
+			"timestamp": "2025-08-30T12:00:00Z",
+			"status": "NEW",
+			"correlation_id": "req-42"
+		}`},
+		{name: "From the caller's job message", body: `{
+			"originalmessage": {"job_type": "report_generation", "message": {"report_name": "Sales Report", "filters": "region=US"}, "correlation_id": "req-42"},
+			"id": "12345",
+			"timestamp": "2025-08-30T12:00:00Z",
+			"status": "NEW"
+		}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _, fakeTopic := withFakeClients(t)
+			logs := captureLogs(t)
+			recorder := tracetest.NewSpanRecorder()
+			previousTracer := tracer
+			tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+			defer func() { tracer = previousTracer }()
+
+			if err := p.processMessage(context.Background(), eventsMessage(tt.body)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if states := publishedFor(fakeTopic.messages, "12345"); len(states) != 1 || states[0].CorrelationID != "req-42" {
+				t.Errorf("expected the end state to echo req-42, got %+v", states)
+			}
+			for _, span := range recorder.Ended() {
+				if span.Name() != "ExecuteJob" {
+					continue
+				}
+				found := false
+				for _, kv := range span.Attributes() {
+					found = found || (kv.Key == "correlation.id" && kv.Value.AsString() == "req-42")
+				}
+				if !found {
+					t.Errorf("expected ExecuteJob tagged with correlation.id req-42")
+				}
+			}
+			if !strings.Contains(logs.String(), `"correlation_id":"req-42"`) {
+				t.Errorf("expected log lines to carry the correlation ID, got %s", logs.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	costFactors map[string]float64 // job type -> estimated cost of one execution, empty disables estimates
+	jobCost     metric.Float64Counter
+)
+
+func init() {
+	var err error
+	jobCost, err = otel.Meter("job-processor").Float64Counter("job_cost_total",
+		metric.WithDescription("Estimated cost of executed jobs"),
+	)
+	if err != nil {
+		log.Printf("failed to create job cost counter: %v", err)
+	}
+}
+
+// parseCostFactors parses a comma separated list of job_type=cost pairs, e.g.
+// report_generation=0.02,data_cleanup=0.005
+func parseCostFactors(factors string) map[string]float64 {
+	costs := map[string]float64{}
+	for _, factor := range strings.Split(factors, ",") {
+		factor = strings.TrimSpace(factor)
+		if factor == "" {
+			continue
+		}
+		jobType, cost, ok := strings.Cut(factor, "=")
+		jobType = strings.TrimSpace(jobType)
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(cost), 64)
+		if !ok || jobType == "" || err != nil || parsed < 0 {
+			log.Printf("ignoring malformed job cost factor: %q", factor)
+			continue
+		}
+		costs[jobType] = parsed
+	}
+	return costs
+}
+
+// recordCost annotates the span with the configured cost estimate for the job
+// type and adds it to the job cost counter.
+func recordCost(ctx context.Context, span trace.Span, jobType string, status joblib.Status) {
+	cost, ok := costFactors[jobType]
+	if !ok {
+		return
+	}
+	span.SetAttributes(attribute.Float64("job.estimated_cost", cost))
+	if jobCost != nil {
+		jobCost.Add(ctx, cost, metric.WithAttributes(
+			attribute.String("job.type", jobType),
+			attribute.String("status", string(status)),
+		))
+	}
+}
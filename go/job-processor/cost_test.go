@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestParseCostFactors(t *testing.T) {
+	tests := []struct {
+		name     string
+		factors  string
+		expected map[string]float64
+	}{
+		{name: "Empty", factors: "", expected: map[string]float64{}},
+		{
+			name:     "Several factors",
+			factors:  "report_generation=0.02, data_cleanup=0.005",
+			expected: map[string]float64{"report_generation": 0.02, "data_cleanup": 0.005},
+		},
+		{
+			name:     "Malformed factors skipped",
+			factors:  "report_generation=cheap,=0.1,data_cleanup,user_onboarding=-1,long_running_job=1.5",
+			expected: map[string]float64{"long_running_job": 1.5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := parseCostFactors(tt.factors); !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRecordCost(t *testing.T) {
+	p, _, _ := withFakeClients(t)
+
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+	previousCost := jobCost
+	jobCost, _ = meter.Float64Counter("job_cost_total")
+	defer func() { jobCost = previousCost }()
+
+	recorder := tracetest.NewSpanRecorder()
+	previousTracer, previousFactors := tracer, costFactors
+	tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	costFactors = map[string]float64{"report_generation": 0.25}
+	defer func() { tracer, costFactors = previousTracer, previousFactors }()
+
+	p.processMessage(context.Background(), eventsMessage(validEnrichedPayload))
+	p.processMessage(context.Background(), eventsMessage(payloadWithID(t, "12346")))
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	for _, span := range spans {
+		found := false
+		for _, kv := range span.Attributes() {
+			if kv.Key == "job.estimated_cost" {
+				found = true
+				if kv.Value.AsFloat64() != 0.25 {
+					t.Errorf("expected job.estimated_cost 0.25, got %v", kv.Value.AsFloat64())
+				}
+			}
+		}
+		if !found {
+			t.Errorf("expected job.estimated_cost attribute on span %s", span.Name())
+		}
+	}
+
+	var metrics metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &metrics); err != nil {
+		t.Fatalf("failed to collect metrics: %v", err)
+	}
+	for _, scope := range metrics.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if m.Name != "job_cost_total" {
+				continue
+			}
+			for _, point := range m.Data.(metricdata.Sum[float64]).DataPoints {
+				if jobType, _ := point.Attributes.Value(attribute.Key("job.type")); jobType.AsString() != "report_generation" {
+					continue
+				}
+				if point.Value != 0.5 {
+					t.Errorf("expected job_cost_total of 0.5, got %v", point.Value)
+				}
+				return
+			}
+		}
+	}
+	t.Errorf("expected a job_cost_total data point for report_generation")
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// SQS limits on a single SendMessageBatch call.
+const (
+	maxBatchEntries = 10
+	maxBatchBytes   = 256 * 1024
+)
+
+// sqsBatchSender is the subset of the SQS client used to send buffered
+// dead letters.
+type sqsBatchSender interface {
+	SendMessageBatch(ctx context.Context, params *sqs.SendMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error)
+}
+
+var (
+	deadLetterBatchClient sqsBatchSender // sends an invocation's dead letters in batches, nil sends each straight away
+	compressDeadLetters   bool           // gzip dead-letter bodies, see joblib.CompressDeadLetter
+)
+
+// deadLetterBuffer collects the dead letters of one invocation.
+type deadLetterBuffer struct {
+	mu      sync.Mutex // records in a batch are processed concurrently
+	letters []deadLetter
+}
+
+// deadLetter is a dead-letter queue message ready to send.
+type deadLetter struct {
+	body       string
+	attributes map[string]types.MessageAttributeValue
+}
+
+// size is how much of the SQS payload limit the message uses, counting its
+// attribute names and values.
+func (l deadLetter) size() int {
+	size := len(l.body)
+	for name, value := range l.attributes {
+		size += len(name) + len(aws.ToString(value.DataType)) + len(aws.ToString(value.StringValue))
+	}
+	return size
+}
+
+type deadLetterBufferKey struct{}
+
+// withDeadLetterBuffer returns a context collecting dead letters into a new
+// buffer, to be sent with flushDeadLetters.
+func withDeadLetterBuffer(ctx context.Context) (context.Context, *deadLetterBuffer) {
+	buffer := &deadLetterBuffer{}
+	return context.WithValue(ctx, deadLetterBufferKey{}, buffer), buffer
+}
+
+// SendMessage adds a dead letter to the buffer, to be sent by
+// flushDeadLetters.
+func (b *deadLetterBuffer) SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.letters = append(b.letters, deadLetter{body: aws.ToString(params.MessageBody), attributes: params.MessageAttributes})
+	return &sqs.SendMessageOutput{}, nil
+}
+
+// deadLetterQueue is where dead letters are sent: the dead-letter queue, or
+// the invocation's buffer when dead letters are batched.
+func (p *processor) deadLetterQueue(ctx context.Context) joblib.DeadLetterQueue {
+	queue := joblib.DeadLetterQueue{Client: p.sqsClient, URL: deadletterURL, Metrics: failureMetrics, Compress: compressDeadLetters}
+	if buffer, ok := ctx.Value(deadLetterBufferKey{}).(*deadLetterBuffer); ok {
+		queue.Client = buffer
+	}
+	return queue
+}
+
+// flushDeadLetters sends the buffered dead letters in as few batches as the
+// SQS entry and payload size limits allow.
+func flushDeadLetters(ctx context.Context, buffer *deadLetterBuffer) {
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+	var entries []types.SendMessageBatchRequestEntry
+	size := 0
+	send := func() {
+		if len(entries) == 0 {
+			return
+		}
+		output, err := deadLetterBatchClient.SendMessageBatch(ctx, &sqs.SendMessageBatchInput{
+			QueueUrl: aws.String(deadletterURL),
+			Entries:  entries,
+		})
+		if err != nil {
+			logger(ctx).Error("failed to send messages to dead-letter queue", "messages", len(entries), "error", err)
+		} else {
+			for _, failed := range output.Failed {
+				logger(ctx).Error("failed to send message to dead-letter queue", "entry_id", aws.ToString(failed.Id), "error", aws.ToString(failed.Message))
+			}
+		}
+		entries, size = nil, 0
+	}
+
+	for i, letter := range buffer.letters {
+		if len(entries) == maxBatchEntries || (len(entries) > 0 && size+letter.size() > maxBatchBytes) {
+			send()
+		}
+		entries = append(entries, types.SendMessageBatchRequestEntry{
+			Id:                aws.String(strconv.Itoa(i)),
+			MessageBody:       aws.String(letter.body),
+			MessageAttributes: letter.attributes,
+		})
+		size += letter.size()
+	}
+	send()
+	buffer.letters = nil
+}
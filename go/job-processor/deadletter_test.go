@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// fakeBatchSQS records batches instead of sending them
+type fakeBatchSQS struct {
+	batches []*sqs.SendMessageBatchInput
+}
+
+func (f *fakeBatchSQS) SendMessageBatch(ctx context.Context, params *sqs.SendMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error) {
+	f.batches = append(f.batches, params)
+	return &sqs.SendMessageBatchOutput{}, nil
+}
+
+// withDeadLetterBatching batches dead letters through a fake for the test
+func withDeadLetterBatching(t *testing.T, compress bool) *fakeBatchSQS {
+	t.Helper()
+	previousClient, previousCompress, previousSink := deadLetterBatchClient, compressDeadLetters, failureSink
+	fake := &fakeBatchSQS{}
+	deadLetterBatchClient, compressDeadLetters, failureSink = fake, compress, joblib.FailureSinkDLQ
+	t.Cleanup(func() {
+		deadLetterBatchClient, compressDeadLetters, failureSink = previousClient, previousCompress, previousSink
+	})
+	return fake
+}
+
+func TestBatchedDeadLetters(t *testing.T) {
+	p, fakeQueue, _ := withFakeClients(t)
+	fake := withDeadLetterBatching(t, false)
+
+	var records []events.SQSMessage
+	for i := 0; i < 12; i++ {
+		records = append(records, events.SQSMessage{MessageId: fmt.Sprintf("sqs-%d", i), Body: fmt.Sprintf("not json %d", i)})
+	}
+	if _, err := p.handler(context.Background(), events.SQSEvent{Records: records}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if sent := fakeQueue.sentTo(deadletterURL); len(sent) != 0 {
+		t.Errorf("expected no individual dead-letter sends, got %d", len(sent))
+	}
+	if len(fake.batches) != 2 || len(fake.batches[0].Entries) != 10 || len(fake.batches[1].Entries) != 2 {
+		t.Fatalf("expected batches of 10 and 2, got %d batches", len(fake.batches))
+	}
+	for _, batch := range fake.batches {
+		if aws.ToString(batch.QueueUrl) != deadletterURL {
+			t.Errorf("expected batches sent to %s, got %s", deadletterURL, aws.ToString(batch.QueueUrl))
+		}
+	}
+	// Records are processed concurrently, so dead letters are batched in the order they failed
+	originals := map[string]bool{}
+	for _, batch := range fake.batches {
+		for _, entry := range batch.Entries {
+			envelope, err := joblib.ParseDeadLetterEnvelope([]byte(aws.ToString(entry.MessageBody)))
+			if err != nil {
+				t.Fatalf("expected a dead-letter envelope, got %s: %v", aws.ToString(entry.MessageBody), err)
+			}
+			originals[envelope.OriginalBody] = true
+			if stage := aws.ToString(entry.MessageAttributes[joblib.DeadLetterStageAttribute].StringValue); stage != joblib.StageParse {
+				t.Errorf("expected the batched dead letter's stage attribute, got %q", stage)
+			}
+		}
+	}
+	if len(originals) != 12 || !originals["not json 11"] {
+		t.Errorf("expected a dead letter for each record, got %v", originals)
+	}
+}
+
+func TestBatchedDeadLettersPayloadLimit(t *testing.T) {
+	p, _, _ := withFakeClients(t)
+	fake := withDeadLetterBatching(t, false)
+
+	// Three 100KiB bodies can't share one 256KiB batch
+	ctx, buffer := withDeadLetterBuffer(context.Background())
+	for i := 0; i < 3; i++ {
+		if err := p.deadLetterQueue(ctx).Send(ctx, joblib.NewDeadLetterEnvelope(strings.Repeat("x", 100*1024), joblib.StageExecute, "failed to execute job", "")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	flushDeadLetters(ctx, buffer)
+
+	if len(fake.batches) != 2 || len(fake.batches[0].Entries) != 2 || len(fake.batches[1].Entries) != 1 {
+		t.Errorf("expected batches of 2 and 1, got %d batches", len(fake.batches))
+	}
+}
+
+func TestCompressedDeadLetters(t *testing.T) {
+	p, fakeQueue, _ := withFakeClients(t)
+	previousCompress, previousSink := compressDeadLetters, failureSink
+	compressDeadLetters, failureSink = true, joblib.FailureSinkDLQ
+	defer func() { compressDeadLetters, failureSink = previousCompress, previousSink }()
+
+	p.processMessage(context.Background(), eventsMessage(`not json`))
+
+	sent := fakeQueue.sentTo(deadletterURL)
+	if len(sent) != 1 {
+		t.Fatalf("expected 1 dead letter, got %d", len(sent))
+	}
+	if !strings.HasPrefix(sent[0], "gzip:") {
+		t.Errorf("expected a compressed dead letter, got %s", sent[0])
+	}
+	body, err := joblib.DecompressDeadLetter(sent[0])
+	if err != nil {
+		t.Fatalf("failed to decompress dead letter: %v", err)
+	}
+	if envelope, err := joblib.ParseDeadLetterEnvelope([]byte(body)); err != nil || envelope.OriginalBody != `not json` {
+		t.Errorf("expected the original body, got %s", body)
+	}
+}
+
+func TestDeadLetterEnvelope(t *testing.T) {
+	p, fakeQueue, _ := withFakeClients(t)
+	previousSink := failureSink
+	failureSink = joblib.FailureSinkDLQ
+	defer func() { failureSink = previousSink }()
+
+	failing := `{
+		"originalmessage": {"job_type": "long_running_job", "message": {"task_name": "Data Migration", "timeout": 60}},
+		"id": "67890",
+		"timestamp": "2025-08-30T12:00:00Z",
+		"status": "NEW",
+		"trace_context": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	}`
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	p.processMessage(ctx, eventsMessage(failing))
+
+	dlq := fakeQueue.deadLetters(t)
+	if len(dlq) != 1 {
+		t.Fatalf("expected 1 dead letter, got %d", len(dlq))
+	}
+	envelope := dlq[0]
+	if envelope.OriginalBody != failing || envelope.Stage != joblib.StageExecute || !strings.HasPrefix(envelope.Reason, "failed to execute job") {
+		t.Errorf("expected the execute failure wrapped, got %+v", envelope)
+	}
+	if envelope.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected the job's trace ID, got %q", envelope.TraceID)
+	}
+	if _, err := time.Parse(time.RFC3339, envelope.Timestamp); err != nil {
+		t.Errorf("expected an RFC 3339 timestamp, got %q", envelope.Timestamp)
+	}
+
+	attributes := fakeQueue.sent[len(fakeQueue.sent)-1].MessageAttributes
+	if stage := aws.ToString(attributes[joblib.DeadLetterStageAttribute].StringValue); stage != joblib.StageExecute {
+		t.Errorf("expected the %s attribute %q, got %q", joblib.DeadLetterStageAttribute, joblib.StageExecute, stage)
+	}
+	if reason := aws.ToString(attributes[joblib.DeadLetterReasonAttribute].StringValue); reason != envelope.ReasonAttribute() {
+		t.Errorf("expected the %s attribute %q, got %q", joblib.DeadLetterReasonAttribute, envelope.ReasonAttribute(), reason)
+	}
+}
+
+func TestOnlyMalformedMessagesDeadLettered(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		expectDLQ bool
+	}{
+		{name: "Malformed body", body: `{"originalmessage": `, expectDLQ: true},
+		{name: "Valid job", body: validEnrichedPayload},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, fakeQueue, _ := withFakeClients(t)
+
+			if _, err := p.handler(context.Background(), events.SQSEvent{Records: []events.SQSMessage{eventsMessage(tt.body)}}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			dlq := fakeQueue.deadLetters(t)
+			if deadLettered := len(dlq) == 1; deadLettered != tt.expectDLQ || len(dlq) > 1 {
+				t.Fatalf("expected dead-lettered %v, got %v", tt.expectDLQ, dlq)
+			}
+			if tt.expectDLQ && (dlq[0].OriginalBody != tt.body || dlq[0].Stage != joblib.StageParse) {
+				t.Errorf("expected the malformed body dead-lettered at the parse stage, got %+v", dlq[0])
+			}
+		})
+	}
+}
+
+// deadLetterCounts returns jobs_deadlettered_total collected from reader, by stage
+func deadLetterCounts(t *testing.T, reader *sdkmetric.ManualReader) map[string]int64 {
+	t.Helper()
+	var metrics metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &metrics); err != nil {
+		t.Fatalf("failed to collect metrics: %v", err)
+	}
+	counts := map[string]int64{}
+	for _, scope := range metrics.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if m.Name != "jobs_deadlettered_total" || !ok {
+				continue
+			}
+			for _, point := range sum.DataPoints {
+				stage, _ := point.Attributes.Value("stage")
+				counts[stage.AsString()] += point.Value
+			}
+		}
+	}
+	return counts
+}
+
+// withFailureMetrics records dead letters and failed SNS publishes on the returned reader
+func withFailureMetrics(t *testing.T) *sdkmetric.ManualReader {
+	t.Helper()
+	reader := sdkmetric.NewManualReader()
+	previous := failureMetrics
+	metrics, err := joblib.NewFailureMetrics(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test"))
+	if err != nil {
+		t.Fatalf("failed to create failure metrics: %v", err)
+	}
+	failureMetrics = metrics
+	t.Cleanup(func() { failureMetrics = previous })
+	return reader
+}
+
+func TestDeadLettersCounted(t *testing.T) {
+	p, _, _ := withFakeClients(t)
+	reader := withFailureMetrics(t)
+
+	p.processMessage(context.Background(), eventsMessage("not json"))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	p.processMessage(ctx, eventsMessage(`{
+		"originalmessage": {"job_type": "long_running_job", "message": {"task_name": "Data Migration", "timeout": 60}},
+		"id": "67890",
+		"timestamp": "2025-08-30T12:00:00Z",
+		"status": "NEW"
+	}`))
+	p.processMessage(context.Background(), eventsMessage(validEnrichedPayload))
+
+	if counts := deadLetterCounts(t, reader); fmt.Sprint(counts) != "map[execute:1 parse:1]" {
+		t.Errorf("expected one parse and one execute dead letter counted, got %v", counts)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// dedupStore records which job IDs have been executed so an SQS redelivery
+// doesn't run a job's side effects twice. A job is claimed before it
+// executes, and the claim either completes it or is released so a retry can
+// run it again. Claims left by an invocation that crashed expire after
+// dedupClaimTTL.
+type dedupStore interface {
+	// Claim reports whether id may execute, atomically recording the claim
+	// so a concurrent invocation holding the same job is refused.
+	Claim(ctx context.Context, id string) (bool, error)
+	// Complete marks id executed so later deliveries are skipped.
+	Complete(ctx context.Context, id string) error
+	// Release gives up the claim on id after it failed, so it can be retried.
+	Release(ctx context.Context, id string) error
+}
+
+var (
+	dedup         dedupStore    // nil disables deduplication
+	dedupClaimTTL time.Duration // how long a claim blocks other deliveries of a job that never finished
+)
+
+// memoryDedup is a dedupStore for the demo, scoped to one Lambda container.
+type memoryDedup struct {
+	mu     sync.Mutex
+	now    func() time.Time
+	claims *joblib.LRUCache[string, dedupClaim]
+}
+
+type dedupClaim struct {
+	status  joblib.Status
+	expires time.Time
+}
+
+func newMemoryDedup(maxEntries int) *memoryDedup {
+	return &memoryDedup{now: time.Now, claims: joblib.NewLRUCache[string, dedupClaim]("dedup_claims", maxEntries)}
+}
+
+func (d *memoryDedup) Claim(ctx context.Context, id string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := d.now()
+	if claim, ok := d.claims.Get(id); ok {
+		if claim.status == joblib.StatusCompleted || (claim.status == joblib.StatusInProgress && now.Before(claim.expires)) {
+			return false, nil
+		}
+	}
+	d.claims.Add(id, dedupClaim{status: joblib.StatusInProgress, expires: now.Add(dedupClaimTTL)})
+	return true, nil
+}
+
+func (d *memoryDedup) Complete(ctx context.Context, id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.claims.Add(id, dedupClaim{status: joblib.StatusCompleted})
+	return nil
+}
+
+func (d *memoryDedup) Release(ctx context.Context, id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.claims.Add(id, dedupClaim{status: joblib.StatusExecuteFailed})
+	return nil
+}
+
+// dynamoWriter is the subset of the DynamoDB client used to record claims
+type dynamoWriter interface {
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+}
+
+// dynamoDedup is a dedupStore backed by a DynamoDB table keyed on job_id,
+// shared by every invocation. Claims are conditional puts, so only one
+// invocation can win a job.
+type dynamoDedup struct {
+	client dynamoWriter
+	table  string
+	now    func() time.Time
+}
+
+func (d *dynamoDedup) Claim(ctx context.Context, id string) (bool, error) {
+	now := d.now()
+	_, err := d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.table),
+		Item:      d.item(id, joblib.StatusInProgress, now.Add(dedupClaimTTL)),
+		// Refuse the claim while the job is completed or held by an unexpired claim
+		ConditionExpression: aws.String("attribute_not_exists(job_id) OR #status = :failed OR (#status = :in_progress AND claim_expires < :now)"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":failed":      &types.AttributeValueMemberS{Value: string(joblib.StatusExecuteFailed)},
+			":in_progress": &types.AttributeValueMemberS{Value: string(joblib.StatusInProgress)},
+			":now":         &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+		},
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to claim job %s in %s: %w", id, d.table, err)
+	}
+	return true, nil
+}
+
+func (d *dynamoDedup) Complete(ctx context.Context, id string) error {
+	return d.put(ctx, id, joblib.StatusCompleted)
+}
+
+func (d *dynamoDedup) Release(ctx context.Context, id string) error {
+	return d.put(ctx, id, joblib.StatusExecuteFailed)
+}
+
+// put overwrites the record for id, only called by the invocation holding its claim.
+func (d *dynamoDedup) put(ctx context.Context, id string, status joblib.Status) error {
+	_, err := d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.table),
+		Item:      d.item(id, status, d.now()),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record job %s as %s in %s: %w", id, status, d.table, err)
+	}
+	return nil
+}
+
+func (d *dynamoDedup) item(id string, status joblib.Status, claimExpires time.Time) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"job_id":        &types.AttributeValueMemberS{Value: id},
+		"status":        &types.AttributeValueMemberS{Value: string(status)},
+		"claim_expires": &types.AttributeValueMemberN{Value: strconv.FormatInt(claimExpires.Unix(), 10)},
+	}
+}
+
+// skipDuplicate claims job id for execution, reporting whether it was already
+// completed or is being executed by another invocation. If the store can't
+// be reached the job runs anyway, as a duplicate is better than a lost job.
+func skipDuplicate(ctx context.Context, span trace.Span, id string) bool {
+	if dedup == nil {
+		return false
+	}
+	claimed, err := dedup.Claim(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		logger(ctx).Warn("unable to check job for duplicates, executing it", "job_id", id, "error", err)
+		return false
+	}
+	if claimed {
+		return false
+	}
+	logger(ctx).Info("job was already processed, skipping duplicate delivery", "job_id", id)
+	span.AddEvent("duplicate.skipped", trace.WithAttributes(
+		attribute.String("message.id", id),
+	))
+	return true
+}
+
+// settleClaim completes the claim on job id, or releases it if the job
+// failed so a retry can execute it.
+func settleClaim(ctx context.Context, id string, executeErr error) {
+	if dedup == nil {
+		return
+	}
+	// Record the outcome even if the invocation is being cancelled
+	ctx = context.WithoutCancel(ctx)
+	var err error
+	if executeErr != nil {
+		err = dedup.Release(ctx, id)
+	} else {
+		err = dedup.Complete(ctx, id)
+	}
+	if err != nil {
+		logger(ctx).Error("failed to settle the duplicate check", "job_id", id, "error", err)
+	}
+}
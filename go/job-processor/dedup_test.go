@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// fakeDynamo keeps items in memory, applying the claim condition the way
+// DynamoDB would evaluate it
+type fakeDynamo struct {
+	mu    sync.Mutex
+	items map[string]map[string]types.AttributeValue
+	err   error
+	puts  []*dynamodb.PutItemInput
+}
+
+func (f *fakeDynamo) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.puts = append(f.puts, params)
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.items == nil {
+		f.items = map[string]map[string]types.AttributeValue{}
+	}
+	id := params.Item["job_id"].(*types.AttributeValueMemberS).Value
+	if existing, ok := f.items[id]; ok && params.ConditionExpression != nil {
+		status := existing["status"].(*types.AttributeValueMemberS).Value
+		expires, _ := strconv.ParseInt(existing["claim_expires"].(*types.AttributeValueMemberN).Value, 10, 64)
+		now, _ := strconv.ParseInt(params.ExpressionAttributeValues[":now"].(*types.AttributeValueMemberN).Value, 10, 64)
+		if status != string(joblib.StatusExecuteFailed) && !(status == string(joblib.StatusInProgress) && expires < now) {
+			return nil, &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+		}
+	}
+	f.items[id] = params.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func TestDedupStores(t *testing.T) {
+	previousTTL := dedupClaimTTL
+	dedupClaimTTL = time.Minute
+	defer func() { dedupClaimTTL = previousTTL }()
+
+	stores := map[string]func(now func() time.Time) dedupStore{
+		"memory": func(now func() time.Time) dedupStore {
+			store := newMemoryDedup(10)
+			store.now = now
+			return store
+		},
+		"dynamodb": func(now func() time.Time) dedupStore {
+			return &dynamoDedup{client: &fakeDynamo{}, table: "job-dedup", now: now}
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			now := time.Date(2025, 8, 30, 12, 0, 0, 0, time.UTC)
+			store := newStore(func() time.Time { return now })
+			ctx := context.Background()
+
+			claim := func(id string, expected bool) {
+				t.Helper()
+				claimed, err := store.Claim(ctx, id)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if claimed != expected {
+					t.Errorf("expected claim on %s to be %v, got %v", id, expected, claimed)
+				}
+			}
+
+			claim("12345", true)
+			claim("12345", false) // held by the first claim
+			claim("67890", true)
+
+			// A failed job can be retried
+			if err := store.Release(ctx, "12345"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			claim("12345", true)
+
+			// A completed job never runs again
+			if err := store.Complete(ctx, "12345"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			claim("12345", false)
+			now = now.Add(time.Hour)
+			claim("12345", false)
+
+			// A claim left by a crashed invocation expires
+			claim("67890", true)
+		})
+	}
+}
+
+func TestDedupConcurrentClaims(t *testing.T) {
+	stores := map[string]dedupStore{
+		"memory":   newMemoryDedup(10),
+		"dynamodb": &dynamoDedup{client: &fakeDynamo{}, table: "job-dedup", now: time.Now},
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			var wins int32
+			var wg sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if claimed, err := store.Claim(context.Background(), "12345"); err == nil && claimed {
+						atomic.AddInt32(&wins, 1)
+					}
+				}()
+			}
+			wg.Wait()
+			if wins != 1 {
+				t.Errorf("expected exactly 1 invocation to claim the job, got %d", wins)
+			}
+		})
+	}
+}
+
+func TestDynamoDedupErrors(t *testing.T) {
+	client := &fakeDynamo{err: errors.New("throttled")}
+	store := &dynamoDedup{client: client, table: "job-dedup", now: time.Now}
+
+	if claimed, err := store.Claim(context.Background(), "12345"); err == nil || claimed {
+		t.Errorf("expected the claim to fail, got %v, %v", claimed, err)
+	}
+	if aws.ToString(client.puts[0].TableName) != "job-dedup" || client.puts[0].ConditionExpression == nil {
+		t.Errorf("expected a conditional put to job-dedup, got %+v", client.puts[0])
+	}
+	if err := store.Complete(context.Background(), "12345"); err == nil {
+		t.Errorf("expected the completion to fail")
+	}
+}
+
+func TestDuplicateSkipped(t *testing.T) {
+	p, _, fakeTopic := withFakeClients(t)
+
+	recorder := tracetest.NewSpanRecorder()
+	previousTracer := tracer
+	tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	defer func() { tracer = previousTracer }()
+
+	p.processMessage(context.Background(), eventsMessage(validEnrichedPayload))
+	p.processMessage(context.Background(), eventsMessage(validEnrichedPayload))
+
+	if states := endStates(fakeTopic.messages); len(states) != 1 || endState(states[0]).Status != joblib.StatusCompleted {
+		t.Errorf("expected the job to execute once, got %v", states)
+	}
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	for i, span := range spans {
+		skipped := false
+		for _, event := range span.Events() {
+			skipped = skipped || event.Name == "duplicate.skipped"
+		}
+		if skipped != (i == 1) {
+			t.Errorf("span %d: expected duplicate.skipped %v, got %v", i, i == 1, skipped)
+		}
+	}
+
+	// A failed job is executed again when it is redelivered
+	failing := `{
+		"originalmessage": {"job_type": "long_running_job", "message": {"task_name": "Data Migration", "timeout": 60}},
+		"id": "67890",
+		"timestamp": "2025-08-30T12:00:00Z",
+		"status": "NEW"
+	}`
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		p.processMessage(ctx, eventsMessage(failing))
+		cancel()
+	}
+	if states := endStates(fakeTopic.messages); len(states) != 3 || endState(states[1]).Status != joblib.StatusExecuteFailed || endState(states[2]).Status != joblib.StatusExecuteFailed {
+		t.Errorf("expected the failed job to execute on each delivery, got %v", states)
+	}
+}
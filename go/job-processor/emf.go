@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// emfNamespace is the CloudWatch namespace EMF metrics are published under
+const emfNamespace = "JobProcessor"
+
+// emfWriter writes job metrics as CloudWatch Embedded Metric Format JSON,
+// one blob per line, which CloudWatch Logs extracts into metrics.
+type emfWriter struct {
+	mu  sync.Mutex
+	out io.Writer
+	now func() time.Time
+}
+
+// emfMetrics is set when METRICS_EXPORTER=emf
+var emfMetrics *emfWriter
+
+type emfMetric struct {
+	Name string `json:"Name"`
+	Unit string `json:"Unit"`
+}
+
+type emfDirective struct {
+	Namespace  string      `json:"Namespace"`
+	Dimensions [][]string  `json:"Dimensions"`
+	Metrics    []emfMetric `json:"Metrics"`
+}
+
+type emfMetadata struct {
+	Timestamp         int64          `json:"Timestamp"`
+	CloudWatchMetrics []emfDirective `json:"CloudWatchMetrics"`
+}
+
+type emfJobBlob struct {
+	AWS         emfMetadata `json:"_aws"`
+	JobType     string      `json:"JobType"`
+	Status      string      `json:"Status"`
+	JobCount    int         `json:"JobCount"`
+	JobDuration float64     `json:"JobDuration"`
+}
+
+// writeJob records one executed job and how long it took, dimensioned by job
+// type and status.
+func (e *emfWriter) writeJob(jobType string, status joblib.Status, duration time.Duration) {
+	if e == nil {
+		return
+	}
+
+	blob, err := json.Marshal(emfJobBlob{
+		AWS: emfMetadata{
+			Timestamp: e.now().UnixMilli(),
+			CloudWatchMetrics: []emfDirective{{
+				Namespace:  emfNamespace,
+				Dimensions: [][]string{{"JobType", "Status"}},
+				Metrics: []emfMetric{
+					{Name: "JobCount", Unit: "Count"},
+					{Name: "JobDuration", Unit: "Milliseconds"},
+				},
+			}},
+		},
+		JobType:     jobType,
+		Status:      string(status),
+		JobCount:    1,
+		JobDuration: float64(duration.Microseconds()) / 1000,
+	})
+	if err != nil {
+		log.Printf("failed to marshal EMF metrics: %v", err)
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, err := e.out.Write(append(blob, '\n')); err != nil {
+		log.Printf("failed to write EMF metrics: %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+func TestEMFJobBlob(t *testing.T) {
+	var out bytes.Buffer
+	writer := &emfWriter{out: &out, now: func() time.Time { return time.UnixMilli(1756555200000) }}
+
+	writer.writeJob("report_generation", joblib.StatusCompleted, 1500*time.Microsecond)
+
+	var blob map[string]any
+	if err := json.Unmarshal(out.Bytes(), &blob); err != nil {
+		t.Fatalf("expected one JSON blob, got %s: %v", out.String(), err)
+	}
+
+	expected := `{
+		"_aws": {
+			"Timestamp": 1756555200000,
+			"CloudWatchMetrics": [{
+				"Namespace": "JobProcessor",
+				"Dimensions": [["JobType", "Status"]],
+				"Metrics": [
+					{"Name": "JobCount", "Unit": "Count"},
+					{"Name": "JobDuration", "Unit": "Milliseconds"}
+				]
+			}]
+		},
+		"JobType": "report_generation",
+		"Status": "COMPLETED",
+		"JobCount": 1,
+		"JobDuration": 1.5
+	}`
+	var expectedBlob map[string]any
+	if err := json.Unmarshal([]byte(expected), &expectedBlob); err != nil {
+		t.Fatalf("invalid expected blob: %v", err)
+	}
+	actualJSON, _ := json.Marshal(blob)
+	expectedJSON, _ := json.Marshal(expectedBlob)
+	if string(actualJSON) != string(expectedJSON) {
+		t.Errorf("expected %s, got %s", expectedJSON, actualJSON)
+	}
+}
+
+func TestEMFWrittenPerJob(t *testing.T) {
+	p, _, _ := withFakeClients(t)
+
+	var out bytes.Buffer
+	previous := emfMetrics
+	emfMetrics = &emfWriter{out: &out, now: time.Now}
+	defer func() { emfMetrics = previous }()
+
+	p.processMessage(context.Background(), eventsMessage(validEnrichedPayload))
+	p.processMessage(context.Background(), eventsMessage(`not json`))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 EMF blob for the executed job, got %d: %s", len(lines), out.String())
+	}
+	var blob emfJobBlob
+	if err := json.Unmarshal([]byte(lines[0]), &blob); err != nil {
+		t.Fatalf("failed to unmarshal EMF blob: %v", err)
+	}
+	if blob.JobType != "report_generation" || blob.Status != string(joblib.StatusCompleted) || blob.JobCount != 1 {
+		t.Errorf("expected a completed report_generation job, got %+v", blob)
+	}
+}
+
+func TestEMFDisabled(t *testing.T) {
+	var writer *emfWriter
+	// A nil writer is a no-op so callers needn't check whether EMF is enabled
+	writer.writeJob("report_generation", joblib.StatusCompleted, time.Second)
+}
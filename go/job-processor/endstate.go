@@ -0,0 +1,18 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// publishEndState publishes a job's end state to the notifications topic as
+// JSON, with its job type and status as message attributes.
+func (p *processor) publishEndState(ctx context.Context, event joblib.JobEndStateEvent) error {
+	message, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return publishToSNS(ctx, p.snsClient, snsTopicArn, string(message), joblib.FilterAttributes(event.JobType, event.Status))
+}
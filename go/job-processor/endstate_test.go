@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestEndStateEvents(t *testing.T) {
+	const longRunning = `{
+		"originalmessage": {"job_type": "long_running_job", "message": {"task_name": "Data Migration", "timeout": 60}},
+		"id": "67890",
+		"timestamp": "2025-08-30T12:00:00Z",
+		"status": "NEW",
+		"trace_context": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	}`
+
+	tests := []struct {
+		name          string
+		body          string
+		timeout       time.Duration
+		expected      joblib.JobEndStateEvent
+		expectedError string
+		expectedFlow  []joblib.Status
+	}{
+		{
+			name:         "Completed",
+			body:         validEnrichedPayload,
+			expected:     joblib.JobEndStateEvent{JobID: "12345", JobType: "report_generation", Status: joblib.StatusCompleted, Output: map[string]any{"report_location": "reports/Sales%20Report.csv"}},
+			expectedFlow: []joblib.Status{joblib.StatusInProgress, joblib.StatusCompleted},
+		},
+		{
+			name:          "Execute failed",
+			body:          longRunning,
+			timeout:       20 * time.Millisecond,
+			expected:      joblib.JobEndStateEvent{JobID: "67890", JobType: "long_running_job", Status: joblib.StatusExecuteFailed, TraceID: "4bf92f3577b34da6a3ce929d0e0e4736"},
+			expectedError: "failed to execute job",
+			expectedFlow:  []joblib.Status{joblib.StatusInProgress, joblib.StatusExecuteFailed},
+		},
+		{
+			name:          "Rejected",
+			body:          "not json",
+			expected:      joblib.JobEndStateEvent{Status: joblib.StatusRejected},
+			expectedError: "failed to parse job message",
+			expectedFlow:  []joblib.Status{joblib.StatusRejected},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _, fakeTopic := withFakeClients(t)
+			previous := notifyOnSuccess
+			notifyOnSuccess = true
+			defer func() { notifyOnSuccess = previous }()
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			p.processMessage(ctx, eventsMessage(tt.body))
+
+			var flow []joblib.Status
+			for _, message := range fakeTopic.messages {
+				flow = append(flow, endState(message).Status)
+			}
+			if !reflect.DeepEqual(flow, tt.expectedFlow) {
+				t.Fatalf("expected statuses %v, got %v", tt.expectedFlow, fakeTopic.messages)
+			}
+			// Subscription filter policies see each event's job type and status
+			for i, message := range fakeTopic.messages {
+				state := endState(message)
+				if expected := joblib.FilterAttributes(state.JobType, state.Status); !reflect.DeepEqual(fakeTopic.attributes[i], expected) {
+					t.Errorf("expected message attributes %v, got %v", expected, fakeTopic.attributes[i])
+				}
+			}
+			event := endState(fakeTopic.messages[len(fakeTopic.messages)-1])
+			if !strings.HasPrefix(event.Error, tt.expectedError) || (tt.expectedError == "") != (event.Error == "") {
+				t.Errorf("expected error starting %q, got %q", tt.expectedError, event.Error)
+			}
+			if _, err := time.Parse(time.RFC3339, event.Timestamp); err != nil {
+				t.Errorf("expected an RFC 3339 timestamp, got %q", event.Timestamp)
+			}
+			event.Error, event.Timestamp = "", ""
+			if !reflect.DeepEqual(event, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, event)
+			}
+		})
+	}
+}
+
+func TestResultMessageOnSpan(t *testing.T) {
+	p, _, _ := withFakeClients(t)
+	recorder := tracetest.NewSpanRecorder()
+	previousTracer := tracer
+	tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	defer func() { tracer = previousTracer }()
+
+	p.processMessage(context.Background(), eventsMessage(validEnrichedPayload))
+
+	found := false
+	for _, span := range recorder.Ended() {
+		for _, event := range span.Events() {
+			found = found || event.Name == "report Sales Report written to reports/Sales%20Report.csv"
+		}
+	}
+	if !found {
+		t.Errorf("expected the job's result message as a span event")
+	}
+}
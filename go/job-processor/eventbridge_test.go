@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestEventBridgeIDTagged(t *testing.T) {
+	p, _, _ := withFakeClients(t)
+	recorder := tracetest.NewSpanRecorder()
+	previousTracer := tracer
+	tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	defer func() { tracer = previousTracer }()
+
+	if err := p.processMessage(context.Background(), eventsMessage(`{
+		"originalmessage": {"job_type": "report_generation", "message": {"report_name": "Sales Report", "filters": "region=US"}},
+		"id": "12345",
+		"timestamp": "2025-08-30T12:00:00Z",
+		"status": "NEW",
+		"eventbridge_id": "eb-1"
+	}`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	found := false
+	for _, span := range recorder.Ended() {
+		for _, kv := range span.Attributes() {
+			if span.Name() == "ExecuteJob" && kv.Key == "eventbridge.event_id" && kv.Value.AsString() == "eb-1" {
+				found = true
+			}
+		}
+	}
+	if !found {
+		t.Errorf("expected the ExecuteJob span tagged with eventbridge.event_id eb-1")
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// jobTTL is how long after ingestion a job may still execute, 0 disables the
+// check. Jobs older than this are dead-lettered as expired.
+var jobTTL time.Duration
+
+// expireStale records how long ago the job was ingested on the span and, if
+// that is longer than jobTTL, reports it as expired rather than executing it.
+// Jobs whose timestamp is missing or invalid are executed as usual.
+func (p *processor) expireStale(ctx context.Context, span trace.Span, msg Message, job *joblib.EnrichedPayload, jobType string) bool {
+	age, err := joblib.PipelineLatency(job.Timestamp, time.Now())
+	if err != nil {
+		logger(ctx).Warn("unable to compute the age of job, processing it anyway", "job_id", job.ID, "error", err)
+		return false
+	}
+	span.SetAttributes(attribute.Float64("job.age_seconds", age.Seconds()))
+	if jobTTL <= 0 || age <= jobTTL {
+		return false
+	}
+
+	err = fmt.Errorf("job is %s old, older than the %s TTL", age.Round(time.Second), jobTTL)
+	spanStatus.Fail(span, err)
+	job.Status = joblib.StatusExpired
+	jobStatuses.Add(job.ID, job.Status)
+	logger(ctx).Info("skipping expired job", "job_id", job.ID, "error", err)
+	span.AddEvent("job expired", trace.WithAttributes(
+		attribute.String("message.id", job.ID),
+		attribute.String("job.type", jobType),
+	))
+	p.reportFailure(ctx, joblib.StageExecute, joblib.NewJobEndStateEvent(ctx, job.ID, jobType, job.Status, fmt.Sprintf("job expired: %v, err: %s", *job, err)), msg.Body)
+	return true
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// payloadWithTimestamp returns the valid payload ingested at timestamp
+func payloadWithTimestamp(t *testing.T, timestamp string) string {
+	t.Helper()
+	var payload joblib.EnrichedPayload
+	if err := json.Unmarshal([]byte(validEnrichedPayload), &payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	payload.Timestamp = timestamp
+	body, _ := json.Marshal(payload)
+	return string(body)
+}
+
+func TestJobTTL(t *testing.T) {
+	tests := []struct {
+		name          string
+		ttl           time.Duration
+		timestamp     string
+		expectedState joblib.Status
+		expectAge     bool
+	}{
+		{name: "Disabled", timestamp: "2025-08-30T12:00:00Z", expectedState: joblib.StatusCompleted, expectAge: true},
+		{name: "Within the TTL", ttl: 15 * time.Minute, timestamp: time.Now().Add(-time.Minute).Format(time.RFC3339), expectedState: joblib.StatusCompleted, expectAge: true},
+		{name: "Older than the TTL", ttl: 15 * time.Minute, timestamp: time.Now().Add(-time.Hour).Format(time.RFC3339), expectedState: joblib.StatusExpired, expectAge: true},
+		{name: "Missing timestamp", ttl: 15 * time.Minute, timestamp: "", expectedState: joblib.StatusCompleted},
+		{name: "Unparseable timestamp", ttl: 15 * time.Minute, timestamp: "yesterday", expectedState: joblib.StatusCompleted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, fakeQueue, fakeTopic := withFakeClients(t)
+			recorder := tracetest.NewSpanRecorder()
+			previousTracer, previousTTL := tracer, jobTTL
+			tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+			jobTTL = tt.ttl
+			defer func() { tracer, jobTTL = previousTracer, previousTTL }()
+
+			body := payloadWithTimestamp(t, tt.timestamp)
+			p.processMessage(context.Background(), eventsMessage(body))
+
+			states := endStates(fakeTopic.messages)
+			if len(states) != 1 || endState(states[0]).Status != tt.expectedState {
+				t.Fatalf("expected a %s end state, got %v", tt.expectedState, states)
+			}
+			dlq := fakeQueue.sentTo(deadletterURL)
+			if expired := tt.expectedState == joblib.StatusExpired; expired != (len(dlq) == 1) {
+				t.Errorf("expected dead-lettered %v, got %v", expired, dlq)
+			}
+			if status, _ := jobStatuses.Get("12345"); status != tt.expectedState {
+				t.Errorf("expected status %s cached, got %s", tt.expectedState, status)
+			}
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 span, got %d", len(spans))
+			}
+			age, ok := 0.0, false
+			for _, kv := range spans[0].Attributes() {
+				if kv.Key == "job.age_seconds" {
+					age, ok = kv.Value.AsFloat64(), true
+				}
+			}
+			if ok != tt.expectAge || (ok && age <= 0) {
+				t.Errorf("expected job.age_seconds recorded %v, got %v (%v)", tt.expectAge, ok, age)
+			}
+		})
+	}
+}
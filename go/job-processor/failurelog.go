@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/global"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	failureLogRecords bool     // emit an OTel log record carrying the failed job
+	redactFields      []string // fields redacted from logged payloads
+	failureLogger     = global.GetLoggerProvider().Logger("job-processor")
+)
+
+// parseRedactFields parses a comma separated list of field names.
+func parseRedactFields(fields string) []string {
+	var parsed []string
+	for _, field := range strings.Split(fields, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			parsed = append(parsed, field)
+		}
+	}
+	return parsed
+}
+
+// emitFailureLog records a failed message as a structured log record with
+// its redacted body and the failure reason. The record is correlated with the
+// span in ctx.
+func emitFailureLog(ctx context.Context, reason, messageBody string) {
+	if !failureLogRecords {
+		return
+	}
+
+	var record otellog.Record
+	record.SetTimestamp(time.Now())
+	record.SetSeverity(otellog.SeverityError)
+	record.SetSeverityText("ERROR")
+	record.SetEventName("job.failed")
+	record.SetBody(otellog.StringValue(reason))
+	record.AddAttributes(
+		otellog.String("failure.reason", reason),
+		otellog.String("job.payload", string(joblib.RedactMessage([]byte(messageBody), redactFields))),
+	)
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		record.AddAttributes(
+			otellog.String("trace_id", spanContext.TraceID().String()),
+			otellog.String("span_id", spanContext.SpanID().String()),
+		)
+	}
+	failureLogger.Emit(ctx, record)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// fakeLogExporter records exported log records
+type fakeLogExporter struct {
+	mu      sync.Mutex
+	records []sdklog.Record
+}
+
+func (e *fakeLogExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, record := range records {
+		e.records = append(e.records, record.Clone())
+	}
+	return nil
+}
+
+func (e *fakeLogExporter) Shutdown(ctx context.Context) error   { return nil }
+func (e *fakeLogExporter) ForceFlush(ctx context.Context) error { return nil }
+
+// withFailureLogs enables failure log records exported to a fake exporter
+func withFailureLogs(t *testing.T, enabled bool) *fakeLogExporter {
+	t.Helper()
+	exporter := &fakeLogExporter{}
+	provider := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(exporter)))
+	previousEnabled, previousLogger, previousFields := failureLogRecords, failureLogger, redactFields
+	failureLogRecords, failureLogger, redactFields = enabled, provider.Logger("test"), []string{"user_id", "user_name"}
+	t.Cleanup(func() {
+		failureLogRecords, failureLogger, redactFields = previousEnabled, previousLogger, previousFields
+	})
+	return exporter
+}
+
+func recordAttributes(record sdklog.Record) map[string]string {
+	attrs := map[string]string{}
+	record.WalkAttributes(func(kv otellog.KeyValue) bool {
+		attrs[kv.Key] = kv.Value.AsString()
+		return true
+	})
+	return attrs
+}
+
+func TestFailureLogRecord(t *testing.T) {
+	const onboardingFailure = `{
+		"originalmessage": {"job_type": "user_onboarding", "message": {"user_id": "user-001"}},
+		"id": "12345",
+		"timestamp": "2025-08-30T12:00:00Z",
+		"status": "NEW"
+	}`
+
+	t.Run("Disabled", func(t *testing.T) {
+		p, _, _ := withFakeClients(t)
+		exporter := withFailureLogs(t, false)
+		p.processMessage(context.Background(), eventsMessage(onboardingFailure))
+		if len(exporter.records) != 0 {
+			t.Errorf("expected no log records, got %d", len(exporter.records))
+		}
+	})
+
+	t.Run("Enabled", func(t *testing.T) {
+		p, _, _ := withFakeClients(t)
+		exporter := withFailureLogs(t, true)
+
+		ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "Invoke")
+		defer span.End()
+		p.processMessage(ctx, eventsMessage(onboardingFailure))
+
+		if len(exporter.records) != 1 {
+			t.Fatalf("expected 1 log record, got %d", len(exporter.records))
+		}
+		record := exporter.records[0]
+		attrs := recordAttributes(record)
+
+		if !strings.HasPrefix(attrs["failure.reason"], "failed to parse job") {
+			t.Errorf("expected a parse failure reason, got %q", attrs["failure.reason"])
+		}
+		if strings.Contains(attrs["job.payload"], "user-001") || !strings.Contains(attrs["job.payload"], "[REDACTED]") {
+			t.Errorf("expected user_id to be redacted from the payload, got %s", attrs["job.payload"])
+		}
+		if attrs["trace_id"] != span.SpanContext().TraceID().String() {
+			t.Errorf("expected trace_id %s, got %s", span.SpanContext().TraceID(), attrs["trace_id"])
+		}
+		if record.TraceID() != span.SpanContext().TraceID() {
+			t.Errorf("expected the record to be correlated with trace %s, got %s", span.SpanContext().TraceID(), record.TraceID())
+		}
+		if record.EventName() != "job.failed" {
+			t.Errorf("expected event name job.failed, got %s", record.EventName())
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// chainedOnboardingPayload is a user_onboarding job with a welcome email, depth
+// links down its chain
+func chainedOnboardingPayload(depth int) string {
+	return fmt.Sprintf(`{
+		"originalmessage": {"job_type": "user_onboarding", "message": {"user_id": "user-001", "user_name": "John Doe", "email": "john@example.com"}},
+		"id": "12345",
+		"timestamp": "2025-08-30T12:00:00Z",
+		"status": "NEW",
+		"trace_context": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"chain_depth": %d
+	}`, depth)
+}
+
+func TestFollowupsEnqueued(t *testing.T) {
+	p, fakeQueue, _ := withFakeClients(t)
+	recorder := tracetest.NewSpanRecorder()
+	previousTracer := tracer
+	tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	defer func() { tracer = previousTracer }()
+
+	if err := p.processMessage(context.Background(), eventsMessage(chainedOnboardingPayload(0))); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	queued := fakeQueue.sentTo(jobsTodoURL)
+	if len(queued) != 1 {
+		t.Fatalf("expected the welcome email queued, got %v", queued)
+	}
+	var followup joblib.EnrichedPayload
+	if err := json.Unmarshal([]byte(queued[0]), &followup); err != nil {
+		t.Fatalf("expected an enriched payload, got %s: %v", queued[0], err)
+	}
+	if followup.ID != joblib.FollowupID("12345", 0) || followup.ParentID != "12345" || followup.ChainDepth != 1 || followup.Status != joblib.StatusNew {
+		t.Errorf("expected a NEW follow-up of 12345 one link down the chain, got %+v", followup)
+	}
+	if job, _, _, err := joblib.ParseJob(followup.OriginalMessage); err != nil || job.Name() != joblib.EmailNotification {
+		t.Errorf("expected an email_notification follow-up, got %s: %v", followup.OriginalMessage, err)
+	}
+
+	// The follow-up continues the trace as a child of the job that queued it
+	for _, span := range recorder.Ended() {
+		if span.Name() != "ExecuteJob" {
+			continue
+		}
+		parent, err := joblib.ParseTraceContext(followup.TraceContext, followup.TraceState)
+		if err != nil || parent.TraceID() != span.SpanContext().TraceID() || parent.SpanID() != span.SpanContext().SpanID() {
+			t.Errorf("expected the ExecuteJob span as the follow-up's parent, got %s", followup.TraceContext)
+		}
+	}
+}
+
+func TestFollowupChainCapped(t *testing.T) {
+	p, fakeQueue, fakeTopic := withFakeClients(t)
+	previous := joblib.MaxChainDepth
+	joblib.MaxChainDepth = 2
+	defer func() { joblib.MaxChainDepth = previous }()
+
+	if err := p.processMessage(context.Background(), eventsMessage(chainedOnboardingPayload(2))); err != nil {
+		t.Fatalf("expected the job to succeed though its follow-ups are dropped, got %v", err)
+	}
+	if queued := fakeQueue.sentTo(jobsTodoURL); len(queued) != 0 {
+		t.Errorf("expected no follow-ups past MAX_CHAIN_DEPTH, got %v", queued)
+	}
+	if states := publishedFor(fakeTopic.messages, joblib.FollowupID("12345", 0)); len(states) != 1 || states[0].Status != joblib.StatusRejected {
+		t.Errorf("expected the dropped follow-up reported as rejected, got %+v", states)
+	}
+	if states := publishedFor(fakeTopic.messages, "12345"); len(states) != 1 || states[0].Status != joblib.StatusCompleted {
+		t.Errorf("expected the onboarding itself to complete, got %+v", states)
+	}
+}
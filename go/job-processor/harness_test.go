@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const harnessResultsURL = "http://localstack:4566/000000000000/job-results"
+
+// pipelineHarness runs a job message through the whole pipeline in-process:
+// the generator puts it on a fake bus, the ingester stage parses and enriches
+// it onto a fake jobs queue, and the processor's handler consumes it. The
+// ingester is a separate main package, so its stage makes the same joblib
+// calls as its handler rather than calling it directly.
+type pipelineHarness struct {
+	t              *testing.T
+	processor      *processor
+	queue          *fakeSQS
+	recorder       *tracetest.SpanRecorder
+	ingesterTracer trace.Tracer
+}
+
+// pipelineOutcome is what came out the far end of the pipeline.
+type pipelineOutcome struct {
+	Result        *joblib.EnrichedPayload // final payload on the results queue, nil if the job never got there
+	DeadLetters   []string
+	IngestTraceID trace.TraceID // trace the ingester started for the job
+	ExecuteSpan   sdktrace.ReadOnlySpan
+}
+
+func newPipelineHarness(t *testing.T) *pipelineHarness {
+	t.Helper()
+	p, fakeQueue, _ := withFakeClients(t)
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previousTracer, previousResults := tracer, resultsQueueURL
+	tracer, resultsQueueURL = provider.Tracer("job-processor"), harnessResultsURL
+	t.Cleanup(func() { tracer, resultsQueueURL = previousTracer, previousResults })
+
+	return &pipelineHarness{t: t, processor: p, queue: fakeQueue, recorder: recorder, ingesterTracer: provider.Tracer("job-ingester")}
+}
+
+// publish is the generator: it wraps the job in the EventBridge event the
+// rule delivers to the ingester's queue.
+func (h *pipelineHarness) publish(jobMessage joblib.JobMessage) string {
+	detail, err := json.Marshal(jobMessage)
+	if err != nil {
+		h.t.Fatalf("failed to marshal job message: %v", err)
+	}
+	event, err := json.Marshal(map[string]any{
+		"version":     "0",
+		"id":          "eb-1",
+		"detail-type": "JobEvent",
+		"source":      "jobs",
+		"time":        "2025-08-30T12:00:00Z",
+		"detail":      json.RawMessage(detail),
+	})
+	if err != nil {
+		h.t.Fatalf("failed to marshal event: %v", err)
+	}
+	return string(event)
+}
+
+// ingest is the ingester: it parses and enriches the event's job onto the
+// jobs queue, or dead-letters the detail if the job is invalid.
+func (h *pipelineHarness) ingest(ctx context.Context, messageID, event string) trace.TraceID {
+	ctx, span := h.ingesterTracer.Start(ctx, "ProcessMessage")
+	defer span.End()
+
+	var envelope struct {
+		Detail json.RawMessage `json:"detail"`
+	}
+	if err := json.Unmarshal([]byte(event), &envelope); err != nil {
+		h.t.Fatalf("failed to parse event: %v", err)
+	}
+	if _, _, _, err := joblib.ParseJob(envelope.Detail); err != nil {
+		h.queue.SendMessage(ctx, &sqs.SendMessageInput{QueueUrl: aws.String(deadletterURL), MessageBody: aws.String(string(envelope.Detail))})
+		return span.SpanContext().TraceID()
+	}
+	payload, err := joblib.Enrich(envelope.Detail, messageID, joblib.SystemClock{}, span)
+	if err != nil {
+		h.t.Fatalf("failed to enrich job: %v", err)
+	}
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		h.t.Fatalf("failed to marshal enriched payload: %v", err)
+	}
+	h.queue.SendMessage(ctx, &sqs.SendMessageInput{QueueUrl: aws.String(jobsTodoURL), MessageBody: aws.String(string(payloadJSON))})
+	return span.SpanContext().TraceID()
+}
+
+// run feeds jobMessage through the pipeline and collects the outcome.
+func (h *pipelineHarness) run(ctx context.Context, jobMessage joblib.JobMessage) pipelineOutcome {
+	var outcome pipelineOutcome
+	// Only the processor's invocation runs on ctx, the ingester's ran before it
+	outcome.IngestTraceID = h.ingest(context.WithoutCancel(ctx), "sqs-ingest-1", h.publish(jobMessage))
+
+	var records []events.SQSMessage
+	for i, body := range h.queue.sentTo(jobsTodoURL) {
+		records = append(records, events.SQSMessage{MessageId: fmt.Sprintf("sqs-todo-%d", i), Body: body})
+	}
+	if len(records) > 0 {
+		if _, err := h.processor.handler(ctx, events.SQSEvent{Records: records}); err != nil {
+			h.t.Fatalf("unexpected handler error: %v", err)
+		}
+	}
+
+	if results := h.queue.sentTo(harnessResultsURL); len(results) == 1 {
+		var payload joblib.EnrichedPayload
+		if err := json.Unmarshal([]byte(results[0]), &payload); err != nil {
+			h.t.Fatalf("failed to unmarshal result: %v", err)
+		}
+		outcome.Result = &payload
+	}
+	outcome.DeadLetters = h.queue.sentTo(deadletterURL)
+	for _, span := range h.recorder.Ended() {
+		if span.Name() == "ExecuteJob" {
+			outcome.ExecuteSpan = span
+		}
+	}
+	return outcome
+}
+
+func TestPipelineHarness(t *testing.T) {
+	tests := []struct {
+		name             string
+		jobMessage       joblib.JobMessage
+		cancelled        bool
+		expectStatus     joblib.Status // empty when the job never reaches the processor
+		expectDeadLetter bool
+	}{
+		{
+			name:         "Job completes",
+			jobMessage:   joblib.JobMessage{JobType: "report_generation", Message: json.RawMessage(`{"report_name":"Sales Report","filters":"region=US"}`)},
+			expectStatus: joblib.StatusCompleted,
+		},
+		{
+			name:             "Invalid job dead-lettered by the ingester",
+			jobMessage:       joblib.JobMessage{JobType: "data_cleanup", Message: json.RawMessage(`{"retention":30}`)},
+			expectDeadLetter: true,
+		},
+		{
+			name:             "Failed job dead-lettered by the processor",
+			jobMessage:       joblib.JobMessage{JobType: "long_running_job", Message: json.RawMessage(`{"task_name":"Data Migration","timeout":1}`)},
+			cancelled:        true,
+			expectStatus:     joblib.StatusExecuteFailed,
+			expectDeadLetter: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			harness := newPipelineHarness(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.cancelled {
+				cancel()
+			}
+			defer cancel()
+
+			outcome := harness.run(ctx, tt.jobMessage)
+
+			if deadLettered := len(outcome.DeadLetters) == 1; deadLettered != tt.expectDeadLetter {
+				t.Errorf("expected dead-lettered to be %v, got %v", tt.expectDeadLetter, outcome.DeadLetters)
+			}
+			if tt.expectStatus == "" {
+				if outcome.Result != nil || outcome.ExecuteSpan != nil {
+					t.Errorf("expected the job to stop at the ingester")
+				}
+				return
+			}
+
+			if outcome.Result == nil || outcome.Result.Status != tt.expectStatus {
+				t.Fatalf("expected a %s result, got %+v", tt.expectStatus, outcome.Result)
+			}
+			if outcome.ExecuteSpan == nil {
+				t.Fatalf("expected the processor to execute the job")
+			}
+			if traceID := outcome.ExecuteSpan.SpanContext().TraceID(); traceID != outcome.IngestTraceID {
+				t.Errorf("expected execution in the ingester's trace %s, got %s", outcome.IngestTraceID, traceID)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	jobsProcessed        metric.Int64Counter     // executed jobs by type and status
+	jobExecutionDuration metric.Float64Histogram // how long executed jobs ran by type and status
+)
+
+func init() {
+	var err error
+	jobsProcessed, err = otel.Meter("job-processor").Int64Counter("jobs_processed_total",
+		metric.WithDescription("Jobs executed by the processor"),
+	)
+	if err != nil {
+		log.Printf("failed to create jobs processed counter: %v", err)
+	}
+	jobExecutionDuration, err = otel.Meter("job-processor").Float64Histogram("job_execution_duration_seconds",
+		metric.WithDescription("Time spent executing a job"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		log.Printf("failed to create job execution duration histogram: %v", err)
+	}
+}
+
+// recordExecuteDuration tags the span with how long Execute ran, as an
+// attribute and an event, whether or not the job succeeded.
+func recordExecuteDuration(span trace.Span, duration time.Duration, executeErr error) {
+	durationMs := float64(duration.Microseconds()) / 1000
+	span.SetAttributes(attribute.Float64("job.duration_ms", durationMs))
+	span.AddEvent("job execution finished", trace.WithAttributes(
+		attribute.Float64("job.duration_ms", durationMs),
+		attribute.Bool("success", executeErr == nil),
+	))
+}
+
+// recordJobProcessed counts an executed job and records how long it ran,
+// both dimensioned by job type and status.
+func recordJobProcessed(ctx context.Context, jobType string, status joblib.Status, duration time.Duration) {
+	attributes := metric.WithAttributes(
+		attribute.String("job_type", jobType),
+		attribute.String("status", string(status)),
+	)
+	if jobsProcessed != nil {
+		jobsProcessed.Add(ctx, 1, attributes)
+	}
+	if jobExecutionDuration != nil {
+		jobExecutionDuration.Record(ctx, duration.Seconds(), attributes)
+	}
+}
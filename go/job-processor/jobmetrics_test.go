@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestJobProcessedMetrics(t *testing.T) {
+	p, _, _ := withFakeClients(t)
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+	previousCounter, previousHistogram := jobsProcessed, jobExecutionDuration
+	jobsProcessed, _ = meter.Int64Counter("jobs_processed_total")
+	jobExecutionDuration, _ = meter.Float64Histogram("job_execution_duration_seconds")
+	defer func() { jobsProcessed, jobExecutionDuration = previousCounter, previousHistogram }()
+
+	p.processMessage(context.Background(), eventsMessage(validEnrichedPayload))
+	p.processMessage(context.Background(), eventsMessage(payloadWithID(t, "12346")))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	p.processMessage(ctx, eventsMessage(`{
+		"originalmessage": {"job_type": "long_running_job", "message": {"task_name": "Data Migration", "timeout": 60}},
+		"id": "67890",
+		"timestamp": "2025-08-30T12:00:00Z",
+		"status": "NEW"
+	}`))
+	// Rejected messages never execute so aren't counted
+	p.processMessage(context.Background(), eventsMessage("not json"))
+
+	var metrics metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &metrics); err != nil {
+		t.Fatalf("failed to collect metrics: %v", err)
+	}
+	counts, durations := map[string]int64{}, map[string]uint64{}
+	for _, scope := range metrics.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, point := range data.DataPoints {
+					counts[dimensions(point.Attributes)] += point.Value
+				}
+			case metricdata.Histogram[float64]:
+				for _, point := range data.DataPoints {
+					durations[dimensions(point.Attributes)] += point.Count
+				}
+			}
+		}
+	}
+
+	expected := map[string]int64{"report_generation/COMPLETED": 2, "long_running_job/EXECUTE_FAILED": 1}
+	if len(counts) != len(expected) {
+		t.Errorf("expected jobs_processed_total %v, got %v", expected, counts)
+	}
+	for key, count := range expected {
+		if counts[key] != count {
+			t.Errorf("expected jobs_processed_total %d for %s, got %d", count, key, counts[key])
+		}
+		if durations[key] != uint64(count) {
+			t.Errorf("expected %d job_execution_duration_seconds observations for %s, got %d", count, key, durations[key])
+		}
+	}
+}
+
+// dimensions formats a data point's job_type and status attributes as job_type/status
+func dimensions(attributes attribute.Set) string {
+	jobType, _ := attributes.Value("job_type")
+	status, _ := attributes.Value("status")
+	return jobType.AsString() + "/" + status.AsString()
+}
+
+func TestExecuteDurationRecorded(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		timeout       time.Duration
+		expectSuccess bool
+	}{
+		{name: "Completed", body: validEnrichedPayload, expectSuccess: true},
+		{
+			name: "Stopped at the deadline",
+			body: `{
+				"originalmessage": {"job_type": "long_running_job", "message": {"task_name": "Data Migration", "timeout": 60}},
+				"id": "67890",
+				"timestamp": "2025-08-30T12:00:00Z",
+				"status": "NEW"
+			}`,
+			timeout: 50 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _, _ := withFakeClients(t)
+			recorder := tracetest.NewSpanRecorder()
+			previousTracer := tracer
+			tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+			defer func() { tracer = previousTracer }()
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			p.processMessage(ctx, eventsMessage(tt.body))
+
+			for _, span := range recorder.Ended() {
+				if span.Name() != "ExecuteJob" {
+					continue
+				}
+				var duration float64
+				for _, kv := range span.Attributes() {
+					if kv.Key == "job.duration_ms" {
+						duration = kv.Value.AsFloat64()
+					}
+				}
+				// The deadline starts before Execute does, so the measured
+				// duration can fall just short of the timeout
+				if duration <= 0 || duration > float64((tt.timeout+5*time.Second).Milliseconds()) {
+					t.Errorf("expected a positive job.duration_ms within 5s of %s, got %v", tt.timeout, duration)
+				}
+				for _, event := range span.Events() {
+					if event.Name != "job execution finished" {
+						continue
+					}
+					for _, kv := range event.Attributes {
+						if kv.Key == "success" && kv.Value.AsBool() != tt.expectSuccess {
+							t.Errorf("expected success %v on the event, got %v", tt.expectSuccess, kv.Value.AsBool())
+						}
+					}
+					return
+				}
+				t.Fatalf("expected a job execution finished event")
+			}
+			t.Fatalf("expected an ExecuteJob span")
+		})
+	}
+}
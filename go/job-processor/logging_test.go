@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// logLines parses the JSON lines in logs, keyed by message
+func logLines(t *testing.T, logs *bytes.Buffer) map[string]map[string]any {
+	t.Helper()
+	lines := map[string]map[string]any{}
+	for _, line := range bytes.Split(bytes.TrimSpace(logs.Bytes()), []byte("\n")) {
+		var fields map[string]any
+		if err := json.Unmarshal(line, &fields); err != nil {
+			t.Fatalf("expected a JSON log line, got %s: %v", line, err)
+		}
+		lines[fields["msg"].(string)] = fields
+	}
+	return lines
+}
+
+func TestLogsCarryTraceContext(t *testing.T) {
+	p, _, _ := withFakeClients(t)
+	recorder := tracetest.NewSpanRecorder()
+	previousTracer := tracer
+	tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	defer func() { tracer = previousTracer }()
+	logs := captureLogs(t)
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	p.processMessage(context.Background(), eventsMessage(payloadWithTraceContext("00-"+traceID+"-00f067aa0ba902b7-01")))
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	executed, ok := logLines(t, logs)["successfully executed job"]
+	if !ok {
+		t.Fatalf("expected the job's success to be logged, got %s", logs.String())
+	}
+	if executed["trace_id"] != traceID || executed["span_id"] != spans[0].SpanContext().SpanID().String() {
+		t.Errorf("expected the ExecuteJob span's trace_id and span_id, got %v", executed)
+	}
+}
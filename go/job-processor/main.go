@@ -3,77 +3,456 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/trace"
 )
 
+// sqsSender is the subset of the SQS client used to queue messages
+type sqsSender interface {
+	SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
+}
+
+// snsPublisher is the subset of the SNS client used to publish end states
+type snsPublisher interface {
+	Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error)
+}
+
+// processor executes the jobs in SQS batches, sending on results and
+// follow-ups and routing failures through the clients it holds.
+type processor struct {
+	sqsClient sqsSender
+	snsClient snsPublisher
+}
+
+// newProcessor creates a processor with the SQS and SNS clients for cfg,
+// giving each call its own span when TRACE_AWS_CALLS is set.
+func newProcessor(cfg aws.Config) *processor {
+	p := &processor{sqsClient: sqs.NewFromConfig(cfg), snsClient: sns.NewFromConfig(cfg)}
+
+	// Optionally give each SQS and SNS call its own span for finer latency attribution
+	if envBool("TRACE_AWS_CALLS", false) {
+		p.sqsClient = joblib.TracedSQS{SQSSender: p.sqsClient, Tracer: otel.Tracer("job-processor")}
+		p.snsClient = joblib.TracedSNS{SNSPublisher: p.snsClient, Tracer: otel.Tracer("job-processor")}
+	}
+	return p
+}
+
 var (
 	tracer        = otel.Tracer("jobs")
-	sqsClient     *sqs.Client
+	awsConfig     aws.Config // loaded by init for every AWS client
 	jobsTodoURL   string
 	deadletterURL string
-	snsClient     *sns.Client
 	snsTopicArn   string
+	failureSink   string            // where failures are routed: dlq, sns or both
+	traceCarrier  string            // where trace context is propagated: body, attributes or both
+	spanStatus    joblib.SpanStatus // sets an Ok or Error status on spans by outcome
+
+	recordSQSAttributes bool
+	recordReceived      bool                      // add a "received" span event describing the raw body
+	bodyPreviewBytes    int                       // how much of the raw body the "received" event previews
+	recordJobParameters bool                      // tag ExecuteJob spans with job parameters such as retention and timeout
+	invocations         joblib.InvocationRecorder // tag spans and logs with the Lambda request ID
+	unwrapSNS           bool                      // unwrap payloads delivered inside an SNS notification envelope
+	signingKey          []byte                    // HMAC key payloads must be signed with, empty disables verification
+	fieldCipher         joblib.FieldCipher        // decrypts sensitive fields before execution, nil disables decryption
+	encryptFields       []string                  // job message fields encrypted by the ingester
+	maxReceiveCount     int                       // messages received more often than this are poison, 0 disables the check
+	notifyOnSuccess     bool                      // publish successful end states to SNS, failures are always published
+	resultsQueueURL     string                    // optional destination for the final enriched payload
+	resultsTopicArn     string                    // optional SNS destination for the final enriched payload
+
+	pipelineLatency metric.Float64Histogram
+	failureMetrics  *joblib.FailureMetrics // dead letters and failed SNS publishes
+
+	inFlight    = joblib.NewInFlightRegistry(joblib.SystemClock{}) // jobs currently executing
+	windowStats = joblib.NewStatsAggregator(joblib.SystemClock{})  // rolling throughput and error rate of executed jobs
+	jobStatuses *joblib.LRUCache[string, joblib.Status]            // job ID -> terminal status of recently processed jobs
+
+	replayCachedResults bool                                             // re-publish cached results for replayed completed jobs instead of re-executing
+	jobResults          *joblib.LRUCache[string, joblib.EnrichedPayload] // job ID -> final payload of recently completed jobs
 )
 
 func init() {
-	// Load AWS configuration
-	cfg, err := config.LoadDefaultConfig(context.TODO(),
-		config.WithRegion("us-east-1"),
-		config.WithEndpointResolver(aws.EndpointResolverFunc(func(service, region string) (aws.Endpoint, error) {
-			if service == sqs.ServiceID {
-				return aws.Endpoint{URL: "http://localstack:4566"}, nil // LocalStack endpoint
-			}
-			return aws.Endpoint{}, &aws.EndpointNotFoundError{}
-		})),
-	)
+	// Log JSON lines at LOG_LEVEL (info by default) and above
+	slog.SetDefault(joblib.NewLogger(os.Stderr, joblib.ParseLogLevel(os.Getenv("LOG_LEVEL"), slog.LevelInfo)))
+
+	// Queues, topic, region and endpoint come from the environment, defaulting to LocalStack
+	serviceConfig := joblib.LoadServiceConfig(os.LookupEnv)
+
+	// Load AWS configuration, retrying with backoff if AWS_INIT_ATTEMPTS allows
+	cfg, err := joblib.LoadAWSConfigWithRetry(context.TODO(), os.LookupEnv, joblib.LoadAWSConfig)
 	if err != nil {
 		log.Fatalf("unable to load AWS SDK config: %v", err)
 	}
-
-	// Create SQS client
-	sqsClient = sqs.NewFromConfig(cfg)
+	awsConfig = cfg
 
 	// Set the jobs-todo queue URL
-	jobsTodoURL = "http://localstack:4566/000000000000/jobs-todo" // Replace with the actual queue URL
+	jobsTodoURL = serviceConfig.JobsTodoURL
 
 	// Dead letter for post mortem analysis
-	deadletterURL = "http://localstack:4566/000000000000/dead-letter-queue"
+	deadletterURL = serviceConfig.DeadLetterURL
+
+	// Optionally buffer each invocation's dead letters into SendMessageBatch calls, and gzip them
+	if envBool("DLQ_BATCH", false) {
+		deadLetterBatchClient = sqs.NewFromConfig(cfg)
+	}
+	compressDeadLetters = envBool("DLQ_COMPRESS", false)
+
+	// Optionally send a metrics record per message for a custom metrics consumer
+	metricsQueueURL = os.Getenv("METRICS_QUEUE_URL")
+
+	// Set the SNS topic ARN
+	snsTopicArn = serviceConfig.SNSTopicArn
+
+	// Route failures to the dead-letter queue, SNS or both (the default)
+	failureSink = joblib.ParseFailureSink(os.Getenv("FAILURE_SINK"), joblib.FailureSinkBoth)
+
+	// Report failed messages back to SQS so only they are redelivered
+	reportBatchItemFailures = envBool("REPORT_BATCH_ITEM_FAILURES", false)
+
+	// Extract trace context from the payload body (the default), SQS message attributes or both
+	traceCarrier = joblib.ParsePropagation(os.Getenv("TRACE_PROPAGATION"), joblib.PropagationBody)
+
+	// Optionally have long-running jobs emit heartbeats, e.g. HEARTBEAT_INTERVAL=30s
+	if interval := os.Getenv("HEARTBEAT_INTERVAL"); interval != "" {
+		heartbeatInterval, err := time.ParseDuration(interval)
+		if err != nil {
+			log.Printf("ignoring invalid HEARTBEAT_INTERVAL %q: %v", interval, err)
+		} else {
+			joblib.HeartbeatInterval = heartbeatInterval
+		}
+	}
+
+	// Record SQS system attributes such as the receive count on spans
+	recordSQSAttributes = envBool("RECORD_SQS_ATTRIBUTES", true)
+
+	// Record job parameters such as cleanup retention and task timeouts on spans
+	recordJobParameters = envBool("RECORD_JOB_PARAMETERS", true)
+
+	// Record the Lambda request ID on spans and logs
+	invocations.Record = envBool("RECORD_INVOCATION_ID", true)
+
+	// Accept job types in any casing, e.g. REPORT_GENERATION
+	joblib.NormalizeJobType = envBool("NORMALIZE_JOB_TYPE", false)
+
+	// Require report filters to be key=value pairs
+	joblib.StrictFilters = envBool("STRICT_FILTERS", false)
+
+	// Reject cleanups below a table's minimum retention, e.g. MIN_RETENTION_DAYS=users=90,audit_log=365
+	joblib.MinRetentionDays = joblib.ParseMinRetention(os.Getenv("MIN_RETENTION_DAYS"))
+
+	// Cap long running timeouts by priority, e.g. PRIORITY_TIMEOUTS=0=60,1=300,2=900
+	if limits := joblib.ParsePriorityTimeouts(os.Getenv("PRIORITY_TIMEOUTS")); len(limits) > 0 {
+		joblib.RegisterCrossFieldValidator(joblib.LongRunning, joblib.PriorityTimeouts(limits))
+	}
+
+	// Reject batches with too many children, e.g. MAX_BATCH_CHILDREN=100
+	joblib.MaxBatchChildren = envInt("MAX_BATCH_CHILDREN", 0)
+	// Run up to BATCH_CONCURRENCY children of a batch job at once, 1 runs them in order
+	joblib.BatchConcurrency = envInt("BATCH_CONCURRENCY", 1)
+	// Stop follow-ups queueing follow-ups of their own past MAX_CHAIN_DEPTH links
+	joblib.MaxChainDepth = envInt("MAX_CHAIN_DEPTH", 3)
+
+	// Optionally enforce a user_id format, e.g. USER_ID_PATTERN=^user_[0-9]+$
+	joblib.UserIDPattern = joblib.ParseUserIDPattern(os.Getenv("USER_ID_PATTERN"))
+
+	// Validate some job types leniently, e.g. LENIENT_JOB_TYPES=report_generation
+	joblib.LenientJobTypes = joblib.ParseJobTypes(os.Getenv("LENIENT_JOB_TYPES"))
+
+	// Ignore, warn about or reject fields a job type doesn't define
+	joblib.UnknownFields = joblib.ParseUnknownFields(os.Getenv("UNKNOWN_FIELDS"), joblib.UnknownFieldsIgnore)
+
+	// Optionally validate job messages against JSON schemas in JOB_SCHEMA_DIR
+	if err := joblib.LoadSchemasFromEnv(); err != nil {
+		log.Fatalf("unable to load job schemas: %v", err)
+	}
+
+	// Fail fast if a job type is registered without working validation
+	if err := joblib.VerifyRegistry(); err != nil {
+		log.Fatalf("job type registry is misconfigured: %v", err)
+	}
+
+	// Unwrap SNS notification envelopes if jobs-todo is subscribed to a topic, raw bodies still work
+	unwrapSNS = envBool("UNWRAP_SNS", true)
+
+	// Optionally verify payload signatures, dead-lettering any that fail
+	signingKey = []byte(os.Getenv("HMAC_SIGNING_KEY"))
+
+	// Decrypt fields the ingester encrypted at rest, batch children are re-encrypted when queued
+	fieldCipher, encryptFields, err = joblib.FieldEncryptionFromEnv()
+	if err != nil {
+		log.Fatalf("unable to configure field encryption: %v", err)
+	}
+
+	// Dead-letter messages stuck in a redelivery loop
+	maxReceiveCount = envInt("MAX_RECEIVE_COUNT", 0)
+
+	// Optionally run some job types under a CPU time/memory watchdog, e.g. SANDBOX_JOB_TYPES=long_running_job
+	sandboxJobTypes = joblib.ParseJobTypes(os.Getenv("SANDBOX_JOB_TYPES"))
+	sandboxLimits = joblib.ResourceLimits{
+		MaxCPUTime:     envDuration("SANDBOX_MAX_CPU_TIME", 0),
+		MaxMemoryBytes: uint64(envInt("SANDBOX_MAX_MEMORY_MB", 0)) << 20,
+	}
+
+	// Stop any job executing longer than this, e.g. EXECUTE_TIMEOUT=30s
+	executeTimeout = envDuration("EXECUTE_TIMEOUT", 0)
+
+	// Optionally join ExecuteJob to the ingester's span by a link alone, starting a new trace
+	traceLinkOnly = envBool("TRACE_LINK_ONLY", false)
+
+	// Count messages whose trace context would break the trace, for propagation QA
+	traceGapDebug = envBool("TRACE_GAP_DEBUG", false)
+
+	// Optionally record each raw body's size and a preview, for debugging deserialization
+	recordReceived = envBool("RECORD_RECEIVED_EVENT", false)
+	bodyPreviewBytes = envInt("BODY_PREVIEW_BYTES", 256)
+
+	// Set an Ok or Error span status by outcome
+	spanStatus.Record = envBool("RECORD_SPAN_STATUS", true)
+
+	// Optionally hold poison messages on a quarantine queue until they are released by hand
+	quarantineURL = os.Getenv("QUARANTINE_QUEUE_URL")
+
+	// Retry jobs stopped by the invocation being cancelled rather than dead-lettering them
+	retryCancelled = envBool("RETRY_CANCELLED_JOBS", false)
+
+	// Dead-letter jobs ingested longer ago than this instead of executing them, e.g. JOB_TTL=15m
+	jobTTL = envDuration("JOB_TTL", 0)
+
+	// Requeue failed jobs this many times before dead-lettering them
+	maxRetries = envInt("MAX_RETRIES", 0)
+
+	// Retry failed executions in-process this many times first, backing off from EXECUTE_RETRY_DELAY
+	maxExecuteRetries = envInt("MAX_EXECUTE_RETRIES", 0)
+	executeRetryDelay = envDuration("EXECUTE_RETRY_DELAY", 100*time.Millisecond)
+
+	// Optionally separate parse failures from execution failures for triage
+	parseErrorQueueURL = os.Getenv("PARSE_ERROR_QUEUE_URL")
+
+	// Optionally throttle tenants executing more than their quota, e.g. TENANT_FIELD=user_id TENANT_QUOTA=10
+	tenantField = os.Getenv("TENANT_FIELD")
+	if quota := envInt("TENANT_QUOTA", 0); tenantField != "" && quota > 0 {
+		tenantQuota = joblib.NewTenantQuota(joblib.SystemClock{}, quota, envDuration("TENANT_QUOTA_WINDOW", time.Minute))
+	}
+	throttleDelay = envDuration("TENANT_THROTTLE_DELAY", 30*time.Second)
+	if throttleDelay > 15*time.Minute {
+		log.Printf("TENANT_THROTTLE_DELAY %s exceeds the SQS maximum delay, using 15m", throttleDelay)
+		throttleDelay = 15 * time.Minute
+	}
+
+	// Process each payload of a body holding a JSON array of them
+	splitArrayBodies = envBool("SPLIT_ARRAY_BODIES", false)
+
+	// Warn about job IDs repeated within a batch
+	detectDuplicateIDs = envBool("DETECT_DUPLICATE_IDS", false)
+
+	// Execute a job once when a batch delivers it more than once
+	coalesceDuplicates = envBool("COALESCE_DUPLICATES", false)
+
+	// Process up to MAX_CONCURRENCY records of a batch at once
+	maxConcurrency = envInt("MAX_CONCURRENCY", 4)
+	if sandboxSharesProcess() {
+		log.Printf("SANDBOX_JOB_TYPES limits are measured across the whole process, using MAX_CONCURRENCY=1 rather than %d so sandboxed jobs aren't stopped for what other records used", maxConcurrency)
+		maxConcurrency = 1
+	}
+
+	// Publish one SNS summary per invocation rather than one message per job
+	snsBatchSummary = envBool("SNS_BATCH_SUMMARY", false)
+
+	// Only publish failures to SNS when NOTIFY_ON_SUCCESS=false, success is still visible in the span metrics
+	notifyOnSuccess = envBool("NOTIFY_ON_SUCCESS", true)
+
+	// Optionally publish JobCompleted/JobFailed events back to EventBridge for chaining
+	if envBool("COMPLETION_EVENTS", false) {
+		eventBridgeClient = eventbridge.NewFromConfig(cfg)
+		if envBool("TRACE_AWS_CALLS", false) {
+			eventBridgeClient = joblib.TracedEventBridge{EventPutter: eventBridgeClient, Tracer: otel.Tracer("job-processor")}
+		}
+	}
+	completionEventBus = envString("COMPLETION_EVENT_BUS", "default")
+	completionEventSource = envString("COMPLETION_EVENT_SOURCE", "jobs.processor")
+	completedEventDetailType = envString("COMPLETION_COMPLETED_DETAIL_TYPE", "JobCompleted")
+	failedEventDetailType = envString("COMPLETION_FAILED_DETAIL_TYPE", "JobFailed")
+
+	// Optionally re-emit the final enriched payload for downstream consumers
+	resultsQueueURL = os.Getenv("RESULTS_QUEUE_URL")
+	resultsTopicArn = os.Getenv("RESULTS_TOPIC_ARN")
+
+	// Optionally estimate job costs, e.g. JOB_COST_FACTORS=report_generation=0.02,data_cleanup=0.005
+	costFactors = parseCostFactors(os.Getenv("JOB_COST_FACTORS"))
+
+	// Capture failed jobs as OTel log records, redacting personal data from the payload
+	failureLogRecords = envBool("FAILURE_LOG_RECORDS", false)
+	redactFields = parseRedactFields("user_id,user_name")
+	if fields, ok := os.LookupEnv("REDACT_FIELDS"); ok {
+		redactFields = parseRedactFields(fields)
+	}
+
+	// Optionally write job metrics to stdout as CloudWatch EMF for users without an OTel backend
+	if os.Getenv("METRICS_EXPORTER") == "emf" {
+		emfMetrics = &emfWriter{out: os.Stdout, now: time.Now}
+	}
+
+	// Cap the in-memory caches so they can't grow unbounded, 0 removes the cap
+	cacheMaxEntries := envInt("CACHE_MAX_ENTRIES", 10000)
+	jobStatuses = joblib.NewLRUCache[string, joblib.Status]("job_statuses", cacheMaxEntries)
+
+	// Serve replays of completed jobs from cache so their side effects don't run twice
+	replayCachedResults = envBool("REPLAY_CACHED_RESULTS", false)
+	jobResults = joblib.NewLRUCache[string, joblib.EnrichedPayload]("job_results", cacheMaxEntries)
+
+	// Skip jobs that were already executed, recorded in DynamoDB when DEDUP_TABLE is set and in memory otherwise
+	dedupClaimTTL = envDuration("DEDUP_CLAIM_TTL", 15*time.Minute)
+	if table := os.Getenv("DEDUP_TABLE"); table != "" {
+		dedup = &dynamoDedup{client: dynamodb.NewFromConfig(cfg), table: table, now: time.Now}
+	} else {
+		dedup = newMemoryDedup(cacheMaxEntries)
+	}
+
+	// Optionally persist each executed job's outcome to DynamoDB for an audit trail
+	if resultsTable = os.Getenv("RESULTS_TABLE"); resultsTable != "" {
+		resultsClient = dynamodb.NewFromConfig(cfg)
+	}
 
-	// Initialize SNS client
-	snsClient = sns.NewFromConfig(cfg)
+	// Optionally archive a sample of completed payloads to S3
+	archiveBucket = os.Getenv("ARCHIVE_BUCKET")
+	archivePrefix = os.Getenv("ARCHIVE_PREFIX")
+	archiveSampleRate = envFloat("ARCHIVE_SAMPLE_RATE", 0)
+	if archiveBucket != "" && archiveSampleRate > 0 {
+		archiveClient = s3.NewFromConfig(cfg, func(o *s3.Options) {
+			o.UsePathStyle = true // LocalStack doesn't create the bucket DNS entries
+		})
+	}
+}
 
-	// Set the SNS topic ARN for LocalStack
-	snsTopicArn = "arn:aws:sns:us-east-1:000000000000:job-end-state-topic"
+// envBool reads a boolean environment variable, returning fallback when it is
+// unset or not a valid boolean.
+func envBool(name string, fallback bool) bool {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return fallback
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("ignoring invalid %s %q: %v", name, value, err)
+		return fallback
+	}
+	return parsed
 }
 
-func initTracer() func() {
-	// Create OTLP HTTP exporter
-	exporter, err := otlptracehttp.New(context.Background(),
-		otlptracehttp.WithEndpoint("otel_collector:4318"),
-		otlptracehttp.WithInsecure(),
+// envString reads a string environment variable, returning fallback when it
+// is unset or empty.
+func envString(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// envDuration reads a duration environment variable such as "500ms",
+// returning fallback when it is unset or not a valid duration.
+func envDuration(name string, fallback time.Duration) time.Duration {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("ignoring invalid %s %q: %v", name, value, err)
+		return fallback
+	}
+	return parsed
+}
+
+// envInt reads an integer environment variable, returning fallback when it is
+// unset or not a valid integer.
+func envInt(name string, fallback int) int {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("ignoring invalid %s %q: %v", name, value, err)
+		return fallback
+	}
+	return parsed
+}
+
+// envFloat reads a float environment variable, returning fallback when it is
+// unset or not a valid number.
+func envFloat(name string, fallback float64) float64 {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return fallback
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("ignoring invalid %s %q: %v", name, value, err)
+		return fallback
+	}
+	return parsed
+}
+
+// isPoisonMessage reports whether a message has been received more times than
+// the configured maximum, which indicates it is stuck in a redelivery loop.
+func isPoisonMessage(message events.SQSMessage) (int, bool) {
+	if maxReceiveCount <= 0 {
+		return 0, false
+	}
+	receiveCount, ok := joblib.ReceiveCount(message.Attributes)
+	return receiveCount, ok && receiveCount > maxReceiveCount
+}
+
+func init() {
+	var err error
+	pipelineLatency, err = otel.Meter("job-processor").Float64Histogram("pipeline_latency_seconds",
+		metric.WithDescription("Time from ingestion to job completion"),
+		metric.WithUnit("s"),
 	)
 	if err != nil {
-		log.Fatalf("failed to create OTLP exporter: %v", err)
+		log.Printf("failed to create pipeline latency histogram: %v", err)
+	}
+	failureMetrics, err = joblib.NewFailureMetrics(otel.Meter("job-processor"))
+	if err != nil {
+		log.Printf("failed to create failure counters: %v", err)
+	}
+}
+
+func initTracer() func() {
+	// Create the span exporter, OTLP unless OTEL_TRACES_EXPORTER=stdout
+	exporter, err := joblib.NewTraceExporter(context.Background(), joblib.LoadTelemetryConfig(os.LookupEnv))
+	if err != nil {
+		log.Fatalf("failed to create trace exporter: %v", err)
 	}
 
-	// Create trace provider
+	// Create trace provider, sampling OTEL_TRACES_SAMPLER_ARG of new traces
 	tp := sdktrace.NewTracerProvider(
 		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(joblib.LoadTelemetryConfig(os.LookupEnv).Sampler()),
 		sdktrace.WithResource(resource.NewSchemaless(
 			attribute.String("service.name", "job-processor"),
 		)),
@@ -89,126 +468,584 @@ func initTracer() func() {
 	}
 }
 
-func handler(ctx context.Context, sqsEvent events.SQSEvent) error {
+// initMeter exports metrics over OTLP to the same collector as the
+// traces, or serves them on /metrics at METRICS_ADDR, returning a function
+// that flushes and shuts the exporter down.
+func initMeter() func() {
+	reader, stopServing, err := joblib.NewMetricReader(context.Background(), joblib.LoadTelemetryConfig(os.LookupEnv))
+	if err != nil {
+		log.Fatalf("failed to create metric exporter: %v", err)
+	}
+
+	mp := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(reader),
+		sdkmetric.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", "job-processor"),
+		)),
+	)
+	otel.SetMeterProvider(mp)
+
+	return func() {
+		if err := mp.Shutdown(context.Background()); err != nil {
+			log.Printf("failed to shut down meter provider: %v", err)
+		}
+		stopServing()
+	}
+}
 
-	for _, message := range sqsEvent.Records {
-		processMessage(ctx, message)
+func (p *processor) handler(ctx context.Context, sqsEvent events.SQSEvent) (events.SQSEventResponse, error) {
+	// Producers may batch several payloads into one body
+	records := sqsEvent.Records
+	if splitArrayBodies {
+		records = expandArrayBodies(records)
 	}
 
-	return nil
+	if detectDuplicateIDs {
+		warnDuplicateIDs(ctx, records)
+	}
+
+	// Duplicates are acked without executing, the first record runs the job
+	var duplicates []coalescedDuplicate
+	if coalesceDuplicates {
+		records, duplicates = coalesceRecords(records)
+	}
+
+	if deadLetterBatchClient != nil {
+		var deadLetters *deadLetterBuffer
+		ctx, deadLetters = withDeadLetterBuffer(ctx)
+		defer flushDeadLetters(ctx, deadLetters)
+	}
+
+	var summary *batchSummary
+	if snsBatchSummary {
+		ctx, summary = withBatchSummary(ctx)
+	}
+
+	// Process the records concurrently, reporting failures in batch order
+	var response events.SQSEventResponse
+	for i, failed := range p.processRecords(ctx, records) {
+		if failed {
+			response.BatchItemFailures = appendBatchItemFailure(response.BatchItemFailures, records[i].MessageId)
+		}
+	}
+	if summary == nil {
+		return response, nil
+	}
+
+	summary.Records = len(records) + len(duplicates)
+	for _, duplicate := range duplicates {
+		recordCtx := withRecordID(ctx, duplicate.MessageID)
+		p.notifyEndState(recordCtx, joblib.NewJobEndStateEvent(recordCtx, duplicate.JobID, "", joblib.StatusCompleted, ""), fmt.Sprintf("coalesced duplicate of job %s", duplicate.JobID))
+	}
+	if err := p.publishBatchSummary(ctx, summary); err != nil {
+		logger(ctx).Error("failed to publish batch summary to SNS", "error", err)
+	}
+	return response, nil
 }
 
-func sendToDeadLetterQueue(ctx context.Context, messageBody string) {
-	_, err := sqsClient.SendMessage(ctx, &sqs.SendMessageInput{
-		QueueUrl:    aws.String(deadletterURL),
-		MessageBody: aws.String(messageBody),
-	})
-	if err != nil {
-		log.Printf("failed to send message to dead-letter queue: %v", err)
+// logger returns the default logger tagged with the trace and span of ctx,
+// and the Lambda request ID to correlate lines with CloudWatch.
+func logger(ctx context.Context) *slog.Logger {
+	l := joblib.LoggerWithTrace(ctx, slog.Default())
+	if id, ok := invocations.ID(ctx); ok {
+		l = l.With(slog.String("request_id", id))
+	}
+	if id := joblib.CorrelationIDFromContext(ctx); id != "" {
+		l = l.With(slog.String("correlation_id", id))
+	}
+	return l
+}
+
+// reportFailure routes a message that failed at stage to the configured
+// failure sinks: an end-state event on the SNS topic and/or the original body,
+// wrapped with the failure, on the dead-letter queue. It is also recorded as
+// an OTel log record when enabled.
+func (p *processor) reportFailure(ctx context.Context, stage string, event joblib.JobEndStateEvent, messageBody string) {
+	emitFailureLog(ctx, event.Error, messageBody)
+	if err := p.failureRouter(ctx).Report(ctx, stage, event, messageBody); err != nil {
+		logger(ctx).Error("failed to report failure", "error", err)
 	}
 }
 
-func processMessage(ctx context.Context, message events.SQSMessage) {
+// failureRouter routes failures to the sinks FAILURE_SINK selects, with SNS
+// notifications folded into the invocation's summary when there is one.
+func (p *processor) failureRouter(ctx context.Context) joblib.FailureRouter {
+	return joblib.FailureRouter{
+		Sink: failureSink,
+		Publish: func(ctx context.Context, event joblib.JobEndStateEvent) error {
+			return p.notifyEndState(ctx, event, event.Error)
+		},
+		DeadLetters:      p.deadLetterQueue(ctx),
+		ParseErrorURL:    parseErrorQueueURL,
+		ParseErrorClient: p.sqsClient,
+	}
+}
+
+func (p *processor) processMessage(ctx context.Context, message events.SQSMessage) error {
+
+	logger(ctx).Info("processing SQS message", "body", message.Body)
 
-	log.Printf("Processing SQS message: %s", message.Body)
+	// The job span only starts once the body has parsed, so the raw body gets its own span
+	if recordReceived {
+		_, span := tracer.Start(ctx, "ReceiveMessage", trace.WithAttributes(
+			attribute.String("sqs.message.id", message.MessageId),
+		))
+		span.AddEvent("received", trace.WithAttributes(joblib.ReceivedAttributes(message.Body, bodyPreviewBytes)...))
+		span.End()
+	}
+
+	// Short-circuit messages caught in a redelivery loop
+	if receiveCount, poison := isPoisonMessage(message); poison {
+		if quarantineURL != "" {
+			logger(ctx).Warn("poison message, quarantining", "sqs_message_id", message.MessageId, "receive_count", receiveCount, "max_receive_count", maxReceiveCount)
+			reason := fmt.Sprintf("received %d times (max %d)", receiveCount, maxReceiveCount)
+			err := p.quarantineMessage(ctx, message, reason, receiveCount)
+			if err == nil {
+				return nil
+			}
+			logger(ctx).Error("failed to quarantine poison message", "sqs_message_id", message.MessageId, "error", err)
+		}
+		logger(ctx).Warn("poison message, sending to dead-letter queue", "sqs_message_id", message.MessageId, "receive_count", receiveCount, "max_receive_count", maxReceiveCount)
+		p.reportFailure(ctx, joblib.StageReceive, joblib.NewJobEndStateEvent(ctx, "", "", joblib.StatusRejected, fmt.Sprintf("poison message received %d times: %s", receiveCount, message.Body)), message.Body)
+		return fmt.Errorf("poison message received %d times", receiveCount)
+	}
 
 	// Parse the SQS message into a Job
-	var job joblib.EnrichedPayload
-	if err := json.Unmarshal([]byte(message.Body), &job); err != nil {
-		log.Printf("failed to parse job message: %s, err: %s", message.Body, err)
-		publishToSNS(snsClient, snsTopicArn, fmt.Sprintf("failed to parse job message: %s, err: %v", message.Body, err))
-		sendToDeadLetterQueue(ctx, message.Body)
-		return
+	msg, err := newMessage(message)
+	if err != nil {
+		logger(ctx).Error("failed to parse job message", "body", message.Body, "error", err)
+		p.reportParseFailure(ctx, joblib.StageParse, joblib.NewJobEndStateEvent(ctx, "", "", joblib.StatusRejected, fmt.Sprintf("failed to parse job message: %s, err: %v", message.Body, err)), message.Body)
+		return err
+	}
+
+	// A payload shape this processor doesn't know could be misread, so turn it away
+	if err := joblib.CheckSchemaVersion(msg.Payload); err != nil {
+		logger(ctx).Error("rejecting job message", "sqs_message_id", msg.ID, "error", err)
+		p.reportFailure(ctx, joblib.StageValidate, joblib.NewJobEndStateEvent(ctx, msg.Payload.ID, "", joblib.StatusRejected, fmt.Sprintf("unsupported job message: %s, err: %v", msg.Body, err)), msg.Body)
+		return err
+	}
+	if len(signingKey) > 0 {
+		if err := joblib.VerifySignature(msg.Payload, signingKey); err != nil {
+			logger(ctx).Error("failed to verify job message", "sqs_message_id", msg.ID, "error", err)
+			p.reportFailure(ctx, joblib.StageValidate, joblib.NewJobEndStateEvent(ctx, msg.Payload.ID, "", joblib.StatusRejected, fmt.Sprintf("failed to verify job message: %s, err: %v", msg.Body, err)), msg.Body)
+			return err
+		}
+	}
+	job := msg.Payload
+
+	// Carry the correlation ID into every log line and end state for the job,
+	// falling back to the caller's for payloads queued without one
+	correlationID := job.CorrelationID
+	if correlationID == "" {
+		correlationID = joblib.MessageCorrelationID(job.OriginalMessage)
+	}
+	ctx = joblib.ContextWithCorrelationID(ctx, correlationID)
+
+	// The payload stays encrypted, only the parsed job sees the plaintext fields
+	originalMessage := job.OriginalMessage
+	if fieldCipher != nil {
+		if originalMessage, err = joblib.DecryptFields(job.OriginalMessage, encryptFields, fieldCipher); err != nil {
+			logger(ctx).Error("failed to decrypt job message", "sqs_message_id", msg.ID, "error", err)
+			p.reportFailure(ctx, joblib.StageParse, joblib.NewJobEndStateEvent(ctx, job.ID, "", joblib.StatusRejected, fmt.Sprintf("failed to decrypt job message: %s, err: %v", msg.Body, err)), msg.Body)
+			return err
+		}
 	}
 
 	// Extract the propagated trace context
-	traceparent := job.TraceContext
-	var executeCtx context.Context
+	traceparent := msg.TraceParent
+	checkTraceContext(ctx, stageReceive, job.ID, traceparent)
+	executeCtx := ctx
+	var ingesterSpan trace.SpanContext
 
 	if traceparent == "" {
-		log.Printf("No trace context found in the job message")
+		logger(ctx).Info("no trace context found in the job message", "job_id", job.ID)
 	} else {
-		// Validate the traceparent format
-		if len(traceparent) != 55 || traceparent[:3] != "00-" {
-			log.Printf("Invalid traceparent format: %s", traceparent)
+		// Continue the remote trace, keeping any vendor-specific tracestate
+		if spanContext, err := joblib.ParseTraceContext(traceparent, msg.TraceState); err == nil {
+			executeCtx = trace.ContextWithRemoteSpanContext(ctx, spanContext)
+			ingesterSpan = spanContext
+			logger(executeCtx).Debug("extracted trace context", "tracestate", msg.TraceState)
 		} else {
-			// Parse the traceID from the traceparent string
-			traceID, err := trace.TraceIDFromHex(traceparent[3:35]) // Extract the trace ID part
-			if err != nil {
-				log.Printf("Failed to parse trace ID from traceparent: %s, err: %v", traceparent, err)
-				return
-			}
-			// Parse the spanID from the traceparent string
-			spanID, err := trace.SpanIDFromHex(traceparent[36:52]) // Extract the span ID part
-			if err != nil {
-				log.Printf("Failed to parse span ID from traceparent: %s, err: %v", traceparent, err)
-				return
-			}
-			// Create a new SpanContext
-			spanContext := trace.NewSpanContext(trace.SpanContextConfig{
-				TraceID:    traceID,
-				SpanID:     spanID,
-				TraceFlags: trace.FlagsSampled,
-				Remote:     true, // Mark this as a remote span
-			})
-
-			// Inject the SpanContext into the context
-			executeCtx = trace.ContextWithSpanContext(ctx, spanContext)
-			log.Printf("Manually added trace context: traceID=%s, spanID=%s", traceID.String(), spanID.String())
+			logger(ctx).Warn("invalid traceparent format", "traceparent", traceparent, "error", err)
 		}
 	}
 
+	// Restore the baggage the ingester propagated, tagging the job's spans with its tenant
+	executeCtx = joblib.ExtractBaggage(executeCtx, job.BaggageContext)
+	if tenant := joblib.TenantFromContext(executeCtx); tenant != "" {
+		logger(executeCtx).Info("job belongs to tenant", "job_id", job.ID, "tenant_id", tenant)
+	}
+
 	// Parse the job from the JobMessage
-	parsedJob, _, jobType, err := joblib.ParseJob(job.OriginalMessage)
+	parsedJob, _, jobType, err := joblib.ParseJob(originalMessage)
 	if err != nil {
-		log.Printf("failed to parse job: %s, err: %s", job.OriginalMessage, err)
-		publishToSNS(snsClient, snsTopicArn, fmt.Sprintf("failed to parse job: %s, err: %s", job.OriginalMessage, err))
-		sendToDeadLetterQueue(ctx, message.Body)
-		return
+		logger(executeCtx).Error("failed to parse job", "job", job.OriginalMessage, "error", err)
+		failedType := ""
+		if jobType != nil {
+			failedType = *jobType
+		}
+		p.reportParseFailure(ctx, joblib.ParseStage(err), joblib.NewJobEndStateEvent(ctx, job.ID, failedType, joblib.RejectedStatus(err), fmt.Sprintf("failed to parse job: %s, err: %s", job.OriginalMessage, err)), msg.Body)
+		return err
+	}
+
+	// Batch jobs are split into children which are queued and tracked independently
+	if batchJob, ok := parsedJob.(joblib.BatchJob); ok {
+		p.enqueueBatchChildren(executeCtx, msg, batchJob)
+		return nil
 	}
 
 	// Execute the job
-	_, jobSpan := tracer.Start(executeCtx, "ExecuteJob", trace.WithAttributes(
+	jobCtx, jobSpan := tracer.Start(executeCtx, "ExecuteJob", append(ingesterSpanOptions(ingesterSpan), trace.WithAttributes(
 		attribute.String("job.type", *jobType),
 		attribute.String("message.id", job.ID),
-		attribute.String("sqs.message.id", message.MessageId),
-	))
+		attribute.String("sqs.message.id", msg.ID),
+		attribute.Int("job.retry_count", job.RetryCount),
+	), trace.WithAttributes(joblib.TenantAttributes(executeCtx)...), trace.WithAttributes(joblib.CorrelationAttributes(executeCtx)...), trace.WithAttributes(joblib.EventBridgeAttributes(job.EventBridgeID)...))...)
+	if job.ParentID != "" {
+		jobSpan.SetAttributes(attribute.String("parent.id", job.ParentID))
+	}
+	jobSpan.SetAttributes(invocations.Attributes(jobCtx)...)
+	if recordSQSAttributes {
+		jobSpan.SetAttributes(joblib.SQSAttributes(msg.Attributes)...)
+	}
+	jobSpan.SetAttributes(joblib.FixtureAttributes(job.OriginalMessage)...)
+	if recordJobParameters {
+		jobSpan.SetAttributes(joblib.ParameterAttributes(parsedJob)...)
+	}
+	if err := joblib.CheckSchemaFingerprint(job, parsedJob); err != nil {
+		logger(jobCtx).Warn("job may have been produced from a different schema", "job_id", job.ID, "job_type", *jobType, "error", err)
+		jobSpan.AddEvent("schema fingerprint mismatch", trace.WithAttributes(
+			attribute.String("job.schema_fingerprint", job.SchemaFingerprint),
+			attribute.String("job.expected_schema_fingerprint", joblib.SchemaFingerprint(parsedJob)),
+		))
+	}
 
 	defer func() {
-		log.Println("Ending ExecuteJob span")
+		logger(jobCtx).Debug("ending ExecuteJob span")
 		jobSpan.End()
 	}()
 
-	if err := parsedJob.Execute(); err != nil {
-		jobSpan.RecordError(err)
+	// Jobs left on the queue too long are no longer worth running
+	if p.expireStale(jobCtx, jobSpan, msg, &job, *jobType) {
+		return nil
+	}
+
+	if replayCachedResults {
+		if cached, ok := jobResults.Get(job.ID); ok {
+			p.replayResult(jobCtx, jobSpan, cached)
+			return nil
+		}
+	}
+
+	if p.throttleTenant(jobCtx, jobSpan, msg, originalMessage) {
+		return nil
+	}
+
+	// SQS delivers at least once, so don't run a job another delivery already ran or is running
+	if skipDuplicate(jobCtx, jobSpan, job.ID) {
+		return nil
+	}
+
+	// Subscribers see the job move from NEW to IN_PROGRESS before its end state
+	job.Status = joblib.StatusInProgress
+	if notifyOnSuccess {
+		p.notifyEndState(jobCtx, joblib.NewJobEndStateEvent(jobCtx, job.ID, *jobType, job.Status, ""), fmt.Sprintf("executing job: %v", job))
+	}
+
+	inFlight.Add(job.ID, *jobType)
+	runCtx, finishRunning := runningJobs.start(jobCtx, job.ID)
+	executeStart := time.Now()
+	result, err := executeWithRetries(runCtx, jobSpan, parsedJob, *jobType)
+	executeDuration := time.Since(executeStart)
+	err = finishRunning(err)
+	inFlight.Remove(job.ID)
+	if errors.Is(err, joblib.ErrCancelRequested) {
+		jobSpan.SetAttributes(attribute.String("job.failure_reason", "cancelled"))
+	}
+	recordExecuteDuration(jobSpan, executeDuration, err)
+	settleClaim(jobCtx, job.ID, err)
+	if err != nil {
+		recordJobProcessed(jobCtx, *jobType, joblib.StatusExecuteFailed, executeDuration)
+	} else {
+		recordJobProcessed(jobCtx, *jobType, joblib.StatusCompleted, executeDuration)
+	}
+	windowStats.Record(err != nil)
+	if err != nil && retryCancelled && joblib.Cancelled(err) && p.requeueCancelled(jobCtx, jobSpan, msg, err) {
+		return nil
+	}
+	// A job stopped by a cancel_job isn't retried
+	if err != nil && !errors.Is(err, joblib.ErrCancelRequested) && p.requeueFailed(jobCtx, jobSpan, msg, err) {
+		return nil
+	}
+	if err != nil {
+		// The job may have failed because the invocation was cancelled or ran
+		// out of time, its failure is still reported
+		reportCtx := context.WithoutCancel(jobCtx)
+		spanStatus.Fail(jobSpan, err)
 		job.Status = joblib.StatusExecuteFailed
-		log.Printf("failed to execute job: %v, err: %s", job, err)
+		jobStatuses.Add(job.ID, job.Status)
+		recordPipelineLatency(reportCtx, jobSpan, job, *jobType)
+		recordCost(reportCtx, jobSpan, *jobType, job.Status)
+		emfMetrics.writeJob(*jobType, job.Status, executeDuration)
+		sendMetricsRecord(reportCtx, metricsRecord{MessageID: msg.ID, JobID: job.ID, JobType: *jobType, Status: job.Status, DurationMS: executeDuration.Milliseconds()})
+		p.emitResult(reportCtx, jobSpan, job, *jobType)
+		emitCompletionEvent(reportCtx, jobSpan, job, *jobType, err)
+		persistOutcome(reportCtx, jobSpan, jobOutcome{JobID: job.ID, JobType: *jobType, Status: job.Status, StartedAt: executeStart, EndedAt: executeStart.Add(executeDuration), Error: err})
+		logger(jobCtx).Error("failed to execute job", "job", job, "error", err)
 		jobSpan.AddEvent("job failed to execute", trace.WithAttributes(
 			attribute.String("message.id", job.ID),
 			attribute.String("job.type", *jobType),
 		))
-		publishToSNS(snsClient, snsTopicArn, fmt.Sprintf("failed to execute job: %v, err: %s", job, err))
-		// add to dead letter for retry
-		sendToDeadLetterQueue(executeCtx, message.Body)
-		return
+		p.reportFailure(reportCtx, joblib.StageExecute, joblib.NewJobEndStateEvent(reportCtx, job.ID, *jobType, job.Status, fmt.Sprintf("failed to execute job: %v, err: %s", job, err)), msg.Body)
+		return err
 	}
 
 	jobSpan.AddEvent("job executed successfully", trace.WithAttributes(
 		attribute.String("message.id", job.ID),
 		attribute.String("job.type", *jobType),
 	))
+	if result.Message != "" {
+		jobSpan.AddEvent(result.Message)
+	}
+	spanStatus.Succeed(jobSpan)
 	job.Status = joblib.StatusCompleted
-	log.Printf("successfully executed job: %v", job)
-	publishToSNS(snsClient, snsTopicArn, fmt.Sprintf("successfully executed job: %v", job))
+	jobStatuses.Add(job.ID, job.Status)
+	if replayCachedResults {
+		jobResults.Add(job.ID, job)
+	}
+	recordPipelineLatency(jobCtx, jobSpan, job, *jobType)
+	recordCost(jobCtx, jobSpan, *jobType, job.Status)
+	emfMetrics.writeJob(*jobType, job.Status, executeDuration)
+	sendMetricsRecord(jobCtx, metricsRecord{MessageID: msg.ID, JobID: job.ID, JobType: *jobType, Status: job.Status, DurationMS: executeDuration.Milliseconds()})
+	p.emitResult(jobCtx, jobSpan, job, *jobType)
+	emitCompletionEvent(jobCtx, jobSpan, job, *jobType, nil)
+	persistOutcome(jobCtx, jobSpan, jobOutcome{JobID: job.ID, JobType: *jobType, Status: job.Status, StartedAt: executeStart, EndedAt: executeStart.Add(executeDuration)})
+	archivePayload(jobCtx, jobSpan, job)
+	p.enqueueFollowups(jobCtx, jobSpan, job, result.Followups)
+	logger(jobCtx).Info("successfully executed job", "job", job)
+	if notifyOnSuccess {
+		event := joblib.NewJobEndStateEvent(jobCtx, job.ID, *jobType, job.Status, "")
+		event.Output = result.Output
+		p.notifyEndState(jobCtx, event, fmt.Sprintf("successfully executed job: %v", job))
+	}
 
+	return nil
+}
+
+// replayResult re-publishes the cached end state of a completed job that was
+// delivered again, without executing it a second time.
+func (p *processor) replayResult(ctx context.Context, span trace.Span, cached joblib.EnrichedPayload) {
+	logger(ctx).Info("job already completed, replaying cached result", "job_id", cached.ID)
+	span.AddEvent("cached result replayed", trace.WithAttributes(
+		attribute.String("message.id", cached.ID),
+		attribute.String("job.status", string(cached.Status)),
+	))
+	p.emitResult(ctx, span, cached, "")
+	spanStatus.Succeed(span)
+	if notifyOnSuccess {
+		p.notifyEndState(ctx, joblib.NewJobEndStateEvent(ctx, cached.ID, "", cached.Status, ""), fmt.Sprintf("successfully executed job: %v", cached))
+	}
+}
+
+// emitResult re-emits the enriched payload with its final status to the
+// configured results queue and/or topic. jobType is empty when it isn't known.
+func (p *processor) emitResult(ctx context.Context, span trace.Span, job joblib.EnrichedPayload, jobType string) {
+	if resultsQueueURL == "" && resultsTopicArn == "" {
+		return
+	}
+
+	resultJSON, err := json.Marshal(job)
+	if err != nil {
+		span.RecordError(err)
+		logger(ctx).Error("failed to marshal job result", "job_id", job.ID, "error", err)
+		return
+	}
+
+	if resultsQueueURL != "" {
+		_, err := p.sqsClient.SendMessage(ctx, &sqs.SendMessageInput{
+			QueueUrl:    aws.String(resultsQueueURL),
+			MessageBody: aws.String(string(resultJSON)),
+		})
+		if err != nil {
+			span.RecordError(err)
+			logger(ctx).Error("failed to send job result to results queue", "job_id", job.ID, "error", err)
+		}
+	}
+	if resultsTopicArn != "" {
+		if err := publishToSNS(ctx, p.snsClient, resultsTopicArn, string(resultJSON), joblib.FilterAttributes(jobType, job.Status)); err != nil {
+			span.RecordError(err)
+			logger(ctx).Error("failed to publish job result to results topic", "job_id", job.ID, "error", err)
+		}
+	}
+
+	span.AddEvent("job result emitted", trace.WithAttributes(
+		attribute.String("message.id", job.ID),
+		attribute.String("job.status", string(job.Status)),
+	))
+}
+
+// recordPipelineLatency records how long a job took from ingestion to its
+// terminal status on the span and the pipeline latency histogram.
+func recordPipelineLatency(ctx context.Context, span trace.Span, job joblib.EnrichedPayload, jobType string) {
+	latency, err := joblib.PipelineLatency(job.Timestamp, time.Now())
+	if err != nil {
+		logger(ctx).Warn("unable to compute pipeline latency", "job_id", job.ID, "error", err)
+		return
+	}
+	span.SetAttributes(attribute.Float64("job.pipeline_latency_seconds", latency.Seconds()))
+	if pipelineLatency != nil {
+		pipelineLatency.Record(ctx, latency.Seconds(), metric.WithAttributes(
+			attribute.String("job.type", jobType),
+			attribute.String("status", string(job.Status)),
+		))
+	}
 }
 
-func publishToSNS(snsClient *sns.Client, topicArn string, message string) error {
+// enqueueBatchChildren splits a batch job into child payloads and queues each
+// on jobs-todo for independent processing.
+func (p *processor) enqueueBatchChildren(ctx context.Context, msg Message, batchJob joblib.BatchJob) {
+	parent := msg.Payload
+	parent.TraceContext, parent.TraceState = msg.TraceParent, msg.TraceState
+	ctx, span := tracer.Start(ctx, "SplitBatchJob", trace.WithAttributes(
+		attribute.String("job.type", string(joblib.Batch)),
+		attribute.String("message.id", parent.ID),
+		attribute.String("sqs.message.id", msg.ID),
+		attribute.Int("batch.children", len(batchJob.Children)),
+	), trace.WithAttributes(joblib.TenantAttributes(ctx)...), trace.WithAttributes(joblib.CorrelationAttributes(ctx)...), trace.WithAttributes(joblib.EventBridgeAttributes(parent.EventBridgeID)...))
+	defer span.End()
+	span.SetAttributes(invocations.Attributes(ctx)...)
+	if recordSQSAttributes {
+		span.SetAttributes(joblib.SQSAttributes(msg.Attributes)...)
+	}
+
+	children, err := joblib.SplitBatch(parent, batchJob)
+	if err != nil {
+		spanStatus.Fail(span, err)
+		logger(ctx).Error("failed to split batch job", "job", parent, "error", err)
+		p.reportFailure(ctx, joblib.StageParse, joblib.NewJobEndStateEvent(ctx, parent.ID, string(joblib.Batch), joblib.StatusRejected, fmt.Sprintf("failed to split batch job: %v, err: %s", parent, err)), msg.Body)
+		return
+	}
+
+	failed := 0
+	for _, child := range children {
+		if !p.enqueueJob(ctx, span, child, "batch child", stageBatchSplit) {
+			failed++
+			continue
+		}
+		span.AddEvent("batch child enqueued", trace.WithAttributes(
+			attribute.String("message.id", child.ID),
+			attribute.String("parent.id", parent.ID),
+		))
+	}
+	if failed > 0 {
+		spanStatus.Fail(span, fmt.Errorf("failed to enqueue %d of %d batch children", failed, len(children)))
+	} else {
+		spanStatus.Succeed(span)
+	}
+
+	logger(ctx).Info("split batch job", "job_id", parent.ID, "children", len(children))
+}
+
+// enqueueJob queues a job the processor derived from another, described as
+// kind in logs and end states, on jobs-todo. A job that can't be queued is
+// reported as rejected and enqueueJob returns false.
+func (p *processor) enqueueJob(ctx context.Context, span trace.Span, payload joblib.EnrichedPayload, kind, stage string) bool {
+	traceparent, tracestate := payload.TraceContext, payload.TraceState
+	if traceCarrier == joblib.PropagationAttributes {
+		payload.TraceContext = ""
+		payload.TraceState = ""
+	}
+	var err error
+	if fieldCipher != nil {
+		if payload.OriginalMessage, err = joblib.EncryptFields(payload.OriginalMessage, encryptFields, fieldCipher); err != nil {
+			span.RecordError(err)
+			logger(ctx).Error("failed to encrypt "+kind, "job_id", payload.ID, "error", err)
+			failed := fmt.Sprintf("failed to encrypt %s %s: %v", kind, payload.ID, err)
+			p.notifyEndState(ctx, joblib.NewJobEndStateEvent(ctx, payload.ID, "", joblib.StatusRejected, failed), failed)
+			return false
+		}
+	}
+	if len(signingKey) > 0 {
+		if payload.Signature, err = joblib.SignPayload(payload, signingKey); err != nil {
+			span.RecordError(err)
+			logger(ctx).Error("failed to sign "+kind, "job_id", payload.ID, "error", err)
+			failed := fmt.Sprintf("failed to sign %s %s: %v", kind, payload.ID, err)
+			p.notifyEndState(ctx, joblib.NewJobEndStateEvent(ctx, payload.ID, "", joblib.StatusRejected, failed), failed)
+			return false
+		}
+	}
+	checkTraceContext(ctx, stage, payload.ID, traceparent)
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		span.RecordError(err)
+		logger(ctx).Error("failed to marshal "+kind, "job_id", payload.ID, "error", err)
+		failed := fmt.Sprintf("failed to marshal %s %s: %v", kind, payload.ID, err)
+		p.notifyEndState(ctx, joblib.NewJobEndStateEvent(ctx, payload.ID, "", joblib.StatusRejected, failed), failed)
+		return false
+	}
+
+	_, err = p.sqsClient.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:          aws.String(jobsTodoURL),
+		MessageBody:       aws.String(string(payloadJSON)),
+		MessageAttributes: joblib.TraceMessageAttributes(traceCarrier, traceparent, tracestate),
+	})
+	if err != nil {
+		span.RecordError(err)
+		logger(ctx).Error("failed to enqueue "+kind, "job_id", payload.ID, "error", err)
+		p.reportFailure(ctx, joblib.StageSend, joblib.NewJobEndStateEvent(ctx, payload.ID, "", joblib.StatusRejected, fmt.Sprintf("failed to enqueue %s: %s, err: %v", kind, string(payloadJSON), err)), string(payloadJSON))
+		return false
+	}
+	return true
+}
+
+// enqueueFollowups queues the follow-ups a job returned on jobs-todo, each a
+// job of its own continuing the trace from span. Follow-ups past the chain's
+// MAX_CHAIN_DEPTH are dropped and reported as rejected, but don't fail the
+// job that returned them.
+func (p *processor) enqueueFollowups(ctx context.Context, span trace.Span, job joblib.EnrichedPayload, followups []joblib.JobMessage) {
+	if len(followups) == 0 {
+		return
+	}
+	payloads, err := joblib.ChainFollowups(job, followups, joblib.SystemClock{}, span)
+	if err != nil {
+		span.RecordError(err)
+		logger(ctx).Error("not queueing follow-ups", "job_id", job.ID, "followups", len(followups), "error", err)
+		failed := fmt.Sprintf("failed to chain follow-up: %v", err)
+		for i := range followups {
+			p.notifyEndState(ctx, joblib.NewJobEndStateEvent(ctx, joblib.FollowupID(job.ID, i), followups[i].JobType, joblib.StatusRejected, failed), failed)
+		}
+		return
+	}
+
+	for _, payload := range payloads {
+		if !p.enqueueJob(ctx, span, payload, "follow-up", stageFollowup) {
+			continue
+		}
+		span.AddEvent("follow-up enqueued", trace.WithAttributes(
+			attribute.String("message.id", payload.ID),
+			attribute.Int("job.chain_depth", payload.ChainDepth),
+		))
+	}
+	logger(ctx).Info("queued follow-ups", "job_id", job.ID, "followups", len(payloads))
+}
+
+// publishToSNS publishes message to topicArn with the given string message
+// attributes, which subscription filter policies can match on.
+func publishToSNS(ctx context.Context, snsClient snsPublisher, topicArn string, message string, attributes map[string]string) error {
 	input := &sns.PublishInput{
 		Message:  aws.String(message),
 		TopicArn: aws.String(topicArn),
 	}
-	_, err := snsClient.Publish(context.TODO(), input)
+	for name, value := range attributes {
+		if input.MessageAttributes == nil {
+			input.MessageAttributes = map[string]snstypes.MessageAttributeValue{}
+		}
+		input.MessageAttributes[name] = snstypes.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(value)}
+	}
+	_, err := snsClient.Publish(ctx, input)
+	if err != nil {
+		failureMetrics.SNSPublishFailed(ctx, topicArn)
+	}
 	return err
 }
 
@@ -216,6 +1053,11 @@ func main() {
 	// Initialize the tracer
 	shutdown := initTracer()
 	defer shutdown()
-	// Start the Lambda handler
-	lambda.Start(handler)
+	// Export metrics alongside the traces
+	shutdownMeter := initMeter()
+	defer shutdownMeter()
+	// Start the Lambda handler with the real clients, metrics records going through the same SQS client
+	p := newProcessor(awsConfig)
+	metricsSender = p.sqsClient
+	lambda.Start(p.handler)
 }
@@ -0,0 +1,982 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// fakeSQS records messages instead of sending them
+type fakeSQS struct {
+	mu   sync.Mutex
+	sent []*sqs.SendMessageInput
+}
+
+func (f *fakeSQS) SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	// As the SDK does, a call made on a done context is never sent
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, params)
+	return &sqs.SendMessageOutput{MessageId: aws.String("fake")}, nil
+}
+
+// sentTo returns the bodies sent to queueURL
+func (f *fakeSQS) sentTo(queueURL string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var bodies []string
+	for _, input := range f.sent {
+		if aws.ToString(input.QueueUrl) == queueURL {
+			bodies = append(bodies, aws.ToString(input.MessageBody))
+		}
+	}
+	return bodies
+}
+
+// deadLetters returns the dead-letter envelopes sent to the dead-letter queue
+func (f *fakeSQS) deadLetters(t *testing.T) []joblib.DeadLetterEnvelope {
+	t.Helper()
+	var envelopes []joblib.DeadLetterEnvelope
+	for _, body := range f.sentTo(deadletterURL) {
+		envelope, err := joblib.ParseDeadLetterEnvelope([]byte(body))
+		if err != nil {
+			t.Fatalf("expected a dead-letter envelope, got %s: %v", body, err)
+		}
+		envelopes = append(envelopes, *envelope)
+	}
+	return envelopes
+}
+
+// fakeSNS records published messages instead of publishing them
+type fakeSNS struct {
+	mu         sync.Mutex
+	messages   []string
+	topics     []string
+	attributes []map[string]string // the string message attributes of each message
+}
+
+func (f *fakeSNS) Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.messages = append(f.messages, aws.ToString(params.Message))
+	f.topics = append(f.topics, aws.ToString(params.TopicArn))
+	f.attributes = append(f.attributes, stringAttributes(params.MessageAttributes))
+	return &sns.PublishOutput{MessageId: aws.String("fake")}, nil
+}
+
+// stringAttributes returns the values of SNS message attributes
+func stringAttributes(attributes map[string]snstypes.MessageAttributeValue) map[string]string {
+	values := map[string]string{}
+	for name, attribute := range attributes {
+		values[name] = aws.ToString(attribute.StringValue)
+	}
+	return values
+}
+
+// publishedTo returns the messages published to topicArn
+func (f *fakeSNS) publishedTo(topicArn string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var messages []string
+	for i, topic := range f.topics {
+		if topic == topicArn {
+			messages = append(messages, f.messages[i])
+		}
+	}
+	return messages
+}
+
+// endState decodes a published end-state event, zero if it isn't one
+func endState(message string) joblib.JobEndStateEvent {
+	var event joblib.JobEndStateEvent
+	_ = json.Unmarshal([]byte(message), &event)
+	return event
+}
+
+// endStates drops the IN_PROGRESS updates from published messages
+func endStates(messages []string) []string {
+	var ended []string
+	for _, message := range messages {
+		if endState(message).Status != joblib.StatusInProgress {
+			ended = append(ended, message)
+		}
+	}
+	return ended
+}
+
+// captureLogs sends log lines to the returned buffer for the rest of the test
+func captureLogs(t *testing.T) *bytes.Buffer {
+	var logs bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(joblib.NewLogger(&logs, slog.LevelDebug))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &logs
+}
+
+// withFakeClients returns a processor whose AWS clients are fakes, starting
+// the test with no jobs recorded as processed
+func withFakeClients(t *testing.T) (*processor, *fakeSQS, *fakeSNS) {
+	t.Helper()
+	previousDedup := dedup
+	fakeQueue, fakeTopic := &fakeSQS{}, &fakeSNS{}
+	// Each test starts with no jobs recorded as processed
+	dedup = newMemoryDedup(0)
+	t.Cleanup(func() { dedup = previousDedup })
+	return &processor{sqsClient: fakeQueue, snsClient: fakeTopic}, fakeQueue, fakeTopic
+}
+
+// eventsMessage wraps a body in an SQS record
+func eventsMessage(body string) events.SQSMessage {
+	return events.SQSMessage{MessageId: "sqs-1", Body: body}
+}
+
+const validEnrichedPayload = `{
+	"originalmessage": {"job_type": "report_generation", "message": {"report_name": "Sales Report", "filters": "region=US"}},
+	"id": "12345",
+	"timestamp": "2025-08-30T12:00:00Z",
+	"status": "NEW"
+}`
+
+func TestIsPoisonMessage(t *testing.T) {
+	tests := []struct {
+		name            string
+		maxReceiveCount int
+		attributes      map[string]string
+		expectPoison    bool
+	}{
+		{
+			name:            "Under threshold",
+			maxReceiveCount: 3,
+			attributes:      map[string]string{"ApproximateReceiveCount": "2"},
+			expectPoison:    false,
+		},
+		{
+			name:            "At threshold",
+			maxReceiveCount: 3,
+			attributes:      map[string]string{"ApproximateReceiveCount": "3"},
+			expectPoison:    false,
+		},
+		{
+			name:            "Over threshold",
+			maxReceiveCount: 3,
+			attributes:      map[string]string{"ApproximateReceiveCount": "4"},
+			expectPoison:    true,
+		},
+		{
+			name:            "Missing receive count",
+			maxReceiveCount: 3,
+			attributes:      map[string]string{},
+			expectPoison:    false,
+		},
+		{
+			name:            "Check disabled",
+			maxReceiveCount: 0,
+			attributes:      map[string]string{"ApproximateReceiveCount": "100"},
+			expectPoison:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := maxReceiveCount
+			maxReceiveCount = tt.maxReceiveCount
+			defer func() { maxReceiveCount = previous }()
+
+			_, poison := isPoisonMessage(events.SQSMessage{Attributes: tt.attributes})
+			if poison != tt.expectPoison {
+				t.Errorf("expected poison %v, got %v", tt.expectPoison, poison)
+			}
+		})
+	}
+}
+
+func TestNotifyOnSuccess(t *testing.T) {
+	tests := []struct {
+		name            string
+		notifyOnSuccess bool
+		body            string
+		expectPublished int
+		expectFailure   bool
+	}{
+		{
+			name:            "Success published by default",
+			notifyOnSuccess: true,
+			body:            validEnrichedPayload,
+			expectPublished: 1,
+		},
+		{
+			name:            "Success not published when disabled",
+			notifyOnSuccess: false,
+			body:            validEnrichedPayload,
+			expectPublished: 0,
+		},
+		{
+			name:            "Failure still published when disabled",
+			notifyOnSuccess: false,
+			body:            `not json`,
+			expectPublished: 1,
+			expectFailure:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, fakeQueue, fakeTopic := withFakeClients(t)
+			previous := notifyOnSuccess
+			notifyOnSuccess = tt.notifyOnSuccess
+			defer func() { notifyOnSuccess = previous }()
+
+			p.processMessage(context.Background(), events.SQSMessage{MessageId: "sqs-1", Body: tt.body})
+
+			published := endStates(fakeTopic.messages)
+			if len(published) != tt.expectPublished {
+				t.Fatalf("expected %d SNS messages, got %d: %v", tt.expectPublished, len(published), published)
+			}
+			if tt.expectFailure {
+				if event := endState(published[0]); event.Status == joblib.StatusCompleted || !strings.HasPrefix(event.Error, "failed") {
+					t.Errorf("expected a failure message, got %s", published[0])
+				}
+				if len(fakeQueue.sentTo(deadletterURL)) != 1 {
+					t.Errorf("expected the failure to be dead-lettered")
+				}
+			}
+		})
+	}
+}
+
+func TestEmitResult(t *testing.T) {
+	const (
+		queueURL = "http://localstack:4566/000000000000/job-results"
+		topicArn = "arn:aws:sns:us-east-1:000000000000:job-results"
+	)
+
+	tests := []struct {
+		name           string
+		body           string
+		cancelled      bool
+		expectedStatus joblib.Status
+	}{
+		{
+			name:           "Completed job",
+			body:           validEnrichedPayload,
+			expectedStatus: joblib.StatusCompleted,
+		},
+		{
+			name: "Failed job",
+			body: `{
+				"originalmessage": {"job_type": "long_running_job", "message": {"task_name": "Data Migration", "timeout": 1}},
+				"id": "67890",
+				"timestamp": "2025-08-30T12:00:00Z",
+				"status": "NEW"
+			}`,
+			cancelled:      true,
+			expectedStatus: joblib.StatusExecuteFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, fakeQueue, fakeTopic := withFakeClients(t)
+			previousQueue, previousTopic := resultsQueueURL, resultsTopicArn
+			resultsQueueURL, resultsTopicArn = queueURL, topicArn
+			defer func() { resultsQueueURL, resultsTopicArn = previousQueue, previousTopic }()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.cancelled {
+				cancel()
+			}
+			defer cancel()
+			p.processMessage(ctx, events.SQSMessage{MessageId: "sqs-1", Body: tt.body})
+
+			results := append(fakeQueue.sentTo(queueURL), fakeTopic.publishedTo(topicArn)...)
+			if len(results) != 2 {
+				t.Fatalf("expected the result on both the queue and topic, got %v", results)
+			}
+			for _, result := range results {
+				var payload joblib.EnrichedPayload
+				if err := json.Unmarshal([]byte(result), &payload); err != nil {
+					t.Fatalf("failed to unmarshal result: %v", err)
+				}
+				if payload.Status != tt.expectedStatus {
+					t.Errorf("expected status %s, got %s", tt.expectedStatus, payload.Status)
+				}
+			}
+			for i, topic := range fakeTopic.topics {
+				if attributes := fakeTopic.attributes[i]; topic == topicArn && (attributes["status"] != string(tt.expectedStatus) || attributes["job_type"] == "") {
+					t.Errorf("expected the result published with its job type and %s status, got %v", tt.expectedStatus, attributes)
+				}
+			}
+		})
+	}
+}
+
+func TestFailureSink(t *testing.T) {
+	tests := []struct {
+		sink      string
+		expectSNS int
+		expectDLQ int
+	}{
+		{sink: joblib.FailureSinkDLQ, expectSNS: 0, expectDLQ: 1},
+		{sink: joblib.FailureSinkSNS, expectSNS: 1, expectDLQ: 0},
+		{sink: joblib.FailureSinkBoth, expectSNS: 1, expectDLQ: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sink, func(t *testing.T) {
+			p, fakeQueue, fakeTopic := withFakeClients(t)
+			previous := failureSink
+			failureSink = tt.sink
+			defer func() { failureSink = previous }()
+
+			p.processMessage(context.Background(), eventsMessage(`not json`))
+
+			if len(fakeTopic.messages) != tt.expectSNS {
+				t.Errorf("expected %d SNS messages, got %d: %v", tt.expectSNS, len(fakeTopic.messages), fakeTopic.messages)
+			}
+			if dlq := fakeQueue.sentTo(deadletterURL); len(dlq) != tt.expectDLQ {
+				t.Errorf("expected %d dead-lettered messages, got %d: %v", tt.expectDLQ, len(dlq), dlq)
+			}
+		})
+	}
+}
+
+func TestInFlightDeregisteredAfterExecution(t *testing.T) {
+	p, _, _ := withFakeClients(t)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.processMessage(context.Background(), eventsMessage(`{
+			"originalmessage": {"job_type": "long_running_job", "message": {"task_name": "Data Migration", "timeout": 1}},
+			"id": "long-1",
+			"timestamp": "2025-08-30T12:00:00Z",
+			"status": "NEW"
+		}`))
+	}()
+
+	deadline := time.After(900 * time.Millisecond)
+	for registered := false; !registered; {
+		select {
+		case <-deadline:
+			t.Fatalf("expected long-1 to be registered while executing")
+		case <-time.After(10 * time.Millisecond):
+			snapshot := inFlight.Snapshot()
+			registered = len(snapshot) == 1 && snapshot[0].ID == "long-1"
+		}
+	}
+
+	<-done
+	if snapshot := inFlight.Snapshot(); len(snapshot) != 0 {
+		t.Errorf("expected no in-flight jobs after execution, got %v", snapshot)
+	}
+}
+
+func TestWindowStatsRecorded(t *testing.T) {
+	p, _, _ := withFakeClients(t)
+	previous := windowStats
+	windowStats = joblib.NewStatsAggregator(joblib.SystemClock{})
+	defer func() { windowStats = previous }()
+
+	p.processMessage(context.Background(), eventsMessage(validEnrichedPayload))
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	p.processMessage(cancelled, eventsMessage(`{
+		"originalmessage": {"job_type": "long_running_job", "message": {"task_name": "Data Migration", "timeout": 1}},
+		"id": "67890",
+		"timestamp": "2025-08-30T12:00:00Z",
+		"status": "NEW"
+	}`))
+
+	window := windowStats.WindowStats()[0]
+	if window.Completed != 1 || window.Failed != 1 {
+		t.Errorf("expected 1 completed and 1 failed job, got %d and %d", window.Completed, window.Failed)
+	}
+}
+
+func TestJobStatusesRecorded(t *testing.T) {
+	p, _, _ := withFakeClients(t)
+	previous := jobStatuses
+	jobStatuses = joblib.NewLRUCache[string, joblib.Status]("test_job_statuses", 1)
+	defer func() { jobStatuses = previous }()
+
+	p.processMessage(context.Background(), eventsMessage(validEnrichedPayload))
+	if status, ok := jobStatuses.Get("12345"); !ok || status != joblib.StatusCompleted {
+		t.Errorf("expected status %s cached for 12345, got %q", joblib.StatusCompleted, status)
+	}
+
+	p.processMessage(context.Background(), eventsMessage(strings.Replace(validEnrichedPayload, `"id": "12345"`, `"id": "67890"`, 1)))
+	if _, ok := jobStatuses.Get("12345"); ok {
+		t.Errorf("expected 12345 to be evicted at the cap")
+	}
+	if jobStatuses.Len() != 1 {
+		t.Errorf("expected 1 cached status, got %d", jobStatuses.Len())
+	}
+}
+
+func TestReplayCachedResult(t *testing.T) {
+	const topicArn = "arn:aws:sns:us-east-1:000000000000:job-results"
+
+	tests := []struct {
+		name           string
+		replay         bool
+		expectReplayed bool
+		expectResults  int
+	}{
+		{name: "Replay served from cache", replay: true, expectReplayed: true, expectResults: 2},
+		{name: "Replay skipped as a duplicate when disabled", replay: false, expectReplayed: false, expectResults: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _, fakeTopic := withFakeClients(t)
+			previousReplay, previousResults, previousTopic := replayCachedResults, jobResults, resultsTopicArn
+			replayCachedResults = tt.replay
+			jobResults = joblib.NewLRUCache[string, joblib.EnrichedPayload]("test_job_results", 10)
+			resultsTopicArn = topicArn
+			defer func() {
+				replayCachedResults, jobResults, resultsTopicArn = previousReplay, previousResults, previousTopic
+			}()
+
+			p.processMessage(context.Background(), eventsMessage(validEnrichedPayload))
+			original := fakeTopic.publishedTo(topicArn)
+			if len(original) != 1 {
+				t.Fatalf("expected 1 result published, got %d", len(original))
+			}
+
+			recorder := tracetest.NewSpanRecorder()
+			previousTracer := tracer
+			tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+			defer func() { tracer = previousTracer }()
+
+			p.processMessage(context.Background(), eventsMessage(validEnrichedPayload))
+			results := fakeTopic.publishedTo(topicArn)
+			if len(results) != tt.expectResults {
+				t.Fatalf("expected %d results published, got %d", tt.expectResults, len(results))
+			}
+
+			replayed := false
+			for _, event := range recorder.Ended()[0].Events() {
+				replayed = replayed || event.Name == "cached result replayed"
+			}
+			if replayed != tt.expectReplayed {
+				t.Errorf("expected replayed %v, got %v", tt.expectReplayed, replayed)
+			}
+			if tt.expectReplayed && results[1] != original[0] {
+				t.Errorf("expected the cached result %s, got %s", original[0], results[1])
+			}
+		})
+	}
+}
+
+func TestInvocationIDRecorded(t *testing.T) {
+	p, _, _ := withFakeClients(t)
+	recorder := tracetest.NewSpanRecorder()
+	previousTracer := tracer
+	tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	defer func() { tracer = previousTracer }()
+
+	logs := captureLogs(t)
+
+	ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: "req-1"})
+	if _, err := p.handler(ctx, events.SQSEvent{Records: []events.SQSMessage{eventsMessage(validEnrichedPayload)}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	found := false
+	for _, kv := range spans[0].Attributes() {
+		if kv.Key == "faas.invocation_id" {
+			found = kv.Value.AsString() == "req-1"
+		}
+	}
+	if !found {
+		t.Errorf("expected faas.invocation_id req-1 on the ExecuteJob span")
+	}
+	if !strings.Contains(logs.String(), `"request_id":"req-1"`) {
+		t.Errorf("expected logs tagged with the request ID, got %s", logs.String())
+	}
+}
+
+func TestSignatureVerification(t *testing.T) {
+	key := []byte("demo-secret")
+	var payload joblib.EnrichedPayload
+	if err := json.Unmarshal([]byte(validEnrichedPayload), &payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	signature, err := joblib.SignPayload(payload, key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	payload.Signature = signature
+	signed, _ := json.Marshal(payload)
+
+	payload.ID = "tampered"
+	tampered, _ := json.Marshal(payload)
+
+	tests := []struct {
+		name             string
+		body             string
+		expectDeadLetter bool
+	}{
+		{name: "Valid signature", body: string(signed)},
+		{name: "Tampered payload", body: string(tampered), expectDeadLetter: true},
+		{name: "Unsigned payload", body: validEnrichedPayload, expectDeadLetter: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, fakeQueue, fakeTopic := withFakeClients(t)
+			previous := signingKey
+			signingKey = key
+			defer func() { signingKey = previous }()
+
+			p.processMessage(context.Background(), eventsMessage(tt.body))
+
+			deadLettered := len(fakeQueue.sentTo(deadletterURL)) == 1
+			if deadLettered != tt.expectDeadLetter {
+				t.Errorf("expected dead-lettered %v, got %v", tt.expectDeadLetter, deadLettered)
+			}
+			if !tt.expectDeadLetter && (len(endStates(fakeTopic.messages)) != 1 || endState(endStates(fakeTopic.messages)[0]).Status != joblib.StatusCompleted) {
+				t.Errorf("expected the job to execute, got %v", fakeTopic.messages)
+			}
+		})
+	}
+}
+
+func TestEncryptedFieldsDecrypted(t *testing.T) {
+	c, err := joblib.NewAESCipher([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	other, err := joblib.NewAESCipher([]byte("fedcba9876543210fedcba9876543210"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fields := []string{"report_name", "filters"}
+
+	var payload joblib.EnrichedPayload
+	if err := json.Unmarshal([]byte(validEnrichedPayload), &payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if payload.OriginalMessage, err = joblib.EncryptFields(payload.OriginalMessage, fields, c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	encrypted, _ := json.Marshal(payload)
+
+	tests := []struct {
+		name             string
+		cipher           joblib.FieldCipher
+		expectDeadLetter bool
+	}{
+		{name: "Matching key", cipher: c},
+		{name: "Wrong key", cipher: other, expectDeadLetter: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, fakeQueue, fakeTopic := withFakeClients(t)
+			previousCipher, previousFields := fieldCipher, encryptFields
+			fieldCipher, encryptFields = tt.cipher, fields
+			defer func() { fieldCipher, encryptFields = previousCipher, previousFields }()
+
+			p.processMessage(context.Background(), eventsMessage(string(encrypted)))
+
+			deadLettered := len(fakeQueue.sentTo(deadletterURL)) == 1
+			if deadLettered != tt.expectDeadLetter {
+				t.Errorf("expected dead-lettered %v, got %v", tt.expectDeadLetter, deadLettered)
+			}
+			if !tt.expectDeadLetter && (len(endStates(fakeTopic.messages)) != 1 || endState(endStates(fakeTopic.messages)[0]).Status != joblib.StatusCompleted) {
+				t.Errorf("expected the job to execute, got %v", fakeTopic.messages)
+			}
+		})
+	}
+}
+
+func TestTraceContextFromMessageAttributes(t *testing.T) {
+	const (
+		bodyTraceparent      = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+		attributeTraceparent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	)
+	var payload joblib.EnrichedPayload
+	if err := json.Unmarshal([]byte(validEnrichedPayload), &payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	payload.TraceContext = bodyTraceparent
+	withBody, _ := json.Marshal(payload)
+
+	tests := []struct {
+		name            string
+		carrier         string
+		attribute       bool
+		expectedTraceID string
+	}{
+		{name: "Body", carrier: joblib.PropagationBody, attribute: true, expectedTraceID: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{name: "Attributes", carrier: joblib.PropagationAttributes, attribute: true, expectedTraceID: "0af7651916cd43dd8448eb211c80319c"},
+		{name: "Both prefers the attribute", carrier: joblib.PropagationBoth, attribute: true, expectedTraceID: "0af7651916cd43dd8448eb211c80319c"},
+		{name: "Both falls back to the body", carrier: joblib.PropagationBoth, expectedTraceID: "4bf92f3577b34da6a3ce929d0e0e4736"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _, _ := withFakeClients(t)
+			recorder := tracetest.NewSpanRecorder()
+			previousTracer, previousCarrier := tracer, traceCarrier
+			tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+			traceCarrier = tt.carrier
+			defer func() { tracer, traceCarrier = previousTracer, previousCarrier }()
+
+			record := eventsMessage(string(withBody))
+			if tt.attribute {
+				record.MessageAttributes = map[string]events.SQSMessageAttribute{
+					joblib.TraceparentAttribute: {DataType: "String", StringValue: aws.String(attributeTraceparent)},
+				}
+			}
+			p.processMessage(context.Background(), record)
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 span, got %d", len(spans))
+			}
+			if traceID := spans[0].SpanContext().TraceID().String(); traceID != tt.expectedTraceID {
+				t.Errorf("expected trace ID %s, got %s", tt.expectedTraceID, traceID)
+			}
+		})
+	}
+}
+
+func TestTraceContextExtracted(t *testing.T) {
+	tests := []struct {
+		name         string
+		traceparent  string
+		expectParent bool
+	}{
+		{name: "Valid", traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", expectParent: true},
+		{name: "Future version with extra fields", traceparent: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", expectParent: true},
+		{name: "One character short", traceparent: "00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01"},
+		{name: "Invalid span ID", traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01"},
+		{name: "Invalid trace ID", traceparent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _, fakeTopic := withFakeClients(t)
+			recorder := tracetest.NewSpanRecorder()
+			previousTracer := tracer
+			tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+			defer func() { tracer = previousTracer }()
+
+			// A bad trace context loses the trace, not the job
+			if err := p.processMessage(context.Background(), eventsMessage(payloadWithTraceContext(tt.traceparent))); err != nil {
+				t.Fatalf("expected the job to execute, got %v", err)
+			}
+			if states := endStates(fakeTopic.messages); len(states) != 1 || endState(states[0]).Status != joblib.StatusCompleted {
+				t.Errorf("expected the job to complete, got %v", states)
+			}
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 span, got %d", len(spans))
+			}
+			parent := spans[0].Parent()
+			if continued := parent.TraceID().String() == "4bf92f3577b34da6a3ce929d0e0e4736" && parent.SpanID().String() == "00f067aa0ba902b7"; continued != tt.expectParent {
+				t.Errorf("expected the trace continued %v, got parent %s/%s", tt.expectParent, parent.TraceID(), parent.SpanID())
+			}
+			if !tt.expectParent && parent.IsValid() {
+				t.Errorf("expected a malformed traceparent to start a new trace, got parent %s", parent.TraceID())
+			}
+		})
+	}
+}
+
+func TestTracestateRestored(t *testing.T) {
+	const (
+		traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+		tracestate  = "vendor=opaque"
+	)
+	tests := []struct {
+		name          string
+		carrier       string
+		tracestate    string
+		expectedState string
+	}{
+		{name: "Body with tracestate", carrier: joblib.PropagationBody, tracestate: tracestate, expectedState: tracestate},
+		{name: "Body without tracestate", carrier: joblib.PropagationBody},
+		{name: "Attributes with tracestate", carrier: joblib.PropagationAttributes, tracestate: tracestate, expectedState: tracestate},
+		{name: "Attributes without tracestate", carrier: joblib.PropagationAttributes},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _, _ := withFakeClients(t)
+			recorder := tracetest.NewSpanRecorder()
+			previousTracer, previousCarrier := tracer, traceCarrier
+			tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+			traceCarrier = tt.carrier
+			defer func() { tracer, traceCarrier = previousTracer, previousCarrier }()
+
+			var payload joblib.EnrichedPayload
+			if err := json.Unmarshal([]byte(payloadWithTraceContext(traceparent)), &payload); err != nil {
+				t.Fatalf("failed to unmarshal payload: %v", err)
+			}
+			record := eventsMessage(validEnrichedPayload)
+			if tt.carrier == joblib.PropagationBody {
+				payload.TraceState = tt.tracestate
+			} else {
+				payload.TraceContext = ""
+				record.MessageAttributes = map[string]events.SQSMessageAttribute{}
+				for name, value := range joblib.TraceMessageAttributes(traceCarrier, traceparent, tt.tracestate) {
+					record.MessageAttributes[name] = events.SQSMessageAttribute{DataType: aws.ToString(value.DataType), StringValue: value.StringValue}
+				}
+			}
+			body, err := json.Marshal(payload)
+			if err != nil {
+				t.Fatalf("failed to marshal payload: %v", err)
+			}
+			record.Body = string(body)
+			p.processMessage(context.Background(), record)
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 span, got %d", len(spans))
+			}
+			parent := spans[0].Parent()
+			if parent.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+				t.Fatalf("expected the trace continued, got parent %s", parent.TraceID())
+			}
+			if actual := parent.TraceState().String(); actual != tt.expectedState {
+				t.Errorf("expected tracestate %q, got %q", tt.expectedState, actual)
+			}
+			if actual := spans[0].SpanContext().TraceState().String(); actual != tt.expectedState {
+				t.Errorf("expected the processor span to carry tracestate %q, got %q", tt.expectedState, actual)
+			}
+		})
+	}
+}
+
+func TestTraceMessageAttributes(t *testing.T) {
+	const traceparent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	previous := traceCarrier
+	defer func() { traceCarrier = previous }()
+
+	traceCarrier = joblib.PropagationBody
+	if attributes := joblib.TraceMessageAttributes(traceCarrier, traceparent, ""); attributes != nil {
+		t.Errorf("expected no attributes in body mode, got %v", attributes)
+	}
+
+	// Attributes written for a child are read back by newMessage
+	traceCarrier = joblib.PropagationAttributes
+	record := eventsMessage(validEnrichedPayload)
+	record.MessageAttributes = map[string]events.SQSMessageAttribute{}
+	for name, value := range joblib.TraceMessageAttributes(traceCarrier, traceparent, "") {
+		record.MessageAttributes[name] = events.SQSMessageAttribute{DataType: aws.ToString(value.DataType), StringValue: value.StringValue}
+	}
+	msg, err := newMessage(record)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msg.TraceParent != traceparent {
+		t.Errorf("expected traceparent %s, got %s", traceparent, msg.TraceParent)
+	}
+}
+
+func TestJobParametersRecorded(t *testing.T) {
+	const cleanupPayload = `{
+	"originalmessage": {"job_type": "data_cleanup", "message": {"target_table": "users", "retention": 30}},
+	"id": "12345",
+	"timestamp": "2025-08-30T12:00:00Z",
+	"status": "NEW"
+}`
+
+	tests := []struct {
+		name     string
+		body     string
+		record   bool
+		expected bool
+	}{
+		{name: "Data cleanup", body: cleanupPayload, record: true, expected: true},
+		{name: "Disabled", body: cleanupPayload},
+		{name: "Report generation", body: validEnrichedPayload, record: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _, _ := withFakeClients(t)
+			recorder := tracetest.NewSpanRecorder()
+			previousTracer, previousRecord := tracer, recordJobParameters
+			tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+			recordJobParameters = tt.record
+			defer func() { tracer, recordJobParameters = previousTracer, previousRecord }()
+
+			p.processMessage(context.Background(), eventsMessage(tt.body))
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 span, got %d", len(spans))
+			}
+			found := false
+			for _, kv := range spans[0].Attributes() {
+				if kv.Key == "cleanup.retention_days" {
+					found = kv.Value.AsInt64() == 30
+				}
+			}
+			if found != tt.expected {
+				t.Errorf("expected cleanup.retention_days recorded %v, got %v", tt.expected, found)
+			}
+		})
+	}
+}
+
+func TestSchemaFingerprintDrift(t *testing.T) {
+	var payload joblib.EnrichedPayload
+	if err := json.Unmarshal([]byte(validEnrichedPayload), &payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		fingerprint string
+		expectDrift bool
+	}{
+		{name: "Matching", fingerprint: joblib.SchemaFingerprint(joblib.ReportGenerationJob{})},
+		{name: "Drifted", fingerprint: "0123456789abcdef", expectDrift: true},
+		{name: "Not recorded"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _, fakeTopic := withFakeClients(t)
+			recorder := tracetest.NewSpanRecorder()
+			previousTracer := tracer
+			tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+			defer func() { tracer = previousTracer }()
+
+			logs := captureLogs(t)
+
+			payload.SchemaFingerprint = tt.fingerprint
+			body, _ := json.Marshal(payload)
+			p.processMessage(context.Background(), eventsMessage(string(body)))
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 span, got %d", len(spans))
+			}
+			drifted := false
+			for _, event := range spans[0].Events() {
+				drifted = drifted || event.Name == "schema fingerprint mismatch"
+			}
+			if drifted != tt.expectDrift {
+				t.Errorf("expected drift event %v, got %v", tt.expectDrift, drifted)
+			}
+			if warned := strings.Contains(logs.String(), "produced from a different schema"); warned != tt.expectDrift {
+				t.Errorf("expected drift warning %v, got logs %s", tt.expectDrift, logs.String())
+			}
+			// Drift is only a warning, the job still runs
+			if ended := endStates(fakeTopic.messages); len(ended) != 1 || endState(ended[0]).Status != joblib.StatusCompleted {
+				t.Errorf("expected the job to execute, got %v", fakeTopic.messages)
+			}
+		})
+	}
+}
+
+func TestSchemaVersion(t *testing.T) {
+	tests := []struct {
+		name          string
+		version       int
+		expectedState joblib.Status
+	}{
+		{name: "Current", version: joblib.CurrentSchemaVersion, expectedState: joblib.StatusCompleted},
+		{name: "Missing treated as v1", version: 0, expectedState: joblib.StatusCompleted},
+		{name: "Unknown", version: 99, expectedState: joblib.StatusRejected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, fakeQueue, fakeTopic := withFakeClients(t)
+
+			var payload joblib.EnrichedPayload
+			if err := json.Unmarshal([]byte(validEnrichedPayload), &payload); err != nil {
+				t.Fatalf("failed to parse payload: %v", err)
+			}
+			payload.SchemaVersion = tt.version
+			body, _ := json.Marshal(payload)
+			p.processMessage(context.Background(), eventsMessage(string(body)))
+
+			states := endStates(fakeTopic.messages)
+			if len(states) != 1 || endState(states[0]).Status != tt.expectedState {
+				t.Fatalf("expected a %s end state, got %v", tt.expectedState, states)
+			}
+			dlq := fakeQueue.deadLetters(t)
+			if tt.expectedState != joblib.StatusRejected {
+				if len(dlq) != 0 {
+					t.Errorf("expected nothing dead-lettered, got %v", dlq)
+				}
+				return
+			}
+			if len(dlq) != 1 || dlq[0].OriginalBody != string(body) || !strings.Contains(dlq[0].Reason, "unsupported payload schema_version 99") {
+				t.Errorf("expected the payload dead-lettered with its unsupported version, got %v", dlq)
+			}
+		})
+	}
+}
+
+func TestPropagatedSamplingDecisionRespected(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+		ratio       float64
+		expectSpans bool
+	}{
+		{name: "Sampled-out ingester trace", traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", ratio: 1},
+		{name: "Sampled ingester trace", traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", ratio: 0, expectSpans: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _, _ := withFakeClients(t)
+			recorder := tracetest.NewSpanRecorder()
+			previousTracer := tracer
+			tracer = sdktrace.NewTracerProvider(
+				sdktrace.WithSampler(joblib.TelemetryConfig{SampleRatio: tt.ratio}.Sampler()),
+				sdktrace.WithSpanProcessor(recorder),
+			).Tracer("test")
+			defer func() { tracer = previousTracer }()
+
+			if err := p.processMessage(context.Background(), eventsMessage(payloadWithTraceContext(tt.traceparent))); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			executeSpans := 0
+			for _, span := range recorder.Ended() {
+				if span.Name() == "ExecuteJob" {
+					executeSpans++
+				}
+			}
+			if (executeSpans > 0) != tt.expectSpans {
+				t.Errorf("expected ExecuteJob recorded %v, got %d spans", tt.expectSpans, executeSpans)
+			}
+		})
+	}
+}
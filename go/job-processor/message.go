@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// Message is an SQS record with its parsed payload, keeping the receipt
+// handle needed to change visibility or delete the record later.
+type Message struct {
+	ID            string
+	ReceiptHandle string
+	Body          string
+	Attributes    map[string]string
+	Payload       joblib.EnrichedPayload
+	TraceParent   string // propagated trace context, from the message attributes or the payload
+	TraceState    string // tracestate accompanying TraceParent, from the same place
+}
+
+// newMessage parses an SQS record into a Message, unwrapping the payload from
+// an SNS notification envelope when jobs-todo is subscribed to a topic.
+func newMessage(record events.SQSMessage) (Message, error) {
+	msg := Message{
+		ID:            record.MessageId,
+		ReceiptHandle: record.ReceiptHandle,
+		Body:          record.Body,
+		Attributes:    record.Attributes,
+	}
+	// Producers may gzip and base64 encode large payloads
+	payload, err := joblib.DecodeBody([]byte(record.Body))
+	if err != nil {
+		return msg, fmt.Errorf("invalid message body: %w", err)
+	}
+	if unwrapSNS {
+		payload, _ = joblib.UnwrapSNS(payload)
+	}
+	if err := json.Unmarshal(payload, &msg.Payload); err != nil {
+		return msg, fmt.Errorf("invalid enriched payload: %w", err)
+	}
+	msg.TraceParent, msg.TraceState = msg.Payload.TraceContext, msg.Payload.TraceState
+	if traceCarrier != joblib.PropagationBody {
+		if attribute, ok := record.MessageAttributes[joblib.TraceparentAttribute]; ok && attribute.StringValue != nil {
+			msg.TraceParent, msg.TraceState = *attribute.StringValue, ""
+			if state, ok := record.MessageAttributes[joblib.TracestateAttribute]; ok && state.StringValue != nil {
+				msg.TraceState = *state.StringValue
+			}
+		}
+	}
+	return msg, nil
+}
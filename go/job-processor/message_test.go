@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+func TestNewMessage(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		expectError bool
+	}{
+		{name: "Valid payload", body: validEnrichedPayload},
+		{name: "Invalid payload", body: `not json`, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := events.SQSMessage{
+				MessageId:     "sqs-1",
+				ReceiptHandle: "receipt-1",
+				Body:          tt.body,
+				Attributes:    map[string]string{joblib.SQSApproximateReceiveCount: "2"},
+			}
+			msg, err := newMessage(record)
+			if tt.expectError != (err != nil) {
+				t.Fatalf("expected error %v, got %v", tt.expectError, err)
+			}
+
+			// The record identity is kept even when the payload is invalid
+			if msg.ReceiptHandle != "receipt-1" {
+				t.Errorf("expected receipt handle receipt-1, got %s", msg.ReceiptHandle)
+			}
+			if msg.ID != "sqs-1" {
+				t.Errorf("expected message ID sqs-1, got %s", msg.ID)
+			}
+			if msg.Body != tt.body {
+				t.Errorf("expected body %s, got %s", tt.body, msg.Body)
+			}
+			if msg.Attributes[joblib.SQSApproximateReceiveCount] != "2" {
+				t.Errorf("expected attributes to be carried, got %v", msg.Attributes)
+			}
+			if !tt.expectError && msg.Payload.ID != "12345" {
+				t.Errorf("expected payload ID 12345, got %s", msg.Payload.ID)
+			}
+		})
+	}
+}
+
+func TestEnqueueBatchChildrenUsesMessage(t *testing.T) {
+	p, fakeQueue, _ := withFakeClients(t)
+
+	msg, err := newMessage(events.SQSMessage{
+		MessageId:     "sqs-1",
+		ReceiptHandle: "receipt-1",
+		Body: `{
+			"originalmessage": {"job_type": "batch_job", "message": {"children": [
+				{"job_type": "data_cleanup", "message": {"target_table": "users", "retention": 30}}
+			]}},
+			"id": "batch-1",
+			"timestamp": "2025-08-30T12:00:00Z",
+			"status": "NEW"
+		}`,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parsedJob, _, _, err := joblib.ParseJob(msg.Payload.OriginalMessage)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	p.enqueueBatchChildren(context.Background(), msg, parsedJob.(joblib.BatchJob))
+
+	children := fakeQueue.sentTo(jobsTodoURL)
+	if len(children) != 1 {
+		t.Fatalf("expected 1 child enqueued, got %d", len(children))
+	}
+	child, err := newMessage(events.SQSMessage{Body: children[0]})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if child.Payload.ParentID != "batch-1" {
+		t.Errorf("expected child ParentID batch-1, got %s", child.Payload.ParentID)
+	}
+}
+
+func TestNewMessageUnwrapsSNS(t *testing.T) {
+	wrapped, err := json.Marshal(map[string]string{
+		"Type":      "Notification",
+		"MessageId": "sns-1",
+		"TopicArn":  "arn:aws:sns:us-east-1:000000000000:jobs",
+		"Message":   validEnrichedPayload,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		unwrap         bool
+		expectUnparsed bool
+	}{
+		{name: "SNS wrapped", body: string(wrapped), unwrap: true},
+		{name: "Raw", body: validEnrichedPayload, unwrap: true},
+		{name: "Raw with unwrapping disabled", body: validEnrichedPayload, unwrap: false},
+		{name: "SNS wrapped with unwrapping disabled", body: string(wrapped), unwrap: false, expectUnparsed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := unwrapSNS
+			unwrapSNS = tt.unwrap
+			defer func() { unwrapSNS = previous }()
+
+			msg, err := newMessage(events.SQSMessage{MessageId: "sqs-1", Body: tt.body})
+			if tt.expectUnparsed {
+				// The envelope parses as an empty payload rather than failing outright
+				if err == nil && msg.Payload.ID != "" {
+					t.Errorf("expected the envelope not to parse as a payload, got ID %s", msg.Payload.ID)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if msg.Payload.ID != "12345" {
+				t.Errorf("expected payload ID 12345, got %s", msg.Payload.ID)
+			}
+			if msg.Body != tt.body {
+				t.Errorf("expected the original body to be kept")
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+var (
+	metricsQueueURL string    // queue per-message metrics records are sent to, empty disables them
+	metricsSender   sqsSender // sends metrics records, the SQS client outside tests
+)
+
+// metricsRecord is the per-message metrics record sent for a custom metrics
+// consumer, separate from the OTel metrics.
+type metricsRecord struct {
+	MessageID  string        `json:"message_id"`
+	JobID      string        `json:"job_id"`
+	JobType    string        `json:"job_type"`
+	Status     joblib.Status `json:"status"`
+	DurationMS int64         `json:"duration_ms"`
+	Timestamp  string        `json:"timestamp"`
+}
+
+// sendMetricsRecord sends one job's metrics record to the metrics queue.
+// Failures are logged and never fail the job.
+func sendMetricsRecord(ctx context.Context, record metricsRecord) {
+	if metricsQueueURL == "" || metricsSender == nil {
+		return
+	}
+	record.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		logger(ctx).Error("failed to marshal metrics record", "error", err)
+		return
+	}
+	_, err = metricsSender.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(metricsQueueURL),
+		MessageBody: aws.String(string(recordJSON)),
+	})
+	if err != nil {
+		logger(ctx).Error("failed to send metrics record", "job_id", record.JobID, "error", err)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+func TestMetricsRecord(t *testing.T) {
+	const queueURL = "http://localstack:4566/000000000000/job-metrics"
+
+	tests := []struct {
+		name            string
+		body            string
+		cancelled       bool
+		expectedJobID   string
+		expectedJobType string
+		expectedStatus  joblib.Status
+	}{
+		{
+			name:            "Completed job",
+			body:            validEnrichedPayload,
+			expectedJobID:   "12345",
+			expectedJobType: "report_generation",
+			expectedStatus:  joblib.StatusCompleted,
+		},
+		{
+			name: "Failed job",
+			body: `{
+				"originalmessage": {"job_type": "long_running_job", "message": {"task_name": "Data Migration", "timeout": 1}},
+				"id": "67890",
+				"timestamp": "2025-08-30T12:00:00Z",
+				"status": "NEW"
+			}`,
+			cancelled:       true,
+			expectedJobID:   "67890",
+			expectedJobType: "long_running_job",
+			expectedStatus:  joblib.StatusExecuteFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _, _ := withFakeClients(t)
+			fake := &fakeSQS{}
+			previousURL, previousSender := metricsQueueURL, metricsSender
+			metricsQueueURL, metricsSender = queueURL, fake
+			defer func() { metricsQueueURL, metricsSender = previousURL, previousSender }()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.cancelled {
+				cancel()
+			}
+			defer cancel()
+			p.processMessage(ctx, eventsMessage(tt.body))
+
+			sent := fake.sentTo(queueURL)
+			if len(sent) != 1 {
+				t.Fatalf("expected 1 metrics record, got %d", len(sent))
+			}
+			var record metricsRecord
+			if err := json.Unmarshal([]byte(sent[0]), &record); err != nil {
+				t.Fatalf("failed to unmarshal metrics record: %v", err)
+			}
+			if record.MessageID != "sqs-1" || record.JobID != tt.expectedJobID || record.JobType != tt.expectedJobType || record.Status != tt.expectedStatus {
+				t.Errorf("expected sqs-1 %s %s %s, got %+v", tt.expectedJobID, tt.expectedJobType, tt.expectedStatus, record)
+			}
+			if record.DurationMS < 0 {
+				t.Errorf("expected a non-negative duration, got %d", record.DurationMS)
+			}
+			if _, err := time.Parse(time.RFC3339, record.Timestamp); err != nil {
+				t.Errorf("expected an RFC3339 timestamp, got %q", record.Timestamp)
+			}
+		})
+	}
+}
+
+func TestMetricsRecordDisabled(t *testing.T) {
+	p, _, _ := withFakeClients(t)
+	fake := &fakeSQS{}
+	previousURL, previousSender := metricsQueueURL, metricsSender
+	metricsQueueURL, metricsSender = "", fake
+	defer func() { metricsQueueURL, metricsSender = previousURL, previousSender }()
+
+	p.processMessage(context.Background(), eventsMessage(validEnrichedPayload))
+	if len(fake.sent) != 0 {
+		t.Errorf("expected no metrics records without a queue, got %d", len(fake.sent))
+	}
+}
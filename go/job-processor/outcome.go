@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	resultsTable  string       // DynamoDB table job outcomes are persisted to
+	resultsClient dynamoWriter // nil disables persisting job outcomes
+)
+
+// jobOutcome is the audit record of an executed job's final state.
+type jobOutcome struct {
+	JobID     string
+	JobType   string
+	Status    joblib.Status
+	StartedAt time.Time
+	EndedAt   time.Time
+	TraceID   string
+	Error     error // why the job failed, nil when it completed
+}
+
+// item is the outcome as a DynamoDB item keyed on job_id.
+func (o jobOutcome) item() map[string]types.AttributeValue {
+	item := map[string]types.AttributeValue{
+		"job_id":     &types.AttributeValueMemberS{Value: o.JobID},
+		"job_type":   &types.AttributeValueMemberS{Value: o.JobType},
+		"status":     &types.AttributeValueMemberS{Value: string(o.Status)},
+		"started_at": &types.AttributeValueMemberS{Value: o.StartedAt.UTC().Format(time.RFC3339Nano)},
+		"ended_at":   &types.AttributeValueMemberS{Value: o.EndedAt.UTC().Format(time.RFC3339Nano)},
+	}
+	if o.TraceID != "" {
+		item["trace_id"] = &types.AttributeValueMemberS{Value: o.TraceID}
+	}
+	if o.Error != nil {
+		item["error"] = &types.AttributeValueMemberS{Value: o.Error.Error()}
+	}
+	return item
+}
+
+// persistOutcome writes the outcome of an executed job to RESULTS_TABLE,
+// overwriting the record of any earlier attempt. Failures are logged but
+// never fail the job.
+func persistOutcome(ctx context.Context, span trace.Span, outcome jobOutcome) {
+	if resultsClient == nil {
+		return
+	}
+	if spanContext := span.SpanContext(); spanContext.HasTraceID() {
+		outcome.TraceID = spanContext.TraceID().String()
+	}
+
+	// Record the outcome even if the invocation is being cancelled
+	_, err := resultsClient.PutItem(context.WithoutCancel(ctx), &dynamodb.PutItemInput{
+		TableName: aws.String(resultsTable),
+		Item:      outcome.item(),
+	})
+	if err != nil {
+		span.RecordError(err)
+		logger(ctx).Error("failed to persist job outcome", "job_id", outcome.JobID, "table", resultsTable, "error", err)
+		return
+	}
+
+	span.AddEvent("job outcome persisted", trace.WithAttributes(
+		attribute.String("message.id", outcome.JobID),
+		attribute.String("job.status", string(outcome.Status)),
+	))
+}
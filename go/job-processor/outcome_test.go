@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// withFakeResults persists job outcomes to an in-memory table
+func withFakeResults(t *testing.T) *fakeDynamo {
+	t.Helper()
+	previousTable, previousClient := resultsTable, resultsClient
+	table := &fakeDynamo{}
+	resultsTable, resultsClient = "job-results", table
+	t.Cleanup(func() { resultsTable, resultsClient = previousTable, previousClient })
+	return table
+}
+
+// stringAttribute returns the string attribute name of item, or "" when it is absent
+func stringAttribute(item map[string]types.AttributeValue, name string) string {
+	if value, ok := item[name].(*types.AttributeValueMemberS); ok {
+		return value.Value
+	}
+	return ""
+}
+
+func TestOutcomePersisted(t *testing.T) {
+	failing := `{
+		"originalmessage": {"job_type": "long_running_job", "message": {"task_name": "Data Migration", "timeout": 60}},
+		"id": "67890",
+		"timestamp": "2025-08-30T12:00:00Z",
+		"status": "NEW",
+		"trace_context": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	}`
+
+	tests := []struct {
+		name           string
+		body           string
+		timeout        time.Duration
+		expectedID     string
+		expectedType   string
+		expectedStatus joblib.Status
+		expectedTrace  string
+		expectError    bool
+	}{
+		{name: "Completed", body: validEnrichedPayload, expectedID: "12345", expectedType: "report_generation", expectedStatus: joblib.StatusCompleted},
+		{name: "Execute failed", body: failing, timeout: 20 * time.Millisecond, expectedID: "67890", expectedType: "long_running_job", expectedStatus: joblib.StatusExecuteFailed, expectedTrace: "4bf92f3577b34da6a3ce929d0e0e4736", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _, _ := withFakeClients(t)
+			table := withFakeResults(t)
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			p.processMessage(ctx, eventsMessage(tt.body))
+
+			if len(table.puts) != 1 {
+				t.Fatalf("expected 1 outcome persisted, got %d", len(table.puts))
+			}
+			put := table.puts[0]
+			if aws.ToString(put.TableName) != "job-results" {
+				t.Errorf("expected the job-results table, got %s", aws.ToString(put.TableName))
+			}
+			item := put.Item
+			if stringAttribute(item, "job_id") != tt.expectedID || stringAttribute(item, "job_type") != tt.expectedType || stringAttribute(item, "status") != string(tt.expectedStatus) {
+				t.Errorf("expected job %s (%s) %s, got %v", tt.expectedID, tt.expectedType, tt.expectedStatus, item)
+			}
+			started, err := time.Parse(time.RFC3339Nano, stringAttribute(item, "started_at"))
+			if err != nil {
+				t.Fatalf("expected an RFC 3339 started_at, got %v", err)
+			}
+			ended, err := time.Parse(time.RFC3339Nano, stringAttribute(item, "ended_at"))
+			if err != nil || ended.Before(started) {
+				t.Errorf("expected ended_at no earlier than %s, got %s", started, stringAttribute(item, "ended_at"))
+			}
+			if traceID := stringAttribute(item, "trace_id"); traceID != tt.expectedTrace {
+				t.Errorf("expected trace ID %q, got %q", tt.expectedTrace, traceID)
+			}
+			if jobErr := stringAttribute(item, "error"); tt.expectError != (jobErr != "") {
+				t.Errorf("expected an error recorded %v, got %q", tt.expectError, jobErr)
+			}
+		})
+	}
+}
+
+func TestOutcomeWriteFailureDoesNotFailJob(t *testing.T) {
+	p, _, fakeTopic := withFakeClients(t)
+	table := withFakeResults(t)
+	table.err = errors.New("table does not exist")
+	logs := captureLogs(t)
+
+	if err := p.processMessage(context.Background(), eventsMessage(validEnrichedPayload)); err != nil {
+		t.Fatalf("expected the job to succeed, got %v", err)
+	}
+	if states := endStates(fakeTopic.messages); len(states) == 0 || endState(states[len(states)-1]).Status != joblib.StatusCompleted {
+		t.Errorf("expected the job to complete, got %v", states)
+	}
+	if !strings.Contains(logs.String(), "failed to persist job outcome") {
+		t.Errorf("expected the failed write logged, got %s", logs.String())
+	}
+}
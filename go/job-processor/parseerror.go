@@ -0,0 +1,22 @@
+package main
+
+import (
+	"context"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// parseErrorQueueURL receives messages that failed to parse, so schema
+// problems can be triaged apart from execution failures on the dead-letter
+// queue. Empty sends them to the dead-letter queue with everything else.
+var parseErrorQueueURL string
+
+// reportParseFailure routes a message that failed to parse like
+// reportFailure, except that the body goes to the parse-error queue when one
+// is configured.
+func (p *processor) reportParseFailure(ctx context.Context, stage string, event joblib.JobEndStateEvent, messageBody string) {
+	emitFailureLog(ctx, event.Error, messageBody)
+	if err := p.failureRouter(ctx).ReportParseFailure(ctx, stage, event, messageBody); err != nil {
+		logger(ctx).Error("failed to report parse failure", "error", err)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseErrorQueue(t *testing.T) {
+	const parseErrors = "http://localhost:4566/000000000000/jobs-parse-errors"
+	failedJob := `{
+		"originalmessage": {"job_type": "long_running_job", "message": {"task_name": "Data Migration", "timeout": 1}},
+		"id": "67890",
+		"timestamp": "2025-08-30T12:00:00Z",
+		"status": "NEW"
+	}`
+
+	tests := []struct {
+		name             string
+		queueURL         string
+		body             string
+		cancelled        bool
+		expectParseError bool
+	}{
+		{name: "Unparseable message", queueURL: parseErrors, body: `not json`, expectParseError: true},
+		{name: "Invalid job", queueURL: parseErrors, body: `{"originalmessage": {"job_type": "data_cleanup", "message": {"retention": 30}}, "id": "1"}`, expectParseError: true},
+		{name: "Execution failure", queueURL: parseErrors, body: failedJob, cancelled: true},
+		{name: "Unparseable message without a parse-error queue", body: `not json`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, fakeQueue, _ := withFakeClients(t)
+			previous := parseErrorQueueURL
+			parseErrorQueueURL = tt.queueURL
+			defer func() { parseErrorQueueURL = previous }()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.cancelled {
+				cancel()
+			}
+			defer cancel()
+			p.processMessage(ctx, eventsMessage(tt.body))
+
+			parsed, dlq := fakeQueue.sentTo(parseErrors), fakeQueue.deadLetters(t)
+			if tt.expectParseError {
+				if len(parsed) != 1 || parsed[0] != tt.body || len(dlq) != 0 {
+					t.Errorf("expected the body on the parse-error queue only, got %v and dead letters %v", parsed, dlq)
+				}
+				return
+			}
+			if len(dlq) != 1 || dlq[0].OriginalBody != tt.body || len(parsed) != 0 {
+				t.Errorf("expected the body on the dead-letter queue only, got %v and parse errors %v", dlq, parsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// quarantineURL is the queue poison messages are held on until an operator
+// releases them, empty sends them to the dead-letter queue instead.
+var quarantineURL string
+
+// quarantineMessage moves a poison message to the quarantine queue, keeping
+// its message attributes and recording why it was quarantined.
+func (p *processor) quarantineMessage(ctx context.Context, message events.SQSMessage, reason string, receiveCount int) error {
+	attributes := map[string]types.MessageAttributeValue{}
+	for name, value := range message.MessageAttributes {
+		if value.StringValue != nil {
+			attributes[name] = types.MessageAttributeValue{DataType: aws.String(value.DataType), StringValue: value.StringValue}
+		}
+	}
+	attributes[joblib.QuarantineReasonAttribute] = types.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(reason)}
+	attributes[joblib.QuarantineReceiveCountAttribute] = types.MessageAttributeValue{DataType: aws.String("Number"), StringValue: aws.String(strconv.Itoa(receiveCount))}
+
+	_, err := p.sqsClient.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:          aws.String(quarantineURL),
+		MessageBody:       aws.String(message.Body),
+		MessageAttributes: attributes,
+	})
+	return err
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// quarantineFailingSQS fails sends to the quarantine queue and records the rest
+type quarantineFailingSQS struct {
+	fakeSQS
+}
+
+func (f *quarantineFailingSQS) SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	if aws.ToString(params.QueueUrl) == quarantineURL {
+		return nil, errors.New("queue unavailable")
+	}
+	return f.fakeSQS.SendMessage(ctx, params, optFns...)
+}
+
+func TestPoisonMessageQuarantined(t *testing.T) {
+	const quarantine = "http://localstack:4566/000000000000/jobs-quarantine"
+
+	tests := []struct {
+		name             string
+		quarantineURL    string
+		expectQuarantine bool
+	}{
+		{name: "Quarantine configured", quarantineURL: quarantine, expectQuarantine: true},
+		{name: "Quarantine disabled", quarantineURL: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, fakeQueue, _ := withFakeClients(t)
+			previousURL, previousMax := quarantineURL, maxReceiveCount
+			quarantineURL, maxReceiveCount = tt.quarantineURL, 3
+			defer func() { quarantineURL, maxReceiveCount = previousURL, previousMax }()
+
+			message := eventsMessage(validEnrichedPayload)
+			message.Attributes = map[string]string{"ApproximateReceiveCount": "4"}
+			message.MessageAttributes = map[string]events.SQSMessageAttribute{
+				joblib.TraceparentAttribute: {DataType: "String", StringValue: aws.String("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")},
+			}
+			p.processMessage(context.Background(), message)
+
+			quarantined := len(fakeQueue.sentTo(quarantine)) == 1
+			deadLettered := len(fakeQueue.sentTo(deadletterURL)) == 1
+			if quarantined != tt.expectQuarantine || deadLettered == tt.expectQuarantine {
+				t.Fatalf("expected quarantined %v, got quarantined %v and dead-lettered %v", tt.expectQuarantine, quarantined, deadLettered)
+			}
+			if !tt.expectQuarantine {
+				return
+			}
+
+			attributes := fakeQueue.sent[0].MessageAttributes
+			if count := aws.ToString(attributes[joblib.QuarantineReceiveCountAttribute].StringValue); count != "4" {
+				t.Errorf("expected receive count 4, got %q", count)
+			}
+			if aws.ToString(attributes[joblib.QuarantineReasonAttribute].StringValue) == "" {
+				t.Errorf("expected a quarantine reason")
+			}
+			if _, ok := attributes[joblib.TraceparentAttribute]; !ok {
+				t.Errorf("expected the original message attributes to be kept, got %v", attributes)
+			}
+		})
+	}
+}
+
+func TestQuarantineFailureDeadLetters(t *testing.T) {
+	p, _, _ := withFakeClients(t)
+	failing := &quarantineFailingSQS{}
+	p.sqsClient = failing
+	previousURL, previousMax := quarantineURL, maxReceiveCount
+	quarantineURL, maxReceiveCount = "http://localstack:4566/000000000000/jobs-quarantine", 3
+	defer func() { quarantineURL, maxReceiveCount = previousURL, previousMax }()
+
+	message := eventsMessage(validEnrichedPayload)
+	message.Attributes = map[string]string{"ApproximateReceiveCount": "4"}
+	p.processMessage(context.Background(), message)
+
+	if len(failing.sentTo(deadletterURL)) != 1 {
+		t.Errorf("expected the poison message to be dead-lettered when quarantine fails")
+	}
+}
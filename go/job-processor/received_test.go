@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestReceivedEvent(t *testing.T) {
+	p, _, _ := withFakeClients(t)
+	recorder := tracetest.NewSpanRecorder()
+	previousTracer, previousRecord, previousPreview := tracer, recordReceived, bodyPreviewBytes
+	tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	recordReceived, bodyPreviewBytes = true, 256
+	defer func() { tracer, recordReceived, bodyPreviewBytes = previousTracer, previousRecord, previousPreview }()
+
+	// Even a body that never parses is described
+	const body = `{"originalmessage": `
+	p.processMessage(context.Background(), eventsMessage(body))
+
+	for _, span := range recorder.Ended() {
+		if span.Name() != "ReceiveMessage" {
+			continue
+		}
+		for _, event := range span.Events() {
+			if event.Name != "received" {
+				continue
+			}
+			attrs := map[string]bool{}
+			for _, kv := range event.Attributes {
+				attrs[string(kv.Key)] = true
+				if kv.Key == "body.size_bytes" && kv.Value.AsInt64() != int64(len(body)) {
+					t.Errorf("expected body.size_bytes %d, got %d", len(body), kv.Value.AsInt64())
+				}
+				if kv.Key == "body.preview" && kv.Value.AsString() != body {
+					t.Errorf("expected body.preview %q, got %q", body, kv.Value.AsString())
+				}
+			}
+			if !attrs["body.size_bytes"] || !attrs["body.preview"] {
+				t.Errorf("expected body.size_bytes and body.preview, got %v", event.Attributes)
+			}
+			return
+		}
+	}
+	t.Errorf("expected a received span event on a ReceiveMessage span")
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// retryCancelled requeues jobs stopped by cancellation, e.g. an invocation
+// about to time out, rather than dead-lettering them as failures
+var retryCancelled bool
+
+// maxRetries is how many times a failed job is requeued to jobs-todo before
+// it is dead-lettered. Zero dead-letters it on the first failure.
+var maxRetries int
+
+// requeueCancelled puts a cancelled job back on jobs-todo to be retried,
+// reporting whether it was requeued. The job's own context is already done,
+// so the send runs without its cancellation.
+func (p *processor) requeueCancelled(ctx context.Context, span trace.Span, msg Message, err error) bool {
+	span.SetAttributes(attribute.String("job.failure_reason", "cancelled"))
+	_, sendErr := p.sqsClient.SendMessage(context.WithoutCancel(ctx), &sqs.SendMessageInput{
+		QueueUrl:          aws.String(jobsTodoURL),
+		MessageBody:       aws.String(msg.Body),
+		MessageAttributes: joblib.TraceMessageAttributes(traceCarrier, msg.TraceParent, msg.TraceState),
+	})
+	if sendErr != nil {
+		span.RecordError(sendErr)
+		logger(ctx).Error("failed to requeue cancelled job, reporting it as failed", "job_id", msg.Payload.ID, "error", sendErr)
+		return false
+	}
+
+	// The attempt still failed, even though another will be made
+	spanStatus.Fail(span, err)
+	logger(ctx).Info("job was cancelled, requeued it for retry", "job_id", msg.Payload.ID, "error", err)
+	span.AddEvent("job cancelled, requeued", trace.WithAttributes(
+		attribute.String("message.id", msg.Payload.ID),
+		attribute.String("error", err.Error()),
+	))
+	return true
+}
+
+// requeueFailed puts a failed job back on jobs-todo with its retry count
+// incremented, reporting whether it was requeued. Jobs that have used up
+// maxRetries, or failed with a joblib.IsPermanent error, are left to be
+// dead-lettered. The ID, timestamp and trace context are kept so every
+// attempt joins the original trace.
+func (p *processor) requeueFailed(ctx context.Context, span trace.Span, msg Message, err error) bool {
+	if joblib.IsPermanent(err) {
+		span.SetAttributes(attribute.String("job.failure_reason", "permanent"))
+		logger(ctx).Info("job failed permanently, dead-lettering it without a retry", "job_id", msg.Payload.ID, "error", err)
+		return false
+	}
+	if msg.Payload.RetryCount >= maxRetries {
+		return false
+	}
+
+	retry := msg.Payload
+	retry.RetryCount++
+	if len(signingKey) > 0 {
+		signature, signErr := joblib.SignPayload(retry, signingKey)
+		if signErr != nil {
+			span.RecordError(signErr)
+			logger(ctx).Error("failed to sign job retry, reporting it as failed", "job_id", retry.ID, "error", signErr)
+			return false
+		}
+		retry.Signature = signature
+	}
+	body, marshalErr := json.Marshal(retry)
+	if marshalErr != nil {
+		span.RecordError(marshalErr)
+		logger(ctx).Error("failed to marshal job retry, reporting it as failed", "job_id", retry.ID, "error", marshalErr)
+		return false
+	}
+	_, sendErr := p.sqsClient.SendMessage(context.WithoutCancel(ctx), &sqs.SendMessageInput{
+		QueueUrl:          aws.String(jobsTodoURL),
+		MessageBody:       aws.String(string(body)),
+		MessageAttributes: joblib.TraceMessageAttributes(traceCarrier, msg.TraceParent, msg.TraceState),
+	})
+	if sendErr != nil {
+		span.RecordError(sendErr)
+		logger(ctx).Error("failed to requeue job for retry, reporting it as failed", "job_id", retry.ID, "error", sendErr)
+		return false
+	}
+
+	spanStatus.Fail(span, err)
+	logger(ctx).Info("job failed, requeued it for retry", "job_id", retry.ID, "error", err, "retry", retry.RetryCount, "max_retries", maxRetries)
+	span.AddEvent("job failed, requeued", trace.WithAttributes(
+		attribute.String("message.id", retry.ID),
+		attribute.Int("job.retry_count", retry.RetryCount),
+		attribute.String("error", err.Error()),
+	))
+	return true
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestRetryCancelled(t *testing.T) {
+	const longRunning = `{
+		"originalmessage": {"job_type": "long_running_job", "message": {"task_name": "Data Migration", "timeout": 60}},
+		"id": "67890",
+		"timestamp": "2025-08-30T12:00:00Z",
+		"status": "NEW",
+		"trace_context": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	}`
+
+	tests := []struct {
+		name          string
+		retry         bool
+		expectRequeue bool
+	}{
+		{name: "Retried", retry: true, expectRequeue: true},
+		{name: "Dead-lettered when disabled", retry: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, fakeQueue, fakeTopic := withFakeClients(t)
+			previous := retryCancelled
+			retryCancelled = tt.retry
+			defer func() { retryCancelled = previous }()
+
+			// The invocation deadline stops the job part way through
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			p.processMessage(ctx, eventsMessage(longRunning))
+
+			requeued, dlq := fakeQueue.sentTo(jobsTodoURL), fakeQueue.sentTo(deadletterURL)
+			if tt.expectRequeue {
+				if len(requeued) != 1 || requeued[0] != longRunning || len(dlq) != 0 {
+					t.Errorf("expected the job requeued unchanged and not dead-lettered, got %v and dead letters %v", requeued, dlq)
+				}
+				if ended := endStates(fakeTopic.messages); len(ended) != 0 {
+					t.Errorf("expected no failure notification for a retried job, got %v", ended)
+				}
+				return
+			}
+			if len(requeued) != 0 || len(dlq) != 1 {
+				t.Errorf("expected the job dead-lettered, got %v requeued and dead letters %v", requeued, dlq)
+			}
+		})
+	}
+}
+
+func TestRequeueFailed(t *testing.T) {
+	tests := []struct {
+		name          string
+		retryCount    int
+		expectRequeue bool
+	}{
+		{name: "First failure requeued", retryCount: 0, expectRequeue: true},
+		{name: "Last retry requeued", retryCount: 1, expectRequeue: true},
+		{name: "Dead-lettered at the limit", retryCount: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, fakeQueue, _ := withFakeClients(t)
+			recorder := tracetest.NewSpanRecorder()
+			previousTracer, previousMax := tracer, maxRetries
+			tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+			maxRetries = 2
+			defer func() { tracer, maxRetries = previousTracer, previousMax }()
+
+			body := fmt.Sprintf(`{
+				"originalmessage": {"job_type": "long_running_job", "message": {"task_name": "Data Migration", "timeout": 60}},
+				"id": "67890",
+				"timestamp": "2025-08-30T12:00:00Z",
+				"status": "NEW",
+				"trace_context": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+				"retry_count": %d
+			}`, tt.retryCount)
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			p.processMessage(ctx, eventsMessage(body))
+
+			requeued, dlq := fakeQueue.sentTo(jobsTodoURL), fakeQueue.sentTo(deadletterURL)
+			if !tt.expectRequeue {
+				if len(requeued) != 0 || len(dlq) != 1 {
+					t.Errorf("expected the job dead-lettered, got %v requeued and dead letters %v", requeued, dlq)
+				}
+				return
+			}
+			if len(requeued) != 1 || len(dlq) != 0 {
+				t.Fatalf("expected the job requeued and not dead-lettered, got %v and dead letters %v", requeued, dlq)
+			}
+			var retry joblib.EnrichedPayload
+			if err := json.Unmarshal([]byte(requeued[0]), &retry); err != nil {
+				t.Fatalf("failed to unmarshal requeued job: %v", err)
+			}
+			if retry.RetryCount != tt.retryCount+1 {
+				t.Errorf("expected retry count %d, got %d", tt.retryCount+1, retry.RetryCount)
+			}
+			if retry.ID != "67890" || retry.Timestamp != "2025-08-30T12:00:00Z" || retry.TraceContext != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+				t.Errorf("expected the ID, timestamp and trace context preserved, got %+v", retry)
+			}
+
+			tagged := false
+			for _, span := range recorder.Ended() {
+				for _, kv := range span.Attributes() {
+					if span.Name() == "ExecuteJob" && kv.Key == "job.retry_count" {
+						tagged = kv.Value.AsInt64() == int64(tt.retryCount)
+					}
+				}
+			}
+			if !tagged {
+				t.Errorf("expected the ExecuteJob span tagged with job.retry_count %d", tt.retryCount)
+			}
+		})
+	}
+}
+
+func TestPermanentFailureNotRetried(t *testing.T) {
+	p, fakeQueue, _ := withFakeClients(t)
+	withExecuteRetries(t, 2, time.Millisecond)
+	recorder := tracetest.NewSpanRecorder()
+	previousTracer, previousMax := tracer, maxRetries
+	tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	maxRetries = 2
+	defer func() { tracer, maxRetries = previousTracer, previousMax }()
+	attempts := 0
+	withJobType(t, "flaky_job", func() joblib.Job { return &flakyJob{attempts: &attempts} })
+
+	err := p.processMessage(context.Background(), eventsMessage(`{
+		"originalmessage": {"job_type": "flaky_job", "message": {"failures": 5, "permanent": true}},
+		"id": "67890",
+		"timestamp": "2025-08-30T12:00:00Z",
+		"status": "NEW"
+	}`))
+	if !joblib.IsPermanent(err) {
+		t.Errorf("expected the permanent failure returned, got %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("expected a single attempt, got %d", attempts)
+	}
+	if requeued, dlq := fakeQueue.sentTo(jobsTodoURL), fakeQueue.deadLetters(t); len(requeued) != 0 || len(dlq) != 1 {
+		t.Errorf("expected the job dead-lettered at once, got %v requeued and dead letters %v", requeued, dlq)
+	}
+
+	tagged := false
+	for _, span := range recorder.Ended() {
+		for _, kv := range span.Attributes() {
+			if span.Name() == "ExecuteJob" && kv.Key == "job.failure_reason" && kv.Value.AsString() == "permanent" {
+				tagged = true
+			}
+		}
+	}
+	if !tagged {
+		t.Errorf("expected the ExecuteJob span tagged with job.failure_reason permanent")
+	}
+}
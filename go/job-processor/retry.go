@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	maxExecuteRetries int           // times a failed execution is retried in-process before giving up
+	executeRetryDelay time.Duration // wait before the first retry, doubling for each one after
+)
+
+// executeWithRetries runs job, retrying a failed execution up to
+// maxExecuteRetries times so a transient failure doesn't dead-letter it. Each
+// attempt is recorded as a span event. Permanent failures aren't retried.
+// Retrying stops as soon as ctx is done, and gives up rather than wait past
+// its deadline, returning the last error.
+func executeWithRetries(ctx context.Context, span trace.Span, job joblib.Job, jobType string) (joblib.JobResult, error) {
+	delay := executeRetryDelay
+	for attempt := 1; ; attempt++ {
+		span.AddEvent("job execute attempt", trace.WithAttributes(attribute.Int("job.attempt", attempt)))
+		result, err := executeJob(ctx, span, job, jobType)
+		if err == nil || joblib.IsPermanent(err) || attempt > maxExecuteRetries || ctx.Err() != nil {
+			return result, err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			logger(ctx).Warn("not retrying job, the invocation would end first", "error", err, "attempt", attempt)
+			return result, err
+		}
+
+		logger(ctx).Warn("job failed to execute, retrying", "error", err, "attempt", attempt, "max_retries", maxExecuteRetries, "delay", delay)
+		select {
+		case <-ctx.Done():
+			return result, err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
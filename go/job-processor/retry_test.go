@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// flakyJob fails its first Failures executions
+type flakyJob struct {
+	Failures  int  `json:"failures"`
+	Permanent bool `json:"permanent"` // fail with a joblib.Permanent error
+	attempts  *int
+}
+
+func (j *flakyJob) Validate() error      { return nil }
+func (j *flakyJob) Name() joblib.JobType { return "flaky_job" }
+
+func (j *flakyJob) Execute(ctx context.Context) (joblib.JobResult, error) {
+	*j.attempts++
+	if *j.attempts <= j.Failures && j.Permanent {
+		return joblib.JobResult{}, joblib.Permanent(errors.New("unknown report template"))
+	}
+	if *j.attempts <= j.Failures {
+		return joblib.JobResult{}, errors.New("downstream unavailable")
+	}
+	return joblib.JobResult{Message: "done"}, nil
+}
+
+// withExecuteRetries retries failed executions up to retries times, from delay
+func withExecuteRetries(t *testing.T, retries int, delay time.Duration) {
+	t.Helper()
+	previousRetries, previousDelay := maxExecuteRetries, executeRetryDelay
+	maxExecuteRetries, executeRetryDelay = retries, delay
+	t.Cleanup(func() { maxExecuteRetries, executeRetryDelay = previousRetries, previousDelay })
+}
+
+// withJobType registers a job type for the duration of a test
+func withJobType(t *testing.T, name string, factory func() joblib.Job) {
+	t.Helper()
+	t.Cleanup(joblib.SwapJobType(name, factory))
+}
+
+func TestExecuteWithRetries(t *testing.T) {
+	tests := []struct {
+		name             string
+		retries          int
+		failures         int
+		expectAttempts   int
+		expectSuccessful bool
+	}{
+		{name: "No retries by default", failures: 1, expectAttempts: 1},
+		{name: "Transient failure retried", retries: 3, failures: 2, expectAttempts: 3, expectSuccessful: true},
+		{name: "Gives up after the final retry", retries: 2, failures: 5, expectAttempts: 3},
+		{name: "Success isn't retried", retries: 3, expectAttempts: 1, expectSuccessful: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withExecuteRetries(t, tt.retries, time.Millisecond)
+			recorder := tracetest.NewSpanRecorder()
+			_, span := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test").Start(context.Background(), "ExecuteJob")
+
+			attempts := 0
+			_, err := executeWithRetries(context.Background(), span, &flakyJob{Failures: tt.failures, attempts: &attempts}, "flaky_job")
+			span.End()
+
+			if attempts != tt.expectAttempts {
+				t.Errorf("expected %d attempts, got %d", tt.expectAttempts, attempts)
+			}
+			if tt.expectSuccessful != (err == nil) {
+				t.Errorf("expected success %v, got %v", tt.expectSuccessful, err)
+			}
+			var recorded []int64
+			for _, event := range recorder.Ended()[0].Events() {
+				for _, kv := range event.Attributes {
+					if event.Name == "job execute attempt" && kv.Key == "job.attempt" {
+						recorded = append(recorded, kv.Value.AsInt64())
+					}
+				}
+			}
+			if len(recorded) != tt.expectAttempts || recorded[len(recorded)-1] != int64(tt.expectAttempts) {
+				t.Errorf("expected attempts 1 to %d as span events, got %v", tt.expectAttempts, recorded)
+			}
+		})
+	}
+}
+
+func TestExecuteRetriesStopWhenCancelled(t *testing.T) {
+	withExecuteRetries(t, 5, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	attempts := 0
+	start := time.Now()
+	_, err := executeWithRetries(ctx, trace.SpanFromContext(ctx), &flakyJob{Failures: 10, attempts: &attempts}, "flaky_job")
+	if err == nil || attempts != 1 {
+		t.Errorf("expected the first failure returned after 1 attempt, got %v after %d", err, attempts)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected cancellation to abort the backoff, took %s", elapsed)
+	}
+}
+
+func TestExecuteRetriesRespectDeadline(t *testing.T) {
+	withExecuteRetries(t, 5, time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	attempts := 0
+	start := time.Now()
+	if _, err := executeWithRetries(ctx, trace.SpanFromContext(ctx), &flakyJob{Failures: 10, attempts: &attempts}, "flaky_job"); err == nil {
+		t.Errorf("expected the failure returned")
+	}
+	if elapsed := time.Since(start); attempts != 1 || elapsed > 500*time.Millisecond {
+		t.Errorf("expected no retry that would outlive the deadline, got %d attempts in %s", attempts, elapsed)
+	}
+}
+
+func TestExecuteRetriedBeforeDeadLettering(t *testing.T) {
+	tests := []struct {
+		name             string
+		failures         int
+		expectAttempts   int
+		expectDeadLetter bool
+	}{
+		{name: "Recovered by a retry", failures: 2, expectAttempts: 3},
+		{name: "Dead-lettered after the final failure", failures: 5, expectAttempts: 3, expectDeadLetter: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, fakeQueue, _ := withFakeClients(t)
+			withExecuteRetries(t, 2, time.Millisecond)
+			attempts := 0
+			withJobType(t, "flaky_job", func() joblib.Job { return &flakyJob{attempts: &attempts} })
+
+			err := p.processMessage(context.Background(), eventsMessage(fmt.Sprintf(`{
+				"originalmessage": {"job_type": "flaky_job", "message": {"failures": %d}},
+				"id": "67890",
+				"timestamp": "2025-08-30T12:00:00Z",
+				"status": "NEW"
+			}`, tt.failures)))
+
+			if attempts != tt.expectAttempts {
+				t.Errorf("expected %d attempts, got %d", tt.expectAttempts, attempts)
+			}
+			if tt.expectDeadLetter != (err != nil) {
+				t.Errorf("expected failure %v, got %v", tt.expectDeadLetter, err)
+			}
+			if deadLetters := fakeQueue.deadLetters(t); tt.expectDeadLetter != (len(deadLetters) == 1) {
+				t.Errorf("expected dead-lettered %v, got %v", tt.expectDeadLetter, deadLetters)
+			}
+		})
+	}
+}
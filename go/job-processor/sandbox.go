@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	sandboxJobTypes map[joblib.JobType]bool // job types executed under the resource watchdog
+	sandboxLimits   joblib.ResourceLimits
+	executeTimeout  time.Duration // longest any job may execute for, 0 for no limit
+)
+
+// sandboxSharesProcess reports whether sandboxed jobs may run alongside other
+// records of their batch. RunWithLimits measures the CPU time and heap of the
+// whole process, so a sandboxed job could then be stopped for what its
+// siblings used, and init drops MAX_CONCURRENCY to 1 for the limits to be
+// accurate.
+func sandboxSharesProcess() bool {
+	limited := sandboxLimits.MaxCPUTime > 0 || sandboxLimits.MaxMemoryBytes > 0
+	return len(sandboxJobTypes) > 0 && limited && maxConcurrency > 1
+}
+
+// executeJob runs job, under the resource watchdog when its type is
+// sandboxed. A job stopped by the watchdog is tagged with the
+// resource_limit_exceeded failure reason, and one stopped for running past
+// executeTimeout with the timeout reason. A job's own timeout still applies,
+// so whichever is shorter stops it.
+func executeJob(ctx context.Context, span trace.Span, job joblib.Job, jobType string) (joblib.JobResult, error) {
+	executeCtx := ctx
+	if executeTimeout > 0 {
+		var cancel context.CancelFunc
+		executeCtx, cancel = context.WithTimeout(ctx, executeTimeout)
+		defer cancel()
+	}
+
+	var result joblib.JobResult
+	var err error
+	if !sandboxJobTypes[joblib.JobType(jobType)] {
+		result, err = job.Execute(executeCtx)
+	} else {
+		result, err = joblib.RunWithLimits(executeCtx, sandboxLimits, job)
+	}
+	if errors.Is(err, joblib.ErrResourceLimitExceeded) {
+		span.SetAttributes(attribute.String("job.failure_reason", joblib.ErrResourceLimitExceeded.Error()))
+	}
+	// Only the execute timeout expired, not the invocation
+	if err != nil && ctx.Err() == nil && errors.Is(executeCtx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("%w: job ran past EXECUTE_TIMEOUT of %s: %w", joblib.ErrTimedOut, executeTimeout, err)
+		span.SetAttributes(attribute.String("job.failure_reason", "timeout"))
+		span.RecordError(err)
+	}
+	return result, err
+}
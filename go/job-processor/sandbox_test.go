@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSandboxResourceLimit(t *testing.T) {
+	p, _, _ := withFakeClients(t)
+	recorder := tracetest.NewSpanRecorder()
+	previousTracer, previousTypes, previousLimits, previousStatuses := tracer, sandboxJobTypes, sandboxLimits, jobStatuses
+	tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	sandboxJobTypes = map[joblib.JobType]bool{joblib.LongRunning: true}
+	// The process always has more than a byte of heap, so the watchdog fires on its first sample
+	sandboxLimits = joblib.ResourceLimits{MaxMemoryBytes: 1, Interval: time.Millisecond}
+	jobStatuses = joblib.NewLRUCache[string, joblib.Status]("test_job_statuses", 10)
+	defer func() {
+		tracer, sandboxJobTypes, sandboxLimits, jobStatuses = previousTracer, previousTypes, previousLimits, previousStatuses
+	}()
+
+	start := time.Now()
+	p.processMessage(context.Background(), eventsMessage(`{
+		"originalmessage": {"job_type": "long_running_job", "message": {"task_name": "Data Migration", "timeout": 5}},
+		"id": "67890",
+		"timestamp": "2025-08-30T12:00:00Z",
+		"status": "NEW"
+	}`))
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the job to be cancelled by the watchdog, ran for %s", elapsed)
+	}
+	if status, _ := jobStatuses.Get("67890"); status != joblib.StatusExecuteFailed {
+		t.Errorf("expected status %s, got %q", joblib.StatusExecuteFailed, status)
+	}
+	found := false
+	for _, span := range recorder.Ended() {
+		for _, kv := range span.Attributes() {
+			if kv.Key == "job.failure_reason" && kv.Value.AsString() == "resource_limit_exceeded" {
+				found = true
+			}
+		}
+	}
+	if !found {
+		t.Errorf("expected a resource_limit_exceeded failure reason on the span")
+	}
+
+	// Job types that aren't sandboxed run without the watchdog
+	p.processMessage(context.Background(), eventsMessage(validEnrichedPayload))
+	if status, _ := jobStatuses.Get("12345"); status != joblib.StatusCompleted {
+		t.Errorf("expected status %s, got %q", joblib.StatusCompleted, status)
+	}
+}
+
+func TestExecuteTimeout(t *testing.T) {
+	tests := []struct {
+		name           string
+		executeTimeout time.Duration
+		jobTimeout     int // the long-running job's own timeout, in seconds
+		expectReason   string
+		expectMessage  string
+	}{
+		{name: "Execute timeout shorter than the job's", executeTimeout: 100 * time.Millisecond, jobTimeout: 60, expectReason: "timeout", expectMessage: "EXECUTE_TIMEOUT"},
+		{name: "Job's own timeout shorter", executeTimeout: 10 * time.Second, jobTimeout: 1, expectMessage: "did not finish within 1 seconds"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, fakeQueue, fakeTopic := withFakeClients(t)
+			recorder := tracetest.NewSpanRecorder()
+			previousTracer, previousTimeout := tracer, executeTimeout
+			tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+			executeTimeout = tt.executeTimeout
+			defer func() { tracer, executeTimeout = previousTracer, previousTimeout }()
+
+			err := p.processMessage(context.Background(), eventsMessage(fmt.Sprintf(`{
+				"originalmessage": {"job_type": "long_running_job", "message": {"task_name": "Data Migration", "timeout": %d, "work_duration": 30}},
+				"id": "67890",
+				"timestamp": "2025-08-30T12:00:00Z",
+				"status": "NEW"
+			}`, tt.jobTimeout)))
+
+			if err == nil || !strings.Contains(err.Error(), tt.expectMessage) {
+				t.Fatalf("expected an error mentioning %q, got %v", tt.expectMessage, err)
+			}
+			if states := endStates(fakeTopic.messages); len(states) == 0 || endState(states[len(states)-1]).Status != joblib.StatusExecuteFailed {
+				t.Errorf("expected the job to end %s, got %v", joblib.StatusExecuteFailed, states)
+			}
+			if len(fakeQueue.deadLetters(t)) != 1 {
+				t.Errorf("expected the job dead-lettered")
+			}
+			reason := ""
+			for _, span := range recorder.Ended() {
+				for _, kv := range span.Attributes() {
+					if kv.Key == "job.failure_reason" {
+						reason = kv.Value.AsString()
+					}
+				}
+			}
+			if reason != tt.expectReason {
+				t.Errorf("expected failure reason %q, got %q", tt.expectReason, reason)
+			}
+		})
+	}
+}
+
+func TestSandboxSharesProcess(t *testing.T) {
+	tests := []struct {
+		name        string
+		jobTypes    map[joblib.JobType]bool
+		limits      joblib.ResourceLimits
+		concurrency int
+		expected    bool
+	}{
+		{name: "Sandboxed alongside other records", jobTypes: map[joblib.JobType]bool{joblib.LongRunning: true}, limits: joblib.ResourceLimits{MaxCPUTime: time.Second}, concurrency: 4, expected: true},
+		{name: "One record at a time", jobTypes: map[joblib.JobType]bool{joblib.LongRunning: true}, limits: joblib.ResourceLimits{MaxMemoryBytes: 64 << 20}, concurrency: 1},
+		{name: "No job types sandboxed", limits: joblib.ResourceLimits{MaxCPUTime: time.Second}, concurrency: 4},
+		{name: "No limits", jobTypes: map[joblib.JobType]bool{joblib.LongRunning: true}, concurrency: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previousTypes, previousLimits, previousConcurrency := sandboxJobTypes, sandboxLimits, maxConcurrency
+			sandboxJobTypes, sandboxLimits, maxConcurrency = tt.jobTypes, tt.limits, tt.concurrency
+			defer func() {
+				sandboxJobTypes, sandboxLimits, maxConcurrency = previousTypes, previousLimits, previousConcurrency
+			}()
+
+			if actual := sandboxSharesProcess(); actual != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestExecuteJobSpanStatus(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		cancelled      bool
+		retries        int
+		recordStatus   bool
+		expectedStatus codes.Code
+	}{
+		{name: "Completed job", body: validEnrichedPayload, recordStatus: true, expectedStatus: codes.Ok},
+		{
+			name: "Failed job",
+			body: `{
+				"originalmessage": {"job_type": "long_running_job", "message": {"task_name": "Data Migration", "timeout": 1}},
+				"id": "67890",
+				"timestamp": "2025-08-30T12:00:00Z",
+				"status": "NEW"
+			}`,
+			cancelled:      true,
+			recordStatus:   true,
+			expectedStatus: codes.Error,
+		},
+		{
+			name: "Failed job requeued for retry",
+			body: `{
+				"originalmessage": {"job_type": "long_running_job", "message": {"task_name": "Data Migration", "timeout": 1}},
+				"id": "67890",
+				"timestamp": "2025-08-30T12:00:00Z",
+				"status": "NEW"
+			}`,
+			cancelled:      true,
+			retries:        2,
+			recordStatus:   true,
+			expectedStatus: codes.Error,
+		},
+		{name: "Disabled", body: validEnrichedPayload, expectedStatus: codes.Unset},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _, _ := withFakeClients(t)
+			recorder := tracetest.NewSpanRecorder()
+			previousTracer, previousStatus, previousRetries := tracer, spanStatus.Record, maxRetries
+			tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+			spanStatus.Record, maxRetries = tt.recordStatus, tt.retries
+			defer func() { tracer, spanStatus.Record, maxRetries = previousTracer, previousStatus, previousRetries }()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.cancelled {
+				cancel()
+			}
+			defer cancel()
+			p.processMessage(ctx, eventsMessage(tt.body))
+
+			found := false
+			for _, span := range recorder.Ended() {
+				if span.Name() != "ExecuteJob" {
+					continue
+				}
+				found = true
+				if span.Status().Code != tt.expectedStatus {
+					t.Errorf("expected status %s, got %s", tt.expectedStatus, span.Status().Code)
+				}
+			}
+			if !found {
+				t.Fatalf("expected an ExecuteJob span")
+			}
+		})
+	}
+}
+
+func TestSplitBatchJobSpanStatus(t *testing.T) {
+	p, _, _ := withFakeClients(t)
+	recorder := tracetest.NewSpanRecorder()
+	previousTracer, previousStatus := tracer, spanStatus.Record
+	tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	spanStatus.Record = true
+	defer func() { tracer, spanStatus.Record = previousTracer, previousStatus }()
+
+	p.processMessage(context.Background(), eventsMessage(`{
+		"originalmessage": {"job_type": "batch_job", "message": {"children": [
+			{"job_type": "data_cleanup", "message": {"target_table": "users", "retention": 30}}
+		]}},
+		"id": "batch-1",
+		"timestamp": "2025-08-30T12:00:00Z",
+		"status": "NEW"
+	}`))
+
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Name() != "SplitBatchJob" {
+		t.Fatalf("expected a SplitBatchJob span, got %d spans", len(spans))
+	}
+	if status := spans[0].Status().Code; status != codes.Ok {
+		t.Errorf("expected status %s once every child was enqueued, got %s", codes.Ok, status)
+	}
+}
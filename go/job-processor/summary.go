@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+// snsBatchSummary publishes one roll-up SNS message per invocation instead of
+// one per job.
+var snsBatchSummary bool
+
+// batchOutcome is one end state reported while processing an invocation's batch.
+type batchOutcome struct {
+	MessageID string `json:"message_id"`
+	Status    string `json:"status"` // succeeded or failed
+	Detail    string `json:"detail"`
+}
+
+// batchSummary collects the end states of one invocation's batch.
+type batchSummary struct {
+	InvocationID string         `json:"invocation_id,omitempty"`
+	Records      int            `json:"records"`
+	Succeeded    int            `json:"succeeded"`
+	Failed       int            `json:"failed"`
+	Outcomes     []batchOutcome `json:"outcomes"`
+
+	mu sync.Mutex // records in a batch are processed concurrently
+}
+
+type batchSummaryKey struct{}
+
+// withBatchSummary returns a context collecting end states into a new summary.
+func withBatchSummary(ctx context.Context) (context.Context, *batchSummary) {
+	summary := &batchSummary{Outcomes: []batchOutcome{}}
+	summary.InvocationID, _ = invocations.ID(ctx)
+	return context.WithValue(ctx, batchSummaryKey{}, summary), summary
+}
+
+// notifyEndState reports a job's end state, publishing it to SNS straight
+// away or adding it to the batch summary in ctx with detail. The summary only
+// collects end states, so progress such as IN_PROGRESS is left out of it.
+func (p *processor) notifyEndState(ctx context.Context, event joblib.JobEndStateEvent, detail string) error {
+	summary, ok := ctx.Value(batchSummaryKey{}).(*batchSummary)
+	if !ok {
+		return p.publishEndState(ctx, event)
+	}
+	if !event.Status.IsTerminal() {
+		return nil
+	}
+	outcome := batchOutcome{MessageID: recordID(ctx), Status: "succeeded", Detail: detail}
+	summary.mu.Lock()
+	defer summary.mu.Unlock()
+	if event.Status == joblib.StatusCompleted {
+		summary.Succeeded++
+	} else {
+		outcome.Status = "failed"
+		summary.Failed++
+	}
+	summary.Outcomes = append(summary.Outcomes, outcome)
+	return nil
+}
+
+// publishBatchSummary publishes the collected summary as one SNS message.
+func (p *processor) publishBatchSummary(ctx context.Context, summary *batchSummary) error {
+	summaryJSON, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	return publishToSNS(ctx, p.snsClient, snsTopicArn, string(summaryJSON), nil)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+func TestBatchSummaryPublished(t *testing.T) {
+	p, _, fakeTopic := withFakeClients(t)
+	previous := snsBatchSummary
+	snsBatchSummary = true
+	defer func() { snsBatchSummary = previous }()
+
+	records := []events.SQSMessage{
+		{MessageId: "sqs-1", Body: validEnrichedPayload},
+		{MessageId: "sqs-2", Body: `not json`},
+		{MessageId: "sqs-3", Body: payloadWithID(t, "12346")},
+	}
+	ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: "req-1"})
+	if _, err := p.handler(ctx, events.SQSEvent{Records: records}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(fakeTopic.messages) != 1 {
+		t.Fatalf("expected 1 summary message, got %d: %v", len(fakeTopic.messages), fakeTopic.messages)
+	}
+	var summary batchSummary
+	if err := json.Unmarshal([]byte(fakeTopic.messages[0]), &summary); err != nil {
+		t.Fatalf("failed to unmarshal summary: %v", err)
+	}
+	if summary.InvocationID != "req-1" || summary.Records != 3 || summary.Succeeded != 2 || summary.Failed != 1 {
+		t.Errorf("expected 3 records, 2 succeeded and 1 failed for req-1, got %+v", &summary)
+	}
+
+	// Records are processed concurrently, so outcomes are in the order they finished
+	expected := map[string]string{"sqs-1": "succeeded", "sqs-2": "failed", "sqs-3": "succeeded"}
+	if len(summary.Outcomes) != len(expected) {
+		t.Fatalf("expected %d outcomes, got %+v", len(expected), summary.Outcomes)
+	}
+	for _, outcome := range summary.Outcomes {
+		if outcome.Status != expected[outcome.MessageID] {
+			t.Errorf("expected %s %s, got %s", outcome.MessageID, expected[outcome.MessageID], outcome.Status)
+		}
+		if outcome.MessageID == "sqs-2" && !strings.HasPrefix(outcome.Detail, "failed to parse job message") {
+			t.Errorf("expected the failure reason in the outcome, got %q", outcome.Detail)
+		}
+	}
+}
+
+func TestPerJobNotificationsByDefault(t *testing.T) {
+	p, _, fakeTopic := withFakeClients(t)
+	previous := snsBatchSummary
+	snsBatchSummary = false
+	defer func() { snsBatchSummary = previous }()
+
+	records := []events.SQSMessage{
+		{MessageId: "sqs-1", Body: validEnrichedPayload},
+		{MessageId: "sqs-2", Body: `not json`},
+	}
+	if _, err := p.handler(context.Background(), events.SQSEvent{Records: records}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ended := endStates(fakeTopic.messages); len(ended) != 2 {
+		t.Errorf("expected one end state per job, got %v", ended)
+	}
+}
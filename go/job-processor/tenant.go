@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	tenantField   string              // job message field identifying the tenant, e.g. user_id
+	tenantQuota   *joblib.TenantQuota // per-tenant execution quota of this instance, nil disables throttling
+	throttleDelay time.Duration       // how long throttled jobs wait on jobs-todo before being retried
+)
+
+// throttleTenant requeues msg with a delay when its tenant is over quota,
+// reporting whether it was throttled. A job that can't be requeued runs
+// anyway rather than being lost.
+func (p *processor) throttleTenant(ctx context.Context, span trace.Span, msg Message, originalMessage []byte) bool {
+	if tenantQuota == nil {
+		return false
+	}
+	tenant, ok := joblib.MessageField(originalMessage, tenantField)
+	if !ok || tenantQuota.Allow(tenant) {
+		return false
+	}
+
+	_, err := p.sqsClient.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:          aws.String(jobsTodoURL),
+		MessageBody:       aws.String(msg.Body),
+		MessageAttributes: joblib.TraceMessageAttributes(traceCarrier, msg.TraceParent, msg.TraceState),
+		DelaySeconds:      int32(throttleDelay / time.Second),
+	})
+	if err != nil {
+		span.RecordError(err)
+		logger(ctx).Warn("failed to requeue throttled job, executing it anyway", "job_id", msg.Payload.ID, "error", err)
+		return false
+	}
+
+	logger(ctx).Info("tenant is over quota, requeued job", "tenant_id", tenant, "job_id", msg.Payload.ID, "delay", throttleDelay.String())
+	span.AddEvent("job throttled", trace.WithAttributes(
+		attribute.String("tenant", tenant),
+		attribute.String("throttle.delay", throttleDelay.String()),
+	))
+	return true
+}
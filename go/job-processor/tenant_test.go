@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// onboardingPayload is an enriched user onboarding job for userID
+func onboardingPayload(id, userID string) string {
+	return fmt.Sprintf(`{
+		"originalmessage": {"job_type": "user_onboarding", "message": {"user_id": %q, "user_name": "John Doe"}},
+		"id": %q,
+		"timestamp": "2025-08-30T12:00:00Z",
+		"status": "NEW"
+	}`, userID, id)
+}
+
+func TestTenantThrottling(t *testing.T) {
+	p, fakeQueue, _ := withFakeClients(t)
+	previousField, previousQuota, previousDelay := tenantField, tenantQuota, throttleDelay
+	previousStatuses := jobStatuses
+	tenantField, throttleDelay = "user_id", 30*time.Second
+	tenantQuota = joblib.NewTenantQuota(joblib.SystemClock{}, 2, time.Minute)
+	jobStatuses = joblib.NewLRUCache[string, joblib.Status]("test_job_statuses", 10)
+	defer func() {
+		tenantField, tenantQuota, throttleDelay = previousField, previousQuota, previousDelay
+		jobStatuses = previousStatuses
+	}()
+
+	// user_1 floods the queue while user_2 sends a single job
+	for i := 1; i <= 4; i++ {
+		p.processMessage(context.Background(), eventsMessage(onboardingPayload(fmt.Sprintf("a-%d", i), "user_1")))
+	}
+	p.processMessage(context.Background(), eventsMessage(onboardingPayload("b-1", "user_2")))
+
+	for _, id := range []string{"a-1", "a-2", "b-1"} {
+		if status, ok := jobStatuses.Get(id); !ok || status != joblib.StatusCompleted {
+			t.Errorf("expected %s to execute, got status %q", id, status)
+		}
+	}
+	for _, id := range []string{"a-3", "a-4"} {
+		if status, ok := jobStatuses.Get(id); ok {
+			t.Errorf("expected %s to be throttled, got status %q", id, status)
+		}
+	}
+
+	requeued := fakeQueue.sentTo(jobsTodoURL)
+	if len(requeued) != 2 {
+		t.Fatalf("expected 2 throttled jobs requeued, got %d", len(requeued))
+	}
+	for _, input := range fakeQueue.sent {
+		if aws.ToString(input.QueueUrl) == jobsTodoURL && input.DelaySeconds != 30 {
+			t.Errorf("expected a 30 second delay, got %d", input.DelaySeconds)
+		}
+	}
+}
+
+func TestTenantThrottlingDisabled(t *testing.T) {
+	p, fakeQueue, _ := withFakeClients(t)
+	previousQuota := tenantQuota
+	tenantQuota = nil
+	defer func() { tenantQuota = previousQuota }()
+
+	for i := 1; i <= 3; i++ {
+		p.processMessage(context.Background(), eventsMessage(onboardingPayload(fmt.Sprintf("a-%d", i), "user_1")))
+	}
+	if requeued := fakeQueue.sentTo(jobsTodoURL); len(requeued) != 0 {
+		t.Errorf("expected no jobs requeued without a quota, got %d", len(requeued))
+	}
+}
+
+func TestTenantBaggageTagsSpans(t *testing.T) {
+	tests := []struct {
+		name           string
+		baggageContext string
+		expectTenant   string
+	}{
+		{name: "Tenant", baggageContext: "tenant_id=acme", expectTenant: "acme"},
+		{name: "No tenant"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _, _ := withFakeClients(t)
+			recorder := tracetest.NewSpanRecorder()
+			previousTracer := tracer
+			tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+			defer func() { tracer = previousTracer }()
+
+			body := fmt.Sprintf(`{
+				"originalmessage": {"job_type": "report_generation", "message": {"report_name": "Sales Report", "filters": "region=US"}},
+				"id": "12345",
+				"timestamp": "2025-08-30T12:00:00Z",
+				"status": "NEW",
+				"baggage_context": %q
+			}`, tt.baggageContext)
+			p.processMessage(context.Background(), eventsMessage(body))
+
+			spans := recorder.Ended()
+			if len(spans) != 1 || spans[0].Name() != "ExecuteJob" {
+				t.Fatalf("expected an ExecuteJob span, got %d spans", len(spans))
+			}
+			tenant := ""
+			for _, attr := range spans[0].Attributes() {
+				if attr.Key == "tenant.id" {
+					tenant = attr.Value.AsString()
+				}
+			}
+			if tenant != tt.expectTenant {
+				t.Errorf("expected tenant.id %q, got %q", tt.expectTenant, tenant)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Pipeline stages at which trace context propagation is checked.
+const (
+	stageReceive    = "receive"     // the payload from the ingester
+	stageBatchSplit = "batch_split" // the payload a batch child is queued with
+	stageFollowup   = "followup"    // the payload a follow-up is queued with
+)
+
+var (
+	traceGapDebug       bool // check propagated trace contexts and count the gaps
+	traceContextMissing metric.Int64Counter
+)
+
+func init() {
+	var err error
+	traceContextMissing, err = otel.Meter("job-processor").Int64Counter("trace_context_missing_total",
+		metric.WithDescription("Messages whose trace context was missing or malformed"),
+	)
+	if err != nil {
+		log.Printf("failed to create trace context counter: %v", err)
+	}
+}
+
+// checkTraceContext flags a traceparent that would break the trace at stage,
+// logging it and counting it by stage and reason. It only runs in the
+// TRACE_GAP_DEBUG mode.
+func checkTraceContext(ctx context.Context, stage, messageID, traceparent string) {
+	if !traceGapDebug {
+		return
+	}
+	gap := joblib.TraceContextGap(traceparent)
+	if gap == "" {
+		return
+	}
+	logger(ctx).Warn("trace context propagation gap", "stage", stage, "job_id", messageID, "gap", gap, "traceparent", traceparent)
+	if traceContextMissing != nil {
+		traceContextMissing.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("stage", stage),
+			attribute.String("reason", gap),
+		))
+	}
+}
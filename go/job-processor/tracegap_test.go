@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// payloadWithTraceContext is validEnrichedPayload carrying traceContext
+func payloadWithTraceContext(traceContext string) string {
+	return fmt.Sprintf(`{
+		"originalmessage": {"job_type": "report_generation", "message": {"report_name": "Sales Report", "filters": "region=US"}},
+		"id": "12345",
+		"timestamp": "2025-08-30T12:00:00Z",
+		"status": "NEW",
+		"trace_context": %q
+	}`, traceContext)
+}
+
+func TestTraceContextGaps(t *testing.T) {
+	tests := []struct {
+		name           string
+		debug          bool
+		traceContext   string
+		expectedReason string
+	}{
+		{name: "Missing", debug: true, traceContext: "", expectedReason: "missing"},
+		{name: "Malformed", debug: true, traceContext: "00-not-a-trace-01", expectedReason: "malformed"},
+		{name: "Valid", debug: true, traceContext: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{name: "Debug disabled", traceContext: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _, _ := withFakeClients(t)
+			reader := sdkmetric.NewManualReader()
+			previousCounter, previousDebug := traceContextMissing, traceGapDebug
+			traceContextMissing, _ = sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test").Int64Counter("trace_context_missing_total")
+			traceGapDebug = tt.debug
+			defer func() { traceContextMissing, traceGapDebug = previousCounter, previousDebug }()
+
+			p.processMessage(context.Background(), eventsMessage(payloadWithTraceContext(tt.traceContext)))
+
+			var metrics metricdata.ResourceMetrics
+			if err := reader.Collect(context.Background(), &metrics); err != nil {
+				t.Fatalf("failed to collect metrics: %v", err)
+			}
+			counted := map[string]int64{}
+			for _, scope := range metrics.ScopeMetrics {
+				for _, m := range scope.Metrics {
+					for _, point := range m.Data.(metricdata.Sum[int64]).DataPoints {
+						stage, _ := point.Attributes.Value(attribute.Key("stage"))
+						reason, _ := point.Attributes.Value(attribute.Key("reason"))
+						counted[stage.AsString()+"/"+reason.AsString()] += point.Value
+					}
+				}
+			}
+
+			if tt.expectedReason == "" {
+				if len(counted) != 0 {
+					t.Errorf("expected no gaps counted, got %v", counted)
+				}
+				return
+			}
+			if key := stageReceive + "/" + tt.expectedReason; len(counted) != 1 || counted[key] != 1 {
+				t.Errorf("expected one %s gap, got %v", key, counted)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traceLinkOnly starts ExecuteJob as the root of its own trace, joined to
+// the ingester's span only by a link, instead of continuing the ingester's
+// trace as its child.
+var traceLinkOnly bool
+
+// ingesterSpanOptions link the ExecuteJob span to the ingester span that
+// queued the job, the span context propagated in its traceparent. No link is
+// added when the job arrived without a valid trace context.
+func ingesterSpanOptions(ingester trace.SpanContext) []trace.SpanStartOption {
+	if !ingester.IsValid() {
+		return nil
+	}
+	options := []trace.SpanStartOption{trace.WithLinks(trace.Link{SpanContext: ingester})}
+	if traceLinkOnly {
+		options = append(options, trace.WithNewRoot())
+	}
+	return options
+}
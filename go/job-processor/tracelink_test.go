@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestExecuteSpanLinkedToIngester(t *testing.T) {
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	tests := []struct {
+		name         string
+		traceparent  string
+		linkOnly     bool
+		expectLink   bool
+		expectParent bool
+	}{
+		{name: "Linked and continued", traceparent: traceparent, expectLink: true, expectParent: true},
+		{name: "Linked only", traceparent: traceparent, linkOnly: true, expectLink: true},
+		{name: "No trace context", traceparent: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _, _ := withFakeClients(t)
+			previous := traceLinkOnly
+			traceLinkOnly = tt.linkOnly
+			defer func() { traceLinkOnly = previous }()
+			recorder := tracetest.NewSpanRecorder()
+			previousTracer := tracer
+			tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+			defer func() { tracer = previousTracer }()
+
+			p.processMessage(context.Background(), eventsMessage(payloadWithTraceContext(tt.traceparent)))
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 span, got %d", len(spans))
+			}
+			span := spans[0]
+			links := span.Links()
+			linked := len(links) == 1 && links[0].SpanContext.TraceID().String() == "4bf92f3577b34da6a3ce929d0e0e4736" && links[0].SpanContext.SpanID().String() == "00f067aa0ba902b7"
+			if linked != tt.expectLink || (!tt.expectLink && len(links) != 0) {
+				t.Errorf("expected a link to the ingester span %v, got %+v", tt.expectLink, links)
+			}
+			if continued := span.Parent().IsValid(); continued != tt.expectParent {
+				t.Errorf("expected the ingester span as parent %v, got %v", tt.expectParent, continued)
+			}
+
+			attributes := map[string]bool{}
+			for _, attr := range span.Attributes() {
+				attributes[string(attr.Key)] = true
+			}
+			for _, key := range []string{"job.type", "message.id", "sqs.message.id"} {
+				if !attributes[key] {
+					t.Errorf("expected the %s attribute to be kept", key)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package job
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SQSSender is the subset of the SQS client used to queue messages
+type SQSSender interface {
+	SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
+}
+
+// SNSPublisher is the subset of the SNS client used to publish messages
+type SNSPublisher interface {
+	Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error)
+}
+
+// EventPutter is the subset of the EventBridge client used to put events
+type EventPutter interface {
+	PutEvents(ctx context.Context, params *eventbridge.PutEventsInput, optFns ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error)
+}
+
+// TracedSQS gives each SQS call its own client span.
+type TracedSQS struct {
+	SQSSender
+	Tracer trace.Tracer
+}
+
+func (c TracedSQS) SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	ctx, span := startAWSSpan(ctx, c.Tracer, "SQS", "SendMessage", aws.ToString(params.QueueUrl))
+	defer span.End()
+	output, err := c.SQSSender.SendMessage(ctx, params, optFns...)
+	if err == nil {
+		span.SetAttributes(attribute.String("messaging.message.id", aws.ToString(output.MessageId)))
+	}
+	endAWSSpan(span, err)
+	return output, err
+}
+
+// TracedSNS gives each SNS call its own client span.
+type TracedSNS struct {
+	SNSPublisher
+	Tracer trace.Tracer
+}
+
+func (c TracedSNS) Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error) {
+	ctx, span := startAWSSpan(ctx, c.Tracer, "SNS", "Publish", aws.ToString(params.TopicArn))
+	defer span.End()
+	output, err := c.SNSPublisher.Publish(ctx, params, optFns...)
+	if err == nil {
+		span.SetAttributes(attribute.String("messaging.message.id", aws.ToString(output.MessageId)))
+	}
+	endAWSSpan(span, err)
+	return output, err
+}
+
+// TracedEventBridge gives each EventBridge call its own client span, failing
+// it when any entry was rejected.
+type TracedEventBridge struct {
+	EventPutter
+	Tracer trace.Tracer
+}
+
+func (c TracedEventBridge) PutEvents(ctx context.Context, params *eventbridge.PutEventsInput, optFns ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error) {
+	var destination string
+	if len(params.Entries) > 0 {
+		destination = aws.ToString(params.Entries[0].EventBusName)
+	}
+	ctx, span := startAWSSpan(ctx, c.Tracer, "EventBridge", "PutEvents", destination)
+	defer span.End()
+	span.SetAttributes(attribute.Int("messaging.batch.message_count", len(params.Entries)))
+	output, err := c.EventPutter.PutEvents(ctx, params, optFns...)
+	if err == nil && output.FailedEntryCount > 0 {
+		span.SetStatus(codes.Error, fmt.Sprintf("%d of %d entries failed", output.FailedEntryCount, len(params.Entries)))
+	}
+	endAWSSpan(span, err)
+	return output, err
+}
+
+// startAWSSpan starts a producer span for an AWS API call to destination,
+// tagged with the tenant of the job it was made for.
+func startAWSSpan(ctx context.Context, tracer trace.Tracer, service, method, destination string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, service+"."+method,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "aws-api"),
+			attribute.String("rpc.service", service),
+			attribute.String("rpc.method", method),
+			attribute.String("messaging.destination.name", destination),
+		),
+		trace.WithAttributes(TenantAttributes(ctx)...))
+}
+
+// endAWSSpan records the outcome of an AWS API call on its span.
+func endAWSSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
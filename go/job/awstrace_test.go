@@ -0,0 +1,117 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fakeAWS records the calls it receives, failing them with err when set
+type fakeAWS struct {
+	calls        int
+	err          error
+	failedEvents int32
+}
+
+func (f *fakeAWS) SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &sqs.SendMessageOutput{MessageId: aws.String("message-1")}, nil
+}
+
+func (f *fakeAWS) Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &sns.PublishOutput{MessageId: aws.String("message-2")}, nil
+}
+
+func (f *fakeAWS) PutEvents(ctx context.Context, params *eventbridge.PutEventsInput, optFns ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &eventbridge.PutEventsOutput{FailedEntryCount: f.failedEvents}, nil
+}
+
+func TestTracedClientsCreateChildSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	client, failing, rejecting := &fakeAWS{}, &fakeAWS{err: errors.New("throttled")}, &fakeAWS{failedEvents: 1}
+	events := &eventbridge.PutEventsInput{Entries: []types.PutEventsRequestEntry{{EventBusName: aws.String("jobs-bus")}}}
+
+	ctx, parent := tracer.Start(ContextWithTenant(context.Background(), "acme"), "ProcessMessage")
+	if _, err := (TracedSQS{client, tracer}).SendMessage(ctx, &sqs.SendMessageInput{QueueUrl: aws.String("jobs-todo")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := (TracedSNS{client, tracer}).Publish(ctx, &sns.PublishInput{TopicArn: aws.String("job-end-state")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := (TracedSNS{failing, tracer}).Publish(ctx, &sns.PublishInput{TopicArn: aws.String("job-end-state")}); err == nil {
+		t.Fatalf("expected the client error to be returned")
+	}
+	if _, err := (TracedEventBridge{client, tracer}).PutEvents(ctx, events); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := (TracedEventBridge{rejecting, tracer}).PutEvents(ctx, events); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 6 {
+		t.Fatalf("expected 6 spans, got %d", len(spans))
+	}
+	expected := []struct {
+		name        string
+		destination string
+		status      codes.Code
+	}{
+		{"SQS.SendMessage", "jobs-todo", codes.Unset},
+		{"SNS.Publish", "job-end-state", codes.Unset},
+		{"SNS.Publish", "job-end-state", codes.Error},
+		{"EventBridge.PutEvents", "jobs-bus", codes.Unset},
+		{"EventBridge.PutEvents", "jobs-bus", codes.Error},
+	}
+	for i, tt := range expected {
+		span := spans[i]
+		if span.Name() != tt.name {
+			t.Errorf("span %d: expected name %s, got %s", i, tt.name, span.Name())
+		}
+		if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("span %d: expected a child of ProcessMessage", i)
+		}
+		if span.SpanKind() != trace.SpanKindProducer {
+			t.Errorf("span %d: expected a producer span, got %s", i, span.SpanKind())
+		}
+		attributes := map[string]string{}
+		for _, kv := range span.Attributes() {
+			attributes[string(kv.Key)] = kv.Value.Emit()
+		}
+		if attributes["messaging.destination.name"] != tt.destination {
+			t.Errorf("span %d: expected destination %s, got %s", i, tt.destination, attributes["messaging.destination.name"])
+		}
+		if attributes["tenant.id"] != "acme" {
+			t.Errorf("span %d: expected tenant acme, got %q", i, attributes["tenant.id"])
+		}
+		if span.Status().Code != tt.status {
+			t.Errorf("span %d: expected status %s, got %s", i, tt.status, span.Status().Code)
+		}
+	}
+	if client.calls != 3 || failing.calls != 1 || rejecting.calls != 1 {
+		t.Errorf("expected the calls to reach the wrapped clients")
+	}
+}
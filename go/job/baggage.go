@@ -0,0 +1,75 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// TenantBaggageKey is the baggage member carrying the tenant a job belongs to.
+const TenantBaggageKey = "tenant_id"
+
+// baggagePropagator is the W3C propagator that reads and writes baggage.
+var baggagePropagator = propagation.Baggage{}
+
+// MessageTenant returns the tenant_id of a job message, or "" when it has
+// none or can't be parsed.
+func MessageTenant(message []byte) string {
+	var jobMessage JobMessage
+	if err := json.Unmarshal(message, &jobMessage); err != nil {
+		return ""
+	}
+	return jobMessage.TenantID
+}
+
+// ContextWithTenant returns ctx with tenant added to its baggage, or ctx
+// unchanged when tenant is empty.
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	if tenant == "" {
+		return ctx
+	}
+	member, err := baggage.NewMemberRaw(TenantBaggageKey, tenant)
+	if err != nil {
+		return ctx
+	}
+	bag, err := baggage.FromContext(ctx).SetMember(member)
+	if err != nil {
+		return ctx
+	}
+	return baggage.ContextWithBaggage(ctx, bag)
+}
+
+// TenantFromContext returns the tenant in ctx's baggage, or "" when it has none.
+func TenantFromContext(ctx context.Context) string {
+	return baggage.FromContext(ctx).Member(TenantBaggageKey).Value()
+}
+
+// TenantAttributes returns the tenant.id span attribute for the tenant in
+// ctx's baggage. It returns nothing for jobs without a tenant.
+func TenantAttributes(ctx context.Context) []attribute.KeyValue {
+	tenant := TenantFromContext(ctx)
+	if tenant == "" {
+		return nil
+	}
+	return []attribute.KeyValue{attribute.String("tenant.id", tenant)}
+}
+
+// InjectBaggage formats ctx's baggage as a W3C baggage header, or "" when
+// it has none.
+func InjectBaggage(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	baggagePropagator.Inject(ctx, carrier)
+	return carrier.Get("baggage")
+}
+
+// ExtractBaggage returns ctx carrying the baggage in header. Empty or
+// malformed headers leave ctx without baggage.
+func ExtractBaggage(ctx context.Context, header string) context.Context {
+	if header == "" {
+		return ctx
+	}
+	return baggagePropagator.Extract(ctx, propagation.MapCarrier{"baggage": header})
+}
@@ -0,0 +1,67 @@
+package job
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMessageTenant(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Tenant",
+			input:    `{"job_type":"data_cleanup","message":{"target_table":"users","retention":30},"tenant_id":"acme"}`,
+			expected: "acme",
+		},
+		{
+			name:  "No tenant",
+			input: `{"job_type":"data_cleanup","message":{"target_table":"users","retention":30}}`,
+		},
+		{
+			name:  "Unparseable message",
+			input: `not json`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tenant := MessageTenant([]byte(tt.input)); tenant != tt.expected {
+				t.Errorf("expected tenant %q, got %q", tt.expected, tenant)
+			}
+		})
+	}
+}
+
+func TestTenantBaggageRoundTrip(t *testing.T) {
+	for _, tenant := range []string{"acme", "acme corp/eu=1"} {
+		t.Run(tenant, func(t *testing.T) {
+			header := InjectBaggage(ContextWithTenant(context.Background(), tenant))
+			if header == "" {
+				t.Fatal("expected a baggage header")
+			}
+			ctx := ExtractBaggage(context.Background(), header)
+			if got := TenantFromContext(ctx); got != tenant {
+				t.Errorf("expected tenant %q from %q, got %q", tenant, header, got)
+			}
+			attrs := TenantAttributes(ctx)
+			if len(attrs) != 1 || attrs[0].Key != "tenant.id" || attrs[0].Value.AsString() != tenant {
+				t.Errorf("expected tenant.id=%s, got %v", tenant, attrs)
+			}
+		})
+	}
+}
+
+func TestNoTenantBaggage(t *testing.T) {
+	ctx := ContextWithTenant(context.Background(), "")
+	if header := InjectBaggage(ctx); header != "" {
+		t.Errorf("expected no baggage header, got %q", header)
+	}
+	for _, header := range []string{"", "not;;valid=baggage=="} {
+		if attrs := TenantAttributes(ExtractBaggage(context.Background(), header)); len(attrs) != 0 {
+			t.Errorf("expected no tenant from %q, got %v", header, attrs)
+		}
+	}
+}
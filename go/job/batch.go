@@ -0,0 +1,143 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// BatchConcurrency is how many children BatchJob.Execute runs at once, set
+// from BATCH_CONCURRENCY by the services. 1, the default, runs them
+// sequentially in order and stops at the first failure, for batches whose
+// children depend on each other.
+var BatchConcurrency = 1
+
+// MaxBatchChildren is the most children a batch job may have, so a huge
+// batch can't exhaust the processor. 0, the default, allows any number.
+var MaxBatchChildren = 0
+
+// BatchJob represents the payload for a "batch_job", a set of child jobs
+// submitted together.
+type BatchJob struct {
+	Children []JobMessage `json:"children"`
+}
+
+func (j BatchJob) Validate() error {
+	if len(j.Children) == 0 {
+		return errors.New("children are required")
+	}
+	if MaxBatchChildren > 0 && len(j.Children) > MaxBatchChildren {
+		return fmt.Errorf("batch has %d children, more than the maximum of %d", len(j.Children), MaxBatchChildren)
+	}
+	var errs []error
+	for i, child := range j.Children {
+		if resolveJobType(child.JobType) == Batch {
+			errs = append(errs, fmt.Errorf("child %d: nested batch jobs are not supported", i))
+			continue
+		}
+		if _, _, _, err := ParseJob([]byte(child.String())); err != nil {
+			errs = append(errs, fmt.Errorf("child %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Execute runs the children, sequentially or BatchConcurrency at a time.
+// The children's results aren't collected, so the batch's result is empty.
+func (j BatchJob) Execute(ctx context.Context) (JobResult, error) {
+	log.Printf("Executing batch of %d jobs\n", len(j.Children))
+	if BatchConcurrency <= 1 {
+		return JobResult{}, j.executeSequentially(ctx)
+	}
+	return JobResult{}, j.executeConcurrently(ctx, BatchConcurrency)
+}
+
+// executeSequentially runs each child in order, stopping at the first failure.
+func (j BatchJob) executeSequentially(ctx context.Context) error {
+	for i := range j.Children {
+		if err := j.executeChild(ctx, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// executeConcurrently runs the children on a pool of workers, returning every
+// child's failure. Children not yet started when ctx is cancelled are skipped.
+func (j BatchJob) executeConcurrently(ctx context.Context, workers int) error {
+	errs := make([]error, len(j.Children))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(j.Children); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				errs[i] = j.executeChild(ctx, i)
+			}
+		}()
+	}
+
+	var cancelled error
+feed:
+	for i := range j.Children {
+		select {
+		case <-ctx.Done():
+			cancelled = ctx.Err()
+			break feed
+		case indexes <- i:
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	return errors.Join(append(errs, cancelled)...)
+}
+
+// executeChild parses and runs the child at index i.
+func (j BatchJob) executeChild(ctx context.Context, i int) error {
+	childJob, _, _, err := ParseJob([]byte(j.Children[i].String()))
+	if err != nil {
+		return fmt.Errorf("child %d: %w", i, err)
+	}
+	if _, err := childJob.Execute(ctx); err != nil {
+		return fmt.Errorf("child %d: %w", i, err)
+	}
+	return nil
+}
+
+// ChildID derives the ID of the child at index within a batch.
+func ChildID(parentID string, index int) string {
+	return fmt.Sprintf("%s-%d", parentID, index)
+}
+
+// SplitBatch expands a batch job into one EnrichedPayload per child so each
+// can be queued, processed and tracked independently. Children inherit the
+// parent's timestamp, trace context, baggage and correlation ID and reference
+// it through ParentID.
+func SplitBatch(parent EnrichedPayload, batch BatchJob) ([]EnrichedPayload, error) {
+	children := make([]EnrichedPayload, 0, len(batch.Children))
+	for i, child := range batch.Children {
+		originalMessage, err := json.Marshal(child)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal child %d: %w", i, err)
+		}
+		children = append(children, EnrichedPayload{
+			OriginalMessage: originalMessage,
+			ID:              ChildID(parent.ID, i),
+			Timestamp:       parent.Timestamp,
+			Status:          StatusNew,
+			TraceContext:    parent.TraceContext,
+			TraceState:      parent.TraceState,
+			BaggageContext:  parent.BaggageContext,
+			ParentID:        parent.ID,
+			CorrelationID:   parent.CorrelationID,
+			EventBridgeID:   parent.EventBridgeID,
+			SchemaVersion:   CurrentSchemaVersion,
+		})
+	}
+	return children, nil
+}
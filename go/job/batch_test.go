@@ -0,0 +1,336 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseBatchJob(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		expectError   bool
+		expectedCount int
+	}{
+		{
+			name: "Valid batch",
+			input: `{
+				"job_type": "batch_job",
+				"message": {
+					"children": [
+						{"job_type": "report_generation", "message": {"report_name": "Sales Report", "filters": "region=US"}},
+						{"job_type": "data_cleanup", "message": {"target_table": "users", "retention": 30}}
+					]
+				}
+			}`,
+			expectedCount: 2,
+		},
+		{
+			name:        "Empty batch",
+			input:       `{"job_type": "batch_job", "message": {"children": []}}`,
+			expectError: true,
+		},
+		{
+			name: "Invalid child",
+			input: `{
+				"job_type": "batch_job",
+				"message": {
+					"children": [
+						{"job_type": "data_cleanup", "message": {"retention": 30}}
+					]
+				}
+			}`,
+			expectError: true,
+		},
+		{
+			name: "Nested batch",
+			input: `{
+				"job_type": "batch_job",
+				"message": {
+					"children": [
+						{"job_type": "batch_job", "message": {"children": []}}
+					]
+				}
+			}`,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job, _, _, err := ParseJob([]byte(tt.input))
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			batch, ok := job.(BatchJob)
+			if !ok {
+				t.Fatalf("expected BatchJob, got %T", job)
+			}
+			if len(batch.Children) != tt.expectedCount {
+				t.Errorf("expected %d children, got %d", tt.expectedCount, len(batch.Children))
+			}
+		})
+	}
+}
+
+func TestSplitBatch(t *testing.T) {
+	input := `{
+		"job_type": "batch_job",
+		"message": {
+			"children": [
+				{"job_type": "report_generation", "message": {"report_name": "Sales Report", "filters": "region=US"}},
+				{"job_type": "user_onboarding", "message": {"user_id": "user-001", "user_name": "John Doe"}}
+			]
+		}
+	}`
+	job, _, _, err := ParseJob([]byte(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	parent := EnrichedPayload{
+		ID:            "12345",
+		Timestamp:     "2025-08-30T12:00:00Z",
+		Status:        StatusNew,
+		TraceContext:  "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		EventBridgeID: "eb-1",
+	}
+	children, err := SplitBatch(parent, job.(BatchJob))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedTypes := []JobType{ReportGeneration, UserOnboarding}
+	if len(children) != len(expectedTypes) {
+		t.Fatalf("expected %d children, got %d", len(expectedTypes), len(children))
+	}
+	for i, child := range children {
+		if child.ParentID != parent.ID {
+			t.Errorf("child %d: expected ParentID %s, got %s", i, parent.ID, child.ParentID)
+		}
+		if child.ID != ChildID(parent.ID, i) {
+			t.Errorf("child %d: expected ID %s, got %s", i, ChildID(parent.ID, i), child.ID)
+		}
+		if child.Status != StatusNew {
+			t.Errorf("child %d: expected Status %s, got %s", i, StatusNew, child.Status)
+		}
+		if child.TraceContext != parent.TraceContext {
+			t.Errorf("child %d: expected TraceContext %s, got %s", i, parent.TraceContext, child.TraceContext)
+		}
+		if child.EventBridgeID != parent.EventBridgeID {
+			t.Errorf("child %d: expected EventBridgeID %s, got %s", i, parent.EventBridgeID, child.EventBridgeID)
+		}
+
+		_, _, jobType, err := ParseJob(child.OriginalMessage)
+		if err != nil {
+			t.Errorf("child %d: failed to parse original message: %v", i, err)
+			continue
+		}
+		if JobType(*jobType) != expectedTypes[i] {
+			t.Errorf("child %d: expected job type %s, got %s", i, expectedTypes[i], *jobType)
+		}
+	}
+}
+
+// cleanupBatch builds a batch of n data cleanup children targeting table0..n-1
+func cleanupBatch(n int) BatchJob {
+	var batch BatchJob
+	for i := 0; i < n; i++ {
+		batch.Children = append(batch.Children, JobMessage{
+			JobType: string(DataCleanup),
+			Message: []byte(fmt.Sprintf(`{"target_table":"table%d","retention":30}`, i)),
+		})
+	}
+	return batch
+}
+
+// stubCleanup stands in for a data cleanup child, executing through run
+type stubCleanup struct {
+	TargetTable string `json:"target_table"`
+	Retention   int    `json:"retention"`
+	run         func(ctx context.Context, job stubCleanup) error
+}
+
+func (stubCleanup) Validate() error { return nil }
+func (j stubCleanup) Execute(ctx context.Context) (JobResult, error) {
+	return JobResult{}, j.run(ctx, j)
+}
+func (stubCleanup) Name() JobType { return DataCleanup }
+
+// withStubCleanups executes data cleanup children through run for the
+// duration of a test
+func withStubCleanups(t *testing.T, run func(ctx context.Context, job stubCleanup) error) {
+	t.Helper()
+	t.Cleanup(SwapJobType(string(DataCleanup), func() Job { return stubCleanup{run: run} }))
+}
+
+func TestBatchExecuteConcurrently(t *testing.T) {
+	previousConcurrency := BatchConcurrency
+	defer func() { BatchConcurrency = previousConcurrency }()
+
+	tests := []struct {
+		concurrency int
+		expectedMax int32
+	}{
+		{concurrency: 1, expectedMax: 1},
+		{concurrency: 3, expectedMax: 3},
+		{concurrency: 10, expectedMax: 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("concurrency %d", tt.concurrency), func(t *testing.T) {
+			var running, maxRunning, completed int32
+			var mu sync.Mutex
+			var order []string
+			var fill sync.Once
+			release := make(chan struct{})
+			BatchConcurrency = tt.concurrency
+			withStubCleanups(t, func(ctx context.Context, job stubCleanup) error {
+				n := atomic.AddInt32(&running, 1)
+				for {
+					m := atomic.LoadInt32(&maxRunning)
+					if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+						break
+					}
+				}
+				// Hold each child until the pool is full so the peak is observed
+				if n == tt.expectedMax {
+					fill.Do(func() { close(release) })
+				}
+				<-release
+				mu.Lock()
+				order = append(order, job.TargetTable)
+				mu.Unlock()
+				atomic.AddInt32(&running, -1)
+				atomic.AddInt32(&completed, 1)
+				return nil
+			})
+
+			if _, err := cleanupBatch(6).Execute(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if completed != 6 {
+				t.Errorf("expected 6 children to complete, got %d", completed)
+			}
+			if maxRunning != tt.expectedMax {
+				t.Errorf("expected at most %d children running at once, got %d", tt.expectedMax, maxRunning)
+			}
+			if tt.concurrency == 1 && strings.Join(order, ",") != "table0,table1,table2,table3,table4,table5" {
+				t.Errorf("expected children to run in order, got %v", order)
+			}
+		})
+	}
+}
+
+func TestBatchExecuteAggregatesErrors(t *testing.T) {
+	previousConcurrency := BatchConcurrency
+	defer func() { BatchConcurrency = previousConcurrency }()
+
+	failing := map[string]bool{"table1": true, "table3": true}
+	var calls int32
+	withStubCleanups(t, func(ctx context.Context, job stubCleanup) error {
+		atomic.AddInt32(&calls, 1)
+		if table := job.TargetTable; failing[table] {
+			return fmt.Errorf("cleanup of %s failed", table)
+		}
+		return nil
+	})
+
+	BatchConcurrency = 2
+	_, err := cleanupBatch(4).Execute(context.Background())
+	if err == nil {
+		t.Fatalf("expected an error but got none")
+	}
+	for _, expected := range []string{"child 1: cleanup of table1 failed", "child 3: cleanup of table3 failed"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error to contain %q, got %v", expected, err)
+		}
+	}
+	if calls != 4 {
+		t.Errorf("expected every child to run concurrently, got %d", calls)
+	}
+
+	// Sequential mode stops at the first failure
+	calls = 0
+	BatchConcurrency = 1
+	_, err = cleanupBatch(4).Execute(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "child 1") || strings.Contains(err.Error(), "child 3") {
+		t.Errorf("expected only the first failure, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected execution to stop after 2 children, got %d", calls)
+	}
+}
+
+func TestBatchExecuteCancelled(t *testing.T) {
+	previousConcurrency := BatchConcurrency
+	defer func() { BatchConcurrency = previousConcurrency }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls int32
+	BatchConcurrency = 2
+	withStubCleanups(t, func(ctx context.Context, job stubCleanup) error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			cancel()
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return nil
+		}
+	})
+
+	_, err := cleanupBatch(10).Execute(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a cancellation error, got %v", err)
+	}
+	if calls >= 10 {
+		t.Errorf("expected children after cancellation to be skipped, got %d calls", calls)
+	}
+}
+
+func TestBatchMaxChildren(t *testing.T) {
+	previous := MaxBatchChildren
+	defer func() { MaxBatchChildren = previous }()
+
+	tests := []struct {
+		name        string
+		max         int
+		children    int
+		expectError bool
+	}{
+		{name: "At the limit", max: 3, children: 3},
+		{name: "Just over the limit", max: 3, children: 4, expectError: true},
+		{name: "Unlimited", max: 0, children: 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			MaxBatchChildren = tt.max
+			err := cleanupBatch(tt.children).Validate()
+			if tt.expectError {
+				if err == nil || !strings.Contains(err.Error(), "more than the maximum of 3") {
+					t.Errorf("expected a maximum children error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
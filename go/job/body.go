@@ -0,0 +1,38 @@
+package job
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+// gzipMagic opens every gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// DecodeBody returns the JSON carried by a message body. Producers may gzip
+// large payloads and base64 encode them to fit in a message, these are
+// decompressed. Any other body, including one that isn't valid JSON, is
+// returned unchanged for the JSON parser to accept or reject.
+func DecodeBody(body []byte) ([]byte, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || trimmed[0] == '{' || trimmed[0] == '[' {
+		return body, nil
+	}
+	compressed, err := base64.StdEncoding.DecodeString(string(trimmed))
+	if err != nil || !bytes.HasPrefix(compressed, gzipMagic) {
+		return body, nil
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress message body: %w", err)
+	}
+	defer reader.Close()
+	decompressed, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress message body: %w", err)
+	}
+	return decompressed, nil
+}
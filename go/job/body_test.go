@@ -0,0 +1,62 @@
+package job
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"testing"
+)
+
+// gzipBase64 compresses body the way producers do for large payloads
+func gzipBase64(t *testing.T, body string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write([]byte(body)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestDecodeBody(t *testing.T) {
+	const job = `{"job_type":"report_generation","message":{"report_name":"Sales Report","filters":"region=US"}}`
+	compressed := gzipBase64(t, job)
+
+	tests := []struct {
+		name        string
+		body        string
+		expected    string
+		expectError bool
+	}{
+		{name: "Plain JSON", body: job, expected: job},
+		{name: "Plain JSON array", body: `[` + job + `]`, expected: `[` + job + `]`},
+		{name: "Gzipped and base64 encoded", body: compressed, expected: job},
+		{name: "Surrounding whitespace", body: "\n" + compressed + "\n", expected: job},
+		{name: "Not JSON or base64", body: "not json", expected: "not json"},
+		{name: "Base64 but not gzip", body: base64.StdEncoding.EncodeToString([]byte(job)), expected: base64.StdEncoding.EncodeToString([]byte(job))},
+		{name: "Empty", body: "", expected: ""},
+		{name: "Truncated gzip stream", body: compressed[:len(compressed)/2/4*4], expectError: true},
+		{name: "Corrupt gzip header", body: base64.StdEncoding.EncodeToString([]byte{0x1f, 0x8b, 0x00, 0x00}), expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, err := DecodeBody([]byte(tt.body))
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected an error but got %s", decoded)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(decoded) != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, decoded)
+			}
+		})
+	}
+}
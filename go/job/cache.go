@@ -0,0 +1,114 @@
+package job
+
+import (
+	"container/list"
+	"context"
+	"log"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var (
+	cacheSizesMu sync.Mutex
+	cacheSizes   = map[string]func() int{} // cache name -> current entry count
+)
+
+func init() {
+	_, err := otel.Meter("jobs").Int64ObservableGauge("cache_entries",
+		metric.WithDescription("Entries currently held in an in-memory cache"),
+		metric.WithInt64Callback(observeCacheSizes),
+	)
+	if err != nil {
+		log.Printf("failed to create cache size gauge: %v", err)
+	}
+}
+
+// observeCacheSizes reports the entry count of every registered cache.
+func observeCacheSizes(ctx context.Context, o metric.Int64Observer) error {
+	cacheSizesMu.Lock()
+	defer cacheSizesMu.Unlock()
+	for name, size := range cacheSizes {
+		o.Observe(int64(size()), metric.WithAttributes(attribute.String("cache.name", name)))
+	}
+	return nil
+}
+
+// registerCacheSize reports size on the cache_entries gauge under name.
+func registerCacheSize(name string, size func() int) {
+	cacheSizesMu.Lock()
+	defer cacheSizesMu.Unlock()
+	cacheSizes[name] = size
+}
+
+// LRUCache is an in-memory cache capped at a number of entries, evicting the
+// least recently used entry once full. Its size is reported on the
+// cache_entries gauge. It is safe for concurrent use.
+type LRUCache[K comparable, V any] struct {
+	maxEntries int // zero or less leaves the cache unbounded
+
+	mu      sync.Mutex
+	order   *list.List // most recently used at the front
+	entries map[K]*list.Element
+}
+
+type cacheEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// NewLRUCache creates a cache holding at most maxEntries entries, reporting
+// its size on the cache_entries gauge tagged with name.
+func NewLRUCache[K comparable, V any](name string, maxEntries int) *LRUCache[K, V] {
+	c := &LRUCache[K, V]{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    map[K]*list.Element{},
+	}
+
+	registerCacheSize(name, c.Len)
+	return c
+}
+
+// Get returns the value cached for key, marking it as recently used.
+func (c *LRUCache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*cacheEntry[K, V]).value, true
+}
+
+// Add caches value for key, evicting the least recently used entry if the
+// cache is full.
+func (c *LRUCache[K, V]) Add(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*cacheEntry[K, V]).value = value
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry[K, V]{key: key, value: value})
+	if c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry[K, V]).key)
+	}
+}
+
+// Len returns the number of cached entries.
+func (c *LRUCache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
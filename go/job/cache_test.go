@@ -0,0 +1,86 @@
+package job
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestLRUCacheEviction(t *testing.T) {
+	cache := NewLRUCache[string, string]("test", 2)
+
+	cache.Add("a", "1")
+	cache.Add("b", "2")
+	cache.Get("a") // b is now least recently used
+	cache.Add("c", "3")
+
+	if cache.Len() != 2 {
+		t.Errorf("expected 2 entries at the cap, got %d", cache.Len())
+	}
+	if _, ok := cache.Get("b"); ok {
+		t.Errorf("expected b to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := cache.Get(key); !ok {
+			t.Errorf("expected %s to be cached", key)
+		}
+	}
+
+	cache.Add("a", "updated")
+	if value, _ := cache.Get("a"); value != "updated" {
+		t.Errorf("expected a to be updated, got %s", value)
+	}
+	if cache.Len() != 2 {
+		t.Errorf("expected updating an entry not to grow the cache, got %d", cache.Len())
+	}
+}
+
+func TestLRUCacheUnbounded(t *testing.T) {
+	cache := NewLRUCache[int, int]("unbounded", 0)
+	for i := 0; i < 100; i++ {
+		cache.Add(i, i)
+	}
+	if cache.Len() != 100 {
+		t.Errorf("expected 100 entries, got %d", cache.Len())
+	}
+}
+
+func TestLRUCacheSizeGauge(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+	if _, err := meter.Int64ObservableGauge("cache_entries", metric.WithInt64Callback(observeCacheSizes)); err != nil {
+		t.Fatalf("failed to create cache size gauge: %v", err)
+	}
+
+	cache := NewLRUCache[string, int]("statuses", 3)
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		cache.Add(key, 0)
+	}
+
+	var metrics metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &metrics); err != nil {
+		t.Fatalf("failed to collect metrics: %v", err)
+	}
+
+	for _, scope := range metrics.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if m.Name != "cache_entries" {
+				continue
+			}
+			for _, point := range m.Data.(metricdata.Gauge[int64]).DataPoints {
+				if name, _ := point.Attributes.Value(attribute.Key("cache.name")); name.AsString() != "statuses" {
+					continue
+				}
+				if point.Value != 3 {
+					t.Errorf("expected gauge of 3 entries, got %d", point.Value)
+				}
+				return
+			}
+		}
+	}
+	t.Errorf("expected a cache_entries data point for the statuses cache, got %+v", metrics)
+}
@@ -0,0 +1,35 @@
+package job
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrTimedOut is the cause of a job abandoned for running past its own
+// timeout. It wraps alongside context.DeadlineExceeded, but isn't Cancelled
+// as a retry would time out again.
+var ErrTimedOut = errors.New("job_timed_out")
+
+// ErrCancelRequested is the cause of a job stopped by a cancel_job. It wraps
+// alongside context.Canceled, but isn't Cancelled as the job was stopped on
+// purpose and shouldn't be retried.
+var ErrCancelRequested = errors.New("job_cancel_requested")
+
+type cancellerKey struct{}
+
+// ContextWithCanceller returns a context in which cancel_job jobs stop their
+// target with cancel, which reports whether the job with id was running and
+// has been cancelled.
+func ContextWithCanceller(ctx context.Context, cancel func(id string) bool) context.Context {
+	return context.WithValue(ctx, cancellerKey{}, cancel)
+}
+
+// Cancelled reports whether a job's Execute error means it was stopped by its
+// context being cancelled or timing out, rather than the job itself failing.
+// Cancelled jobs are safe to retry.
+func Cancelled(err error) bool {
+	if errors.Is(err, ErrTimedOut) || errors.Is(err, ErrCancelRequested) {
+		return false
+	}
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,131 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCancelled(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "Cancelled", err: context.Canceled, expected: true},
+		{name: "Deadline exceeded", err: context.DeadlineExceeded, expected: true},
+		{name: "Wrapped cancellation", err: fmt.Errorf("child 1: %w", context.Canceled), expected: true},
+		{name: "Genuine failure", err: errors.New("report service unavailable")},
+		{name: "Resource limit", err: fmt.Errorf("%w: cpu", ErrResourceLimitExceeded)},
+		{name: "Own timeout", err: fmt.Errorf("%w: %w", ErrTimedOut, context.DeadlineExceeded)},
+		{name: "Cancel requested", err: fmt.Errorf("%w: %w", ErrCancelRequested, context.Canceled)},
+		{name: "No error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := Cancelled(tt.err); actual != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestLongRunningJobDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := LongRunningJob{TaskName: "Data Migration", Timeout: 60}.Execute(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) || !Cancelled(err) {
+		t.Errorf("expected a deadline exceeded error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the job to stop at the deadline, ran for %s", elapsed)
+	}
+}
+
+func TestLongRunningJobTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		job         LongRunningJob
+		expectError error
+		minElapsed  time.Duration
+		maxElapsed  time.Duration
+	}{
+		{
+			name:       "Work finishes within the timeout",
+			job:        LongRunningJob{TaskName: "Data Migration", Timeout: 5, WorkDuration: 1},
+			minElapsed: time.Second,
+			maxElapsed: 2 * time.Second,
+		},
+		{
+			name:        "Work runs past the timeout",
+			job:         LongRunningJob{TaskName: "Data Migration", Timeout: 1, WorkDuration: 60},
+			expectError: ErrTimedOut,
+			minElapsed:  time.Second,
+			maxElapsed:  2 * time.Second,
+		},
+		{
+			name:       "Work fills an unset duration",
+			job:        LongRunningJob{TaskName: "Data Migration", Timeout: 1},
+			minElapsed: time.Second,
+			maxElapsed: 2 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			_, err := tt.job.Execute(context.Background())
+			elapsed := time.Since(start)
+
+			if !errors.Is(err, tt.expectError) {
+				t.Errorf("expected error %v, got %v", tt.expectError, err)
+			}
+			if tt.expectError != nil && (!errors.Is(err, context.DeadlineExceeded) || Cancelled(err)) {
+				t.Errorf("expected a deadline exceeded error that isn't retried, got %v", err)
+			}
+			if elapsed < tt.minElapsed || elapsed > tt.maxElapsed {
+				t.Errorf("expected the job to run for %s to %s, ran for %s", tt.minElapsed, tt.maxElapsed, elapsed)
+			}
+		})
+	}
+}
+
+func TestCancelJob(t *testing.T) {
+	running := map[string]bool{"67890": true}
+	cancel := func(id string) bool {
+		if !running[id] {
+			return false
+		}
+		delete(running, id)
+		return true
+	}
+
+	tests := []struct {
+		name            string
+		ctx             context.Context
+		targetID        string
+		expectCancelled bool
+	}{
+		{name: "Running target", ctx: ContextWithCanceller(context.Background(), cancel), targetID: "67890", expectCancelled: true},
+		{name: "Target already cancelled", ctx: ContextWithCanceller(context.Background(), cancel), targetID: "67890"},
+		{name: "Target not running", ctx: ContextWithCanceller(context.Background(), cancel), targetID: "12345"},
+		{name: "No canceller", ctx: context.Background(), targetID: "67890"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := CancelJob{TargetID: tt.targetID}.Execute(tt.ctx)
+			if err != nil {
+				t.Fatalf("expected a cancel to succeed either way, got %v", err)
+			}
+			if result.Output["target_id"] != tt.targetID || result.Output["cancelled"] != tt.expectCancelled {
+				t.Errorf("expected target %s cancelled %v, got %v", tt.targetID, tt.expectCancelled, result.Output)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// Defaults for the LocalStack demo, used when the environment doesn't say
+// otherwise.
+const (
+	defaultEndpointURL   = "http://localstack:4566"
+	defaultJobsTodoURL   = "http://localstack:4566/000000000000/jobs-todo"
+	defaultDeadLetterURL = "http://localstack:4566/000000000000/dead-letter-queue"
+	defaultSNSTopicArn   = "arn:aws:sns:us-east-1:000000000000:job-end-state-topic"
+	defaultRegion        = "us-east-1"
+)
+
+// ServiceConfig is where the ingester and processor find their queues, topic
+// and AWS endpoint.
+type ServiceConfig struct {
+	JobsTodoURL   string
+	DeadLetterURL string
+	SNSTopicArn   string
+	Region        string
+	EndpointURL   string // empty uses the SDK's default endpoint resolver, i.e. real AWS
+}
+
+// LoadServiceConfig reads the service config with lookup, normally
+// os.LookupEnv, from JOBS_TODO_QUEUE_URL, DEAD_LETTER_QUEUE_URL,
+// SNS_TOPIC_ARN, AWS_REGION and AWS_ENDPOINT_URL. Unset variables fall back
+// to the LocalStack demo. AWS_ENDPOINT_URL may be set empty to talk to real
+// AWS.
+func LoadServiceConfig(lookup func(string) (string, bool)) ServiceConfig {
+	setting := func(name, fallback string) string {
+		if value, ok := lookup(name); ok && value != "" {
+			return value
+		}
+		return fallback
+	}
+
+	cfg := ServiceConfig{
+		JobsTodoURL:   setting("JOBS_TODO_QUEUE_URL", defaultJobsTodoURL),
+		DeadLetterURL: setting("DEAD_LETTER_QUEUE_URL", defaultDeadLetterURL),
+		SNSTopicArn:   setting("SNS_TOPIC_ARN", defaultSNSTopicArn),
+		Region:        setting("AWS_REGION", defaultRegion),
+		EndpointURL:   defaultEndpointURL,
+	}
+	if endpoint, ok := lookup("AWS_ENDPOINT_URL"); ok {
+		cfg.EndpointURL = endpoint
+	}
+	return cfg
+}
+
+// LoadAWSConfig loads the AWS SDK configuration for the region and endpoint
+// LoadServiceConfig reads from the environment, LocalStack in us-east-1
+// unless AWS_REGION and AWS_ENDPOINT_URL say otherwise. It is shared by every
+// binary so they all reach AWS the same way.
+func LoadAWSConfig(ctx context.Context) (aws.Config, error) {
+	return LoadServiceConfig(os.LookupEnv).AWSConfig(ctx)
+}
+
+// AWSConfigLoader loads the AWS SDK configuration, e.g. LoadAWSConfig.
+type AWSConfigLoader func(ctx context.Context) (aws.Config, error)
+
+// LoadAWSConfigWithRetry loads the AWS SDK configuration with load, making up
+// to AWS_INIT_ATTEMPTS attempts (1 by default) and doubling the wait between
+// them from AWS_INIT_BACKOFF (1s by default). Both are read with lookup,
+// normally os.LookupEnv.
+func LoadAWSConfigWithRetry(ctx context.Context, lookup func(string) (string, bool), load AWSConfigLoader) (aws.Config, error) {
+	attempts, backoff := 1, time.Second
+	if value, ok := lookup("AWS_INIT_ATTEMPTS"); ok {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			log.Printf("ignoring invalid AWS_INIT_ATTEMPTS %q: %v", value, err)
+		} else {
+			attempts = parsed
+		}
+	}
+	if value, ok := lookup("AWS_INIT_BACKOFF"); ok {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			log.Printf("ignoring invalid AWS_INIT_BACKOFF %q: %v", value, err)
+		} else {
+			backoff = parsed
+		}
+	}
+	return retryAWSConfig(ctx, load, attempts, backoff)
+}
+
+// retryAWSConfig calls load up to attempts times, doubling the wait between
+// attempts from backoff, and returns the last error if every attempt fails.
+func retryAWSConfig(ctx context.Context, load AWSConfigLoader, attempts int, backoff time.Duration) (aws.Config, error) {
+	if attempts < 1 {
+		attempts = 1
+	}
+	var err error
+	for attempt := 1; ; attempt++ {
+		var cfg aws.Config
+		if cfg, err = load(ctx); err == nil {
+			return cfg, nil
+		}
+		if attempt >= attempts {
+			return aws.Config{}, fmt.Errorf("after %d attempts: %w", attempts, err)
+		}
+		log.Printf("failed to load AWS SDK config (attempt %d of %d), retrying in %s: %v", attempt, attempts, backoff, err)
+		select {
+		case <-ctx.Done():
+			return aws.Config{}, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// AWSConfig loads the AWS SDK configuration for c's region, with every
+// service client sent to c's endpoint when it has one.
+func (c ServiceConfig) AWSConfig(ctx context.Context) (aws.Config, error) {
+	options := []func(*config.LoadOptions) error{config.WithRegion(c.Region)}
+	if c.EndpointURL != "" {
+		options = append(options, config.WithBaseEndpoint(c.EndpointURL)) // e.g. LocalStack
+	}
+	return config.LoadDefaultConfig(ctx, options...)
+}
@@ -0,0 +1,147 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestLoadServiceConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected ServiceConfig
+	}{
+		{
+			name: "LocalStack defaults when unset",
+			env:  map[string]string{},
+			expected: ServiceConfig{
+				JobsTodoURL:   "http://localstack:4566/000000000000/jobs-todo",
+				DeadLetterURL: "http://localstack:4566/000000000000/dead-letter-queue",
+				SNSTopicArn:   "arn:aws:sns:us-east-1:000000000000:job-end-state-topic",
+				Region:        "us-east-1",
+				EndpointURL:   "http://localstack:4566",
+			},
+		},
+		{
+			name: "Real AWS",
+			env: map[string]string{
+				"JOBS_TODO_QUEUE_URL":   "https://sqs.eu-west-2.amazonaws.com/123456789012/jobs-todo",
+				"DEAD_LETTER_QUEUE_URL": "https://sqs.eu-west-2.amazonaws.com/123456789012/dead-letter-queue",
+				"SNS_TOPIC_ARN":         "arn:aws:sns:eu-west-2:123456789012:job-end-state-topic",
+				"AWS_REGION":            "eu-west-2",
+				"AWS_ENDPOINT_URL":      "",
+			},
+			expected: ServiceConfig{
+				JobsTodoURL:   "https://sqs.eu-west-2.amazonaws.com/123456789012/jobs-todo",
+				DeadLetterURL: "https://sqs.eu-west-2.amazonaws.com/123456789012/dead-letter-queue",
+				SNSTopicArn:   "arn:aws:sns:eu-west-2:123456789012:job-end-state-topic",
+				Region:        "eu-west-2",
+			},
+		},
+		{
+			name: "Empty values fall back",
+			env:  map[string]string{"JOBS_TODO_QUEUE_URL": "", "AWS_REGION": "", "AWS_ENDPOINT_URL": "http://localhost:4566"},
+			expected: ServiceConfig{
+				JobsTodoURL:   "http://localstack:4566/000000000000/jobs-todo",
+				DeadLetterURL: "http://localstack:4566/000000000000/dead-letter-queue",
+				SNSTopicArn:   "arn:aws:sns:us-east-1:000000000000:job-end-state-topic",
+				Region:        "us-east-1",
+				EndpointURL:   "http://localhost:4566",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookup := func(name string) (string, bool) {
+				value, ok := tt.env[name]
+				return value, ok
+			}
+			if actual := LoadServiceConfig(lookup); actual != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestLoadAWSConfig(t *testing.T) {
+	tests := []struct {
+		name           string
+		env            map[string]string
+		expectRegion   string
+		expectEndpoint string
+	}{
+		{name: "LocalStack", env: map[string]string{}, expectRegion: "us-east-1", expectEndpoint: "http://localstack:4566"},
+		{name: "LocalStack from the host", env: map[string]string{"AWS_ENDPOINT_URL": "http://localhost:4566"}, expectRegion: "us-east-1", expectEndpoint: "http://localhost:4566"},
+		{name: "Real AWS", env: map[string]string{"AWS_ENDPOINT_URL": "", "AWS_REGION": "eu-west-2"}, expectRegion: "eu-west-2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Unset, restored when the test ends
+			for _, name := range []string{"AWS_REGION", "AWS_ENDPOINT_URL"} {
+				t.Setenv(name, "")
+				os.Unsetenv(name)
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			cfg, err := LoadAWSConfig(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.Region != tt.expectRegion || aws.ToString(cfg.BaseEndpoint) != tt.expectEndpoint {
+				t.Errorf("expected region %q and endpoint %q, got %q and %q", tt.expectRegion, tt.expectEndpoint, cfg.Region, aws.ToString(cfg.BaseEndpoint))
+			}
+		})
+	}
+}
+
+func TestLoadAWSConfigWithRetry(t *testing.T) {
+	tests := []struct {
+		name          string
+		failures      int
+		env           map[string]string
+		expectError   bool
+		expectedCalls int
+	}{
+		{name: "Succeeds first time", failures: 0, expectedCalls: 1},
+		{name: "Fails without retries", failures: 1, expectError: true, expectedCalls: 1},
+		{name: "Fails then succeeds", failures: 2, env: map[string]string{"AWS_INIT_ATTEMPTS": "3", "AWS_INIT_BACKOFF": "1ms"}, expectedCalls: 3},
+		{name: "Gives up after attempts", failures: 5, env: map[string]string{"AWS_INIT_ATTEMPTS": "3", "AWS_INIT_BACKOFF": "1ms"}, expectError: true, expectedCalls: 3},
+		{name: "Invalid attempts ignored", failures: 1, env: map[string]string{"AWS_INIT_ATTEMPTS": "many"}, expectError: true, expectedCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			loader := func(ctx context.Context) (aws.Config, error) {
+				calls++
+				if calls <= tt.failures {
+					return aws.Config{}, errors.New("no credentials")
+				}
+				return aws.Config{Region: "us-east-1"}, nil
+			}
+			lookup := func(name string) (string, bool) {
+				value, ok := tt.env[name]
+				return value, ok
+			}
+
+			cfg, err := LoadAWSConfigWithRetry(context.Background(), lookup, loader)
+			if tt.expectError != (err != nil) {
+				t.Fatalf("expected error %v, got %v", tt.expectError, err)
+			}
+			if calls != tt.expectedCalls {
+				t.Errorf("expected %d calls, got %d", tt.expectedCalls, calls)
+			}
+			if !tt.expectError && cfg.Region != "us-east-1" {
+				t.Errorf("expected the loaded config, got region %q", cfg.Region)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// MessageCorrelationID returns the correlation_id a caller gave a job
+// message, or "" when it has none or can't be parsed.
+func MessageCorrelationID(message []byte) string {
+	var jobMessage JobMessage
+	if err := json.Unmarshal(message, &jobMessage); err != nil {
+		return ""
+	}
+	return jobMessage.CorrelationID
+}
+
+// NewCorrelationID generates a correlation ID for a job whose caller didn't
+// supply one.
+func NewCorrelationID() string {
+	return uuid.NewString()
+}
+
+type correlationIDKey struct{}
+
+// ContextWithCorrelationID returns ctx carrying the correlation ID of the job
+// it handles, or ctx unchanged when id is empty. End-state events created
+// with the context echo it.
+func ContextWithCorrelationID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationIDFromContext returns the correlation ID ctx carries, or "".
+func CorrelationIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
+
+// CorrelationAttributes tags a span with the correlation ID ctx carries, if
+// any.
+func CorrelationAttributes(ctx context.Context) []attribute.KeyValue {
+	if id := CorrelationIDFromContext(ctx); id != "" {
+		return []attribute.KeyValue{attribute.String("correlation.id", id)}
+	}
+	return nil
+}
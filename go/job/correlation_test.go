@@ -0,0 +1,56 @@
+package job
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestMessageCorrelationID(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		expected string
+	}{
+		{name: "Correlation ID", message: `{"job_type":"data_cleanup","message":{},"correlation_id":"req-42"}`, expected: "req-42"},
+		{name: "No correlation ID", message: `{"job_type":"data_cleanup","message":{}}`},
+		{name: "Not JSON", message: `not json`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := MessageCorrelationID([]byte(tt.message)); actual != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestNewCorrelationID(t *testing.T) {
+	first, second := NewCorrelationID(), NewCorrelationID()
+	if _, err := uuid.Parse(first); err != nil {
+		t.Errorf("expected a UUID, got %q: %v", first, err)
+	}
+	if first == second {
+		t.Errorf("expected a new ID each time, got %q twice", first)
+	}
+}
+
+func TestCorrelationIDEchoedInEndState(t *testing.T) {
+	ctx := ContextWithCorrelationID(context.Background(), "req-42")
+	if event := NewJobEndStateEvent(ctx, "12345", "data_cleanup", StatusCompleted, ""); event.CorrelationID != "req-42" {
+		t.Errorf("expected the end state to echo req-42, got %q", event.CorrelationID)
+	}
+	if attributes := CorrelationAttributes(ctx); len(attributes) != 1 || attributes[0].Value.AsString() != "req-42" {
+		t.Errorf("expected a correlation.id attribute, got %v", attributes)
+	}
+
+	// Without a correlation ID the context, events and spans are left alone
+	if ctx := ContextWithCorrelationID(context.Background(), ""); CorrelationIDFromContext(ctx) != "" || len(CorrelationAttributes(ctx)) != 0 {
+		t.Errorf("expected no correlation ID from an empty one")
+	}
+	if event := NewJobEndStateEvent(context.Background(), "12345", "data_cleanup", StatusCompleted, ""); event.CorrelationID != "" {
+		t.Errorf("expected no correlation ID, got %q", event.CorrelationID)
+	}
+}
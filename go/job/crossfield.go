@@ -0,0 +1,52 @@
+package job
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// CrossFieldValidator checks rules spanning several fields of a job, run by
+// ParseJob after the job's own field validation passes.
+type CrossFieldValidator func(job Job) error
+
+var (
+	crossFieldMu         sync.RWMutex
+	crossFieldValidators = map[JobType][]CrossFieldValidator{}
+)
+
+// RegisterCrossFieldValidator adds a cross-field rule for jobType. Rules run
+// in the order they were registered and the first failure rejects the job.
+func RegisterCrossFieldValidator(jobType JobType, validator CrossFieldValidator) {
+	crossFieldMu.Lock()
+	defer crossFieldMu.Unlock()
+	crossFieldValidators[jobType] = append(crossFieldValidators[jobType], validator)
+}
+
+// validateCrossFields runs the cross-field rules registered for jobType.
+func validateCrossFields(jobType JobType, job Job) error {
+	crossFieldMu.RLock()
+	validators := crossFieldValidators[jobType]
+	crossFieldMu.RUnlock()
+	for _, validator := range validators {
+		if err := validator(job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// QuickTaskTimeout is a sample cross-field rule for long_running_job: tasks
+// whose name starts with prefix must time out within maxSeconds.
+func QuickTaskTimeout(prefix string, maxSeconds int) CrossFieldValidator {
+	return func(job Job) error {
+		task, ok := job.(LongRunningJob)
+		if !ok || !strings.HasPrefix(task.TaskName, prefix) {
+			return nil
+		}
+		if task.Timeout > maxSeconds {
+			return fmt.Errorf("timeout %d exceeds %d seconds for %s tasks", task.Timeout, maxSeconds, prefix)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,96 @@
+package job
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// withoutCrossFieldValidators starts a test with no cross-field rules
+// registered, putting back the previous ones when it ends
+func withoutCrossFieldValidators(t *testing.T) {
+	t.Helper()
+	crossFieldMu.Lock()
+	defer crossFieldMu.Unlock()
+	previous := crossFieldValidators
+	crossFieldValidators = map[JobType][]CrossFieldValidator{}
+	t.Cleanup(func() {
+		crossFieldMu.Lock()
+		defer crossFieldMu.Unlock()
+		crossFieldValidators = previous
+	})
+}
+
+func TestCrossFieldValidation(t *testing.T) {
+	withoutCrossFieldValidators(t)
+	RegisterCrossFieldValidator(LongRunning, QuickTaskTimeout("quick-", 60))
+
+	tests := []struct {
+		name        string
+		input       string
+		expectError bool
+	}{
+		{
+			name:  "Quick task within limit",
+			input: `{"job_type": "long_running_job", "message": {"task_name": "quick-reindex", "timeout": 30}}`,
+		},
+		{
+			name:        "Quick task over limit",
+			input:       `{"job_type": "long_running_job", "message": {"task_name": "quick-reindex", "timeout": 120}}`,
+			expectError: true,
+		},
+		{
+			name:  "Other task over limit",
+			input: `{"job_type": "long_running_job", "message": {"task_name": "reindex", "timeout": 120}}`,
+		},
+		{
+			name:  "Other job type",
+			input: `{"job_type": "data_cleanup", "message": {"target_table": "quick-users", "retention": 120}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, _, err := ParseJob([]byte(tt.input))
+			if tt.expectError {
+				if err == nil || !strings.Contains(err.Error(), "cross-field") {
+					t.Errorf("expected a cross-field validation error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCrossFieldValidatorsRunAfterFieldValidation(t *testing.T) {
+	withoutCrossFieldValidators(t)
+	var calls []string
+	RegisterCrossFieldValidator(DataCleanup, func(job Job) error {
+		calls = append(calls, "first")
+		return errors.New("rejected")
+	})
+	RegisterCrossFieldValidator(DataCleanup, func(job Job) error {
+		calls = append(calls, "second")
+		return nil
+	})
+
+	// Field validation fails first, so no rule runs
+	if _, _, _, err := ParseJob([]byte(`{"job_type": "data_cleanup", "message": {"retention": 30}}`)); err == nil {
+		t.Fatalf("expected an error but got none")
+	}
+	if len(calls) != 0 {
+		t.Errorf("expected no cross-field rules to run for an invalid job, got %v", calls)
+	}
+
+	// The first failing rule rejects the job
+	_, _, _, err := ParseJob([]byte(`{"job_type": "data_cleanup", "message": {"target_table": "users", "retention": 30}}`))
+	if err == nil || !strings.Contains(err.Error(), "rejected") {
+		t.Errorf("expected the rule's error, got %v", err)
+	}
+	if strings.Join(calls, ",") != "first" {
+		t.Errorf("expected only the first rule to run, got %v", calls)
+	}
+}
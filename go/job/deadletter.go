@@ -0,0 +1,241 @@
+package job
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// compressedDeadLetterPrefix marks a dead-letter body compressed by
+// CompressDeadLetter.
+const compressedDeadLetterPrefix = "gzip:"
+
+// Stages of the pipeline at which a message can be dead-lettered.
+const (
+	StageReceive  = "receive" // redelivered too many times to be processed
+	StageParse    = "parse"
+	StageValidate = "validate"
+	StageExecute  = "execute"
+	StageMarshal  = "marshal"
+	StageSend     = "send"
+)
+
+// SQS message attributes mirroring a dead letter's envelope, so the queue can
+// be inspected without parsing each body.
+const (
+	DeadLetterReasonAttribute = "failure_reason"
+	DeadLetterStageAttribute  = "failure_stage"
+)
+
+// maxReasonAttributeBytes caps the failure_reason attribute, as reasons often
+// quote the whole message body that is already in the envelope.
+const maxReasonAttributeBytes = 256
+
+// DeadLetterEnvelope wraps a dead-lettered message with why and where it
+// failed. The original body is kept as a string so that bodies which were
+// never valid JSON survive the round trip byte for byte.
+type DeadLetterEnvelope struct {
+	OriginalBody string `json:"original_body"`
+	Reason       string `json:"reason"`
+	Stage        string `json:"stage"`
+	Timestamp    string `json:"timestamp"`
+	TraceID      string `json:"trace_id,omitempty"`
+	ExpiresAt    string `json:"expires_at,omitempty"`
+}
+
+// NewDeadLetterEnvelope wraps body with the failure details, timestamped now.
+func NewDeadLetterEnvelope(body, stage, reason, traceID string) DeadLetterEnvelope {
+	return DeadLetterEnvelope{
+		OriginalBody: body,
+		Reason:       reason,
+		Stage:        stage,
+		Timestamp:    time.Now().Format(time.RFC3339),
+		TraceID:      traceID,
+	}
+}
+
+// Marshal encodes the envelope as a dead-letter queue message body.
+func (e DeadLetterEnvelope) Marshal() (string, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal dead-letter envelope: %w", err)
+	}
+	return string(data), nil
+}
+
+// ReasonAttribute is the envelope's reason for the failure_reason attribute,
+// truncated to maxReasonAttributeBytes.
+func (e DeadLetterEnvelope) ReasonAttribute() string {
+	if len(e.Reason) <= maxReasonAttributeBytes {
+		return e.Reason
+	}
+	return strings.ToValidUTF8(e.Reason[:maxReasonAttributeBytes-3], "") + "..."
+}
+
+// DeadLetterQueue sends envelopes to a dead-letter queue, counting each on
+// jobs_deadlettered_total by the stage it failed at.
+type DeadLetterQueue struct {
+	Client   SQSSender
+	URL      string
+	Metrics  *FailureMetrics // nil counts nothing
+	Compress bool            // gzip bodies, see CompressDeadLetter
+}
+
+// Send sends envelope to the queue, with its reason and stage mirrored as
+// message attributes, and returns the client's error. A body that can't be
+// wrapped or compressed is sent as it is rather than lost.
+func (q DeadLetterQueue) Send(ctx context.Context, envelope DeadLetterEnvelope) error {
+	q.Metrics.DeadLettered(ctx, envelope.Stage)
+	body, err := envelope.Marshal()
+	if err != nil {
+		LoggerWithTrace(ctx, slog.Default()).Warn("failed to wrap dead letter, sending the original body", "error", err)
+		body = envelope.OriginalBody
+	}
+	if q.Compress {
+		compressed, err := CompressDeadLetter(body)
+		if err != nil {
+			LoggerWithTrace(ctx, slog.Default()).Warn("failed to compress dead letter, sending it uncompressed", "error", err)
+		} else {
+			body = compressed
+		}
+	}
+
+	_, err = q.Client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(q.URL),
+		MessageBody: aws.String(body),
+		MessageAttributes: map[string]types.MessageAttributeValue{
+			DeadLetterReasonAttribute: {DataType: aws.String("String"), StringValue: aws.String(envelope.ReasonAttribute())},
+			DeadLetterStageAttribute:  {DataType: aws.String("String"), StringValue: aws.String(envelope.Stage)},
+		},
+	})
+	return err
+}
+
+// ParseDeadLetterTTLs parses a comma separated list of job_type=duration
+// pairs, e.g. "report_generation=1h,user_onboarding=30m". Malformed entries
+// are logged and skipped.
+func ParseDeadLetterTTLs(ttls string) map[JobType]time.Duration {
+	parsed := map[JobType]time.Duration{}
+	for _, entry := range strings.Split(ttls, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		jobType, value, ok := strings.Cut(entry, "=")
+		ttl, err := time.ParseDuration(strings.TrimSpace(value))
+		if !ok || err != nil || ttl <= 0 {
+			log.Printf("ignoring malformed dead-letter TTL: %q", entry)
+			continue
+		}
+		parsed[JobType(strings.TrimSpace(jobType))] = ttl
+	}
+	return parsed
+}
+
+// WithExpiry sets ExpiresAt to the envelope's timestamp plus the TTL
+// configured for jobType. Envelopes for job types without a TTL never expire.
+func (e DeadLetterEnvelope) WithExpiry(jobType JobType, ttls map[JobType]time.Duration) DeadLetterEnvelope {
+	ttl, ok := ttls[jobType]
+	if !ok {
+		return e
+	}
+	failedAt, err := time.Parse(time.RFC3339, e.Timestamp)
+	if err != nil {
+		log.Printf("cannot set dead-letter expiry from timestamp %q: %v", e.Timestamp, err)
+		return e
+	}
+	e.ExpiresAt = failedAt.Add(ttl).Format(time.RFC3339)
+	return e
+}
+
+// Expired reports whether the envelope's TTL has passed at now, after which
+// the failure is too stale to redrive. Envelopes without a valid ExpiresAt
+// never expire.
+func (e DeadLetterEnvelope) Expired(now time.Time) bool {
+	if e.ExpiresAt == "" {
+		return false
+	}
+	expiresAt, err := time.Parse(time.RFC3339, e.ExpiresAt)
+	if err != nil {
+		return false
+	}
+	return !now.Before(expiresAt)
+}
+
+// CompressDeadLetter gzips a dead-letter body, base64 encoded so it stays a
+// valid SQS message body.
+func CompressDeadLetter(body string) (string, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write([]byte(body)); err != nil {
+		return "", fmt.Errorf("failed to compress dead-letter body: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("failed to compress dead-letter body: %w", err)
+	}
+	return compressedDeadLetterPrefix + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// DecompressDeadLetter reverses CompressDeadLetter. Bodies that weren't
+// compressed are returned unchanged.
+func DecompressDeadLetter(body string) (string, error) {
+	encoded, ok := strings.CutPrefix(body, compressedDeadLetterPrefix)
+	if !ok {
+		return body, nil
+	}
+	compressed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode compressed dead-letter body: %w", err)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress dead-letter body: %w", err)
+	}
+	defer reader.Close()
+	decompressed, err := io.ReadAll(reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress dead-letter body: %w", err)
+	}
+	return string(decompressed), nil
+}
+
+// ParseDeadLetterEnvelope parses a dead-letter queue message body. It fails if
+// the body is not an envelope, e.g. a raw message dead-lettered by SQS itself.
+func ParseDeadLetterEnvelope(message []byte) (*DeadLetterEnvelope, error) {
+	decompressed, err := DecompressDeadLetter(string(message))
+	if err != nil {
+		return nil, err
+	}
+	message = []byte(decompressed)
+
+	var envelope DeadLetterEnvelope
+	if err := json.Unmarshal(message, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to parse dead-letter envelope: %w", err)
+	}
+	if envelope.OriginalBody == "" {
+		return nil, errors.New("not a dead-letter envelope: original_body is missing")
+	}
+	return &envelope, nil
+}
+
+// RecoverOriginal returns the message body as it was before it was
+// dead-lettered, ready to be redriven.
+func (e DeadLetterEnvelope) RecoverOriginal() ([]byte, error) {
+	if e.OriginalBody == "" {
+		return nil, errors.New("dead-letter envelope has no original body")
+	}
+	return []byte(e.OriginalBody), nil
+}
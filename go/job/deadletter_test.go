@@ -0,0 +1,281 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestDeadLetterEnvelopeRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{
+			name: "JSON body",
+			body: `{"job_type":"data_cleanup","message":{"retention":30}}`,
+		},
+		{
+			name: "Body that is not JSON",
+			body: `{"job_type": "data_cleanup", "message": {`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envelope := NewDeadLetterEnvelope(tt.body, StageValidate, "target_table is required", "4bf92f3577b34da6a3ce929d0e0e4736")
+			data, err := json.Marshal(envelope)
+			if err != nil {
+				t.Fatalf("failed to marshal envelope: %v", err)
+			}
+
+			parsed, err := ParseDeadLetterEnvelope(data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if *parsed != envelope {
+				t.Errorf("expected envelope %+v, got %+v", envelope, *parsed)
+			}
+
+			original, err := parsed.RecoverOriginal()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(original) != tt.body {
+				t.Errorf("expected original body %s, got %s", tt.body, original)
+			}
+		})
+	}
+}
+
+func TestParseDeadLetterEnvelopeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{
+			name:  "Invalid JSON",
+			input: `not json`,
+		},
+		{
+			name:  "Raw message rather than an envelope",
+			input: `{"job_type":"data_cleanup","message":{"retention":30}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseDeadLetterEnvelope([]byte(tt.input)); err == nil {
+				t.Errorf("expected an error but got none")
+			}
+		})
+	}
+}
+
+func TestRecoverOriginalEmpty(t *testing.T) {
+	if _, err := (DeadLetterEnvelope{}).RecoverOriginal(); err == nil {
+		t.Errorf("expected an error but got none")
+	}
+}
+
+func TestCompressDeadLetterRoundTrip(t *testing.T) {
+	envelope, err := json.Marshal(NewDeadLetterEnvelope(`{"job_type": "data_cleanup", "message": {`, StageParse, "unexpected end of JSON input", ""))
+	if err != nil {
+		t.Fatalf("failed to marshal envelope: %v", err)
+	}
+
+	for _, body := range []string{string(envelope), `not json`, ""} {
+		compressed, err := CompressDeadLetter(body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.HasPrefix(compressed, "gzip:") {
+			t.Errorf("expected a gzip: prefix, got %s", compressed)
+		}
+		decompressed, err := DecompressDeadLetter(compressed)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if decompressed != body {
+			t.Errorf("expected %q, got %q", body, decompressed)
+		}
+	}
+
+	// Compressed envelopes parse like plain ones
+	compressed, _ := CompressDeadLetter(string(envelope))
+	parsed, err := ParseDeadLetterEnvelope([]byte(compressed))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if parsed.Stage != StageParse {
+		t.Errorf("expected stage %s, got %s", StageParse, parsed.Stage)
+	}
+}
+
+func TestDecompressDeadLetter(t *testing.T) {
+	if body, err := DecompressDeadLetter(`{"plain":true}`); err != nil || body != `{"plain":true}` {
+		t.Errorf("expected an uncompressed body unchanged, got %q, %v", body, err)
+	}
+	for _, body := range []string{"gzip:not base64!", "gzip:bm90IGd6aXA="} {
+		if _, err := DecompressDeadLetter(body); err == nil {
+			t.Errorf("expected an error decompressing %q", body)
+		}
+	}
+}
+
+func TestParseDeadLetterTTLs(t *testing.T) {
+	ttls := ParseDeadLetterTTLs("report_generation=1h, user_onboarding=30m,data_cleanup=soon,long_running_job=-1m,,batch_job")
+	if len(ttls) != 2 || ttls[ReportGeneration] != time.Hour || ttls[UserOnboarding] != 30*time.Minute {
+		t.Errorf("expected TTLs for report_generation and user_onboarding only, got %v", ttls)
+	}
+}
+
+func TestDeadLetterExpiry(t *testing.T) {
+	ttls := map[JobType]time.Duration{ReportGeneration: time.Hour}
+	failedAt := time.Date(2025, 8, 30, 12, 0, 0, 0, time.UTC)
+	envelope := DeadLetterEnvelope{OriginalBody: "{}", Timestamp: failedAt.Format(time.RFC3339)}
+
+	tests := []struct {
+		name          string
+		jobType       JobType
+		now           time.Time
+		expectExpires string
+		expectExpired bool
+	}{
+		{
+			name:          "Within the TTL",
+			jobType:       ReportGeneration,
+			now:           failedAt.Add(59 * time.Minute),
+			expectExpires: "2025-08-30T13:00:00Z",
+		},
+		{
+			name:          "Past the TTL",
+			jobType:       ReportGeneration,
+			now:           failedAt.Add(time.Hour),
+			expectExpires: "2025-08-30T13:00:00Z",
+			expectExpired: true,
+		},
+		{
+			name:    "Job type without a TTL",
+			jobType: DataCleanup,
+			now:     failedAt.Add(24 * time.Hour),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expiring := envelope.WithExpiry(tt.jobType, ttls)
+			if expiring.ExpiresAt != tt.expectExpires {
+				t.Errorf("expected ExpiresAt %q, got %q", tt.expectExpires, expiring.ExpiresAt)
+			}
+			if expired := expiring.Expired(tt.now); expired != tt.expectExpired {
+				t.Errorf("expected expired to be %v, got %v", tt.expectExpired, expired)
+			}
+		})
+	}
+}
+
+func TestDeadLetterReasonAttribute(t *testing.T) {
+	short := NewDeadLetterEnvelope(`not json`, StageParse, "failed to parse job message", "")
+	if reason := short.ReasonAttribute(); reason != "failed to parse job message" {
+		t.Errorf("expected a short reason unchanged, got %q", reason)
+	}
+
+	long := NewDeadLetterEnvelope(`not json`, StageParse, "failed to parse job message: "+strings.Repeat("é", 200), "")
+	reason := long.ReasonAttribute()
+	if len(reason) > maxReasonAttributeBytes || !strings.HasSuffix(reason, "...") || !strings.HasPrefix(reason, "failed to parse job message: ") {
+		t.Errorf("expected the reason truncated to %d bytes, got %d: %q", maxReasonAttributeBytes, len(reason), reason)
+	}
+	if !utf8.ValidString(reason) {
+		t.Errorf("expected the truncated reason to be valid UTF-8, got %q", reason)
+	}
+
+	body, err := long.Marshal()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parsed, err := ParseDeadLetterEnvelope([]byte(body))
+	if err != nil || parsed.Reason != long.Reason {
+		t.Errorf("expected the full reason in the body, got %v, %v", parsed, err)
+	}
+}
+
+// recordingSQS records the messages sent to it, failing them with err when set
+type recordingSQS struct {
+	sent []*sqs.SendMessageInput
+	err  error
+}
+
+func (r *recordingSQS) SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	r.sent = append(r.sent, params)
+	return &sqs.SendMessageOutput{}, r.err
+}
+
+func TestDeadLetterQueueSend(t *testing.T) {
+	tests := []struct {
+		name        string
+		compress    bool
+		sendErr     error
+		expectError bool
+	}{
+		{name: "Sent"},
+		{name: "Compressed", compress: true},
+		{name: "Send failed", sendErr: errors.New("throttled"), expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := sdkmetric.NewManualReader()
+			metrics, err := NewFailureMetrics(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			client := &recordingSQS{err: tt.sendErr}
+			queue := DeadLetterQueue{Client: client, URL: "deadletter", Metrics: metrics, Compress: tt.compress}
+
+			envelope := NewDeadLetterEnvelope(`not json`, StageParse, "failed to parse job message", "")
+			err = queue.Send(context.Background(), envelope)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("expected error: %v, got %v", tt.expectError, err)
+			}
+
+			if len(client.sent) != 1 {
+				t.Fatalf("expected 1 message sent, got %d", len(client.sent))
+			}
+			sent := client.sent[0]
+			if aws.ToString(sent.QueueUrl) != "deadletter" {
+				t.Errorf("expected the deadletter queue, got %s", aws.ToString(sent.QueueUrl))
+			}
+			if stage := aws.ToString(sent.MessageAttributes[DeadLetterStageAttribute].StringValue); stage != StageParse {
+				t.Errorf("expected stage attribute %s, got %s", StageParse, stage)
+			}
+			body, err := DecompressDeadLetter(aws.ToString(sent.MessageBody))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if compressed := body != aws.ToString(sent.MessageBody); compressed != tt.compress {
+				t.Errorf("expected compressed: %v, got %v", tt.compress, compressed)
+			}
+			if parsed, err := ParseDeadLetterEnvelope([]byte(body)); err != nil || parsed.OriginalBody != `not json` {
+				t.Errorf("expected the envelope as the body, got %v, %v", parsed, err)
+			}
+
+			var collected metricdata.ResourceMetrics
+			if err := reader.Collect(context.Background(), &collected); err != nil {
+				t.Fatalf("failed to collect metrics: %v", err)
+			}
+			if len(collected.ScopeMetrics) != 1 || collected.ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[int64]).DataPoints[0].Value != 1 {
+				t.Errorf("expected one dead letter counted, got %+v", collected.ScopeMetrics)
+			}
+		})
+	}
+}
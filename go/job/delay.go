@@ -0,0 +1,48 @@
+package job
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+// MaxDelaySeconds is the longest DelaySeconds SQS accepts.
+const MaxDelaySeconds = 900
+
+// DelaySeconds is the SQS DelaySeconds that holds a job until runAt, as seen
+// from now. Clock skew between producer and consumer can put runAt in the
+// past or implausibly far ahead, so the delay is clamped to
+// [0, MaxDelaySeconds] with a warning logged when that happens.
+func DelaySeconds(runAt, now time.Time) int32 {
+	delay := math.Ceil(runAt.Sub(now).Seconds())
+	switch {
+	case delay < 0:
+		log.Printf("warning: scheduled time %s is %s in the past, running without a delay", runAt.Format(time.RFC3339), now.Sub(runAt))
+		return 0
+	case delay > MaxDelaySeconds:
+		log.Printf("warning: scheduled time %s is %s ahead, capping the delay at %d seconds", runAt.Format(time.RFC3339), runAt.Sub(now), MaxDelaySeconds)
+		return MaxDelaySeconds
+	}
+	return int32(delay)
+}
+
+// MessageDelay returns the delay a job message asks for, in seconds, or 0
+// when it has none or can't be parsed. ParseJob has already rejected
+// delays outside [0, MaxDelaySeconds].
+func MessageDelay(message []byte) int32 {
+	var jobMessage JobMessage
+	if err := json.Unmarshal(message, &jobMessage); err != nil {
+		return 0
+	}
+	return int32(jobMessage.Delay)
+}
+
+// validateDelay checks a job message's delay is one SQS accepts.
+func validateDelay(delay int) error {
+	if delay < 0 || delay > MaxDelaySeconds {
+		return fmt.Errorf("delay %d must be between 0 and %d seconds", delay, MaxDelaySeconds)
+	}
+	return nil
+}
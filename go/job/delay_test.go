@@ -0,0 +1,65 @@
+package job
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDelaySeconds(t *testing.T) {
+	now := time.Date(2025, 8, 30, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		delta    time.Duration
+		expected int32
+	}{
+		{name: "Negative from skew", delta: -30 * time.Second, expected: 0},
+		{name: "Now", delta: 0, expected: 0},
+		{name: "In range", delta: 2 * time.Minute, expected: 120},
+		{name: "Partial seconds round up", delta: 1500 * time.Millisecond, expected: 2},
+		{name: "At the cap", delta: 15 * time.Minute, expected: 900},
+		{name: "Over the cap", delta: 24 * time.Hour, expected: 900},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := DelaySeconds(now.Add(tt.delta), now); actual != tt.expected {
+				t.Errorf("expected %d, got %d", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestMessageDelay(t *testing.T) {
+	tests := []struct {
+		name        string
+		delay       string
+		expected    int32
+		expectError bool
+	}{
+		{name: "No delay", delay: "", expected: 0},
+		{name: "In range", delay: `, "delay": 300`, expected: 300},
+		{name: "At the cap", delay: `, "delay": 900`, expected: 900},
+		{name: "Over the cap", delay: `, "delay": 901`, expectError: true},
+		{name: "Negative", delay: `, "delay": -1`, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := []byte(`{"job_type": "user_onboarding", "message": {"user_id": "user-001", "user_name": "John Doe"}` + tt.delay + `}`)
+			_, _, _, err := ParseJob(message)
+			if tt.expectError {
+				if err == nil || ParseStage(err) != StageValidate {
+					t.Errorf("expected a validation error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual := MessageDelay(message); actual != tt.expected {
+				t.Errorf("expected %d, got %d", tt.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package job
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+)
+
+// FieldChange is a single field that differs between two payloads. Fields
+// inside the original message are named by their dotted JSON path, e.g.
+// originalmessage.message.user_id. Old or New is empty when the field was
+// added or removed.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old,omitempty"`
+	New   string `json:"new,omitempty"`
+}
+
+// DiffEnrichedPayload returns the field-level differences from a to b, sorted
+// by field name, or nil when the payloads are identical.
+func DiffEnrichedPayload(a, b EnrichedPayload) []FieldChange {
+	before, after := payloadFields(a), payloadFields(b)
+
+	var changes []FieldChange
+	for field, old := range before {
+		if updated, ok := after[field]; !ok || updated != old {
+			changes = append(changes, FieldChange{Field: field, Old: old, New: updated})
+		}
+	}
+	for field, added := range after {
+		if _, ok := before[field]; !ok {
+			changes = append(changes, FieldChange{Field: field, New: added})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// payloadFields flattens a payload into its set fields keyed by JSON name.
+func payloadFields(p EnrichedPayload) map[string]string {
+	fields := map[string]string{}
+	set := func(name, value string) {
+		if value != "" {
+			fields[name] = value
+		}
+	}
+	set("id", p.ID)
+	set("timestamp", p.Timestamp)
+	set("status", string(p.Status))
+	set("trace_context", p.TraceContext)
+	set("tracestate", p.TraceState)
+	set("parent_id", p.ParentID)
+	set("signature", p.Signature)
+	set("schema_fingerprint", p.SchemaFingerprint)
+	set("correlation_id", p.CorrelationID)
+	set("eventbridge_id", p.EventBridgeID)
+	if p.ShardKey != 0 {
+		set("shard_key", strconv.Itoa(p.ShardKey))
+	}
+	if p.ChainDepth != 0 {
+		set("chain_depth", strconv.Itoa(p.ChainDepth))
+	}
+
+	if len(p.OriginalMessage) > 0 {
+		var message any
+		if err := json.Unmarshal(p.OriginalMessage, &message); err != nil {
+			set("originalmessage", string(p.OriginalMessage))
+		} else {
+			flattenJSON("originalmessage", message, fields)
+		}
+	}
+	return fields
+}
+
+// flattenJSON records each leaf of value under its dotted path from prefix.
+func flattenJSON(prefix string, value any, fields map[string]string) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			flattenJSON(prefix+"."+key, child, fields)
+		}
+	case []any:
+		for i, child := range v {
+			flattenJSON(prefix+"."+strconv.Itoa(i), child, fields)
+		}
+	default:
+		leaf, _ := json.Marshal(v)
+		fields[prefix] = string(leaf)
+	}
+}
@@ -0,0 +1,77 @@
+package job
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDiffEnrichedPayload(t *testing.T) {
+	base := EnrichedPayload{
+		OriginalMessage: json.RawMessage(`{"job_type": "user_onboarding", "message": {"user_id": "user-001", "user_name": "John Doe"}}`),
+		ID:              "12345",
+		Timestamp:       "2025-08-30T12:00:00Z",
+		Status:          StatusNew,
+		TraceContext:    "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	}
+
+	tests := []struct {
+		name     string
+		modify   func(p *EnrichedPayload)
+		expected []FieldChange
+	}{
+		{
+			name:     "Identical payloads",
+			modify:   func(p *EnrichedPayload) {},
+			expected: nil,
+		},
+		{
+			name: "Identical original message with different formatting",
+			modify: func(p *EnrichedPayload) {
+				p.OriginalMessage = json.RawMessage(`{"message":{"user_name":"John Doe","user_id":"user-001"},"job_type":"user_onboarding"}`)
+			},
+			expected: nil,
+		},
+		{
+			name:   "Changed status",
+			modify: func(p *EnrichedPayload) { p.Status = StatusCompleted },
+			expected: []FieldChange{
+				{Field: "status", Old: string(StatusNew), New: string(StatusCompleted)},
+			},
+		},
+		{
+			name: "Added fields",
+			modify: func(p *EnrichedPayload) {
+				p.ParentID = "batch-1"
+				p.OriginalMessage = json.RawMessage(`{"job_type": "user_onboarding", "message": {"user_id": "user-001", "user_name": "John Doe"}, "fixture": "good_jobs.json#0"}`)
+			},
+			expected: []FieldChange{
+				{Field: "originalmessage.fixture", New: `"good_jobs.json#0"`},
+				{Field: "parent_id", New: "batch-1"},
+			},
+		},
+		{
+			name: "Removed and changed nested fields",
+			modify: func(p *EnrichedPayload) {
+				p.TraceContext = ""
+				p.OriginalMessage = json.RawMessage(`{"job_type": "user_onboarding", "message": {"user_id": "user-002"}}`)
+			},
+			expected: []FieldChange{
+				{Field: "originalmessage.message.user_id", Old: `"user-001"`, New: `"user-002"`},
+				{Field: "originalmessage.message.user_name", Old: `"John Doe"`},
+				{Field: "trace_context", Old: base.TraceContext},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modified := base
+			tt.modify(&modified)
+			actual := DiffEnrichedPayload(base, modified)
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package job
+
+import (
+	"math"
+	"time"
+)
+
+// NeverDrains is returned by EstimateDrainTime when a backlog has no
+// throughput to drain it.
+const NeverDrains = time.Duration(math.MaxInt64)
+
+// EstimateDrainTime estimates how long a queue backlog of depth messages takes
+// to drain when jobs complete at throughputPerSec. An empty queue drains
+// immediately, and a backlog with no throughput returns NeverDrains.
+func EstimateDrainTime(depth int, throughputPerSec float64) time.Duration {
+	if depth <= 0 {
+		return 0
+	}
+	if throughputPerSec <= 0 || math.IsNaN(throughputPerSec) {
+		return NeverDrains
+	}
+	seconds := float64(depth) / throughputPerSec
+	if seconds >= NeverDrains.Seconds() {
+		return NeverDrains
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,33 @@
+package job
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEstimateDrainTime(t *testing.T) {
+	tests := []struct {
+		name       string
+		depth      int
+		throughput float64
+		expected   time.Duration
+	}{
+		{name: "Steady throughput", depth: 120, throughput: 2, expected: time.Minute},
+		{name: "Fractional throughput", depth: 3, throughput: 0.5, expected: 6 * time.Second},
+		{name: "Sub-second drain", depth: 1, throughput: 4, expected: 250 * time.Millisecond},
+		{name: "Empty queue", depth: 0, throughput: 0, expected: 0},
+		{name: "Zero throughput", depth: 10, throughput: 0, expected: NeverDrains},
+		{name: "Negative throughput", depth: 10, throughput: -1, expected: NeverDrains},
+		{name: "NaN throughput", depth: 10, throughput: math.NaN(), expected: NeverDrains},
+		{name: "Overflowing estimate", depth: math.MaxInt32, throughput: 1e-9, expected: NeverDrains},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := EstimateDrainTime(tt.depth, tt.throughput); actual != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package job
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// encryptedPrefix marks a field value encrypted by EncryptFields
+const encryptedPrefix = "enc:v1:"
+
+// FieldCipher encrypts and decrypts individual payload field values. The
+// local AES-GCM cipher is used by default, a KMS backed cipher can be
+// swapped in by implementing this interface.
+type FieldCipher interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// AESCipher is a FieldCipher using AES-GCM with a local key.
+type AESCipher struct {
+	aead cipher.AEAD
+}
+
+// NewAESCipher creates an AESCipher from a 16, 24 or 32 byte key.
+func NewAESCipher(key []byte) (*AESCipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &AESCipher{aead: aead}, nil
+}
+
+// Encrypt seals plaintext, prefixing the random nonce.
+func (c *AESCipher) Encrypt(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt opens ciphertext produced by Encrypt.
+func (c *AESCipher) Decrypt(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < c.aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, sealed := ciphertext[:c.aead.NonceSize()], ciphertext[c.aead.NonceSize():]
+	return c.aead.Open(nil, nonce, sealed, nil)
+}
+
+// FieldEncryptionFromEnv creates the cipher and field list configured by
+// ENCRYPT_FIELDS, a comma separated list of field names, and ENCRYPTION_KEY, a
+// base64 encoded AES key. The cipher is nil when no fields are configured.
+func FieldEncryptionFromEnv() (FieldCipher, []string, error) {
+	var fields []string
+	for _, field := range strings.Split(os.Getenv("ENCRYPT_FIELDS"), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(os.Getenv("ENCRYPTION_KEY"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid ENCRYPTION_KEY: %w", err)
+	}
+	c, err := NewAESCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, fields, nil
+}
+
+// EncryptFields returns a copy of a JSON document with the values of the
+// named fields, wherever they appear, replaced by encrypted strings.
+func EncryptFields(message []byte, fields []string, c FieldCipher) ([]byte, error) {
+	return transformFields(message, fields, func(value any) (any, error) {
+		if s, ok := value.(string); ok && strings.HasPrefix(s, encryptedPrefix) {
+			return value, nil // already encrypted
+		}
+		plaintext, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		ciphertext, err := c.Encrypt(plaintext)
+		if err != nil {
+			return nil, err
+		}
+		return encryptedPrefix + base64.StdEncoding.EncodeToString(ciphertext), nil
+	})
+}
+
+// DecryptFields reverses EncryptFields, restoring the original values of the
+// named fields.
+func DecryptFields(message []byte, fields []string, c FieldCipher) ([]byte, error) {
+	return transformFields(message, fields, func(value any) (any, error) {
+		s, ok := value.(string)
+		if !ok || !strings.HasPrefix(s, encryptedPrefix) {
+			return value, nil // not encrypted
+		}
+		ciphertext, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(s, encryptedPrefix))
+		if err != nil {
+			return nil, err
+		}
+		plaintext, err := c.Decrypt(ciphertext)
+		if err != nil {
+			return nil, err
+		}
+		var original any
+		if err := decodeJSON(plaintext, &original); err != nil {
+			return nil, err
+		}
+		return original, nil
+	})
+}
+
+// transformFields applies fn to the value of each named field in a JSON
+// document.
+func transformFields(message []byte, fields []string, fn func(any) (any, error)) ([]byte, error) {
+	if len(fields) == 0 {
+		return message, nil
+	}
+	var document any
+	if err := decodeJSON(message, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse message: %w", err)
+	}
+
+	names := map[string]bool{}
+	for _, field := range fields {
+		names[field] = true
+	}
+	var walk func(value any) error
+	walk = func(value any) error {
+		switch v := value.(type) {
+		case map[string]any:
+			for key, child := range v {
+				if names[key] {
+					transformed, err := fn(child)
+					if err != nil {
+						return fmt.Errorf("field %s: %w", key, err)
+					}
+					v[key] = transformed
+					continue
+				}
+				if err := walk(child); err != nil {
+					return err
+				}
+			}
+		case []any:
+			for _, child := range v {
+				if err := walk(child); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := walk(document); err != nil {
+		return nil, err
+	}
+	return json.Marshal(document)
+}
+
+// decodeJSON unmarshals data keeping numbers exact.
+func decodeJSON(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
@@ -0,0 +1,130 @@
+package job
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func testCipher(t *testing.T) *AESCipher {
+	t.Helper()
+	c, err := NewAESCipher([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return c
+}
+
+func TestEncryptFieldsRoundTrip(t *testing.T) {
+	c := testCipher(t)
+	fields := []string{"user_id", "user_name", "retention"}
+
+	tests := []struct {
+		name    string
+		message string
+	}{
+		{name: "Onboarding", message: `{"job_type":"user_onboarding","message":{"user_id":"user-001","user_name":"John Doe"}}`},
+		{name: "Number field", message: `{"job_type":"data_cleanup","message":{"retention":30,"target_table":"users"}}`},
+		{name: "Nested batch", message: `{"job_type":"batch_job","message":{"children":[{"job_type":"user_onboarding","message":{"user_id":"user-002","user_name":"Jane Doe"}}]}}`},
+		{name: "No sensitive fields", message: `{"job_type":"report_generation","message":{"filters":"region=US","report_name":"Sales Report"}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encrypted, err := EncryptFields([]byte(tt.message), fields, c)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, secret := range []string{"user-001", "John Doe", "user-002", "Jane Doe", `"retention":30`} {
+				if strings.Contains(string(encrypted), secret) {
+					t.Errorf("expected %s to be encrypted, got %s", secret, encrypted)
+				}
+			}
+
+			decrypted, err := DecryptFields(encrypted, fields, c)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !jsonEqual(t, decrypted, []byte(tt.message)) {
+				t.Errorf("expected %s after round trip, got %s", tt.message, decrypted)
+			}
+
+			// Decrypting twice, or plaintext, leaves values alone
+			if again, err := DecryptFields(decrypted, fields, c); err != nil || !jsonEqual(t, again, []byte(tt.message)) {
+				t.Errorf("expected plaintext to be unchanged, got %s, %v", again, err)
+			}
+		})
+	}
+}
+
+func TestDecryptFieldsWrongKey(t *testing.T) {
+	encrypted, err := EncryptFields([]byte(`{"message":{"user_id":"user-001"}}`), []string{"user_id"}, testCipher(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	other, err := NewAESCipher([]byte("fedcba9876543210fedcba9876543210"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := DecryptFields(encrypted, []string{"user_id"}, other); err == nil {
+		t.Errorf("expected decrypting with the wrong key to fail")
+	}
+}
+
+func TestNewAESCipherKeyLength(t *testing.T) {
+	if _, err := NewAESCipher([]byte("short")); err == nil {
+		t.Errorf("expected an invalid key length to fail")
+	}
+}
+
+func TestFieldEncryptionFromEnv(t *testing.T) {
+	tests := []struct {
+		name         string
+		fields       string
+		key          string
+		expectCipher bool
+		expectError  bool
+	}{
+		{name: "Disabled", fields: ""},
+		{name: "Enabled", fields: "user_id, user_name", key: "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=", expectCipher: true},
+		{name: "Missing key", fields: "user_id", expectError: true},
+		{name: "Invalid key", fields: "user_id", key: "not base64!", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ENCRYPT_FIELDS", tt.fields)
+			t.Setenv("ENCRYPTION_KEY", tt.key)
+			c, fields, err := FieldEncryptionFromEnv()
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (c != nil) != tt.expectCipher {
+				t.Errorf("expected cipher %v, got %v", tt.expectCipher, c != nil)
+			}
+			if tt.expectCipher && len(fields) != 2 {
+				t.Errorf("expected 2 fields, got %v", fields)
+			}
+		})
+	}
+}
+
+func jsonEqual(t *testing.T, a, b []byte) bool {
+	t.Helper()
+	var x, y any
+	if err := json.Unmarshal(a, &x); err != nil {
+		t.Fatalf("invalid JSON %s: %v", a, err)
+	}
+	if err := json.Unmarshal(b, &y); err != nil {
+		t.Fatalf("invalid JSON %s: %v", b, err)
+	}
+	xs, _ := json.Marshal(x)
+	ys, _ := json.Marshal(y)
+	return string(xs) == string(ys)
+}
@@ -0,0 +1,61 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// JobEndStateEvent is the machine-readable end state of a job published to
+// the notifications topic, so subscribers can route on Status. The processor
+// also publishes one with StatusInProgress as it starts executing a job.
+type JobEndStateEvent struct {
+	JobID         string         `json:"job_id,omitempty"`
+	JobType       string         `json:"job_type,omitempty"`
+	Status        Status         `json:"status"`
+	Error         string         `json:"error,omitempty"`
+	Output        map[string]any `json:"output,omitempty"` // the JobResult output of a completed job
+	Timestamp     string         `json:"timestamp"`
+	TraceID       string         `json:"trace_id,omitempty"`
+	CorrelationID string         `json:"correlation_id,omitempty"`
+}
+
+// NewJobEndStateEvent describes a job's end state, timestamped now and
+// tagged with the trace of the span in ctx and the correlation ID ctx
+// carries, if there are any. errorMessage is empty for jobs that succeeded.
+func NewJobEndStateEvent(ctx context.Context, jobID, jobType string, status Status, errorMessage string) JobEndStateEvent {
+	event := JobEndStateEvent{
+		JobID:         jobID,
+		JobType:       jobType,
+		Status:        status,
+		Error:         errorMessage,
+		Timestamp:     time.Now().Format(time.RFC3339),
+		CorrelationID: CorrelationIDFromContext(ctx),
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+		event.TraceID = spanContext.TraceID().String()
+	}
+	return event
+}
+
+// SNS message attributes end states and results are published with, so
+// subscription filter policies can select by job type and status.
+const (
+	JobTypeAttribute = "job_type"
+	StatusAttribute  = "status"
+)
+
+// FilterAttributes returns the values of the SNS message attributes a job of
+// jobType reaching status is published with. job_type is left out when it
+// isn't known, as SNS rejects empty attribute values.
+func FilterAttributes(jobType string, status Status) map[string]string {
+	attributes := map[string]string{}
+	if jobType != "" {
+		attributes[JobTypeAttribute] = jobType
+	}
+	if status != "" {
+		attributes[StatusAttribute] = string(status)
+	}
+	return attributes
+}
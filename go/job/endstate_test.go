@@ -0,0 +1,82 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNewJobEndStateEvent(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	traced := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}))
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		status        Status
+		errorMessage  string
+		expectTraceID string
+		expectJSON    string
+	}{
+		{
+			name:          "Completed job in a trace",
+			ctx:           traced,
+			status:        StatusCompleted,
+			expectTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			expectJSON:    `{"job_id":"12345","job_type":"report_generation","status":"COMPLETED","timestamp":"%s","trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"}`,
+		},
+		{
+			name:         "Failed job without a trace",
+			ctx:          context.Background(),
+			status:       StatusExecuteFailed,
+			errorMessage: "report service unavailable",
+			expectJSON:   `{"job_id":"12345","job_type":"report_generation","status":"EXECUTE_FAILED","error":"report service unavailable","timestamp":"%s"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := time.Now().Add(-time.Second)
+			event := NewJobEndStateEvent(tt.ctx, "12345", string(ReportGeneration), tt.status, tt.errorMessage)
+			if event.TraceID != tt.expectTraceID {
+				t.Errorf("expected trace ID %q, got %q", tt.expectTraceID, event.TraceID)
+			}
+			if stamped, err := time.Parse(time.RFC3339, event.Timestamp); err != nil || stamped.Before(before) {
+				t.Errorf("expected the event timestamped now, got %s", event.Timestamp)
+			}
+
+			data, err := json.Marshal(event)
+			if err != nil {
+				t.Fatalf("failed to marshal event: %v", err)
+			}
+			if expected := fmt.Sprintf(tt.expectJSON, event.Timestamp); string(data) != expected {
+				t.Errorf("expected %s, got %s", expected, data)
+			}
+		})
+	}
+}
+
+func TestFilterAttributes(t *testing.T) {
+	tests := []struct {
+		name     string
+		jobType  string
+		status   Status
+		expected map[string]string
+	}{
+		{name: "Known job type", jobType: "data_cleanup", status: StatusExecuteFailed, expected: map[string]string{"job_type": "data_cleanup", "status": "EXECUTE_FAILED"}},
+		{name: "Unknown job type", status: StatusRejected, expected: map[string]string{"status": "REJECTED"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if attributes := FilterAttributes(tt.jobType, tt.status); fmt.Sprint(attributes) != fmt.Sprint(tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, attributes)
+			}
+		})
+	}
+}
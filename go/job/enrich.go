@@ -0,0 +1,45 @@
+package job
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Clock supplies the current time so enrichment can be tested deterministically.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the Clock backed by time.Now.
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time { return time.Now() }
+
+// Enrich wraps a validated job detail in the EnrichedPayload shared between the
+// ingester and processor. sourceID identifies the message the detail arrived
+// in, and span is the span whose context is propagated to the processor.
+func Enrich(detail []byte, sourceID string, clock Clock, span trace.Span) (EnrichedPayload, error) {
+	if len(detail) == 0 {
+		return EnrichedPayload{}, errors.New("cannot enrich an empty job detail")
+	}
+	if !json.Valid(detail) {
+		return EnrichedPayload{}, errors.New("cannot enrich a job detail that is not valid JSON")
+	}
+	if sourceID == "" {
+		return EnrichedPayload{}, errors.New("cannot enrich a job without a source ID")
+	}
+
+	payload := EnrichedPayload{
+		OriginalMessage: json.RawMessage(detail),
+		ID:              sourceID,
+		Timestamp:       clock.Now().Format(time.RFC3339),
+		Status:          StatusNew,
+		SchemaVersion:   CurrentSchemaVersion,
+	}
+	payload.TraceContext = InjectTraceparent(span.SpanContext())
+	payload.TraceState = InjectTracestate(span.SpanContext())
+	return payload, nil
+}
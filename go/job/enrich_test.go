@@ -0,0 +1,84 @@
+package job
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fixedClock is a Clock stuck at a single instant
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time { return time.Time(c) }
+
+func TestEnrich(t *testing.T) {
+	clock := fixedClock(time.Date(2025, 8, 30, 12, 0, 0, 0, time.UTC))
+	detail := []byte(`{"job_type":"data_cleanup","message":{"target_table":"users","retention":30}}`)
+
+	tp := sdktrace.NewTracerProvider()
+	_, span := tp.Tracer("test").Start(context.Background(), "ProcessMessage")
+	defer span.End()
+
+	payload, err := Enrich(detail, "sqs-1", clock, span)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(payload.OriginalMessage) != string(detail) {
+		t.Errorf("expected OriginalMessage %s, got %s", detail, payload.OriginalMessage)
+	}
+	if payload.ID != "sqs-1" {
+		t.Errorf("expected ID sqs-1, got %s", payload.ID)
+	}
+	if payload.Timestamp != "2025-08-30T12:00:00Z" {
+		t.Errorf("expected Timestamp 2025-08-30T12:00:00Z, got %s", payload.Timestamp)
+	}
+	if payload.Status != StatusNew {
+		t.Errorf("expected Status %s, got %s", StatusNew, payload.Status)
+	}
+	if payload.SchemaVersion != CurrentSchemaVersion {
+		t.Errorf("expected SchemaVersion %d, got %d", CurrentSchemaVersion, payload.SchemaVersion)
+	}
+	expectedTraceContext := "00-" + span.SpanContext().TraceID().String() + "-" + span.SpanContext().SpanID().String() + "-01"
+	if payload.TraceContext != expectedTraceContext {
+		t.Errorf("expected TraceContext %s, got %s", expectedTraceContext, payload.TraceContext)
+	}
+}
+
+func TestEnrichWithoutRecordingSpan(t *testing.T) {
+	clock := fixedClock(time.Date(2025, 8, 30, 12, 0, 0, 0, time.UTC))
+	detail := []byte(`{"job_type":"data_cleanup","message":{"target_table":"users","retention":30}}`)
+
+	payload, err := Enrich(detail, "sqs-1", clock, trace.SpanFromContext(context.Background()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if payload.TraceContext != "" {
+		t.Errorf("expected no TraceContext without a valid span, got %s", payload.TraceContext)
+	}
+}
+
+func TestEnrichErrors(t *testing.T) {
+	clock := fixedClock(time.Now())
+	span := trace.SpanFromContext(context.Background())
+
+	tests := []struct {
+		name     string
+		detail   string
+		sourceID string
+	}{
+		{name: "Empty detail", detail: "", sourceID: "sqs-1"},
+		{name: "Invalid JSON", detail: "{", sourceID: "sqs-1"},
+		{name: "Missing source ID", detail: `{"job_type":"data_cleanup"}`, sourceID: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Enrich([]byte(tt.detail), tt.sourceID, clock, span); err == nil {
+				t.Errorf("expected an error but got none")
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package job
+
+import "go.opentelemetry.io/otel/attribute"
+
+// EventBridgeAttributes tags a span with the ID of the EventBridge event a
+// job arrived in, the EventId the PutEvents call that sent it returned, if it
+// has one.
+func EventBridgeAttributes(eventID string) []attribute.KeyValue {
+	if eventID == "" {
+		return nil
+	}
+	return []attribute.KeyValue{attribute.String("eventbridge.event_id", eventID)}
+}
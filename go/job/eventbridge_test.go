@@ -0,0 +1,13 @@
+package job
+
+import "testing"
+
+func TestEventBridgeAttributes(t *testing.T) {
+	if attributes := EventBridgeAttributes(""); len(attributes) != 0 {
+		t.Errorf("expected no attributes for a job sent straight to the queue, got %v", attributes)
+	}
+	attributes := EventBridgeAttributes("eb-1")
+	if len(attributes) != 1 || attributes[0].Key != "eventbridge.event_id" || attributes[0].Value.AsString() != "eb-1" {
+		t.Errorf("expected eventbridge.event_id eb-1, got %v", attributes)
+	}
+}
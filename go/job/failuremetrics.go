@@ -0,0 +1,50 @@
+package job
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// FailureMetrics counts the messages the services dead-letter and the SNS
+// publishes that fail, so one alert can cover the whole pipeline. A nil
+// FailureMetrics records nothing.
+type FailureMetrics struct {
+	deadLettered       metric.Int64Counter
+	snsPublishFailures metric.Int64Counter
+}
+
+// NewFailureMetrics creates the jobs_deadlettered_total and
+// sns_publish_failures_total counters on meter.
+func NewFailureMetrics(meter metric.Meter) (*FailureMetrics, error) {
+	deadLettered, err := meter.Int64Counter("jobs_deadlettered_total",
+		metric.WithDescription("Messages sent to the dead-letter queue, by the stage they failed at"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	snsPublishFailures, err := meter.Int64Counter("sns_publish_failures_total",
+		metric.WithDescription("SNS publishes that failed, by topic"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &FailureMetrics{deadLettered: deadLettered, snsPublishFailures: snsPublishFailures}, nil
+}
+
+// DeadLettered counts a message dead-lettered at stage, e.g. StageExecute.
+func (m *FailureMetrics) DeadLettered(ctx context.Context, stage string) {
+	if m == nil {
+		return
+	}
+	m.deadLettered.Add(ctx, 1, metric.WithAttributes(attribute.String("stage", stage)))
+}
+
+// SNSPublishFailed counts a failed publish to topicArn.
+func (m *FailureMetrics) SNSPublishFailed(ctx context.Context, topicArn string) {
+	if m == nil {
+		return
+	}
+	m.snsPublishFailures.Add(ctx, 1, metric.WithAttributes(attribute.String("sns.topic.arn", topicArn)))
+}
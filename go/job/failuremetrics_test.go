@@ -0,0 +1,56 @@
+package job
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestFailureMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	metrics, err := NewFailureMetrics(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := context.Background()
+	metrics.DeadLettered(ctx, StageExecute)
+	metrics.DeadLettered(ctx, StageExecute)
+	metrics.DeadLettered(ctx, StageParse)
+	metrics.SNSPublishFailed(ctx, "arn:aws:sns:us-east-1:000000000000:job-end-state-topic")
+
+	var collected metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &collected); err != nil {
+		t.Fatalf("failed to collect metrics: %v", err)
+	}
+	counts := map[string]int64{}
+	for _, scope := range collected.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			for _, point := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				for _, key := range []attribute.Key{"stage", "sns.topic.arn"} {
+					if value, ok := point.Attributes.Value(key); ok {
+						counts[m.Name+" "+value.AsString()] = point.Value
+					}
+				}
+			}
+		}
+	}
+	expected := map[string]int64{
+		"jobs_deadlettered_total execute": 2,
+		"jobs_deadlettered_total parse":   1,
+		"sns_publish_failures_total arn:aws:sns:us-east-1:000000000000:job-end-state-topic": 1,
+	}
+	for name, count := range expected {
+		if counts[name] != count {
+			t.Errorf("expected %s counted %d times, got %d", name, count, counts[name])
+		}
+	}
+
+	// Without metrics configured nothing is recorded, and nothing panics
+	var disabled *FailureMetrics
+	disabled.DeadLettered(ctx, StageExecute)
+	disabled.SNSPublishFailed(ctx, "topic")
+}
@@ -0,0 +1,98 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// Where the services route failed messages, selected with FAILURE_SINK.
+const (
+	FailureSinkDLQ  = "dlq"  // dead-letter queue only
+	FailureSinkSNS  = "sns"  // SNS notification only
+	FailureSinkBoth = "both" // dead-letter queue and SNS notification
+)
+
+// ParseFailureSink validates a FAILURE_SINK value, returning fallback when it
+// is empty or unrecognised.
+func ParseFailureSink(value, fallback string) string {
+	switch sink := strings.ToLower(strings.TrimSpace(value)); sink {
+	case FailureSinkDLQ, FailureSinkSNS, FailureSinkBoth:
+		return sink
+	case "":
+		return fallback
+	default:
+		log.Printf("ignoring invalid FAILURE_SINK %q", value)
+		return fallback
+	}
+}
+
+// FailureRouter routes a failed message to the sinks Sink selects: its
+// end-state event, handed to Publish, and the original body, wrapped with the
+// failure, on the dead-letter queue.
+type FailureRouter struct {
+	Sink        string
+	Publish     func(ctx context.Context, event JobEndStateEvent) error
+	DeadLetters DeadLetterQueue
+
+	// Messages that failed to parse are sent as they are to ParseErrorURL
+	// with ParseErrorClient, so schema problems can be triaged apart from
+	// the other failures. Empty sends them to the dead-letter queue.
+	ParseErrorURL    string
+	ParseErrorClient SQSSender
+}
+
+// Report routes a message that failed at stage, returning what went wrong
+// sending it anywhere.
+func (r FailureRouter) Report(ctx context.Context, stage string, event JobEndStateEvent, messageBody string) error {
+	errs := []error{r.publish(ctx, event)}
+	if r.Sink != FailureSinkSNS {
+		if err := r.DeadLetters.Send(ctx, NewDeadLetterEnvelope(messageBody, stage, event.Error, event.TraceID)); err != nil {
+			errs = append(errs, fmt.Errorf("failed to send message to dead-letter queue: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ReportParseFailure routes a message that failed to parse like Report,
+// except that the body goes to the parse-error queue when one is configured,
+// counted as dead-lettered at stage. Bodies the parse-error queue rejects
+// fall back to the dead-letter queue so they aren't lost.
+func (r FailureRouter) ReportParseFailure(ctx context.Context, stage string, event JobEndStateEvent, messageBody string) error {
+	if r.ParseErrorURL == "" {
+		return r.Report(ctx, stage, event, messageBody)
+	}
+
+	errs := []error{r.publish(ctx, event)}
+	_, err := r.ParseErrorClient.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(r.ParseErrorURL),
+		MessageBody: aws.String(messageBody),
+	})
+	if err == nil {
+		r.DeadLetters.Metrics.DeadLettered(ctx, stage)
+		return errors.Join(errs...)
+	}
+	LoggerWithTrace(ctx, slog.Default()).Warn("failed to send message to parse-error queue, dead-lettering it", "error", err)
+	if err := r.DeadLetters.Send(ctx, NewDeadLetterEnvelope(messageBody, stage, event.Error, event.TraceID)); err != nil {
+		errs = append(errs, fmt.Errorf("failed to send message to dead-letter queue: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// publish hands event to Publish unless failures only go to the dead-letter
+// queue.
+func (r FailureRouter) publish(ctx context.Context, event JobEndStateEvent) error {
+	if r.Sink == FailureSinkDLQ {
+		return nil
+	}
+	if err := r.Publish(ctx, event); err != nil {
+		return fmt.Errorf("failed to publish failure to SNS: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestParseFailureSink(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{value: "", expected: FailureSinkBoth},
+		{value: "dlq", expected: FailureSinkDLQ},
+		{value: "SNS", expected: FailureSinkSNS},
+		{value: " both ", expected: FailureSinkBoth},
+		{value: "email", expected: FailureSinkBoth},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if actual := ParseFailureSink(tt.value, FailureSinkBoth); actual != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFailureRouter(t *testing.T) {
+	const (
+		deadLetters = "deadletter"
+		parseErrors = "parse-errors"
+	)
+	tests := []struct {
+		name            string
+		sink            string
+		parseErrorURL   string
+		parseErrorFails bool
+		publishFails    bool
+		expectPublished int
+		expectQueues    []string
+		expectError     bool
+	}{
+		{name: "Both", sink: FailureSinkBoth, expectPublished: 1, expectQueues: []string{deadLetters}},
+		{name: "Dead-letter queue only", sink: FailureSinkDLQ, expectQueues: []string{deadLetters}},
+		{name: "SNS only", sink: FailureSinkSNS, expectPublished: 1},
+		{name: "Publish failed", sink: FailureSinkBoth, publishFails: true, expectQueues: []string{deadLetters}, expectError: true},
+		{name: "Parse-error queue", sink: FailureSinkBoth, parseErrorURL: parseErrors, expectPublished: 1, expectQueues: []string{parseErrors}},
+		{name: "Parse-error queue with SNS only", sink: FailureSinkSNS, parseErrorURL: parseErrors, expectPublished: 1, expectQueues: []string{parseErrors}},
+		{name: "Parse-error queue rejected", sink: FailureSinkDLQ, parseErrorURL: parseErrors, parseErrorFails: true, expectQueues: []string{parseErrors, deadLetters}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := sdkmetric.NewManualReader()
+			metrics, err := NewFailureMetrics(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			queues := &recordingSQS{}
+			parseErrorClient := SQSSender(queues)
+			if tt.parseErrorFails {
+				parseErrorClient = &rejectingSQS{queues}
+			}
+			published := 0
+			router := FailureRouter{
+				Sink: tt.sink,
+				Publish: func(ctx context.Context, event JobEndStateEvent) error {
+					if tt.publishFails {
+						return errors.New("throttled")
+					}
+					published++
+					return nil
+				},
+				DeadLetters:      DeadLetterQueue{Client: queues, URL: deadLetters, Metrics: metrics},
+				ParseErrorURL:    tt.parseErrorURL,
+				ParseErrorClient: parseErrorClient,
+			}
+
+			event := NewJobEndStateEvent(context.Background(), "", "", StatusRejected, "failed to parse job message")
+			err = router.ReportParseFailure(context.Background(), StageParse, event, `not json`)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("expected error: %v, got %v", tt.expectError, err)
+			}
+
+			if published != tt.expectPublished {
+				t.Errorf("expected %d published, got %d", tt.expectPublished, published)
+			}
+			var sentTo []string
+			for _, message := range queues.sent {
+				sentTo = append(sentTo, aws.ToString(message.QueueUrl))
+			}
+			if !reflect.DeepEqual(sentTo, tt.expectQueues) {
+				t.Errorf("expected messages sent to %v, got %v", tt.expectQueues, sentTo)
+			}
+
+			// Whichever queue the body reached, it is counted once
+			var collected metricdata.ResourceMetrics
+			if err := reader.Collect(context.Background(), &collected); err != nil {
+				t.Fatalf("failed to collect metrics: %v", err)
+			}
+			counted := int64(0)
+			for _, scope := range collected.ScopeMetrics {
+				for _, m := range scope.Metrics {
+					for _, point := range m.Data.(metricdata.Sum[int64]).DataPoints {
+						if stage, _ := point.Attributes.Value("stage"); stage.AsString() == StageParse {
+							counted += point.Value
+						}
+					}
+				}
+			}
+			if expected := int64(min(len(tt.expectQueues), 1)); counted != expected {
+				t.Errorf("expected %d dead letters counted, got %d", expected, counted)
+			}
+		})
+	}
+}
+
+// rejectingSQS records the messages sent to it and fails them
+type rejectingSQS struct {
+	*recordingSQS
+}
+
+func (r *rejectingSQS) SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	r.recordingSQS.SendMessage(ctx, params, optFns...)
+	return nil, errors.New("access denied")
+}
@@ -0,0 +1,34 @@
+package job
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// groupIDPattern is what SQS accepts as a MessageGroupId: up to 128
+// alphanumeric or punctuation characters.
+var groupIDPattern = regexp.MustCompile(`^[[:alnum:][:punct:]]{1,128}$`)
+
+// MessageGroupID returns the FIFO message group a job message belongs to: its
+// group_id, or its job type when it has none, so jobs of a type run in order.
+// It is empty when the message can't be parsed.
+func MessageGroupID(message []byte) string {
+	var jobMessage JobMessage
+	if err := json.Unmarshal(message, &jobMessage); err != nil {
+		return ""
+	}
+	if jobMessage.GroupID != "" {
+		return jobMessage.GroupID
+	}
+	return jobMessage.JobType
+}
+
+// validateGroupID checks a job message's group_id, if it has one, is a
+// MessageGroupId SQS accepts.
+func validateGroupID(groupID string) error {
+	if groupID != "" && !groupIDPattern.MatchString(groupID) {
+		return fmt.Errorf("group_id %q must be 1 to 128 alphanumeric or punctuation characters", groupID)
+	}
+	return nil
+}
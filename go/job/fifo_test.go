@@ -0,0 +1,49 @@
+package job
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMessageGroupID(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		expected string
+	}{
+		{name: "Explicit group", message: `{"job_type": "data_cleanup", "message": {}, "group_id": "acme-orders"}`, expected: "acme-orders"},
+		{name: "Defaults to the job type", message: `{"job_type": "data_cleanup", "message": {}}`, expected: "data_cleanup"},
+		{name: "Unparseable", message: `not json`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := MessageGroupID([]byte(tt.message)); actual != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestParseJobGroupID(t *testing.T) {
+	tests := []struct {
+		name        string
+		groupID     string
+		expectError bool
+	}{
+		{name: "No group", groupID: ""},
+		{name: "Valid group", groupID: "tenant-42/orders"},
+		{name: "Whitespace", groupID: "acme orders", expectError: true},
+		{name: "Too long", groupID: strings.Repeat("g", 129), expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := `{"job_type": "data_cleanup", "message": {"target_table": "logs", "retention": 30}, "group_id": "` + tt.groupID + `"}`
+			_, _, _, err := ParseJob([]byte(message))
+			if tt.expectError != (err != nil) {
+				t.Errorf("expected error %v, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
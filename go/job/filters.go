@@ -0,0 +1,28 @@
+package job
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StrictFilters makes report_generation jobs reject filters that aren't
+// comma separated key=value pairs, e.g. "region=US,year=2025". When false
+// (the default) filters are any non-empty string.
+var StrictFilters bool
+
+// ParseFilters parses comma separated key=value report filters.
+func ParseFilters(filters string) (map[string]string, error) {
+	parsed := map[string]string{}
+	for i, pair := range strings.Split(filters, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || key == "" || value == "" {
+			return nil, fmt.Errorf("filter %d %q is not a key=value pair", i, strings.TrimSpace(pair))
+		}
+		if _, duplicate := parsed[key]; duplicate {
+			return nil, fmt.Errorf("filter %q is given more than once", key)
+		}
+		parsed[key] = value
+	}
+	return parsed, nil
+}
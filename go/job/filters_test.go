@@ -0,0 +1,68 @@
+package job
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFilters(t *testing.T) {
+	tests := []struct {
+		name        string
+		filters     string
+		expected    map[string]string
+		expectError bool
+	}{
+		{name: "Single pair", filters: "region=US", expected: map[string]string{"region": "US"}},
+		{name: "Several pairs", filters: "region=US, year=2025", expected: map[string]string{"region": "US", "year": "2025"}},
+		{name: "Value containing equals", filters: "query=a=b", expected: map[string]string{"query": "a=b"}},
+		{name: "No equals", filters: "region", expectError: true},
+		{name: "Missing key", filters: "=US", expectError: true},
+		{name: "Missing value", filters: "region=", expectError: true},
+		{name: "Trailing comma", filters: "region=US,", expectError: true},
+		{name: "Duplicate key", filters: "region=US,region=EU", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := ParseFilters(tt.filters)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestStrictFilters(t *testing.T) {
+	tests := []struct {
+		name        string
+		filters     string
+		strict      bool
+		expectError bool
+	}{
+		{name: "Valid filters strict", filters: "region=US", strict: true},
+		{name: "Malformed filters strict", filters: "region US", strict: true, expectError: true},
+		{name: "Malformed filters lenient", filters: "region US", strict: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := StrictFilters
+			StrictFilters = tt.strict
+			defer func() { StrictFilters = previous }()
+
+			err := ReportGenerationJob{ReportName: "Sales Report", Filters: tt.filters}.Validate()
+			if tt.expectError != (err != nil) {
+				t.Errorf("expected error %v, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
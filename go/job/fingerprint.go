@@ -0,0 +1,51 @@
+package job
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SchemaFingerprint derives a short fingerprint of a job's field set from its
+// struct definition: each JSON field name and Go type. Producers and
+// consumers built from different definitions get different fingerprints.
+func SchemaFingerprint(job Job) string {
+	t := reflect.TypeOf(job)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, name+":"+field.Type.String())
+	}
+	sort.Strings(fields)
+	sum := sha256.Sum256([]byte(t.Name() + "{" + strings.Join(fields, ";") + "}"))
+	return hex.EncodeToString(sum[:8])
+}
+
+// CheckSchemaFingerprint compares the fingerprint a producer recorded on
+// payload against this build's fingerprint for job. Payloads without a
+// fingerprint always match.
+func CheckSchemaFingerprint(payload EnrichedPayload, job Job) error {
+	if payload.SchemaFingerprint == "" {
+		return nil
+	}
+	if expected := SchemaFingerprint(job); payload.SchemaFingerprint != expected {
+		return fmt.Errorf("schema fingerprint %s does not match expected %s", payload.SchemaFingerprint, expected)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package job
+
+import (
+	"context"
+	"testing"
+)
+
+// driftedCleanupJob is DataCleanupJob as a producer with an extra field sees it
+type driftedCleanupJob struct {
+	TargetTable string `json:"target_table"`
+	Retention   int    `json:"retention"`
+	DryRun      bool   `json:"dry_run"`
+}
+
+func (driftedCleanupJob) Validate() error                                { return nil }
+func (driftedCleanupJob) Execute(ctx context.Context) (JobResult, error) { return JobResult{}, nil }
+func (driftedCleanupJob) Name() JobType                                  { return DataCleanup }
+
+func TestSchemaFingerprint(t *testing.T) {
+	cleanup := DataCleanupJob{TargetTable: "users", Retention: 30}
+	if SchemaFingerprint(cleanup) != SchemaFingerprint(DataCleanupJob{}) {
+		t.Errorf("expected the fingerprint to depend only on the struct definition")
+	}
+	if SchemaFingerprint(cleanup) != SchemaFingerprint(&cleanup) {
+		t.Errorf("expected pointers to share the fingerprint of their struct")
+	}
+	if SchemaFingerprint(cleanup) == SchemaFingerprint(ReportGenerationJob{}) {
+		t.Errorf("expected different job types to have different fingerprints")
+	}
+	if SchemaFingerprint(cleanup) == SchemaFingerprint(driftedCleanupJob{}) {
+		t.Errorf("expected a changed field set to change the fingerprint")
+	}
+}
+
+func TestCheckSchemaFingerprint(t *testing.T) {
+	job := DataCleanupJob{TargetTable: "users", Retention: 30}
+
+	tests := []struct {
+		name        string
+		fingerprint string
+		expectError bool
+	}{
+		{name: "Matching", fingerprint: SchemaFingerprint(job)},
+		{name: "Drifted", fingerprint: SchemaFingerprint(driftedCleanupJob{}), expectError: true},
+		{name: "Not recorded", fingerprint: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckSchemaFingerprint(EnrichedPayload{SchemaFingerprint: tt.fingerprint}, job)
+			if tt.expectError != (err != nil) {
+				t.Errorf("expected error %v, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
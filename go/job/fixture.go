@@ -0,0 +1,18 @@
+package job
+
+import (
+	"encoding/json"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// FixtureAttributes returns the demo.fixture span attribute for a job message
+// tagged by the generator. It returns nothing for untagged or unparseable
+// messages so it can be applied before the job itself is validated.
+func FixtureAttributes(message []byte) []attribute.KeyValue {
+	var jobMessage JobMessage
+	if err := json.Unmarshal(message, &jobMessage); err != nil || jobMessage.Fixture == "" {
+		return nil
+	}
+	return []attribute.KeyValue{attribute.String("demo.fixture", jobMessage.Fixture)}
+}
@@ -0,0 +1,54 @@
+package job
+
+import (
+	"testing"
+)
+
+func TestFixtureAttributes(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Tagged message",
+			input:    `{"job_type":"does_not_exist","message":{},"fixture":"bad_jobs.json#1"}`,
+			expected: "bad_jobs.json#1",
+		},
+		{
+			name:  "Untagged message",
+			input: `{"job_type":"data_cleanup","message":{"target_table":"users","retention":30}}`,
+		},
+		{
+			name:  "Unparseable message",
+			input: `not json`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs := FixtureAttributes([]byte(tt.input))
+			if tt.expected == "" {
+				if len(attrs) != 0 {
+					t.Errorf("expected no attributes, got %v", attrs)
+				}
+				return
+			}
+			if len(attrs) != 1 || attrs[0].Key != "demo.fixture" || attrs[0].Value.AsString() != tt.expected {
+				t.Errorf("expected demo.fixture=%s, got %v", tt.expected, attrs)
+			}
+		})
+	}
+}
+
+func TestFixtureSurvivesParseJob(t *testing.T) {
+	input := `{"job_type":"data_cleanup","message":{"target_table":"users","retention":30},"fixture":"good_jobs.json#1"}`
+	_, originalMessage, _, err := ParseJob([]byte(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	attrs := FixtureAttributes(originalMessage)
+	if len(attrs) != 1 || attrs[0].Value.AsString() != "good_jobs.json#1" {
+		t.Errorf("expected the fixture to survive parsing, got %v", attrs)
+	}
+}
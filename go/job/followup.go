@@ -0,0 +1,58 @@
+package job
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// MaxChainDepth is how many follow-ups deep a chain of jobs may go, so jobs
+// whose follow-ups queue follow-ups of their own can't loop forever.
+var MaxChainDepth = 3
+
+// ErrChainTooDeep is returned for follow-ups that would be queued deeper in
+// their chain than MaxChainDepth.
+var ErrChainTooDeep = errors.New("job_chain_too_deep")
+
+// FollowupID derives the ID of the follow-up at index queued by a job, so a
+// redelivered job queues follow-ups that are recognised as duplicates.
+func FollowupID(parentID string, index int) string {
+	return fmt.Sprintf("%s-followup-%d", parentID, index)
+}
+
+// ChainFollowups turns the follow-ups a job returned into one EnrichedPayload
+// each, to be queued as jobs of their own one link further down the chain.
+// They are timestamped now, continue the trace with span as their parent,
+// inherit the job's baggage and correlation ID and reference it through
+// ParentID.
+func ChainFollowups(parent EnrichedPayload, followups []JobMessage, clock Clock, span trace.Span) ([]EnrichedPayload, error) {
+	depth := parent.ChainDepth + 1
+	if depth > MaxChainDepth {
+		return nil, fmt.Errorf("%w: follow-ups of job %s would be %d deep, more than the maximum of %d", ErrChainTooDeep, parent.ID, depth, MaxChainDepth)
+	}
+
+	payloads := make([]EnrichedPayload, 0, len(followups))
+	for i, followup := range followups {
+		originalMessage, err := json.Marshal(followup)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal follow-up %d: %w", i, err)
+		}
+		payloads = append(payloads, EnrichedPayload{
+			OriginalMessage: originalMessage,
+			ID:              FollowupID(parent.ID, i),
+			Timestamp:       clock.Now().Format(time.RFC3339),
+			Status:          StatusNew,
+			TraceContext:    InjectTraceparent(span.SpanContext()),
+			TraceState:      InjectTracestate(span.SpanContext()),
+			BaggageContext:  parent.BaggageContext,
+			ParentID:        parent.ID,
+			ChainDepth:      depth,
+			CorrelationID:   parent.CorrelationID,
+			SchemaVersion:   CurrentSchemaVersion,
+		})
+	}
+	return payloads, nil
+}
@@ -0,0 +1,86 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestUserOnboardingFollowups(t *testing.T) {
+	tests := []struct {
+		name            string
+		job             UserOnboardingJob
+		expectFollowups int
+	}{
+		{name: "Without an email", job: UserOnboardingJob{UserID: "user-001", UserName: "John Doe"}},
+		{name: "With an email", job: UserOnboardingJob{UserID: "user-001", UserName: "John Doe", Email: "john@example.com"}, expectFollowups: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.job.Execute(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(result.Followups) != tt.expectFollowups {
+				t.Fatalf("expected %d follow-ups, got %v", tt.expectFollowups, result.Followups)
+			}
+			for _, followup := range result.Followups {
+				job, _, _, err := ParseJob([]byte(followup.String()))
+				if err != nil {
+					t.Fatalf("expected a valid follow-up, got %v", err)
+				}
+				if email, ok := job.(EmailNotificationJob); !ok || email.Recipient != tt.job.Email {
+					t.Errorf("expected a welcome email to %s, got %+v", tt.job.Email, job)
+				}
+			}
+		})
+	}
+}
+
+func TestInvalidOnboardingEmail(t *testing.T) {
+	if err := (UserOnboardingJob{UserID: "user-001", UserName: "John Doe", Email: "john"}).Validate(); err == nil {
+		t.Errorf("expected an email without an @ to fail validation")
+	}
+}
+
+func TestChainFollowups(t *testing.T) {
+	clock := fixedClock(time.Date(2025, 8, 30, 12, 5, 0, 0, time.UTC))
+	_, span := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "ExecuteJob")
+	defer span.End()
+
+	parent := EnrichedPayload{ID: "12345", Timestamp: "2025-08-30T12:00:00Z", Status: StatusCompleted, BaggageContext: "tenant.id=acme", ChainDepth: 1}
+	welcome, _ := json.Marshal(EmailNotificationJob{Recipient: "john@example.com", Subject: "Welcome"})
+	followups := []JobMessage{
+		{JobType: string(EmailNotification), Message: welcome},
+		{JobType: string(EmailNotification), Message: welcome},
+	}
+
+	payloads, err := ChainFollowups(parent, followups, clock, span)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(payloads) != 2 {
+		t.Fatalf("expected 2 follow-ups, got %d", len(payloads))
+	}
+	for i, payload := range payloads {
+		if payload.ID != FollowupID("12345", i) || payload.ParentID != "12345" || payload.Status != StatusNew || payload.ChainDepth != 2 {
+			t.Errorf("follow-up %d: expected a NEW job chained from 12345 at depth 2, got %+v", i, payload)
+		}
+		if payload.Timestamp != "2025-08-30T12:05:00Z" || payload.BaggageContext != parent.BaggageContext {
+			t.Errorf("follow-up %d: expected it timestamped now with the parent's baggage, got %+v", i, payload)
+		}
+		if spanContext, err := ParseTraceContext(payload.TraceContext, payload.TraceState); err != nil || spanContext.SpanID() != span.SpanContext().SpanID() {
+			t.Errorf("follow-up %d: expected the executing span as its trace parent, got %s", i, payload.TraceContext)
+		}
+	}
+
+	parent.ChainDepth = MaxChainDepth
+	if _, err := ChainFollowups(parent, followups, clock, span); !errors.Is(err, ErrChainTooDeep) {
+		t.Errorf("expected follow-ups past MaxChainDepth to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package job
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// HeartbeatInterval is how often a long-running job reports that it is still
+// alive while executing. Zero (the default) disables heartbeats.
+var HeartbeatInterval time.Duration
+
+var heartbeatCounter metric.Int64Counter
+
+func init() {
+	var err error
+	heartbeatCounter, err = otel.Meter("jobs").Int64Counter("job_heartbeats_total",
+		metric.WithDescription("Heartbeats emitted by long-running jobs while executing"),
+	)
+	if err != nil {
+		log.Printf("failed to create heartbeat counter: %v", err)
+	}
+}
+
+// startHeartbeat emits a "heartbeat" span event and counter increment every
+// interval until the returned stop func is called or ctx is cancelled.
+func startHeartbeat(ctx context.Context, interval time.Duration, taskName string) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		start := time.Now()
+		beats := 0
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				beats++
+				trace.SpanFromContext(ctx).AddEvent("heartbeat", trace.WithAttributes(
+					attribute.String("task.name", taskName),
+					attribute.Int("heartbeat.count", beats),
+					attribute.Int64("heartbeat.elapsed_ms", time.Since(start).Milliseconds()),
+				))
+				if heartbeatCounter != nil {
+					heartbeatCounter.Add(ctx, 1, metric.WithAttributes(
+						attribute.String("job.type", string(LongRunning)),
+					))
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
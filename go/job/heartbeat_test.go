@@ -0,0 +1,87 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func countEvents(span sdktrace.ReadOnlySpan, name string) int {
+	count := 0
+	for _, event := range span.Events() {
+		if event.Name == name {
+			count++
+		}
+	}
+	return count
+}
+
+func TestLongRunningJobHeartbeat(t *testing.T) {
+	tests := []struct {
+		name        string
+		interval    time.Duration
+		cancelAfter time.Duration
+		expectError error
+		minBeats    int
+		maxBeats    int
+	}{
+		{
+			name:     "Heartbeats disabled",
+			interval: 0,
+			minBeats: 0,
+			maxBeats: 0,
+		},
+		{
+			name:     "Heartbeats at 100ms over a 1s job",
+			interval: 100 * time.Millisecond,
+			minBeats: 8,
+			maxBeats: 10,
+		},
+		{
+			name:        "Heartbeats stop on cancellation",
+			interval:    100 * time.Millisecond,
+			cancelAfter: 350 * time.Millisecond,
+			expectError: context.Canceled,
+			minBeats:    2,
+			maxBeats:    4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := HeartbeatInterval
+			HeartbeatInterval = tt.interval
+			defer func() { HeartbeatInterval = previous }()
+
+			recorder := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancelAfter > 0 {
+				time.AfterFunc(tt.cancelAfter, cancel)
+			}
+
+			ctx, span := tp.Tracer("test").Start(ctx, "ExecuteJob")
+			_, err := LongRunningJob{TaskName: "Data Migration", Timeout: 1}.Execute(ctx)
+			span.End()
+
+			if !errors.Is(err, tt.expectError) {
+				t.Errorf("expected error %v, got %v", tt.expectError, err)
+			}
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 span, got %d", len(spans))
+			}
+			beats := countEvents(spans[0], "heartbeat")
+			if beats < tt.minBeats || beats > tt.maxBeats {
+				t.Errorf("expected between %d and %d heartbeats, got %d", tt.minBeats, tt.maxBeats, beats)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package job
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// InFlightJob is a job that is currently executing.
+type InFlightJob struct {
+	ID      string        `json:"id"`
+	JobType string        `json:"job_type"`
+	Started time.Time     `json:"started"`
+	Elapsed time.Duration `json:"elapsed"`
+}
+
+// InFlightRegistry tracks the jobs currently executing so stuck ones can be
+// spotted. It is safe for concurrent use.
+type InFlightRegistry struct {
+	clock Clock
+	mu    sync.Mutex
+	jobs  map[string]InFlightJob
+}
+
+// NewInFlightRegistry creates an empty registry timed by clock.
+func NewInFlightRegistry(clock Clock) *InFlightRegistry {
+	return &InFlightRegistry{clock: clock, jobs: map[string]InFlightJob{}}
+}
+
+// Add records that the job with id has started executing.
+func (r *InFlightRegistry) Add(id, jobType string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs[id] = InFlightJob{ID: id, JobType: jobType, Started: r.clock.Now()}
+}
+
+// Remove records that the job with id has finished executing.
+func (r *InFlightRegistry) Remove(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.jobs, id)
+}
+
+// Snapshot returns the in-flight jobs, longest running first, with their
+// elapsed time as of now.
+func (r *InFlightRegistry) Snapshot() []InFlightJob {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.clock.Now()
+	snapshot := make([]InFlightJob, 0, len(r.jobs))
+	for _, job := range r.jobs {
+		job.Elapsed = now.Sub(job.Started)
+		snapshot = append(snapshot, job)
+	}
+	sort.Slice(snapshot, func(i, j int) bool {
+		if snapshot[i].Started.Equal(snapshot[j].Started) {
+			return snapshot[i].ID < snapshot[j].ID
+		}
+		return snapshot[i].Started.Before(snapshot[j].Started)
+	})
+	return snapshot
+}
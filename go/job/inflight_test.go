@@ -0,0 +1,64 @@
+package job
+
+import (
+	"testing"
+	"time"
+)
+
+// steppedClock is a Clock that only moves when advanced
+type steppedClock struct {
+	now time.Time
+}
+
+func (c *steppedClock) Now() time.Time { return c.now }
+
+func (c *steppedClock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func TestInFlightRegistry(t *testing.T) {
+	clock := &steppedClock{now: time.Date(2025, 8, 30, 12, 0, 0, 0, time.UTC)}
+	registry := NewInFlightRegistry(clock)
+
+	if snapshot := registry.Snapshot(); len(snapshot) != 0 {
+		t.Fatalf("expected empty registry, got %v", snapshot)
+	}
+
+	registry.Add("job-1", string(LongRunning))
+	clock.advance(30 * time.Second)
+	registry.Add("job-2", string(ReportGeneration))
+	clock.advance(10 * time.Second)
+
+	snapshot := registry.Snapshot()
+	expected := []struct {
+		id      string
+		elapsed time.Duration
+	}{
+		{id: "job-1", elapsed: 40 * time.Second},
+		{id: "job-2", elapsed: 10 * time.Second},
+	}
+	if len(snapshot) != len(expected) {
+		t.Fatalf("expected %d in-flight jobs, got %d", len(expected), len(snapshot))
+	}
+	for i, want := range expected {
+		if snapshot[i].ID != want.id {
+			t.Errorf("job %d: expected ID %s, got %s", i, want.id, snapshot[i].ID)
+		}
+		if snapshot[i].Elapsed != want.elapsed {
+			t.Errorf("job %d: expected elapsed %s, got %s", i, want.elapsed, snapshot[i].Elapsed)
+		}
+	}
+
+	registry.Remove("job-1")
+	registry.Remove("unknown")
+	snapshot = registry.Snapshot()
+	if len(snapshot) != 1 || snapshot[0].ID != "job-2" {
+		t.Fatalf("expected only job-2 in flight, got %v", snapshot)
+	}
+	if snapshot[0].JobType != string(ReportGeneration) {
+		t.Errorf("expected job type %s, got %s", ReportGeneration, snapshot[0].JobType)
+	}
+
+	registry.Remove("job-2")
+	if snapshot := registry.Snapshot(); len(snapshot) != 0 {
+		t.Errorf("expected empty registry, got %v", snapshot)
+	}
+}
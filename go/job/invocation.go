@@ -0,0 +1,35 @@
+package job
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// InvocationRecorder reads the Lambda request ID of the invocation a context
+// belongs to, so spans and logs can be correlated with CloudWatch.
+type InvocationRecorder struct {
+	Record bool // report the request ID, otherwise report none
+}
+
+// ID returns the Lambda request ID carried by ctx, if recording it is
+// enabled.
+func (r InvocationRecorder) ID(ctx context.Context) (string, bool) {
+	if !r.Record {
+		return "", false
+	}
+	lc, ok := lambdacontext.FromContext(ctx)
+	if !ok || lc.AwsRequestID == "" {
+		return "", false
+	}
+	return lc.AwsRequestID, true
+}
+
+// Attributes tags a span with the Lambda request ID, if known.
+func (r InvocationRecorder) Attributes(ctx context.Context) []attribute.KeyValue {
+	if id, ok := r.ID(ctx); ok {
+		return []attribute.KeyValue{attribute.String("faas.invocation_id", id)}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package job
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+func TestInvocationRecorder(t *testing.T) {
+	invocation := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: "req-1"})
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		record     bool
+		expectedID string
+	}{
+		{name: "Recorded", ctx: invocation, record: true, expectedID: "req-1"},
+		{name: "Disabled", ctx: invocation},
+		{name: "Outside Lambda", ctx: context.Background(), record: true},
+		{name: "Empty request ID", ctx: lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{}), record: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := InvocationRecorder{Record: tt.record}
+			id, ok := recorder.ID(tt.ctx)
+			if id != tt.expectedID || ok != (tt.expectedID != "") {
+				t.Errorf("expected request ID %q, got %q, %v", tt.expectedID, id, ok)
+			}
+			attributes := recorder.Attributes(tt.ctx)
+			if tt.expectedID == "" {
+				if len(attributes) != 0 {
+					t.Errorf("expected no attributes, got %v", attributes)
+				}
+				return
+			}
+			if len(attributes) != 1 || attributes[0].Key != "faas.invocation_id" || attributes[0].Value.AsString() != tt.expectedID {
+				t.Errorf("expected faas.invocation_id %s, got %v", tt.expectedID, attributes)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package job
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// NewJobMessage builds the JobMessage submitting payload as a jobType job,
+// validating payload first, so Go code can create jobs without writing their
+// JSON by hand.
+func NewJobMessage(jobType JobType, payload Job) (JobMessage, error) {
+	if payload == nil {
+		return JobMessage{}, errors.New("cannot build a job message without a payload")
+	}
+	if _, ok := jobFactory(jobType); !ok {
+		return JobMessage{}, fmt.Errorf("unknown job type: %s", jobType)
+	}
+	if payload.Name() != jobType {
+		return JobMessage{}, fmt.Errorf("cannot submit a %s payload as a %s job", payload.Name(), jobType)
+	}
+	if err := payload.Validate(); err != nil {
+		return JobMessage{}, fmt.Errorf("invalid %s job: %w", jobType, err)
+	}
+
+	message, err := json.Marshal(payload)
+	if err != nil {
+		return JobMessage{}, fmt.Errorf("failed to marshal %s job: %w", jobType, err)
+	}
+	return JobMessage{JobType: string(jobType), Message: message}, nil
+}
+
+// Parse parses and validates the job jm submits, as ParseJob does for its
+// JSON.
+func (jm JobMessage) Parse() (Job, error) {
+	message, err := json.Marshal(jm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal job message: %w", err)
+	}
+	job, _, _, err := ParseJob(message)
+	return job, err
+}
@@ -0,0 +1,66 @@
+package job
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewJobMessageRoundTrip(t *testing.T) {
+	tests := []Job{
+		ReportGenerationJob{ReportName: "Sales Report", Filters: "region=US"},
+		DataCleanupJob{TargetTable: "users", Retention: 30},
+		UserOnboardingJob{UserID: "user-001", UserName: "John Doe"},
+		LongRunningJob{TaskName: "Data Migration", Timeout: 60},
+		EmailNotificationJob{Recipient: "jane@example.com", Subject: "Welcome", Body: "Hello"},
+	}
+
+	for _, payload := range tests {
+		t.Run(string(payload.Name()), func(t *testing.T) {
+			jm, err := NewJobMessage(payload.Name(), payload)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if jm.JobType != string(payload.Name()) {
+				t.Errorf("expected job_type %s, got %s", payload.Name(), jm.JobType)
+			}
+			parsed, err := jm.Parse()
+			if err != nil {
+				t.Fatalf("unexpected error parsing %s: %v", jm, err)
+			}
+			if !reflect.DeepEqual(parsed, payload) {
+				t.Errorf("expected %+v back, got %+v", payload, parsed)
+			}
+		})
+	}
+}
+
+func TestNewJobMessageErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		jobType       JobType
+		payload       Job
+		expectedError string
+	}{
+		{name: "Invalid payload", jobType: DataCleanup, payload: DataCleanupJob{Retention: 30}, expectedError: "invalid data_cleanup job"},
+		{name: "Mismatched type", jobType: ReportGeneration, payload: DataCleanupJob{TargetTable: "users", Retention: 30}, expectedError: "cannot submit a data_cleanup payload as a report_generation job"},
+		{name: "Unknown type", jobType: "video_transcode", payload: DataCleanupJob{TargetTable: "users", Retention: 30}, expectedError: "unknown job type"},
+		{name: "No payload", jobType: DataCleanup, expectedError: "without a payload"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewJobMessage(tt.jobType, tt.payload)
+			if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+				t.Errorf("expected error containing %q, got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestJobMessageParseRejectsInvalidJobs(t *testing.T) {
+	jm := JobMessage{JobType: string(DataCleanup), Message: []byte(`{"retention": 30}`)}
+	if _, err := jm.Parse(); err == nil || !strings.Contains(err.Error(), "target_table") {
+		t.Errorf("expected the missing target_table reported, got %v", err)
+	}
+}
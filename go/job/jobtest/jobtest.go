@@ -0,0 +1,115 @@
+// Package jobtest builds the SQS events the ingester and processor lambdas
+// are invoked with, so tests can start from jobs rather than hand-written
+// event JSON.
+package jobtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// The envelope the jobs are delivered in, as the generator sends them to the
+// demo's LocalStack event bus by default.
+const (
+	Source     = "jobs"
+	DetailType = "JobEvent"
+	AccountID  = "000000000000"
+	Region     = "us-east-1"
+)
+
+// Time is when every event built here was sent, and when enriched payloads
+// were ingested.
+var Time = time.Date(2025, 8, 30, 12, 0, 0, 0, time.UTC)
+
+type fixedClock struct{}
+
+func (fixedClock) Now() time.Time { return Time }
+
+// NewSQSEventFromJobs builds the event the ingester is invoked with for jobs,
+// each wrapped in its EventBridge event as the rule queues it. The records
+// have message IDs sqs-1, sqs-2 and so on, their event IDs eb-1, eb-2 and
+// so on. A job that can't be marshalled panics, as the test is wrong.
+func NewSQSEventFromJobs(jobs ...joblib.JobMessage) events.SQSEvent {
+	records := make([]events.SQSMessage, len(jobs))
+	for i, jobMessage := range jobs {
+		detail, err := json.Marshal(jobMessage)
+		if err != nil {
+			panic(fmt.Sprintf("jobtest: failed to marshal job %d: %v", i, err))
+		}
+		body, err := json.Marshal(events.EventBridgeEvent{
+			Version:    "0",
+			ID:         fmt.Sprintf("eb-%d", i+1),
+			DetailType: DetailType,
+			Source:     Source,
+			AccountID:  AccountID,
+			Time:       Time,
+			Region:     Region,
+			Resources:  []string{},
+			Detail:     detail,
+		})
+		if err != nil {
+			panic(fmt.Sprintf("jobtest: failed to marshal event %d: %v", i, err))
+		}
+		records[i] = sqsMessage(i, "ingester", string(body))
+	}
+	return events.SQSEvent{Records: records}
+}
+
+// NewEnrichedSQSEvent builds the event the processor is invoked with for
+// jobs, each enriched as the ingester queues it on jobs-todo. Each payload
+// takes its ID from the ingester's message, sqs-1, sqs-2 and so on, and
+// carries no trace context. A job that can't be enriched panics.
+func NewEnrichedSQSEvent(jobs ...joblib.JobMessage) events.SQSEvent {
+	payloads := make([]joblib.EnrichedPayload, len(jobs))
+	for i, jobMessage := range jobs {
+		detail, err := json.Marshal(jobMessage)
+		if err != nil {
+			panic(fmt.Sprintf("jobtest: failed to marshal job %d: %v", i, err))
+		}
+		payload, err := joblib.Enrich(detail, fmt.Sprintf("sqs-%d", i+1), fixedClock{}, trace.SpanFromContext(context.Background()))
+		if err != nil {
+			panic(fmt.Sprintf("jobtest: failed to enrich job %d: %v", i, err))
+		}
+		payload.EventBridgeID = fmt.Sprintf("eb-%d", i+1)
+		payloads[i] = payload
+	}
+	return NewSQSEventFromPayloads(payloads...)
+}
+
+// NewSQSEventFromPayloads builds the event the processor is invoked with for
+// payloads already enriched, e.g. retries or batch children.
+func NewSQSEventFromPayloads(payloads ...joblib.EnrichedPayload) events.SQSEvent {
+	records := make([]events.SQSMessage, len(payloads))
+	for i, payload := range payloads {
+		body, err := json.Marshal(payload)
+		if err != nil {
+			panic(fmt.Sprintf("jobtest: failed to marshal payload %d: %v", i, err))
+		}
+		records[i] = sqsMessage(i, "jobs-todo", string(body))
+	}
+	return events.SQSEvent{Records: records}
+}
+
+// sqsMessage is the ith record received from queue, on its first delivery.
+func sqsMessage(i int, queue, body string) events.SQSMessage {
+	return events.SQSMessage{
+		MessageId:     fmt.Sprintf("sqs-%d", i+1),
+		ReceiptHandle: fmt.Sprintf("receipt-%d", i+1),
+		Body:          body,
+		Attributes: map[string]string{
+			"ApproximateReceiveCount":          "1",
+			"SentTimestamp":                    strconv.FormatInt(Time.UnixMilli(), 10),
+			"ApproximateFirstReceiveTimestamp": strconv.FormatInt(Time.UnixMilli(), 10),
+		},
+		EventSource:    "aws:sqs",
+		EventSourceARN: fmt.Sprintf("arn:aws:sqs:%s:%s:%s", Region, AccountID, queue),
+		AWSRegion:      Region,
+	}
+}
@@ -0,0 +1,69 @@
+package jobtest
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	joblib "github.com/jherbage/Event-Driven-Microservices-with-Observability-Demo/go/job"
+)
+
+var (
+	cleanup = joblib.JobMessage{JobType: string(joblib.DataCleanup), Message: json.RawMessage(`{"target_table":"users","retention":30}`)}
+	report  = joblib.JobMessage{JobType: string(joblib.ReportGeneration), Message: json.RawMessage(`{"report_name":"Sales Report","filters":"region=US"}`)}
+)
+
+func TestNewSQSEventFromJobs(t *testing.T) {
+	event := NewSQSEventFromJobs(cleanup, report)
+	if len(event.Records) != 2 {
+		t.Fatalf("expected a record per job, got %d", len(event.Records))
+	}
+
+	for i, expected := range []joblib.JobMessage{cleanup, report} {
+		record := event.Records[i]
+		if id := []string{"sqs-1", "sqs-2"}[i]; record.MessageId != id {
+			t.Errorf("expected message ID %s, got %s", id, record.MessageId)
+		}
+		var envelope events.EventBridgeEvent
+		if err := json.Unmarshal([]byte(record.Body), &envelope); err != nil {
+			t.Fatalf("expected an EventBridge event body, got %v", err)
+		}
+		if envelope.Source != Source || envelope.DetailType != DetailType || envelope.ID != []string{"eb-1", "eb-2"}[i] || !envelope.Time.Equal(Time) {
+			t.Errorf("expected the generator's envelope, got %+v", envelope)
+		}
+		job, _, jobType, err := joblib.ParseJob(envelope.Detail)
+		if err != nil {
+			t.Fatalf("expected the detail to parse as the job, got %v", err)
+		}
+		if *jobType != expected.JobType || job.Name() != joblib.JobType(expected.JobType) {
+			t.Errorf("expected a %s job, got %s", expected.JobType, *jobType)
+		}
+	}
+}
+
+func TestNewEnrichedSQSEvent(t *testing.T) {
+	event := NewEnrichedSQSEvent(cleanup, report)
+	if len(event.Records) != 2 {
+		t.Fatalf("expected a record per job, got %d", len(event.Records))
+	}
+
+	for i, expected := range []joblib.JobMessage{cleanup, report} {
+		job, payload, jobType, err := joblib.ParseEnrichedPayload([]byte(event.Records[i].Body))
+		if err != nil {
+			t.Fatalf("expected the body to parse as an enriched payload, got %v", err)
+		}
+		if *jobType != expected.JobType || job.Name() != joblib.JobType(expected.JobType) {
+			t.Errorf("expected a %s job, got %s", expected.JobType, *jobType)
+		}
+		if payload.ID != []string{"sqs-1", "sqs-2"}[i] || payload.EventBridgeID != []string{"eb-1", "eb-2"}[i] {
+			t.Errorf("expected the payload to carry the ingester's IDs, got %+v", payload)
+		}
+		if payload.Status != joblib.StatusNew || payload.Timestamp != Time.Format(time.RFC3339) || payload.SchemaVersion != joblib.CurrentSchemaVersion {
+			t.Errorf("expected a NEW payload ingested at %s, got %+v", Time, payload)
+		}
+		if err := joblib.CheckSchemaVersion(*payload); err != nil {
+			t.Errorf("expected a payload the processor accepts, got %v", err)
+		}
+	}
+}
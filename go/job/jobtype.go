@@ -0,0 +1,17 @@
+package job
+
+import "strings"
+
+// NormalizeJobType makes ParseJob match job types case-insensitively, so
+// "Report_Generation" and "REPORT_GENERATION" resolve to report_generation.
+// When false (the default) job types must match exactly.
+var NormalizeJobType bool
+
+// resolveJobType returns the JobType a job_type field refers to, normalizing
+// it first when NormalizeJobType is enabled.
+func resolveJobType(jobType string) JobType {
+	if NormalizeJobType {
+		return JobType(strings.ToLower(strings.TrimSpace(jobType)))
+	}
+	return JobType(jobType)
+}
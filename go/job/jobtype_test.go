@@ -0,0 +1,65 @@
+package job
+
+import (
+	"testing"
+)
+
+func TestNormalizeJobType(t *testing.T) {
+	tests := []struct {
+		name         string
+		jobType      string
+		normalize    bool
+		expectError  bool
+		expectedType string
+	}{
+		{name: "Exact match strict", jobType: "report_generation", normalize: false, expectedType: "report_generation"},
+		{name: "Title case strict", jobType: "Report_Generation", normalize: false, expectError: true},
+		{name: "Upper case strict", jobType: "REPORT_GENERATION", normalize: false, expectError: true},
+		{name: "Exact match normalized", jobType: "report_generation", normalize: true, expectedType: "report_generation"},
+		{name: "Title case normalized", jobType: "Report_Generation", normalize: true, expectedType: "report_generation"},
+		{name: "Upper case normalized", jobType: "REPORT_GENERATION", normalize: true, expectedType: "report_generation"},
+		{name: "Padded normalized", jobType: " report_generation ", normalize: true, expectedType: "report_generation"},
+		{name: "Unknown normalized", jobType: "REPORT", normalize: true, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := NormalizeJobType
+			NormalizeJobType = tt.normalize
+			defer func() { NormalizeJobType = previous }()
+
+			input := `{"job_type": "` + tt.jobType + `", "message": {"report_name": "Sales Report", "filters": "region=US"}}`
+			job, _, jobType, err := ParseJob([]byte(input))
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, ok := job.(ReportGenerationJob); !ok {
+				t.Errorf("expected ReportGenerationJob, got %T", job)
+			}
+			if *jobType != tt.expectedType {
+				t.Errorf("expected job type %s, got %s", tt.expectedType, *jobType)
+			}
+		})
+	}
+}
+
+func TestNormalizeJobTypeRejectsNestedBatch(t *testing.T) {
+	previous := NormalizeJobType
+	NormalizeJobType = true
+	defer func() { NormalizeJobType = previous }()
+
+	input := `{"job_type": "batch_job", "message": {"children": [
+		{"job_type": "BATCH_JOB", "message": {"children": [
+			{"job_type": "data_cleanup", "message": {"target_table": "users", "retention": 30}}
+		]}}
+	]}}`
+	if _, _, _, err := ParseJob([]byte(input)); err == nil {
+		t.Errorf("expected nested batch to be rejected")
+	}
+}
@@ -0,0 +1,21 @@
+package job
+
+import (
+	"fmt"
+	"time"
+)
+
+// PipelineLatency is how long a job spent in the pipeline, from the enriched
+// payload's ingestion Timestamp until completed. A timestamp in the future,
+// i.e. clock skew between ingester and processor, is clamped to zero.
+func PipelineLatency(timestamp string, completed time.Time) (time.Duration, error) {
+	ingested, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse timestamp %q: %w", timestamp, err)
+	}
+	latency := completed.Sub(ingested)
+	if latency < 0 {
+		return 0, nil
+	}
+	return latency, nil
+}
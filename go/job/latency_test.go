@@ -0,0 +1,56 @@
+package job
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPipelineLatency(t *testing.T) {
+	completed := time.Date(2025, 8, 30, 12, 0, 30, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		timestamp   string
+		expected    time.Duration
+		expectError bool
+	}{
+		{
+			name:      "Ingested before completion",
+			timestamp: "2025-08-30T12:00:00Z",
+			expected:  30 * time.Second,
+		},
+		{
+			name:      "Timestamp in another zone",
+			timestamp: "2025-08-30T13:00:00+01:00",
+			expected:  30 * time.Second,
+		},
+		{
+			name:      "Skewed timestamp in the future is clamped to zero",
+			timestamp: "2025-08-30T12:01:00Z",
+			expected:  0,
+		},
+		{
+			name:        "Unparseable timestamp",
+			timestamp:   "yesterday",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			latency, err := PipelineLatency(tt.timestamp, completed)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if latency != tt.expected {
+				t.Errorf("expected latency %v, got %v", tt.expected, latency)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package job
+
+import (
+	"context"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewLogger returns a logger writing JSON lines to w at level and above, the
+// format every service logs in so the log aggregator can parse them.
+func NewLogger(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level}))
+}
+
+// ParseLogLevel parses a log level such as "debug" or "WARN", returning
+// fallback when it is empty or unrecognised.
+func ParseLogLevel(value string, fallback slog.Level) slog.Level {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return fallback
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		log.Printf("ignoring invalid log level %q", value)
+		return fallback
+	}
+	return level
+}
+
+// LoggerWithTrace returns logger with the trace_id and span_id of the span
+// in ctx, so its lines can be joined to the trace. It is returned unchanged
+// when ctx has no valid span.
+func LoggerWithTrace(ctx context.Context, logger *slog.Logger) *slog.Logger {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return logger
+	}
+	return logger.With(
+		slog.String("trace_id", sc.TraceID().String()),
+		slog.String("span_id", sc.SpanID().String()),
+	)
+}
@@ -0,0 +1,63 @@
+package job
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected slog.Level
+	}{
+		{value: "", expected: slog.LevelInfo},
+		{value: "debug", expected: slog.LevelDebug},
+		{value: "WARN", expected: slog.LevelWarn},
+		{value: " error ", expected: slog.LevelError},
+		{value: "verbose", expected: slog.LevelInfo},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if actual := ParseLogLevel(tt.value, slog.LevelInfo); actual != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestLoggerWithTrace(t *testing.T) {
+	var logs bytes.Buffer
+	logger := NewLogger(&logs, slog.LevelInfo)
+
+	ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "ProcessMessage")
+	defer span.End()
+
+	LoggerWithTrace(ctx, logger).Info("traced")
+	LoggerWithTrace(context.Background(), logger).Info("untraced")
+	logger.Debug("below the level")
+
+	lines := bytes.Split(bytes.TrimSpace(logs.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 log lines, got %s", logs.String())
+	}
+
+	var traced, untraced map[string]any
+	if err := json.Unmarshal(lines[0], &traced); err != nil {
+		t.Fatalf("expected a JSON log line, got %s: %v", lines[0], err)
+	}
+	if traced["trace_id"] != span.SpanContext().TraceID().String() || traced["span_id"] != span.SpanContext().SpanID().String() {
+		t.Errorf("expected the span's trace_id and span_id, got %s", lines[0])
+	}
+	if err := json.Unmarshal(lines[1], &untraced); err != nil {
+		t.Fatalf("expected a JSON log line, got %s: %v", lines[1], err)
+	}
+	if _, ok := untraced["trace_id"]; ok {
+		t.Errorf("expected no trace_id without a span, got %s", lines[1])
+	}
+}
@@ -1,17 +1,28 @@
 package job
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"net/url"
+	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // input schema for users
 type JobMessage struct {
-	JobType string          `json:"job_type"`
-	Message json.RawMessage `json:"message"`
+	JobType       string          `json:"job_type"`
+	Message       json.RawMessage `json:"message"`
+	Fixture       string          `json:"fixture,omitempty"`        // demo only: the generator fixture this job came from
+	TenantID      string          `json:"tenant_id,omitempty"`      // optional tenant the job belongs to, propagated as baggage
+	Delay         int             `json:"delay,omitempty"`          // optional seconds to hold the job on jobs-todo before it runs, up to MaxDelaySeconds
+	GroupID       string          `json:"group_id,omitempty"`       // optional FIFO message group the job is ordered within, the job type by default
+	CorrelationID string          `json:"correlation_id,omitempty"` // optional caller's ID for the request, e.g. its X-Correlation-Id, echoed in logs and end states
 }
 
 func (jm JobMessage) String() string {
@@ -25,36 +36,77 @@ func (jm JobMessage) String() string {
 
 // schema to share between job ingester and worker
 type EnrichedPayload struct {
-	OriginalMessage json.RawMessage `json:"originalmessage"`
-	ID              string          `json:"id"`
-	Timestamp       string          `json:"timestamp"`
-	Status          string          `json:"status"`
-	TraceContext    string          `json:"trace_context"`
+	OriginalMessage   json.RawMessage `json:"originalmessage"`
+	ID                string          `json:"id"`
+	Timestamp         string          `json:"timestamp"`
+	Status            Status          `json:"status"`
+	TraceContext      string          `json:"trace_context"`
+	TraceState        string          `json:"tracestate,omitempty"`         // optional W3C tracestate accompanying TraceContext
+	ParentID          string          `json:"parent_id,omitempty"`          // set on children split out of a batch job and follow-ups queued by a job
+	ShardKey          int             `json:"shard_key,omitempty"`          // optional partition bucket, see ShardKey
+	Signature         string          `json:"signature,omitempty"`          // optional HMAC of the payload, see SignPayload
+	SchemaFingerprint string          `json:"schema_fingerprint,omitempty"` // optional fingerprint of the job's field set, see SchemaFingerprint
+	RetryCount        int             `json:"retry_count,omitempty"`        // times the processor has requeued the job after it failed
+	BaggageContext    string          `json:"baggage_context,omitempty"`    // optional W3C baggage, e.g. the job's tenant_id
+	SchemaVersion     int             `json:"schema_version,omitempty"`     // shape of the payload, see CheckSchemaVersion
+	ChainDepth        int             `json:"chain_depth,omitempty"`        // follow-ups deep the job is in its chain, see ChainFollowups
+	CorrelationID     string          `json:"correlation_id,omitempty"`     // the job's correlation ID, the caller's or one the ingester generated
+	EventBridgeID     string          `json:"eventbridge_id,omitempty"`     // ID of the EventBridge event the job arrived in, empty for jobs sent straight to the queue
 }
 
 // Job is the interface that all job types must implement.
 type Job interface {
-	Validate() error // Validate ensures the job payload is well-formed.
-	Execute() error
+	Validate() error                                // Validate ensures the job payload is well-formed, joining every violation it finds.
+	Execute(ctx context.Context) (JobResult, error) // Execute runs the job, stopping early if ctx is cancelled.
+	Name() JobType                                  // Name is the job_type the job is submitted as.
+}
+
+// JobResult is what a job produced, published with its end state so
+// consumers can act on it. Jobs with nothing to report return the zero value.
+type JobResult struct {
+	Output    map[string]any `json:"output,omitempty"`  // machine-readable results, e.g. where a report was written
+	Message   string         `json:"message,omitempty"` // human-readable summary of what the job did
+	Followups []JobMessage   `json:"-"`                 // jobs to queue once this one has succeeded, see ChainFollowups
 }
 
 // JobType represents the type of the job
 type JobType string
 
 const (
-	ReportGeneration JobType = "report_generation"
-	DataCleanup      JobType = "data_cleanup"
-	UserOnboarding   JobType = "user_onboarding"
-	LongRunning      JobType = "long_running_job"
+	ReportGeneration  JobType = "report_generation"
+	DataCleanup       JobType = "data_cleanup"
+	UserOnboarding    JobType = "user_onboarding"
+	LongRunning       JobType = "long_running_job"
+	Batch             JobType = "batch_job"
+	EmailNotification JobType = "email_notification"
+	Cancel            JobType = "cancel_job"
+	Generic           JobType = "generic"
 )
 
+// Status is where a job is in its lifecycle
+type Status string
+
 // job statuses
 const (
-	StatusNew           = "NEW"
-	StatusCompleted     = "COMPLETED"
-	StatusExecuteFailed = "EXECUTE_FAILED"
+	StatusNew              Status = "NEW"
+	StatusInProgress       Status = "IN_PROGRESS" // the processor has started executing it
+	StatusCompleted        Status = "COMPLETED"
+	StatusExecuteFailed    Status = "EXECUTE_FAILED"
+	StatusRejected         Status = "REJECTED"          // turned away before executing, e.g. failed to parse
+	StatusValidationFailed Status = "VALIDATION_FAILED" // turned away before executing for failing validation
+	StatusExpired          Status = "EXPIRED"           // dropped unexecuted for waiting longer than its TTL
 )
 
+// IsTerminal reports whether a job has reached its end state.
+func (s Status) IsTerminal() bool {
+	switch s {
+	case StatusCompleted, StatusExecuteFailed, StatusRejected, StatusValidationFailed, StatusExpired:
+		return true
+	default:
+		return false
+	}
+}
+
 // ReportGenerationJob represents the payload for a "report_generation" job.
 type ReportGenerationJob struct {
 	ReportName string `json:"report_name"`
@@ -71,80 +123,235 @@ type DataCleanupJob struct {
 type UserOnboardingJob struct {
 	UserID   string `json:"user_id"`
 	UserName string `json:"user_name"`
+	Email    string `json:"email,omitempty"` // optional address a welcome email_notification is queued to once the user is onboarded
 }
 
 // LongRunningJob represents the payload for a "long_running_job".
 type LongRunningJob struct {
-	TaskName string `json:"task_name"`
-	Timeout  int    `json:"timeout"` // Timeout in seconds
+	TaskName     string `json:"task_name"`
+	Timeout      int    `json:"timeout"`                 // Most seconds the task may run before it is abandoned
+	WorkDuration int    `json:"work_duration,omitempty"` // demo only: seconds the simulated work takes, the whole timeout when unset
+	Priority     int    `json:"priority,omitempty"`      // Higher priorities may be allowed longer timeouts, see PriorityTimeouts
+}
+
+// EmailNotificationJob represents the payload for an "email_notification" job.
+type EmailNotificationJob struct {
+	Recipient string `json:"recipient"`
+	Subject   string `json:"subject"`
+	Body      string `json:"body"`
+}
+
+// CancelJob represents the payload for a "cancel_job", which stops another
+// job while it executes.
+type CancelJob struct {
+	TargetID string `json:"target_id"` // ID of the job to cancel
+}
+
+// GenericJob represents the payload for a "generic" job, an ad-hoc action
+// for prototyping job types the library doesn't have a struct for yet.
+type GenericJob struct {
+	Action string         `json:"action"`
+	Params map[string]any `json:"params,omitempty"`
 }
 
 // Validate methods for each job type.
 
 func (j ReportGenerationJob) Validate() error {
+	var errs []error
 	if j.ReportName == "" {
-		return errors.New("report_name is required")
+		errs = append(errs, errors.New("report_name is required"))
 	}
 	if j.Filters == "" {
-		return errors.New("filters are required")
+		errs = append(errs, errors.New("filters are required"))
+	} else if StrictFilters {
+		if _, err := ParseFilters(j.Filters); err != nil {
+			errs = append(errs, fmt.Errorf("invalid filters: %w", err))
+		}
 	}
-	return nil
+	return errors.Join(errs...)
 }
 
-func (j ReportGenerationJob) Execute() error {
+func (j ReportGenerationJob) Execute(ctx context.Context) (JobResult, error) {
 	log.Printf("Generating report %s with filters %s\n", j.ReportName, j.Filters)
-	return nil
+	location := reportLocation(j.ReportName)
+	return JobResult{
+		Output:  map[string]any{"report_location": location},
+		Message: fmt.Sprintf("report %s written to %s", j.ReportName, location),
+	}, nil
+}
+
+// reportLocation is where the report named reportName is written.
+func reportLocation(reportName string) string {
+	return "reports/" + url.PathEscape(reportName) + ".csv"
 }
 
 func (j DataCleanupJob) Validate() error {
+	var errs []error
 	if j.TargetTable == "" {
-		return errors.New("target_table is required")
+		errs = append(errs, errors.New("target_table is required"))
 	}
 	if j.Retention <= 0 {
-		return errors.New("retention must be greater than 0")
+		errs = append(errs, errors.New("retention must be greater than 0"))
+	} else if minimum, ok := MinRetentionDays[j.TargetTable]; ok && j.Retention < minimum {
+		errs = append(errs, fmt.Errorf("retention %d is below the %d day minimum for table %s", j.Retention, minimum, j.TargetTable))
 	}
-	return nil
+	return errors.Join(errs...)
 }
 
-func (j DataCleanupJob) Execute() error {
+func (j DataCleanupJob) Execute(ctx context.Context) (JobResult, error) {
 	log.Printf("Executing data cleanup on table %s with retention %d days\n", j.TargetTable, j.Retention)
-	return nil
+	return JobResult{}, nil
 }
 
 func (j UserOnboardingJob) Validate() error {
+	var errs []error
 	if j.UserID == "" {
-		return errors.New("user_id is required")
+		errs = append(errs, errors.New("user_id is required"))
+	} else if err := validateUserID(j.UserID); err != nil {
+		errs = append(errs, err)
 	}
 	if j.UserName == "" {
-		return errors.New("user_name is required")
+		errs = append(errs, errors.New("user_name is required"))
 	}
-	return nil
+	if j.Email != "" && !strings.Contains(j.Email, "@") {
+		errs = append(errs, fmt.Errorf("email %q is not an email address", j.Email))
+	}
+	return errors.Join(errs...)
 }
 
-func (j UserOnboardingJob) Execute() error {
+func (j UserOnboardingJob) Execute(ctx context.Context) (JobResult, error) {
 	log.Printf("Onboarding user %s with ID %s\n", j.UserName, j.UserID)
-	return nil
+	result := JobResult{
+		Output:  map[string]any{"user_id": j.UserID},
+		Message: fmt.Sprintf("user %s onboarded with ID %s", j.UserName, j.UserID),
+	}
+	if j.Email != "" {
+		welcome, err := json.Marshal(EmailNotificationJob{
+			Recipient: j.Email,
+			Subject:   fmt.Sprintf("Welcome, %s", j.UserName),
+			Body:      fmt.Sprintf("Your account %s is ready.", j.UserID),
+		})
+		if err != nil {
+			return JobResult{}, fmt.Errorf("failed to marshal welcome email: %w", err)
+		}
+		result.Followups = []JobMessage{{JobType: string(EmailNotification), Message: welcome}}
+	}
+	return result, nil
 }
 
 func (j LongRunningJob) Validate() error {
+	var errs []error
 	if j.TaskName == "" {
-		return errors.New("task_name is required")
+		errs = append(errs, errors.New("task_name is required"))
 	}
 	if j.Timeout <= 0 {
-		return errors.New("timeout must be greater than 0")
+		errs = append(errs, errors.New("timeout must be greater than 0"))
 	}
-	return nil
+	if j.WorkDuration < 0 {
+		errs = append(errs, errors.New("work_duration must not be negative"))
+	}
+	return errors.Join(errs...)
 }
 
-func (j LongRunningJob) Execute() error {
+func (j LongRunningJob) Execute(ctx context.Context) (JobResult, error) {
 	log.Printf("Starting long-running task %s with timeout %d seconds\n", j.TaskName, j.Timeout)
-	time.Sleep(time.Duration(j.Timeout) * time.Second)
 
+	stopHeartbeat := startHeartbeat(ctx, HeartbeatInterval, j.TaskName)
+	defer stopHeartbeat()
+
+	// The timeout is a ceiling on the work, which is abandoned if it runs past it
+	timeout := time.Duration(j.Timeout) * time.Second
+	deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Jobs that don't say how long their work takes use their whole timeout
+	if j.WorkDuration == 0 {
+		<-deadlineCtx.Done()
+		return JobResult{}, ctx.Err()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		work := time.NewTimer(time.Duration(j.WorkDuration) * time.Second)
+		defer work.Stop()
+		select {
+		case <-work.C:
+			close(done)
+		case <-deadlineCtx.Done():
+		}
+	}()
+
+	select {
+	case <-done:
+		return JobResult{}, nil
+	case <-deadlineCtx.Done():
+		if err := ctx.Err(); err != nil {
+			return JobResult{}, err
+		}
+		return JobResult{}, fmt.Errorf("%w: task %s did not finish within %d seconds: %w", ErrTimedOut, j.TaskName, j.Timeout, context.DeadlineExceeded)
+	}
+}
+
+func (j EmailNotificationJob) Validate() error {
+	var errs []error
+	if j.Recipient == "" {
+		errs = append(errs, errors.New("recipient is required"))
+	} else if !strings.Contains(j.Recipient, "@") {
+		errs = append(errs, fmt.Errorf("recipient %q is not an email address", j.Recipient))
+	}
+	if j.Subject == "" {
+		errs = append(errs, errors.New("subject is required"))
+	}
+	return errors.Join(errs...)
+}
+
+func (j EmailNotificationJob) Execute(ctx context.Context) (JobResult, error) {
+	// The demo only logs the send rather than talking to a mail server
+	log.Printf("Sending email to %s with subject %q (%d byte body)\n", j.Recipient, j.Subject, len(j.Body))
+	return JobResult{}, nil
+}
+
+func (j CancelJob) Validate() error {
+	if j.TargetID == "" {
+		return errors.New("target_id is required")
+	}
 	return nil
 }
 
+// Execute cancels the target job through the canceller ctx carries, see
+// ContextWithCanceller. A target that isn't running is left alone, recorded
+// as a span event rather than failing the cancel.
+func (j CancelJob) Execute(ctx context.Context) (JobResult, error) {
+	cancel, ok := ctx.Value(cancellerKey{}).(func(string) bool)
+	if !ok || !cancel(j.TargetID) {
+		trace.SpanFromContext(ctx).AddEvent("cancel target not running", trace.WithAttributes(attribute.String("job.target_id", j.TargetID)))
+		return JobResult{
+			Output:  map[string]any{"target_id": j.TargetID, "cancelled": false},
+			Message: fmt.Sprintf("job %s is not running, nothing to cancel", j.TargetID),
+		}, nil
+	}
+	log.Printf("Cancelled job %s\n", j.TargetID)
+	return JobResult{
+		Output:  map[string]any{"target_id": j.TargetID, "cancelled": true},
+		Message: fmt.Sprintf("cancelled job %s", j.TargetID),
+	}, nil
+}
+
 // ParseJob parses a JSON message into the appropriate job type and validates it.
+// A job that parses but fails validation is returned alongside the error, so
+// callers can say what was sent, see ParseStage.
 func ParseJob(message []byte) (Job, json.RawMessage, *string, error) {
+	return parseJob(message, UnknownFields)
+}
+
+// ParseJobStrict is ParseJob, but rejects fields in the job's message that
+// its type doesn't define whatever UnknownFields is set to, so a typo'd
+// field fails with an error naming it rather than parsing as empty.
+func ParseJobStrict(message []byte) (Job, json.RawMessage, *string, error) {
+	return parseJob(message, UnknownFieldsError)
+}
+
+func parseJob(message []byte, unknownFields string) (Job, json.RawMessage, *string, error) {
 	// Parse the top-level JobMessage
 	var jobMessage JobMessage
 
@@ -152,40 +359,40 @@ func ParseJob(message []byte) (Job, json.RawMessage, *string, error) {
 		return nil, nil, nil, fmt.Errorf("failed to parse job message: %w", err)
 	}
 
-	// Determine the job type and parse the message field into the correct schema
-	var job Job
-	switch JobType(jobMessage.JobType) {
-	case ReportGeneration:
-		var reportJob ReportGenerationJob
-		if err := json.Unmarshal(jobMessage.Message, &reportJob); err != nil {
-			return nil, json.RawMessage(message), stringPtr(string(ReportGeneration)), fmt.Errorf("failed to parse report_generation job: %w", err)
-		}
-		job = reportJob
-	case DataCleanup:
-		var cleanupJob DataCleanupJob
-		if err := json.Unmarshal(jobMessage.Message, &cleanupJob); err != nil {
-			return nil, json.RawMessage(message), stringPtr(string(DataCleanup)), fmt.Errorf("failed to parse data_cleanup job: %w", err)
-		}
-		job = cleanupJob
-	case UserOnboarding:
-		var onboardingJob UserOnboardingJob
-		if err := json.Unmarshal(jobMessage.Message, &onboardingJob); err != nil {
-			return nil, json.RawMessage(message), stringPtr(string(UserOnboarding)), fmt.Errorf("failed to parse user_onboarding job: %w", err)
-		}
-		job = onboardingJob
-	case LongRunning:
-		var longJob LongRunningJob
-		if err := json.Unmarshal(jobMessage.Message, &longJob); err != nil {
-			return nil, json.RawMessage(message), stringPtr(string(LongRunning)), fmt.Errorf("failed to parse long_running_job: %w", err)
-		}
-		job = longJob
-	default:
+	// Normalize the job type casing if enabled
+	jobMessage.JobType = string(resolveJobType(jobMessage.JobType))
+
+	// Look up the job type and parse the message field into its schema
+	factory, ok := jobFactory(JobType(jobMessage.JobType))
+	if !ok {
 		return nil, nil, nil, fmt.Errorf("unknown job type: %s, raw message %s", jobMessage.JobType, string(message))
 	}
+	job, err := decodeJob(factory, jobMessage.Message, unknownFields)
+	if err != nil {
+		return nil, json.RawMessage(message), stringPtr(jobMessage.JobType), fmt.Errorf("failed to parse %s job: %w", jobMessage.JobType, err)
+	}
+
+	// Validate the message against its JSON schema, if one was loaded
+	if err := validateSchema(JobType(jobMessage.JobType), jobMessage.Message); err != nil {
+		return job, nil, stringPtr(string(jobMessage.JobType)), validationError{fmt.Errorf("job schema validation failed: %w", err)}
+	}
 
-	// Validate the job
-	if err := job.Validate(); err != nil {
-		return nil, nil, stringPtr(string(jobMessage.JobType)), fmt.Errorf("job validation failed: %w", err)
+	// Validate the job, leniently if its type is configured to be
+	if err := validateJob(JobType(jobMessage.JobType), job); err != nil {
+		return job, nil, stringPtr(string(jobMessage.JobType)), validationError{fmt.Errorf("job validation failed: %w", err)}
+	}
+
+	// Apply any rules spanning several fields
+	if err := validateCrossFields(JobType(jobMessage.JobType), job); err != nil {
+		return job, nil, stringPtr(string(jobMessage.JobType)), validationError{fmt.Errorf("job cross-field validation failed: %w", err)}
+	}
+
+	// The delay is passed to SQS, which refuses anything outside its limits
+	if err := validateDelay(jobMessage.Delay); err != nil {
+		return job, nil, stringPtr(string(jobMessage.JobType)), validationError{fmt.Errorf("job validation failed: %w", err)}
+	}
+	if err := validateGroupID(jobMessage.GroupID); err != nil {
+		return job, nil, stringPtr(string(jobMessage.JobType)), validationError{fmt.Errorf("job validation failed: %w", err)}
 	}
 
 	return job, json.RawMessage(message), stringPtr(string(jobMessage.JobType)), nil
@@ -207,6 +414,49 @@ func ParseEnrichedPayload(message []byte) (Job, *EnrichedPayload, *string, error
 	return job, &enrichedPayload, jobType, nil
 }
 
+// validationError marks a ParseJob error for a job that parsed but failed
+// validation, see ParseStage.
+type validationError struct {
+	error
+}
+
+func (e validationError) Unwrap() error {
+	return e.error
+}
+
+// ParseStage is the pipeline stage a ParseJob or ParseEnrichedPayload error
+// happened at: StageValidate for a job that parsed but failed validation,
+// StageParse otherwise.
+func ParseStage(err error) string {
+	if errors.As(err, &validationError{}) {
+		return StageValidate
+	}
+	return StageParse
+}
+
+// RejectedStatus is the end state of a job a ParseJob or ParseEnrichedPayload
+// error turned away: StatusValidationFailed for a job that parsed but failed
+// validation, StatusRejected otherwise.
+func RejectedStatus(err error) Status {
+	if errors.As(err, &validationError{}) {
+		return StatusValidationFailed
+	}
+	return StatusRejected
+}
+
 func stringPtr(s string) *string {
 	return &s
 }
+
+func (j GenericJob) Validate() error {
+	if j.Action == "" {
+		return errors.New("action is required")
+	}
+	return nil
+}
+
+func (j GenericJob) Execute(ctx context.Context) (JobResult, error) {
+	// The demo only logs the action, there is nothing to run it against
+	log.Printf("Running generic action %s with params %v\n", j.Action, j.Params)
+	return JobResult{Message: fmt.Sprintf("ran generic action %s", j.Action)}, nil
+}
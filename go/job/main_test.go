@@ -1,7 +1,10 @@
 package job
 
 import (
+	"context"
 	"encoding/json"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -49,6 +52,7 @@ func TestParseJob(t *testing.T) {
                 }
             }`,
 			expectError: true,
+			expectedJob: DataCleanupJob{Retention: 30},
 		},
 		{
 			name: "Valid UserOnboarding Job",
@@ -66,6 +70,80 @@ func TestParseJob(t *testing.T) {
 			},
 			expectedJobType: stringPtr(string(UserOnboarding)),
 		},
+		{
+			name: "Valid EmailNotification Job",
+			input: `{
+                "job_type": "email_notification",
+                "message": {
+                    "recipient": "jane@example.com",
+                    "subject": "Your report is ready",
+                    "body": "The Sales Report has finished."
+                }
+            }`,
+			expectError: false,
+			expectedJob: EmailNotificationJob{
+				Recipient: "jane@example.com",
+				Subject:   "Your report is ready",
+				Body:      "The Sales Report has finished.",
+			},
+			expectedJobType: stringPtr(string(EmailNotification)),
+		},
+		{
+			name: "Invalid EmailNotification Job (missing recipient)",
+			input: `{
+                "job_type": "email_notification",
+                "message": {
+                    "subject": "Your report is ready"
+                }
+            }`,
+			expectError: true,
+			expectedJob: EmailNotificationJob{Subject: "Your report is ready"},
+		},
+		{
+			name: "Valid CancelJob",
+			input: `{
+                "job_type": "cancel_job",
+                "message": {
+                    "target_id": "67890"
+                }
+            }`,
+			expectError:     false,
+			expectedJob:     CancelJob{TargetID: "67890"},
+			expectedJobType: stringPtr(string(Cancel)),
+		},
+		{
+			name: "Valid GenericJob",
+			input: `{
+                "job_type": "generic",
+                "message": {
+                    "action": "resize_image",
+                    "params": {"width": 640, "format": "png"}
+                }
+            }`,
+			expectError:     false,
+			expectedJob:     GenericJob{Action: "resize_image", Params: map[string]any{"width": float64(640), "format": "png"}},
+			expectedJobType: stringPtr(string(Generic)),
+		},
+		{
+			name: "Invalid GenericJob (missing action)",
+			input: `{
+                "job_type": "generic",
+                "message": {
+                    "params": {"width": 640}
+                }
+            }`,
+			expectError: true,
+			expectedJob: GenericJob{Params: map[string]any{"width": float64(640)}},
+		},
+		{
+			name: "Invalid CancelJob (missing target_id)",
+			input: `{
+                "job_type": "cancel_job",
+                "message": {}
+            }`,
+			expectError: true,
+			expectedJob: CancelJob{},
+		},
 		{
 			name: "Unknown Job Type",
 			input: `{
@@ -83,6 +161,14 @@ func TestParseJob(t *testing.T) {
 				if err == nil {
 					t.Errorf("expected an error but got none")
 				}
+				// A job that only failed validation comes back for callers to inspect
+				if tt.expectedJob == nil {
+					if job != nil {
+						t.Errorf("expected no job, got %+v", job)
+					}
+				} else if ParseStage(err) != StageValidate || !reflect.DeepEqual(job, tt.expectedJob) {
+					t.Errorf("expected the invalid job %+v with a validation error, got %+v with %v", tt.expectedJob, job, err)
+				}
 				return
 			}
 
@@ -137,6 +223,30 @@ func TestParseJob(t *testing.T) {
 				if actual.UserID != expected.UserID || actual.UserName != expected.UserName {
 					t.Errorf("expected job %+v, got %+v", expected, actual)
 				}
+			case CancelJob:
+				actual, ok := job.(CancelJob)
+				if !ok {
+					t.Errorf("expected CancelJob, got %T", job)
+				}
+				if actual != expected {
+					t.Errorf("expected job %+v, got %+v", expected, actual)
+				}
+			case EmailNotificationJob:
+				actual, ok := job.(EmailNotificationJob)
+				if !ok {
+					t.Errorf("expected EmailNotificationJob, got %T", job)
+				}
+				if actual != expected {
+					t.Errorf("expected job %+v, got %+v", expected, actual)
+				}
+			case GenericJob:
+				actual, ok := job.(GenericJob)
+				if !ok {
+					t.Errorf("expected GenericJob, got %T", job)
+				}
+				if !reflect.DeepEqual(actual, expected) {
+					t.Errorf("expected job %+v, got %+v", expected, actual)
+				}
 			default:
 				t.Errorf("unexpected job type: %T", job)
 			}
@@ -144,6 +254,51 @@ func TestParseJob(t *testing.T) {
 	}
 }
 
+func TestValidateReportsEveryViolation(t *testing.T) {
+	tests := []struct {
+		name     string
+		job      Job
+		expected []string
+	}{
+		{name: "ReportGeneration", job: ReportGenerationJob{}, expected: []string{"report_name is required", "filters are required"}},
+		{name: "DataCleanup", job: DataCleanupJob{}, expected: []string{"target_table is required", "retention must be greater than 0"}},
+		{name: "UserOnboarding", job: UserOnboardingJob{}, expected: []string{"user_id is required", "user_name is required"}},
+		{name: "LongRunning", job: LongRunningJob{WorkDuration: -1}, expected: []string{"task_name is required", "timeout must be greater than 0", "work_duration must not be negative"}},
+		{name: "EmailNotification", job: EmailNotificationJob{Recipient: "jane"}, expected: []string{`recipient "jane" is not an email address`, "subject is required"}},
+		{name: "Generic", job: GenericJob{Params: map[string]any{"width": 640}}, expected: []string{"action is required"}},
+		{
+			name: "Batch",
+			job: BatchJob{Children: []JobMessage{
+				{JobType: string(DataCleanup), Message: []byte(`{"retention":30}`)},
+				{JobType: string(Batch), Message: []byte(`{"children":[]}`)},
+				{JobType: string(UserOnboarding), Message: []byte(`{"user_id":"user-001"}`)},
+			}},
+			expected: []string{"child 0: job validation failed: target_table is required", "child 1: nested batch jobs are not supported", "child 2: job validation failed: user_name is required"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.job.Validate()
+			if err == nil {
+				t.Fatalf("expected an error but got none")
+			}
+			if actual, expected := err.Error(), strings.Join(tt.expected, "\n"); actual != expected {
+				t.Errorf("expected every violation %q, got %q", expected, actual)
+			}
+
+			// ParseJob still rejects the job, listing every violation
+			message, marshalErr := ToJobMessage(tt.job)
+			if marshalErr != nil {
+				t.Fatalf("unexpected error: %v", marshalErr)
+			}
+			if _, _, _, err := ParseJob([]byte(message.String())); err == nil || !strings.Contains(err.Error(), strings.Join(tt.expected, "\n")) {
+				t.Errorf("expected ParseJob to report every violation, got %v", err)
+			}
+		})
+	}
+}
+
 func TestParseEnrichedPayload(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -216,7 +371,7 @@ func TestParseEnrichedPayload(t *testing.T) {
                 }`),
 				ID:        "67890",
 				Timestamp: "2025-08-30T12:30:00Z",
-				Status:    "IN_PROGRESS",
+				Status:    StatusInProgress,
 			},
 		},
 		{
@@ -325,3 +480,93 @@ func equalJSONObjects(a, b map[string]interface{}) bool {
 
 	return string(aJSON) == string(bJSON)
 }
+
+func TestStatusIsTerminal(t *testing.T) {
+	tests := []struct {
+		status   Status
+		expected bool
+	}{
+		{status: StatusNew, expected: false},
+		{status: StatusInProgress, expected: false},
+		{status: StatusCompleted, expected: true},
+		{status: StatusExecuteFailed, expected: true},
+		{status: StatusRejected, expected: true},
+		{status: StatusValidationFailed, expected: true},
+		{status: StatusExpired, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			if actual := tt.status.IsTerminal(); actual != tt.expected {
+				t.Errorf("expected IsTerminal %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestExecuteResults(t *testing.T) {
+	tests := []struct {
+		name         string
+		job          Job
+		expectOutput map[string]any
+	}{
+		{
+			name:         "Report location",
+			job:          ReportGenerationJob{ReportName: "Monthly Sales", Filters: "region=US"},
+			expectOutput: map[string]any{"report_location": "reports/Monthly%20Sales.csv"},
+		},
+		{
+			name:         "Onboarded user ID",
+			job:          UserOnboardingJob{UserID: "U12345", UserName: "Jane Doe"},
+			expectOutput: map[string]any{"user_id": "U12345"},
+		},
+		{
+			name: "Nothing to report",
+			job:  DataCleanupJob{TargetTable: "users", Retention: 30},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.job.Execute(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result.Output, tt.expectOutput) {
+				t.Errorf("expected output %v, got %v", tt.expectOutput, result.Output)
+			}
+			if (result.Message != "") != (tt.expectOutput != nil) {
+				t.Errorf("expected a message only with output, got %q", result.Message)
+			}
+		})
+	}
+}
+
+func TestParseStage(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          string
+		expected       string
+		expectedStatus Status
+	}{
+		{name: "Invalid JSON", input: `not json`, expected: StageParse, expectedStatus: StatusRejected},
+		{name: "Unknown job type", input: `{"job_type":"does_not_exist","message":{}}`, expected: StageParse, expectedStatus: StatusRejected},
+		{name: "Wrong field type", input: `{"job_type":"data_cleanup","message":{"target_table":"users","retention":"30"}}`, expected: StageParse, expectedStatus: StatusRejected},
+		{name: "Failed validation", input: `{"job_type":"data_cleanup","message":{"target_table":"users","retention":0}}`, expected: StageValidate, expectedStatus: StatusValidationFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, _, err := ParseJob([]byte(tt.input))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if stage := ParseStage(err); stage != tt.expected {
+				t.Errorf("expected stage %s for %v, got %s", tt.expected, err, stage)
+			}
+			if status := RejectedStatus(err); status != tt.expectedStatus {
+				t.Errorf("expected status %s for %v, got %s", tt.expectedStatus, err, status)
+			}
+		})
+	}
+}
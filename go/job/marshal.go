@@ -0,0 +1,26 @@
+package job
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func (ReportGenerationJob) Name() JobType  { return ReportGeneration }
+func (DataCleanupJob) Name() JobType       { return DataCleanup }
+func (UserOnboardingJob) Name() JobType    { return UserOnboarding }
+func (LongRunningJob) Name() JobType       { return LongRunning }
+func (BatchJob) Name() JobType             { return Batch }
+func (EmailNotificationJob) Name() JobType { return EmailNotification }
+func (CancelJob) Name() JobType            { return Cancel }
+func (GenericJob) Name() JobType           { return Generic }
+
+// ToJobMessage converts a parsed job back into the JobMessage it would be
+// submitted as, the reverse of ParseJob, so jobs can be re-enqueued or
+// chained.
+func ToJobMessage(j Job) (JobMessage, error) {
+	message, err := json.Marshal(j)
+	if err != nil {
+		return JobMessage{}, fmt.Errorf("failed to marshal %s job: %w", j.Name(), err)
+	}
+	return JobMessage{JobType: string(j.Name()), Message: message}, nil
+}
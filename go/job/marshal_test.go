@@ -0,0 +1,56 @@
+package job
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestToJobMessageRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "ReportGeneration", input: `{"job_type": "report_generation", "message": {"report_name": "Sales Report", "filters": "region=US"}}`},
+		{name: "DataCleanup", input: `{"job_type": "data_cleanup", "message": {"target_table": "users", "retention": 30}}`},
+		{name: "UserOnboarding", input: `{"job_type": "user_onboarding", "message": {"user_id": "user_001", "user_name": "John Doe"}}`},
+		{name: "LongRunning", input: `{"job_type": "long_running_job", "message": {"task_name": "Data Migration", "timeout": 5}}`},
+		{name: "Batch", input: `{
+			"job_type": "batch_job",
+			"message": {
+				"children": [
+					{"job_type": "report_generation", "message": {"report_name": "Sales Report", "filters": "region=US"}},
+					{"job_type": "data_cleanup", "message": {"target_table": "users", "retention": 30}}
+				]
+			}
+		}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job, _, jobType, err := ParseJob([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			jobMessage, err := ToJobMessage(job)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if jobMessage.JobType != *jobType {
+				t.Errorf("expected job type %s, got %s", *jobType, jobMessage.JobType)
+			}
+
+			reparsed, _, _, err := ParseJob([]byte(jobMessage.String()))
+			if err != nil {
+				t.Fatalf("failed to parse round-tripped job: %v", err)
+			}
+			// Batch children keep their raw JSON, so compare the jobs as JSON
+			expected, _ := json.Marshal(job)
+			actual, _ := json.Marshal(reparsed)
+			if reflect.TypeOf(job) != reflect.TypeOf(reparsed) || string(expected) != string(actual) {
+				t.Errorf("expected %T %s, got %T %s", job, expected, reparsed, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package job
+
+import "go.opentelemetry.io/otel/attribute"
+
+// ParameterAttributes returns span attributes for the parameters of a parsed
+// job that are useful when correlating failures, such as a cleanup's
+// retention or a long-running task's timeout. Other job types have none.
+func ParameterAttributes(job Job) []attribute.KeyValue {
+	switch j := job.(type) {
+	case DataCleanupJob:
+		return []attribute.KeyValue{attribute.Int("cleanup.retention_days", j.Retention)}
+	case LongRunningJob:
+		return []attribute.KeyValue{attribute.Int("task.timeout_seconds", j.Timeout)}
+	default:
+		return nil
+	}
+}
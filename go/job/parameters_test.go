@@ -0,0 +1,48 @@
+package job
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestParameterAttributes(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []attribute.KeyValue
+	}{
+		{
+			name:     "Data cleanup",
+			input:    `{"job_type": "data_cleanup", "message": {"target_table": "users", "retention": 30}}`,
+			expected: []attribute.KeyValue{attribute.Int("cleanup.retention_days", 30)},
+		},
+		{
+			name:     "Long running",
+			input:    `{"job_type": "long_running_job", "message": {"task_name": "reindex", "timeout": 45}}`,
+			expected: []attribute.KeyValue{attribute.Int("task.timeout_seconds", 45)},
+		},
+		{
+			name:  "Report generation",
+			input: `{"job_type": "report_generation", "message": {"report_name": "Sales Report", "filters": "region=US"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job, _, _, err := ParseJob([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			attrs := ParameterAttributes(job)
+			if len(attrs) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, attrs)
+			}
+			for i := range attrs {
+				if attrs[i] != tt.expected[i] {
+					t.Errorf("expected %v, got %v", tt.expected[i], attrs[i])
+				}
+			}
+		})
+	}
+}
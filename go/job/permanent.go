@@ -0,0 +1,36 @@
+package job
+
+import "errors"
+
+// PermanentError marks a job error that retrying can't fix, e.g. a payload
+// the job can never act on, so the processor dead-letters the job at once
+// rather than requeueing it. Custom job types mark their own errors with
+// Permanent.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent marks err as permanent, returning nil for a nil err.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// IsPermanent reports whether a job error is permanent: one marked with
+// Permanent, or a job failing validation. Every other error, including
+// execution failures and the job's context ending, is transient and worth
+// retrying.
+func IsPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent) || errors.As(err, &validationError{})
+}
@@ -0,0 +1,39 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestIsPermanent(t *testing.T) {
+	_, _, _, validationErr := ParseJob([]byte(`{"job_type":"data_cleanup","message":{"target_table":"users","retention":0}}`))
+
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "Marked permanent", err: Permanent(errors.New("unknown report template")), expected: true},
+		{name: "Wrapped permanent", err: fmt.Errorf("child 1: %w", Permanent(errors.New("unknown report template"))), expected: true},
+		{name: "Failed validation", err: validationErr, expected: true},
+		{name: "Execution failure", err: errors.New("connection reset")},
+		{name: "Deadline exceeded", err: context.DeadlineExceeded},
+		{name: "Timed out", err: fmt.Errorf("%w: %w", ErrTimedOut, context.DeadlineExceeded)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := IsPermanent(tt.err); actual != tt.expected {
+				t.Errorf("expected IsPermanent %v for %v, got %v", tt.expected, tt.err, actual)
+			}
+		})
+	}
+}
+
+func TestPermanentNil(t *testing.T) {
+	if err := Permanent(nil); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+}
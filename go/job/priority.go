@@ -0,0 +1,59 @@
+package job
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParsePriorityTimeouts parses a comma separated list of priority=seconds
+// pairs, e.g. "0=60,1=300,2=900". Malformed entries are logged and skipped.
+func ParsePriorityTimeouts(limits string) map[int]int {
+	parsed := map[int]int{}
+	for _, limit := range strings.Split(limits, ",") {
+		limit = strings.TrimSpace(limit)
+		if limit == "" {
+			continue
+		}
+		priority, seconds, ok := strings.Cut(limit, "=")
+		level, priorityErr := strconv.Atoi(strings.TrimSpace(priority))
+		maximum, secondsErr := strconv.Atoi(strings.TrimSpace(seconds))
+		if !ok || priorityErr != nil || secondsErr != nil || maximum <= 0 {
+			log.Printf("ignoring malformed priority timeout: %q", limit)
+			continue
+		}
+		parsed[level] = maximum
+	}
+	return parsed
+}
+
+// PriorityTimeouts is a cross-field rule for long_running_job capping the
+// timeout by priority, so low priority tasks can't hog long timeouts. A task
+// gets the cap of the highest configured priority at or below its own, and
+// priorities below every configured one get the lowest priority's cap.
+func PriorityTimeouts(limits map[int]int) CrossFieldValidator {
+	priorities := make([]int, 0, len(limits))
+	for priority := range limits {
+		priorities = append(priorities, priority)
+	}
+	sort.Ints(priorities)
+
+	return func(job Job) error {
+		task, ok := job.(LongRunningJob)
+		if !ok || len(priorities) == 0 {
+			return nil
+		}
+		level := priorities[0]
+		for _, priority := range priorities {
+			if priority <= task.Priority {
+				level = priority
+			}
+		}
+		if maximum := limits[level]; task.Timeout > maximum {
+			return fmt.Errorf("timeout %d exceeds %d seconds for priority %d tasks", task.Timeout, maximum, task.Priority)
+		}
+		return nil
+	}
+}
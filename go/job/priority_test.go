@@ -0,0 +1,61 @@
+package job
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePriorityTimeouts(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[int]int
+	}{
+		{name: "Empty", input: "", expected: map[int]int{}},
+		{name: "Multiple limits", input: "0=60, 1=300,2=900", expected: map[int]int{0: 60, 1: 300, 2: 900}},
+		{name: "Malformed entries are skipped", input: "0,high=60,1=0,2=soon,3=1800", expected: map[int]int{3: 1800}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := ParsePriorityTimeouts(tt.input); !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestPriorityTimeouts(t *testing.T) {
+	withoutCrossFieldValidators(t)
+	RegisterCrossFieldValidator(LongRunning, PriorityTimeouts(map[int]int{0: 60, 2: 900}))
+
+	tests := []struct {
+		name        string
+		input       string
+		expectError bool
+	}{
+		{name: "Default priority within cap", input: `{"job_type": "long_running_job", "message": {"task_name": "reindex", "timeout": 60}}`},
+		{name: "Default priority over cap", input: `{"job_type": "long_running_job", "message": {"task_name": "reindex", "timeout": 61}}`, expectError: true},
+		{name: "Between configured priorities", input: `{"job_type": "long_running_job", "message": {"task_name": "reindex", "timeout": 120, "priority": 1}}`, expectError: true},
+		{name: "Higher priority allowed longer", input: `{"job_type": "long_running_job", "message": {"task_name": "reindex", "timeout": 900, "priority": 2}}`},
+		{name: "Above highest priority", input: `{"job_type": "long_running_job", "message": {"task_name": "reindex", "timeout": 901, "priority": 5}}`, expectError: true},
+		{name: "Below lowest priority", input: `{"job_type": "long_running_job", "message": {"task_name": "reindex", "timeout": 120, "priority": -1}}`, expectError: true},
+		{name: "Other job type", input: `{"job_type": "data_cleanup", "message": {"target_table": "users", "retention": 120}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, _, err := ParseJob([]byte(tt.input))
+			if tt.expectError {
+				if err == nil || !strings.Contains(err.Error(), "cross-field") {
+					t.Errorf("expected a cross-field validation error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package job
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceparentAttribute is the SQS message attribute carrying the W3C
+// traceparent when trace context is propagated as message attributes.
+const TraceparentAttribute = "traceparent"
+
+// TracestateAttribute is the SQS message attribute carrying the W3C
+// tracestate alongside TraceparentAttribute, when there is any.
+const TracestateAttribute = "tracestate"
+
+// Where the trace context travels between the ingester and the processor.
+const (
+	PropagationBody       = "body"       // trace_context in the enriched payload
+	PropagationAttributes = "attributes" // the traceparent SQS message attribute
+	PropagationBoth       = "both"       // both, the processor prefers the attribute
+)
+
+// ParsePropagation validates a trace propagation mode, returning fallback
+// when it is empty or unrecognised.
+func ParsePropagation(value, fallback string) string {
+	switch mode := strings.ToLower(strings.TrimSpace(value)); mode {
+	case PropagationBody, PropagationAttributes, PropagationBoth:
+		return mode
+	case "":
+		return fallback
+	default:
+		log.Printf("ignoring invalid trace propagation mode %q", value)
+		return fallback
+	}
+}
+
+// TraceMessageAttributes returns the SQS message attributes carrying
+// traceparent and any tracestate, or nil when carrier only sends trace
+// context in the body.
+func TraceMessageAttributes(carrier, traceparent, tracestate string) map[string]types.MessageAttributeValue {
+	if carrier == PropagationBody || traceparent == "" {
+		return nil
+	}
+	attributes := map[string]types.MessageAttributeValue{
+		TraceparentAttribute: {DataType: aws.String("String"), StringValue: aws.String(traceparent)},
+	}
+	if tracestate != "" {
+		attributes[TracestateAttribute] = types.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(tracestate)}
+	}
+	return attributes
+}
+
+// traceContext is the W3C propagator that reads and writes traceparent.
+var traceContext = propagation.TraceContext{}
+
+// InjectTraceparent formats spanContext as a W3C traceparent, or "" when it
+// is not valid.
+func InjectTraceparent(spanContext trace.SpanContext) string {
+	carrier := propagation.MapCarrier{}
+	traceContext.Inject(trace.ContextWithSpanContext(context.Background(), spanContext), carrier)
+	return carrier.Get(TraceparentAttribute)
+}
+
+// InjectTracestate formats the vendor-specific trace state of spanContext as
+// a W3C tracestate, or "" when it has none.
+func InjectTracestate(spanContext trace.SpanContext) string {
+	return spanContext.TraceState().String()
+}
+
+// traceparentPattern is the shape of a W3C traceparent: version, trace ID,
+// parent span ID and flags, with anything after the flags only allowed in
+// versions after 00.
+var traceparentPattern = regexp.MustCompile(`^([0-9a-f]{2})-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})(-.*)?$`)
+
+// ParseTraceContext parses a propagated traceparent and tracestate into the
+// remote span context they describe. A tracestate that is not valid is
+// dropped rather than losing the trace, as the W3C spec asks.
+func ParseTraceContext(traceparent, tracestate string) (trace.SpanContext, error) {
+	match := traceparentPattern.FindStringSubmatch(traceparent)
+	if match == nil {
+		return trace.SpanContext{}, fmt.Errorf("traceparent %q is not of the form version-traceid-spanid-flags", traceparent)
+	}
+	version, traceIDHex, spanIDHex, flagsHex, extra := match[1], match[2], match[3], match[4], match[5]
+	if version == "ff" || (version == "00" && extra != "") {
+		return trace.SpanContext{}, fmt.Errorf("traceparent %q has an invalid version", traceparent)
+	}
+	traceID, err := trace.TraceIDFromHex(traceIDHex)
+	if err != nil {
+		return trace.SpanContext{}, fmt.Errorf("traceparent %q has an invalid trace ID: %w", traceparent, err)
+	}
+	spanID, err := trace.SpanIDFromHex(spanIDHex)
+	if err != nil {
+		return trace.SpanContext{}, fmt.Errorf("traceparent %q has an invalid span ID: %w", traceparent, err)
+	}
+	flags, err := hex.DecodeString(flagsHex)
+	if err != nil {
+		return trace.SpanContext{}, fmt.Errorf("traceparent %q has invalid flags: %w", traceparent, err)
+	}
+
+	state, err := trace.ParseTraceState(tracestate)
+	if err != nil {
+		log.Printf("ignoring invalid tracestate %q: %v", tracestate, err)
+		state = trace.TraceState{}
+	}
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.TraceFlags(flags[0]) & trace.FlagsSampled,
+		TraceState: state,
+		Remote:     true,
+	})
+	if !spanContext.IsValid() {
+		return trace.SpanContext{}, errors.New("traceparent does not identify a span")
+	}
+	return spanContext, nil
+}
+
+// ExtractTraceContext returns ctx carrying the remote span context in
+// traceparent and tracestate, or ctx unchanged when traceparent is empty or
+// malformed.
+func ExtractTraceContext(ctx context.Context, traceparent, tracestate string) context.Context {
+	spanContext, err := ParseTraceContext(traceparent, tracestate)
+	if err != nil {
+		return ctx
+	}
+	return trace.ContextWithRemoteSpanContext(ctx, spanContext)
+}
+
+// ExtractTraceparent is ExtractTraceContext for a traceparent without any
+// tracestate.
+func ExtractTraceparent(ctx context.Context, traceparent string) context.Context {
+	return ExtractTraceContext(ctx, traceparent, "")
+}
@@ -0,0 +1,132 @@
+package job
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestParsePropagation(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{value: "", expected: PropagationBody},
+		{value: "body", expected: PropagationBody},
+		{value: "Attributes", expected: PropagationAttributes},
+		{value: " both ", expected: PropagationBoth},
+		{value: "header", expected: PropagationBody},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if actual := ParsePropagation(tt.value, PropagationBody); actual != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestTraceMessageAttributes(t *testing.T) {
+	const traceparent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	tests := []struct {
+		name        string
+		carrier     string
+		traceparent string
+		tracestate  string
+		expected    map[string]string
+	}{
+		{name: "Body only", carrier: PropagationBody, traceparent: traceparent},
+		{name: "No trace context", carrier: PropagationAttributes},
+		{name: "Attributes", carrier: PropagationAttributes, traceparent: traceparent, expected: map[string]string{TraceparentAttribute: traceparent}},
+		{
+			name:        "Both with tracestate",
+			carrier:     PropagationBoth,
+			traceparent: traceparent,
+			tracestate:  "vendor=opaque",
+			expected:    map[string]string{TraceparentAttribute: traceparent, TracestateAttribute: "vendor=opaque"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attributes := TraceMessageAttributes(tt.carrier, tt.traceparent, tt.tracestate)
+			if len(attributes) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, attributes)
+			}
+			for name, value := range tt.expected {
+				if aws.ToString(attributes[name].StringValue) != value || aws.ToString(attributes[name].DataType) != "String" {
+					t.Errorf("expected %s of %q, got %+v", name, value, attributes[name])
+				}
+			}
+		})
+	}
+}
+
+func TestTraceparentRoundTrip(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+		expected    string
+	}{
+		{name: "Sampled", traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", expected: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{name: "Not sampled", traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", expected: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"},
+		{name: "Future version with extra fields", traceparent: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", expected: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{name: "One character short", traceparent: "00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01", expected: ""},
+		{name: "Empty", traceparent: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spanContext := trace.SpanContextFromContext(ExtractTraceparent(context.Background(), tt.traceparent))
+			if tt.expected != "" && !spanContext.IsRemote() {
+				t.Errorf("expected a remote span context from %q", tt.traceparent)
+			}
+			if actual := InjectTraceparent(spanContext); actual != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestParseTraceContext(t *testing.T) {
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	tests := []struct {
+		name          string
+		traceparent   string
+		tracestate    string
+		expectErr     bool
+		expectedState string
+	}{
+		{name: "Without tracestate", traceparent: traceparent},
+		{name: "With tracestate", traceparent: traceparent, tracestate: "vendor=opaque,other=1", expectedState: "vendor=opaque,other=1"},
+		{name: "Invalid tracestate is dropped", traceparent: traceparent, tracestate: "not a list member"},
+		{name: "Future version with extra fields", traceparent: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"},
+		{name: "Version 00 with extra fields", traceparent: traceparent + "-extra", expectErr: true},
+		{name: "Forbidden version", traceparent: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", expectErr: true},
+		{name: "Uppercase hex", traceparent: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", expectErr: true},
+		{name: "Zero trace ID", traceparent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01", expectErr: true},
+		{name: "Zero span ID", traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", expectErr: true},
+		{name: "Empty", traceparent: "", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spanContext, err := ParseTraceContext(tt.traceparent, tt.tracestate)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("expected error %v, got %v", tt.expectErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if !spanContext.IsValid() || !spanContext.IsRemote() || !spanContext.IsSampled() {
+				t.Errorf("expected a valid, remote, sampled span context, got %+v", spanContext)
+			}
+			if actual := InjectTracestate(spanContext); actual != tt.expectedState {
+				t.Errorf("expected tracestate %q, got %q", tt.expectedState, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package job
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SQS message attributes recorded on quarantined messages.
+const (
+	QuarantineReasonAttribute       = "quarantine_reason"
+	QuarantineReceiveCountAttribute = "quarantine_receive_count"
+)
+
+// ValidateQuarantined checks that a quarantined message body is now a valid
+// enriched payload holding a valid job, so it is safe to release back onto
+// jobs-todo.
+func ValidateQuarantined(body []byte) error {
+	payload, _ := UnwrapSNS(body)
+	var enriched EnrichedPayload
+	if err := json.Unmarshal(payload, &enriched); err != nil {
+		return fmt.Errorf("invalid enriched payload: %w", err)
+	}
+	if enriched.ID == "" {
+		return fmt.Errorf("enriched payload has no ID")
+	}
+	if _, _, _, err := ParseJob(enriched.OriginalMessage); err != nil {
+		return fmt.Errorf("invalid job: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package job
+
+import "testing"
+
+func TestValidateQuarantined(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		expectError bool
+	}{
+		{
+			name: "Valid payload",
+			body: `{"originalmessage": {"job_type": "data_cleanup", "message": {"target_table": "users", "retention": 30}}, "id": "12345", "status": "NEW"}`,
+		},
+		{
+			name: "SNS wrapped payload",
+			body: `{"Type": "Notification", "TopicArn": "arn:aws:sns:us-east-1:000000000000:jobs", "Message": "{\"originalmessage\": {\"job_type\": \"data_cleanup\", \"message\": {\"target_table\": \"users\", \"retention\": 30}}, \"id\": \"12345\"}"}`,
+		},
+		{
+			name:        "Invalid job",
+			body:        `{"originalmessage": {"job_type": "data_cleanup", "message": {"retention": 30}}, "id": "12345"}`,
+			expectError: true,
+		},
+		{
+			name:        "Missing ID",
+			body:        `{"originalmessage": {"job_type": "data_cleanup", "message": {"target_table": "users", "retention": 30}}}`,
+			expectError: true,
+		},
+		{
+			name:        "Not JSON",
+			body:        `not json`,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateQuarantined([]byte(tt.body))
+			if tt.expectError != (err != nil) {
+				t.Errorf("expected error %v, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
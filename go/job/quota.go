@@ -0,0 +1,46 @@
+package job
+
+import (
+	"sync"
+	"time"
+)
+
+// TenantQuota limits how many jobs each tenant may execute within a sliding
+// window, so no single tenant starves the others. It is safe for concurrent
+// use.
+type TenantQuota struct {
+	clock  Clock
+	limit  int
+	window time.Duration
+
+	mu         sync.Mutex
+	executions map[string][]time.Time // tenant -> start times within the window, oldest first
+}
+
+// NewTenantQuota creates a quota allowing each tenant limit executions per
+// window, timed by clock.
+func NewTenantQuota(clock Clock, limit int, window time.Duration) *TenantQuota {
+	return &TenantQuota{clock: clock, limit: limit, window: window, executions: map[string][]time.Time{}}
+}
+
+// Allow reports whether tenant is within its quota, recording an execution
+// if so. Throttled jobs don't count against the quota.
+func (q *TenantQuota) Allow(tenant string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.clock.Now()
+	executions := q.executions[tenant]
+	expired := 0
+	for expired < len(executions) && !executions[expired].After(now.Add(-q.window)) {
+		expired++
+	}
+	executions = executions[expired:]
+
+	if len(executions) >= q.limit {
+		q.executions[tenant] = executions
+		return false
+	}
+	q.executions[tenant] = append(executions, now)
+	return true
+}
@@ -0,0 +1,57 @@
+package job
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTenantQuota(t *testing.T) {
+	clock := &steppedClock{now: time.Date(2025, 8, 30, 12, 0, 0, 0, time.UTC)}
+	quota := NewTenantQuota(clock, 2, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		if !quota.Allow("tenant-a") {
+			t.Fatalf("expected execution %d of tenant-a to be allowed", i+1)
+		}
+	}
+	if quota.Allow("tenant-a") {
+		t.Errorf("expected tenant-a to be throttled over its quota")
+	}
+	if !quota.Allow("tenant-b") {
+		t.Errorf("expected tenant-b to proceed while tenant-a is throttled")
+	}
+
+	// Throttled attempts don't extend the window
+	clock.advance(30 * time.Second)
+	if quota.Allow("tenant-a") {
+		t.Errorf("expected tenant-a to still be throttled within the window")
+	}
+	clock.advance(30 * time.Second)
+	if !quota.Allow("tenant-a") {
+		t.Errorf("expected tenant-a to be allowed once the window has slid")
+	}
+}
+
+func TestMessageField(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		field    string
+		expected string
+		ok       bool
+	}{
+		{name: "String", message: `{"job_type": "user_onboarding", "message": {"user_id": "user_1"}}`, field: "user_id", expected: "user_1", ok: true},
+		{name: "Number", message: `{"job_type": "data_cleanup", "message": {"retention": 30}}`, field: "retention", expected: "30", ok: true},
+		{name: "Missing", message: `{"job_type": "data_cleanup", "message": {"retention": 30}}`, field: "tenant_id"},
+		{name: "Invalid", message: `not json`, field: "user_id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, ok := MessageField([]byte(tt.message), tt.field)
+			if actual != tt.expected || ok != tt.ok {
+				t.Errorf("expected %q %v, got %q %v", tt.expected, tt.ok, actual, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package job
+
+import (
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ReceivedAttributes describes a raw message body for the "received" span
+// event: its size and up to previewBytes of it, cut on a rune boundary.
+func ReceivedAttributes(body string, previewBytes int) []attribute.KeyValue {
+	preview := body
+	if previewBytes >= 0 && len(preview) > previewBytes {
+		preview = preview[:previewBytes]
+		for len(preview) > 0 && !utf8.ValidString(preview) {
+			preview = preview[:len(preview)-1]
+		}
+	}
+	return []attribute.KeyValue{
+		attribute.Int("body.size_bytes", len(body)),
+		attribute.String("body.preview", preview),
+		attribute.Bool("body.truncated", len(preview) < len(body)),
+	}
+}
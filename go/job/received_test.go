@@ -0,0 +1,40 @@
+package job
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestReceivedAttributes(t *testing.T) {
+	tests := []struct {
+		name              string
+		body              string
+		previewBytes      int
+		expectedPreview   string
+		expectedTruncated bool
+	}{
+		{name: "Short body", body: `{"id":"1"}`, previewBytes: 64, expectedPreview: `{"id":"1"}`},
+		{name: "Truncated", body: `{"id":"12345"}`, previewBytes: 6, expectedPreview: `{"id":`, expectedTruncated: true},
+		{name: "Cut on a rune boundary", body: `{"n":"é"}`, previewBytes: 7, expectedPreview: `{"n":"`, expectedTruncated: true},
+		{name: "Empty", body: "", previewBytes: 64, expectedPreview: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs := map[attribute.Key]attribute.Value{}
+			for _, kv := range ReceivedAttributes(tt.body, tt.previewBytes) {
+				attrs[kv.Key] = kv.Value
+			}
+			if size := attrs["body.size_bytes"].AsInt64(); size != int64(len(tt.body)) {
+				t.Errorf("expected body.size_bytes %d, got %d", len(tt.body), size)
+			}
+			if preview := attrs["body.preview"].AsString(); preview != tt.expectedPreview {
+				t.Errorf("expected body.preview %q, got %q", tt.expectedPreview, preview)
+			}
+			if truncated := attrs["body.truncated"].AsBool(); truncated != tt.expectedTruncated {
+				t.Errorf("expected body.truncated %v, got %v", tt.expectedTruncated, truncated)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package job
+
+import (
+	"encoding/json"
+)
+
+// Redacted replaces the values of redacted fields.
+const Redacted = "[REDACTED]"
+
+// RedactMessage returns a copy of a JSON document with the values of the
+// named fields replaced by Redacted wherever they appear, so payloads can be
+// logged without leaking personal data. Documents that aren't valid JSON are
+// returned as Redacted in full.
+func RedactMessage(message []byte, fields []string) []byte {
+	var document any
+	if err := json.Unmarshal(message, &document); err != nil {
+		return []byte(`"` + Redacted + `"`)
+	}
+	if len(fields) == 0 {
+		return message
+	}
+
+	redact := map[string]bool{}
+	for _, field := range fields {
+		redact[field] = true
+	}
+	redacted, err := json.Marshal(redactValue(document, redact))
+	if err != nil {
+		return []byte(`"` + Redacted + `"`)
+	}
+	return redacted
+}
+
+func redactValue(value any, redact map[string]bool) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			if redact[key] {
+				v[key] = Redacted
+			} else {
+				v[key] = redactValue(child, redact)
+			}
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = redactValue(child, redact)
+		}
+	case string:
+		// Enriched payloads carry the original message as nested JSON
+		var nested any
+		if err := json.Unmarshal([]byte(v), &nested); err == nil {
+			if _, ok := nested.(map[string]any); ok {
+				redacted, _ := json.Marshal(redactValue(nested, redact))
+				return string(redacted)
+			}
+		}
+	}
+	return value
+}
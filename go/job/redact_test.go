@@ -0,0 +1,60 @@
+package job
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRedactMessage(t *testing.T) {
+	fields := []string{"user_id", "user_name"}
+
+	tests := []struct {
+		name     string
+		message  string
+		fields   []string
+		expected string
+	}{
+		{
+			name:     "Job message",
+			message:  `{"job_type":"user_onboarding","message":{"user_id":"user-001","user_name":"John Doe"}}`,
+			fields:   fields,
+			expected: `{"job_type":"user_onboarding","message":{"user_id":"[REDACTED]","user_name":"[REDACTED]"}}`,
+		},
+		{
+			name:     "Enriched payload",
+			message:  `{"originalmessage":{"job_type":"user_onboarding","message":{"user_id":"user-001","user_name":"John Doe"}},"id":"12345"}`,
+			fields:   fields,
+			expected: `{"id":"12345","originalmessage":{"job_type":"user_onboarding","message":{"user_id":"[REDACTED]","user_name":"[REDACTED]"}}}`,
+		},
+		{
+			name:     "Nothing to redact",
+			message:  `{"job_type":"data_cleanup","message":{"target_table":"users","retention":30}}`,
+			fields:   fields,
+			expected: `{"job_type":"data_cleanup","message":{"retention":30,"target_table":"users"}}`,
+		},
+		{
+			name:     "No fields configured",
+			message:  `{"job_type":"user_onboarding","message":{"user_id":"user-001"}}`,
+			fields:   nil,
+			expected: `{"job_type":"user_onboarding","message":{"user_id":"user-001"}}`,
+		},
+		{
+			name:     "Invalid JSON",
+			message:  `user-001 John Doe`,
+			fields:   fields,
+			expected: `"[REDACTED]"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := RedactMessage([]byte(tt.message), tt.fields)
+			if !json.Valid(actual) {
+				t.Fatalf("expected valid JSON, got %s", actual)
+			}
+			if string(actual) != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package job
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+var (
+	registryMu         sync.RWMutex
+	registeredJobTypes = map[JobType]func() Job{}
+)
+
+func init() {
+	RegisterJobType(string(ReportGeneration), func() Job { return ReportGenerationJob{} })
+	RegisterJobType(string(DataCleanup), func() Job { return DataCleanupJob{} })
+	RegisterJobType(string(UserOnboarding), func() Job { return UserOnboardingJob{} })
+	RegisterJobType(string(LongRunning), func() Job { return LongRunningJob{} })
+	RegisterJobType(string(Batch), func() Job { return BatchJob{} })
+	RegisterJobType(string(EmailNotification), func() Job { return EmailNotificationJob{} })
+	RegisterJobType(string(Cancel), func() Job { return CancelJob{} })
+	RegisterJobType(string(Generic), func() Job { return GenericJob{} })
+}
+
+// RegisterJobType makes ParseJob accept jobs whose job_type is name, decoding
+// their message into the job factory returns. Registering a name again
+// replaces its factory. It is safe to call from init in any package.
+func RegisterJobType(name string, factory func() Job) {
+	if factory == nil {
+		panic(fmt.Sprintf("job: RegisterJobType factory for %q is nil", name))
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registeredJobTypes[JobType(name)] = factory
+}
+
+// SwapJobType registers factory for name as RegisterJobType does, returning
+// a func that puts back whatever name was registered as before, or removes it
+// if it wasn't. It lets tests register a job type without leaking it into
+// RegisteredJobTypes and VerifyRegistry for the rest of the process.
+func SwapJobType(name string, factory func() Job) (restore func()) {
+	previous, existed := jobFactory(JobType(name))
+	RegisterJobType(name, factory)
+	return func() {
+		registryMu.Lock()
+		defer registryMu.Unlock()
+		if existed {
+			registeredJobTypes[JobType(name)] = previous
+		} else {
+			delete(registeredJobTypes, JobType(name))
+		}
+	}
+}
+
+// RegisteredJobTypes returns the job types ParseJob accepts, the built-ins
+// and any added with RegisterJobType, sorted by name.
+func RegisteredJobTypes() []JobType {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return sortedJobTypes()
+}
+
+// sortedJobTypes returns the registered job types sorted by name. The caller
+// must hold registryMu.
+func sortedJobTypes() []JobType {
+	jobTypes := make([]JobType, 0, len(registeredJobTypes))
+	for jobType := range registeredJobTypes {
+		jobTypes = append(jobTypes, jobType)
+	}
+	sort.Slice(jobTypes, func(i, j int) bool { return jobTypes[i] < jobTypes[j] })
+	return jobTypes
+}
+
+// jobFactory returns the factory registered for jobType.
+func jobFactory(jobType JobType) (func() Job, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	factory, ok := registeredJobTypes[jobType]
+	return factory, ok
+}
+
+// decodeJob decodes message into a new job made by factory. Factories
+// returning a pointer are decoded into directly, otherwise into a copy of the
+// returned value so any defaults it sets are kept. Unknown fields are handled
+// as unknownFields, see decodeMessage.
+func decodeJob(factory func() Job, message json.RawMessage, unknownFields string) (Job, error) {
+	job := factory()
+	if job == nil {
+		return nil, errors.New("job type factory returned no job")
+	}
+	value := reflect.ValueOf(job)
+	if value.Kind() == reflect.Pointer {
+		if err := decodeMessage(message, job, unknownFields); err != nil {
+			return nil, err
+		}
+		return job, nil
+	}
+
+	target := reflect.New(value.Type())
+	target.Elem().Set(value)
+	if err := decodeMessage(message, target.Interface(), unknownFields); err != nil {
+		return nil, err
+	}
+	return target.Elem().Interface().(Job), nil
+}
+
+// VerifyRegistry checks every registered job type is wired up correctly: its
+// factory returns a job, the job reports the type it is registered as, and
+// Validate rejects its zero value. Services call it at startup so a
+// registration mistake fails fast rather than accepting empty jobs.
+func VerifyRegistry() error {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	var errs []error
+	for _, jobType := range sortedJobTypes() {
+		job := registeredJobTypes[jobType]()
+		switch {
+		case job == nil:
+			errs = append(errs, fmt.Errorf("%s: factory returned no job", jobType))
+		case job.Name() != jobType:
+			errs = append(errs, fmt.Errorf("%s: factory returned a %s job", jobType, job.Name()))
+		case job.Validate() == nil:
+			errs = append(errs, fmt.Errorf("%s: Validate accepts the zero value", jobType))
+		}
+	}
+	return errors.Join(errs...)
+}
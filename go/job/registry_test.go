@@ -0,0 +1,191 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// permissiveJob accepts anything, as a type with Validate left unimplemented would
+type permissiveJob struct{}
+
+func (permissiveJob) Validate() error                                { return nil }
+func (permissiveJob) Execute(ctx context.Context) (JobResult, error) { return JobResult{}, nil }
+func (permissiveJob) Name() JobType                                  { return "permissive_job" }
+
+// emailJob is a job type registered from outside the built-ins
+type emailJob struct {
+	To       string `json:"to"`
+	Priority string `json:"priority"`
+}
+
+func (j emailJob) Validate() error {
+	if j.To == "" {
+		return errors.New("to is required")
+	}
+	return nil
+}
+func (emailJob) Execute(ctx context.Context) (JobResult, error) { return JobResult{}, nil }
+func (emailJob) Name() JobType                                  { return "send_email" }
+
+// withJobType registers a job type for the duration of a test
+func withJobType(t *testing.T, name string, factory func() Job) {
+	t.Helper()
+	t.Cleanup(SwapJobType(name, factory))
+}
+
+func TestRegisterJobType(t *testing.T) {
+	tests := []struct {
+		name     string
+		factory  func() Job
+		expected Job
+	}{
+		{
+			name:     "Value factory keeps its defaults",
+			factory:  func() Job { return emailJob{Priority: "normal"} },
+			expected: emailJob{To: "ops@example.com", Priority: "normal"},
+		},
+		{
+			name:     "Pointer factory",
+			factory:  func() Job { return &emailJob{} },
+			expected: &emailJob{To: "ops@example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withJobType(t, "send_email", tt.factory)
+
+			job, _, jobType, err := ParseJob([]byte(`{"job_type":"send_email","message":{"to":"ops@example.com"}}`))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if *jobType != "send_email" || fmt.Sprintf("%#v", job) != fmt.Sprintf("%#v", tt.expected) {
+				t.Errorf("expected %#v, got %#v", tt.expected, job)
+			}
+
+			if _, _, _, err := ParseJob([]byte(`{"job_type":"send_email","message":{}}`)); err == nil || !strings.Contains(err.Error(), "to is required") {
+				t.Errorf("expected the registered type's validation to run, got %v", err)
+			}
+		})
+	}
+
+	if _, _, _, err := ParseJob([]byte(`{"job_type":"send_email","message":{"to":"ops@example.com"}}`)); err == nil || !strings.Contains(err.Error(), "unknown job type") {
+		t.Errorf("expected an unknown job type error once unregistered, got %v", err)
+	}
+}
+
+func TestSwapJobType(t *testing.T) {
+	builtins := RegisteredJobTypes()
+
+	restore := SwapJobType("send_email", func() Job { return emailJob{} })
+	restoreBuiltin := SwapJobType(string(Cancel), func() Job { return emailJob{} })
+	if job, _, _, err := ParseJob([]byte(`{"job_type":"cancel_job","message":{"to":"jane@example.com"}}`)); err != nil || job.Name() != "send_email" {
+		t.Fatalf("expected cancel_job swapped for send_email, got %v, %v", job, err)
+	}
+	restoreBuiltin()
+	restore()
+
+	if actual := RegisteredJobTypes(); !reflect.DeepEqual(actual, builtins) {
+		t.Errorf("expected the job types registered before, %v, got %v", builtins, actual)
+	}
+	if job, _, _, err := ParseJob([]byte(`{"job_type":"cancel_job","message":{"target_id":"67890"}}`)); err != nil || job.Name() != Cancel {
+		t.Errorf("expected cancel_job restored, got %v, %v", job, err)
+	}
+}
+
+func TestRegisterJobTypeNilFactory(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected registering a nil factory to panic")
+		}
+	}()
+	RegisterJobType("nil_job", nil)
+}
+
+func TestRegisterJobTypeConcurrently(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("concurrent_job_%d", i)
+		t.Cleanup(func() {
+			registryMu.Lock()
+			defer registryMu.Unlock()
+			delete(registeredJobTypes, JobType(name))
+		})
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			RegisterJobType(name, func() Job { return emailJob{} })
+		}()
+		go func() {
+			defer wg.Done()
+			ParseJob([]byte(`{"job_type":"report_generation","message":{"report_name":"Sales Report","filters":"region=US"}}`))
+		}()
+	}
+	wg.Wait()
+	for i := 0; i < 10; i++ {
+		if _, ok := jobFactory(JobType(fmt.Sprintf("concurrent_job_%d", i))); !ok {
+			t.Errorf("expected concurrent_job_%d to be registered", i)
+		}
+	}
+}
+
+func TestVerifyRegistry(t *testing.T) {
+	if err := VerifyRegistry(); err != nil {
+		t.Fatalf("expected the built-in job types to verify, got %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		jobType  string
+		factory  func() Job
+		expected string
+	}{
+		{
+			name:     "Validate accepts the zero value",
+			jobType:  "permissive_job",
+			factory:  func() Job { return permissiveJob{} },
+			expected: "permissive_job: Validate accepts the zero value",
+		},
+		{
+			name:     "Factory returns another type",
+			jobType:  "misregistered_job",
+			factory:  func() Job { return DataCleanupJob{} },
+			expected: "misregistered_job: factory returned a data_cleanup job",
+		},
+		{
+			name:     "Factory returns nil",
+			jobType:  "missing_job",
+			factory:  func() Job { return nil },
+			expected: "missing_job: factory returned no job",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withJobType(t, tt.jobType, tt.factory)
+
+			err := VerifyRegistry()
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected an error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestRegisteredJobTypes(t *testing.T) {
+	builtIns := []JobType{Batch, Cancel, DataCleanup, EmailNotification, Generic, LongRunning, ReportGeneration, UserOnboarding}
+	if actual := RegisteredJobTypes(); !reflect.DeepEqual(actual, builtIns) {
+		t.Errorf("expected the built-in job types %v, got %v", builtIns, actual)
+	}
+
+	withJobType(t, "send_email", func() Job { return emailJob{} })
+	expected := []JobType{Batch, Cancel, DataCleanup, EmailNotification, Generic, LongRunning, ReportGeneration, "send_email", UserOnboarding}
+	if actual := RegisteredJobTypes(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v once send_email is registered, got %v", expected, actual)
+	}
+}
@@ -0,0 +1,33 @@
+package job
+
+import (
+	"log"
+	"strconv"
+	"strings"
+)
+
+// MinRetentionDays maps target tables to the shortest retention, in days, a
+// data cleanup may use on them. Tables without an entry accept any positive
+// retention.
+var MinRetentionDays = map[string]int{}
+
+// ParseMinRetention parses a comma separated list of table=days pairs, e.g.
+// "users=90,audit_log=365". Malformed entries are logged and skipped.
+func ParseMinRetention(policies string) map[string]int {
+	parsed := map[string]int{}
+	for _, policy := range strings.Split(policies, ",") {
+		policy = strings.TrimSpace(policy)
+		if policy == "" {
+			continue
+		}
+		table, days, ok := strings.Cut(policy, "=")
+		table = strings.TrimSpace(table)
+		minimum, err := strconv.Atoi(strings.TrimSpace(days))
+		if !ok || table == "" || err != nil || minimum <= 0 {
+			log.Printf("ignoring malformed retention policy: %q", policy)
+			continue
+		}
+		parsed[table] = minimum
+	}
+	return parsed
+}
@@ -0,0 +1,52 @@
+package job
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMinRetention(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]int
+	}{
+		{name: "Empty", input: "", expected: map[string]int{}},
+		{name: "Multiple policies", input: "users=90, audit_log=365", expected: map[string]int{"users": 90, "audit_log": 365}},
+		{name: "Malformed entries are skipped", input: "users,=30,orders=soon,logs=0,sessions=7", expected: map[string]int{"sessions": 7}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := ParseMinRetention(tt.input); !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDataCleanupMinRetention(t *testing.T) {
+	previous := MinRetentionDays
+	MinRetentionDays = map[string]int{"users": 90}
+	defer func() { MinRetentionDays = previous }()
+
+	tests := []struct {
+		name        string
+		job         DataCleanupJob
+		expectError bool
+	}{
+		{name: "Above minimum", job: DataCleanupJob{TargetTable: "users", Retention: 120}},
+		{name: "At minimum", job: DataCleanupJob{TargetTable: "users", Retention: 90}},
+		{name: "Below minimum", job: DataCleanupJob{TargetTable: "users", Retention: 30}, expectError: true},
+		{name: "Table without a minimum", job: DataCleanupJob{TargetTable: "sessions", Retention: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.job.Validate()
+			if tt.expectError != (err != nil) {
+				t.Errorf("expected error %v, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package job
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// SampleValue hashes id to a number in [0, 1), uniform across IDs and always
+// the same for a given ID. SHA-256 rather than FNV keeps sequential IDs such
+// as 12345, 12346 spread evenly.
+func SampleValue(id string) float64 {
+	sum := sha256.Sum256([]byte(id))
+	return float64(binary.BigEndian.Uint64(sum[:8])>>11) / (1 << 53)
+}
+
+// SampleDecision reports whether id falls in a ratio sized sample, e.g. 0.1
+// for 10%. The decision depends only on the ID, so a job is sampled the same
+// way by every feature, invocation and retry.
+func SampleDecision(id string, ratio float64) bool {
+	if ratio <= 0 {
+		return false
+	}
+	if ratio >= 1 {
+		return true
+	}
+	return SampleValue(id) < ratio
+}
@@ -0,0 +1,39 @@
+package job
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestSampleDecisionStable(t *testing.T) {
+	for _, id := range []string{"12345", "12345-0", "job-a", ""} {
+		first := SampleDecision(id, 0.5)
+		for i := 0; i < 10; i++ {
+			if SampleDecision(id, 0.5) != first {
+				t.Fatalf("expected a stable decision for %q", id)
+			}
+		}
+		// Raising the ratio only ever adds IDs to the sample
+		if first && !SampleDecision(id, 0.75) {
+			t.Errorf("expected %q to stay sampled at a higher ratio", id)
+		}
+	}
+}
+
+func TestSampleDecisionRatio(t *testing.T) {
+	const ids = 20000
+	for _, ratio := range []float64{0, 0.01, 0.1, 0.5, 0.9, 1} {
+		t.Run(fmt.Sprintf("ratio %v", ratio), func(t *testing.T) {
+			sampled := 0
+			for i := 0; i < ids; i++ {
+				if SampleDecision(fmt.Sprintf("%d", 10000+i), ratio) {
+					sampled++
+				}
+			}
+			if actual := float64(sampled) / ids; math.Abs(actual-ratio) > 0.02 {
+				t.Errorf("expected about %.2f of IDs sampled, got %.4f", ratio, actual)
+			}
+		})
+	}
+}
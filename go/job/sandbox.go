@@ -0,0 +1,95 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/metrics"
+	"time"
+)
+
+// ErrResourceLimitExceeded is the cause of a sandboxed job's cancellation
+// when it uses more CPU time or memory than its limits allow.
+var ErrResourceLimitExceeded = errors.New("resource_limit_exceeded")
+
+// ResourceLimits bound a sandboxed job. Go can't attribute resources to a
+// goroutine, so the watchdog samples the whole process: CPU time used since
+// the job started and live heap memory. Zero disables a limit.
+type ResourceLimits struct {
+	MaxCPUTime     time.Duration
+	MaxMemoryBytes uint64
+	Interval       time.Duration // how often usage is sampled, defaults to 100ms
+}
+
+// resourceSample is a point-in-time reading of the process' resource usage.
+type resourceSample struct {
+	cpuTime     time.Duration
+	memoryBytes uint64
+}
+
+// sampleResources reads the process' resource usage, replaced in tests.
+var sampleResources = func() resourceSample {
+	samples := []metrics.Sample{
+		{Name: "/cpu/classes/total:cpu-seconds"},
+		{Name: "/memory/classes/heap/objects:bytes"},
+	}
+	metrics.Read(samples)
+	var sample resourceSample
+	if samples[0].Value.Kind() == metrics.KindFloat64 {
+		sample.cpuTime = time.Duration(samples[0].Value.Float64() * float64(time.Second))
+	}
+	if samples[1].Value.Kind() == metrics.KindUint64 {
+		sample.memoryBytes = samples[1].Value.Uint64()
+	}
+	return sample
+}
+
+// RunWithLimits runs job under a watchdog goroutine that cancels its context
+// once limits are exceeded, returning an error wrapping
+// ErrResourceLimitExceeded.
+func RunWithLimits(ctx context.Context, limits ResourceLimits, job Job) (JobResult, error) {
+	interval := limits.Interval
+	if interval <= 0 {
+		interval = 100 * time.Millisecond
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	done := make(chan struct{})
+	watchdogStopped := make(chan struct{})
+	go func() {
+		defer close(watchdogStopped)
+		start := sampleResources()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				sample := sampleResources()
+				if used := sample.cpuTime - start.cpuTime; limits.MaxCPUTime > 0 && used > limits.MaxCPUTime {
+					cancel(fmt.Errorf("%w: used %s of CPU time, limit %s", ErrResourceLimitExceeded, used, limits.MaxCPUTime))
+					return
+				}
+				if limits.MaxMemoryBytes > 0 && sample.memoryBytes > limits.MaxMemoryBytes {
+					cancel(fmt.Errorf("%w: using %d bytes of memory, limit %d", ErrResourceLimitExceeded, sample.memoryBytes, limits.MaxMemoryBytes))
+					return
+				}
+			}
+		}
+	}()
+
+	result, err := job.Execute(ctx)
+	close(done)
+	<-watchdogStopped
+
+	// Report the limit rather than the bare cancellation it caused
+	if cause := context.Cause(ctx); errors.Is(cause, ErrResourceLimitExceeded) {
+		return result, cause
+	}
+	return result, err
+}
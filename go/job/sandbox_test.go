@@ -0,0 +1,64 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunWithLimits(t *testing.T) {
+	previous := sampleResources
+	defer func() { sampleResources = previous }()
+
+	tests := []struct {
+		name        string
+		limits      ResourceLimits
+		cpuPerTick  time.Duration
+		memory      uint64
+		expectLimit bool
+	}{
+		{name: "CPU time exceeded", limits: ResourceLimits{MaxCPUTime: 25 * time.Millisecond}, cpuPerTick: 10 * time.Millisecond, expectLimit: true},
+		{name: "Memory exceeded", limits: ResourceLimits{MaxMemoryBytes: 1024}, memory: 4096, expectLimit: true},
+		{name: "Within limits", limits: ResourceLimits{MaxCPUTime: time.Hour, MaxMemoryBytes: 1 << 30}, cpuPerTick: time.Millisecond, memory: 4096},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ticks int64
+			sampleResources = func() resourceSample {
+				tick := atomic.AddInt64(&ticks, 1)
+				return resourceSample{cpuTime: time.Duration(tick) * tt.cpuPerTick, memoryBytes: tt.memory}
+			}
+			tt.limits.Interval = time.Millisecond
+
+			job := LongRunningJob{TaskName: "Hog", Timeout: 1}
+			start := time.Now()
+			_, err := RunWithLimits(context.Background(), tt.limits, job)
+
+			if !tt.expectLimit {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrResourceLimitExceeded) {
+				t.Fatalf("expected a resource limit error, got %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("expected the job to be cancelled early, ran for %s", elapsed)
+			}
+		})
+	}
+}
+
+func TestRunWithLimitsJobError(t *testing.T) {
+	// A job failing on its own keeps its error
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := RunWithLimits(ctx, ResourceLimits{MaxMemoryBytes: 1 << 40}, LongRunningJob{TaskName: "Hog", Timeout: 1})
+	if !errors.Is(err, context.Canceled) || errors.Is(err, ErrResourceLimitExceeded) {
+		t.Errorf("expected the job's own cancellation, got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package job
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+var (
+	schemasMu  sync.RWMutex
+	jobSchemas = map[JobType]*jsonschema.Schema{}
+)
+
+// LoadSchemas compiles a JSON Schema for each <job_type>.json file in dir.
+// ParseJob then validates the message of any job with a loaded schema before
+// the struct level Validate runs. Successive calls replace the loaded set.
+func LoadSchemas(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("failed to list schemas in %s: %w", dir, err)
+	}
+
+	schemas := map[JobType]*jsonschema.Schema{}
+	for _, path := range paths {
+		schema, err := jsonschema.Compile(path)
+		if err != nil {
+			return fmt.Errorf("failed to compile schema %s: %w", path, err)
+		}
+		jobType := JobType(strings.TrimSuffix(filepath.Base(path), ".json"))
+		schemas[jobType] = schema
+		log.Printf("Loaded JSON schema for %s from %s", jobType, path)
+	}
+
+	schemasMu.Lock()
+	jobSchemas = schemas
+	schemasMu.Unlock()
+	return nil
+}
+
+// LoadSchemasFromEnv loads schemas from JOB_SCHEMA_DIR when it is set.
+func LoadSchemasFromEnv() error {
+	dir := os.Getenv("JOB_SCHEMA_DIR")
+	if dir == "" {
+		return nil
+	}
+	return LoadSchemas(dir)
+}
+
+// validateSchema validates a job's message against the schema loaded for its
+// type. Types without a schema always pass.
+func validateSchema(jobType JobType, message json.RawMessage) error {
+	schemasMu.RLock()
+	schema, ok := jobSchemas[jobType]
+	schemasMu.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(message))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return fmt.Errorf("failed to decode message: %w", err)
+	}
+	return schema.Validate(document)
+}
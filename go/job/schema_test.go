@@ -0,0 +1,72 @@
+package job
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+const reportGenerationSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"properties": {
+		"report_name": {"type": "string", "maxLength": 20},
+		"filters": {"type": "string"}
+	},
+	"required": ["report_name", "filters"]
+}`
+
+func TestLoadSchemas(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "report_generation.json"), []byte(reportGenerationSchema), 0o644); err != nil {
+		t.Fatalf("failed to write schema: %v", err)
+	}
+	if err := LoadSchemas(dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { jobSchemas = map[JobType]*jsonschema.Schema{} }()
+
+	tests := []struct {
+		name        string
+		input       string
+		expectError bool
+	}{
+		{
+			name:  "Payload accepted by schema",
+			input: `{"job_type":"report_generation","message":{"report_name":"Sales Report","filters":"region=US"}}`,
+		},
+		{
+			name:        "Report name too long for schema but accepted by the struct",
+			input:       `{"job_type":"report_generation","message":{"report_name":"Quarterly Sales Report For All Regions","filters":"region=US"}}`,
+			expectError: true,
+		},
+		{
+			name:  "Job type without a schema",
+			input: `{"job_type":"data_cleanup","message":{"target_table":"a_very_long_table_name_indeed","retention":30}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, _, err := ParseJob([]byte(tt.input))
+			if tt.expectError && err == nil {
+				t.Errorf("expected an error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadSchemasInvalid(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "report_generation.json"), []byte(`{"type": 5}`), 0o644); err != nil {
+		t.Fatalf("failed to write schema: %v", err)
+	}
+	if err := LoadSchemas(dir); err == nil {
+		t.Errorf("expected an error but got none")
+	}
+}
@@ -0,0 +1,48 @@
+package job
+
+import (
+	"encoding/json"
+	"hash/fnv"
+)
+
+// ShardKey hashes the value of field in a job message's payload into one of
+// buckets shards, so the same tenant or user always maps to the same shard.
+// Jobs without the field, or with fewer than one bucket, map to shard 0.
+func ShardKey(message []byte, field string, buckets int) int {
+	if buckets < 1 {
+		return 0
+	}
+
+	value, ok := MessageField(message, field)
+	if !ok {
+		return 0
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(value))
+	return int(hash.Sum32() % uint32(buckets))
+}
+
+// MessageField returns the value of field in a job message's payload. String
+// values are returned unquoted so "user-001" and the raw JSON agree, other
+// values as their raw JSON.
+func MessageField(message []byte, field string) (string, bool) {
+	var jobMessage JobMessage
+	if err := json.Unmarshal(message, &jobMessage); err != nil {
+		return "", false
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(jobMessage.Message, &fields); err != nil {
+		return "", false
+	}
+	raw, ok := fields[field]
+	if !ok {
+		return "", false
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, true
+	}
+	return string(raw), true
+}
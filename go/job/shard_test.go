@@ -0,0 +1,62 @@
+package job
+
+import (
+	"testing"
+)
+
+func TestShardKey(t *testing.T) {
+	onboarding := func(userID string) []byte {
+		return []byte(`{"job_type": "user_onboarding", "message": {"user_id": "` + userID + `", "user_name": "John Doe"}}`)
+	}
+
+	t.Run("Consistent for the same input", func(t *testing.T) {
+		first := ShardKey(onboarding("user-001"), "user_id", 16)
+		for i := 0; i < 10; i++ {
+			if actual := ShardKey(onboarding("user-001"), "user_id", 16); actual != first {
+				t.Fatalf("expected shard %d, got %d", first, actual)
+			}
+		}
+	})
+
+	t.Run("Within bucket range", func(t *testing.T) {
+		seen := map[int]bool{}
+		for _, userID := range []string{"user-001", "user-002", "user-003", "user-004", "user-005", "user-006", "user-007", "user-008"} {
+			shard := ShardKey(onboarding(userID), "user_id", 4)
+			if shard < 0 || shard >= 4 {
+				t.Errorf("expected shard in [0, 4), got %d", shard)
+			}
+			seen[shard] = true
+		}
+		if len(seen) < 2 {
+			t.Errorf("expected users to spread over more than one shard, got %v", seen)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		message  string
+		field    string
+		buckets  int
+		expected int
+	}{
+		{name: "Missing field", message: string(onboarding("user-001")), field: "tenant_id", buckets: 16, expected: 0},
+		{name: "Single bucket", message: string(onboarding("user-001")), field: "user_id", buckets: 1, expected: 0},
+		{name: "No buckets", message: string(onboarding("user-001")), field: "user_id", buckets: 0, expected: 0},
+		{name: "Invalid message", message: `not json`, field: "user_id", buckets: 16, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := ShardKey([]byte(tt.message), tt.field, tt.buckets); actual != tt.expected {
+				t.Errorf("expected shard %d, got %d", tt.expected, actual)
+			}
+		})
+	}
+
+	t.Run("Numeric field", func(t *testing.T) {
+		message := []byte(`{"job_type": "data_cleanup", "message": {"target_table": "users", "retention": 30}}`)
+		if ShardKey(message, "retention", 16) != ShardKey(message, "retention", 16) {
+			t.Errorf("expected numeric fields to shard consistently")
+		}
+	})
+}
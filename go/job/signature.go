@@ -0,0 +1,62 @@
+package job
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidSignature is returned by VerifySignature when a payload's
+// signature is missing or doesn't match its contents.
+var ErrInvalidSignature = errors.New("invalid payload signature")
+
+// SignPayload returns the hex encoded HMAC-SHA256 of the canonical form of
+// payload, for storing in its Signature field.
+func SignPayload(payload EnrichedPayload, key []byte) (string, error) {
+	canonical, err := canonicalPayload(payload)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(canonical)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// VerifySignature checks payload's Signature against its contents.
+func VerifySignature(payload EnrichedPayload, key []byte) error {
+	if payload.Signature == "" {
+		return fmt.Errorf("%w: payload is not signed", ErrInvalidSignature)
+	}
+	signature, err := hex.DecodeString(payload.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	canonical, err := canonicalPayload(payload)
+	if err != nil {
+		return err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(canonical)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// canonicalPayload is the payload without its signature and with the
+// original message compacted, so the signature survives re-indentation.
+func canonicalPayload(payload EnrichedPayload) ([]byte, error) {
+	payload.Signature = ""
+	if len(payload.OriginalMessage) > 0 {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, payload.OriginalMessage); err != nil {
+			return nil, fmt.Errorf("failed to canonicalise original message: %w", err)
+		}
+		payload.OriginalMessage = compact.Bytes()
+	}
+	return json.Marshal(payload)
+}
@@ -0,0 +1,71 @@
+package job
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestPayloadSignature(t *testing.T) {
+	key := []byte("demo-secret")
+	payload := EnrichedPayload{
+		OriginalMessage: json.RawMessage(`{"job_type": "user_onboarding", "message": {"user_id": "user-001", "user_name": "John Doe"}}`),
+		ID:              "12345",
+		Timestamp:       "2025-08-30T12:00:00Z",
+		Status:          StatusNew,
+	}
+	signature, err := SignPayload(payload, key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	payload.Signature = signature
+
+	// A signed payload still verifies after a trip through indented JSON
+	indented, err := json.MarshalIndent(payload, "", "  ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var reparsed EnrichedPayload
+	if err := json.Unmarshal(indented, &reparsed); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		modify      func(p *EnrichedPayload)
+		key         []byte
+		expectError bool
+	}{
+		{name: "Valid signature", modify: func(p *EnrichedPayload) {}, key: key},
+		{name: "Valid after re-indentation", modify: func(p *EnrichedPayload) { *p = reparsed }, key: key},
+		{name: "Tampered status", modify: func(p *EnrichedPayload) { p.Status = StatusCompleted }, key: key, expectError: true},
+		{
+			name: "Tampered original message",
+			modify: func(p *EnrichedPayload) {
+				p.OriginalMessage = json.RawMessage(`{"job_type": "user_onboarding", "message": {"user_id": "admin", "user_name": "John Doe"}}`)
+			},
+			key:         key,
+			expectError: true,
+		},
+		{name: "Wrong key", modify: func(p *EnrichedPayload) {}, key: []byte("other-secret"), expectError: true},
+		{name: "Missing signature", modify: func(p *EnrichedPayload) { p.Signature = "" }, key: key, expectError: true},
+		{name: "Malformed signature", modify: func(p *EnrichedPayload) { p.Signature = "not-hex" }, key: key, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signed := payload
+			tt.modify(&signed)
+			err := VerifySignature(signed, tt.key)
+			if tt.expectError {
+				if !errors.Is(err, ErrInvalidSignature) {
+					t.Errorf("expected ErrInvalidSignature, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package job
+
+import "encoding/json"
+
+// snsEnvelope is the JSON body SQS receives when subscribed to an SNS topic
+// without raw message delivery.
+type snsEnvelope struct {
+	Type      string `json:"Type"`
+	MessageID string `json:"MessageId"`
+	TopicArn  string `json:"TopicArn"`
+	Message   string `json:"Message"`
+}
+
+// UnwrapSNS returns the message inside an SNS notification envelope and true,
+// or body unchanged and false when it isn't an SNS notification.
+func UnwrapSNS(body []byte) ([]byte, bool) {
+	var envelope snsEnvelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return body, false
+	}
+	if envelope.Type != "Notification" || envelope.TopicArn == "" {
+		return body, false
+	}
+	return []byte(envelope.Message), true
+}
@@ -0,0 +1,53 @@
+package job
+
+import (
+	"testing"
+)
+
+func TestUnwrapSNS(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		expected      string
+		expectWrapped bool
+	}{
+		{
+			name:          "SNS notification",
+			body:          `{"Type":"Notification","MessageId":"sns-1","TopicArn":"arn:aws:sns:us-east-1:000000000000:jobs","Message":"{\"id\":\"12345\"}"}`,
+			expected:      `{"id":"12345"}`,
+			expectWrapped: true,
+		},
+		{
+			name:     "Raw payload",
+			body:     `{"id":"12345","status":"NEW"}`,
+			expected: `{"id":"12345","status":"NEW"}`,
+		},
+		{
+			name:     "Subscription confirmation",
+			body:     `{"Type":"SubscriptionConfirmation","TopicArn":"arn:aws:sns:us-east-1:000000000000:jobs","Message":"confirm"}`,
+			expected: `{"Type":"SubscriptionConfirmation","TopicArn":"arn:aws:sns:us-east-1:000000000000:jobs","Message":"confirm"}`,
+		},
+		{
+			name:     "Payload with a Type field",
+			body:     `{"Type":"Notification","Message":"not from SNS"}`,
+			expected: `{"Type":"Notification","Message":"not from SNS"}`,
+		},
+		{
+			name:     "Not JSON",
+			body:     `not json`,
+			expected: `not json`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, wrapped := UnwrapSNS([]byte(tt.body))
+			if wrapped != tt.expectWrapped {
+				t.Errorf("expected wrapped %v, got %v", tt.expectWrapped, wrapped)
+			}
+			if string(actual) != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, actual)
+			}
+		})
+	}
+}
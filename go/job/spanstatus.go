@@ -0,0 +1,27 @@
+package job
+
+import (
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SpanStatus sets an Ok or Error status on spans by outcome, so the tracing
+// backend can colour them. Errors are recorded on the span either way.
+type SpanStatus struct {
+	Record bool // set the status, otherwise leave it Unset
+}
+
+// Fail records err on span and marks it as failed.
+func (s SpanStatus) Fail(span trace.Span, err error) {
+	span.RecordError(err)
+	if s.Record {
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// Succeed marks span as successful.
+func (s SpanStatus) Succeed(span trace.Span) {
+	if s.Record {
+		span.SetStatus(codes.Ok, "")
+	}
+}
@@ -0,0 +1,47 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSpanStatus(t *testing.T) {
+	tests := []struct {
+		name           string
+		record         bool
+		err            error
+		expectedStatus codes.Code
+	}{
+		{name: "Succeeded", record: true, expectedStatus: codes.Ok},
+		{name: "Failed", record: true, err: errors.New("boom"), expectedStatus: codes.Error},
+		{name: "Succeeded without status", expectedStatus: codes.Unset},
+		{name: "Failed without status", err: errors.New("boom"), expectedStatus: codes.Unset},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			_, span := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test").Start(context.Background(), "ExecuteJob")
+			status := SpanStatus{Record: tt.record}
+			if tt.err != nil {
+				status.Fail(span, tt.err)
+			} else {
+				status.Succeed(span)
+			}
+			span.End()
+
+			ended := recorder.Ended()[0]
+			if ended.Status().Code != tt.expectedStatus {
+				t.Errorf("expected status %s, got %s", tt.expectedStatus, ended.Status().Code)
+			}
+			if recorded := len(ended.Events()) == 1; recorded != (tt.err != nil) {
+				t.Errorf("expected the error to be recorded: %v, got events %v", tt.err != nil, ended.Events())
+			}
+		})
+	}
+}
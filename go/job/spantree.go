@@ -0,0 +1,65 @@
+package job
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// FormatSpanTree renders the spans recorded for a trace as an indented tree,
+// one span per line labelled with the service that produced it, e.g.
+//
+//	ProcessMessage [job-ingester]
+//	  ExecuteJob [job-processor]
+//
+// It is a test and development aid for checking that trace context
+// propagates from the ingester to the processor. Spans whose parent wasn't
+// recorded are shown as roots.
+func FormatSpanTree(spans []sdktrace.ReadOnlySpan, traceID trace.TraceID) string {
+	byID := map[trace.SpanID]bool{}
+	var inTrace []sdktrace.ReadOnlySpan
+	for _, span := range spans {
+		if span.SpanContext().TraceID() == traceID {
+			inTrace = append(inTrace, span)
+			byID[span.SpanContext().SpanID()] = true
+		}
+	}
+	sort.SliceStable(inTrace, func(i, j int) bool { return inTrace[i].StartTime().Before(inTrace[j].StartTime()) })
+
+	children := map[trace.SpanID][]sdktrace.ReadOnlySpan{}
+	var roots []sdktrace.ReadOnlySpan
+	for _, span := range inTrace {
+		if parent := span.Parent(); parent.IsValid() && byID[parent.SpanID()] {
+			children[parent.SpanID()] = append(children[parent.SpanID()], span)
+		} else {
+			roots = append(roots, span)
+		}
+	}
+
+	var tree strings.Builder
+	var write func(span sdktrace.ReadOnlySpan, depth int)
+	write = func(span sdktrace.ReadOnlySpan, depth int) {
+		fmt.Fprintf(&tree, "%s%s [%s]\n", strings.Repeat("  ", depth), span.Name(), spanService(span))
+		for _, child := range children[span.SpanContext().SpanID()] {
+			write(child, depth+1)
+		}
+	}
+	for _, root := range roots {
+		write(root, 0)
+	}
+	return tree.String()
+}
+
+// spanService returns the service.name resource attribute of span.
+func spanService(span sdktrace.ReadOnlySpan) string {
+	if span.Resource() != nil {
+		if name, ok := span.Resource().Set().Value(attribute.Key("service.name")); ok {
+			return name.AsString()
+		}
+	}
+	return "unknown"
+}
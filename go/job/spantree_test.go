@@ -0,0 +1,63 @@
+package job
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestFormatSpanTree(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := func(service string) trace.Tracer {
+		return sdktrace.NewTracerProvider(
+			sdktrace.WithSpanProcessor(recorder),
+			sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", service))),
+		).Tracer("test")
+	}
+	ingester, processor := provider("job-ingester"), provider("job-processor")
+
+	// The ingester span context reaches the processor through the payload
+	_, ingestSpan := ingester.Start(context.Background(), "ProcessMessage")
+	remote := trace.ContextWithSpanContext(context.Background(), ingestSpan.SpanContext().WithRemote(true))
+	ingestSpan.End()
+
+	ctx, executeSpan := processor.Start(remote, "ExecuteJob")
+	_, childSpan := processor.Start(ctx, "GenerateReport")
+	childSpan.End()
+	executeSpan.End()
+
+	// Spans from another trace are left out
+	_, otherSpan := ingester.Start(context.Background(), "ProcessMessage")
+	otherSpan.End()
+
+	expected := "ProcessMessage [job-ingester]\n" +
+		"  ExecuteJob [job-processor]\n" +
+		"    GenerateReport [job-processor]\n"
+	if actual := FormatSpanTree(recorder.Ended(), ingestSpan.SpanContext().TraceID()); actual != expected {
+		t.Errorf("expected tree:\n%s\ngot:\n%s", expected, actual)
+	}
+}
+
+func TestFormatSpanTreeMissingParent(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(recorder),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", "job-processor"))),
+	).Tracer("test")
+
+	// The ingester span wasn't recorded, so the processor span is a root
+	_, ingestSpan := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "ProcessMessage")
+	remote := trace.ContextWithSpanContext(context.Background(), ingestSpan.SpanContext().WithRemote(true))
+	_, executeSpan := tracer.Start(remote, "ExecuteJob")
+	executeSpan.End()
+
+	expected := "ExecuteJob [job-processor]\n"
+	if actual := FormatSpanTree(recorder.Ended(), ingestSpan.SpanContext().TraceID()); actual != expected {
+		t.Errorf("expected tree:\n%s\ngot:\n%s", expected, actual)
+	}
+}
@@ -0,0 +1,49 @@
+package job
+
+import (
+	"log"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// SQS system attributes delivered on each record
+const (
+	SQSApproximateReceiveCount = "ApproximateReceiveCount"
+	SQSSentTimestamp           = "SentTimestamp"
+)
+
+// ReceiveCount returns the ApproximateReceiveCount of an SQS record, and
+// false when it is absent or malformed.
+func ReceiveCount(attributes map[string]string) (int, bool) {
+	value, ok := attributes[SQSApproximateReceiveCount]
+	if !ok {
+		return 0, false
+	}
+	receiveCount, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, false
+	}
+	return receiveCount, true
+}
+
+// SQSAttributes converts the SQS system attributes worth tracing into span
+// attributes. Attributes that are absent or not numeric are skipped.
+func SQSAttributes(attributes map[string]string) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if value, ok := attributes[SQSApproximateReceiveCount]; ok {
+		if receiveCount, err := strconv.Atoi(value); err == nil {
+			attrs = append(attrs, attribute.Int("sqs.approximate_receive_count", receiveCount))
+		} else {
+			log.Printf("ignoring non-numeric %s: %q", SQSApproximateReceiveCount, value)
+		}
+	}
+	if value, ok := attributes[SQSSentTimestamp]; ok {
+		if sentTimestamp, err := strconv.ParseInt(value, 10, 64); err == nil {
+			attrs = append(attrs, attribute.Int64("sqs.sent_timestamp", sentTimestamp))
+		} else {
+			log.Printf("ignoring non-numeric %s: %q", SQSSentTimestamp, value)
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,71 @@
+package job
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSQSAttributes(t *testing.T) {
+	tests := []struct {
+		name       string
+		attributes map[string]string
+		expected   map[attribute.Key]attribute.Value
+	}{
+		{
+			name: "Receive count and sent timestamp",
+			attributes: map[string]string{
+				"ApproximateReceiveCount": "3",
+				"SentTimestamp":           "1756555200000",
+				"SenderId":                "AIDAIENQZJOLO23YVJ4VO",
+			},
+			expected: map[attribute.Key]attribute.Value{
+				"sqs.approximate_receive_count": attribute.IntValue(3),
+				"sqs.sent_timestamp":            attribute.Int64Value(1756555200000),
+			},
+		},
+		{
+			name:       "Absent attributes",
+			attributes: map[string]string{},
+			expected:   map[attribute.Key]attribute.Value{},
+		},
+		{
+			name: "Non-numeric attributes are skipped",
+			attributes: map[string]string{
+				"ApproximateReceiveCount": "many",
+				"SentTimestamp":           "1756555200000",
+			},
+			expected: map[attribute.Key]attribute.Value{
+				"sqs.sent_timestamp": attribute.Int64Value(1756555200000),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+			_, span := tp.Tracer("test").Start(context.Background(), "ProcessMessage",
+				trace.WithAttributes(SQSAttributes(tt.attributes)...),
+			)
+			span.End()
+
+			actual := map[attribute.Key]attribute.Value{}
+			for _, kv := range recorder.Ended()[0].Attributes() {
+				actual[kv.Key] = kv.Value
+			}
+			if len(actual) != len(tt.expected) {
+				t.Errorf("expected attributes %v, got %v", tt.expected, actual)
+			}
+			for key, value := range tt.expected {
+				if actual[key] != value {
+					t.Errorf("expected %s=%v, got %v", key, value.Emit(), actual[key].Emit())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package job
+
+import (
+	"sync"
+	"time"
+)
+
+// StatsBucket is the granularity the stats aggregator counts completions at.
+// Windows slide a bucket at a time.
+const StatsBucket = 10 * time.Second
+
+// StatsWindows are the rolling windows WindowStats reports on.
+var StatsWindows = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute}
+
+// WindowStat summarises the jobs completed within a rolling window.
+type WindowStat struct {
+	Window    time.Duration `json:"window"`
+	Completed int           `json:"completed"`
+	Failed    int           `json:"failed"`
+	PerMinute float64       `json:"per_minute"`
+	ErrorRate float64       `json:"error_rate"`
+}
+
+// statsBucket counts the completions within one StatsBucket.
+type statsBucket struct {
+	index     int64
+	completed int
+	failed    int
+}
+
+// StatsAggregator counts job completions in a ring of buckets covering the
+// longest of StatsWindows. It is safe for concurrent use.
+type StatsAggregator struct {
+	clock   Clock
+	mu      sync.Mutex
+	buckets []statsBucket
+}
+
+// NewStatsAggregator creates an empty aggregator timed by clock.
+func NewStatsAggregator(clock Clock) *StatsAggregator {
+	var longest time.Duration
+	for _, window := range StatsWindows {
+		if window > longest {
+			longest = window
+		}
+	}
+	return &StatsAggregator{clock: clock, buckets: make([]statsBucket, longest/StatsBucket)}
+}
+
+// Record counts a job that finished as of now, failed or not.
+func (a *StatsAggregator) Record(failed bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	index := a.clock.Now().UnixNano() / int64(StatsBucket)
+	bucket := &a.buckets[index%int64(len(a.buckets))]
+	if bucket.index != index {
+		*bucket = statsBucket{index: index}
+	}
+	if failed {
+		bucket.failed++
+	} else {
+		bucket.completed++
+	}
+}
+
+// WindowStats returns the throughput and error rate over each of
+// StatsWindows ending now, shortest first.
+func (a *StatsAggregator) WindowStats() []WindowStat {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	current := a.clock.Now().UnixNano() / int64(StatsBucket)
+	stats := make([]WindowStat, 0, len(StatsWindows))
+	for _, window := range StatsWindows {
+		stat := WindowStat{Window: window}
+		oldest := current - int64(window/StatsBucket)
+		for _, bucket := range a.buckets {
+			// Skip buckets outside the window, including ones not yet overwritten
+			if bucket.index <= oldest || bucket.index > current {
+				continue
+			}
+			stat.Completed += bucket.completed
+			stat.Failed += bucket.failed
+		}
+		if total := stat.Completed + stat.Failed; total > 0 {
+			stat.PerMinute = float64(total) / window.Minutes()
+			stat.ErrorRate = float64(stat.Failed) / float64(total)
+		}
+		stats = append(stats, stat)
+	}
+	return stats
+}
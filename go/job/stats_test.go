@@ -0,0 +1,81 @@
+package job
+
+import (
+	"testing"
+	"time"
+)
+
+// windowCounts is the completed/failed counts expected per window
+type windowCounts struct {
+	completed, failed int
+}
+
+func assertWindowStats(t *testing.T, stats []WindowStat, expected []windowCounts) {
+	t.Helper()
+	if len(stats) != len(expected) {
+		t.Fatalf("expected %d windows, got %d", len(expected), len(stats))
+	}
+	for i, stat := range stats {
+		if stat.Completed != expected[i].completed || stat.Failed != expected[i].failed {
+			t.Errorf("window %s: expected %d completed and %d failed, got %d and %d",
+				stat.Window, expected[i].completed, expected[i].failed, stat.Completed, stat.Failed)
+		}
+	}
+}
+
+func TestWindowStats(t *testing.T) {
+	clock := &steppedClock{now: time.Date(2025, 8, 30, 12, 0, 0, 0, time.UTC)}
+	stats := NewStatsAggregator(clock)
+
+	assertWindowStats(t, stats.WindowStats(), []windowCounts{{0, 0}, {0, 0}, {0, 0}})
+
+	// Three completions and a failure now
+	for i := 0; i < 3; i++ {
+		stats.Record(false)
+	}
+	stats.Record(true)
+	assertWindowStats(t, stats.WindowStats(), []windowCounts{{3, 1}, {3, 1}, {3, 1}})
+
+	windows := stats.WindowStats()
+	if windows[0].PerMinute != 4 || windows[0].ErrorRate != 0.25 {
+		t.Errorf("expected 4 per minute at a 0.25 error rate, got %v and %v", windows[0].PerMinute, windows[0].ErrorRate)
+	}
+	if windows[1].PerMinute != 0.8 {
+		t.Errorf("expected 0.8 per minute over 5 minutes, got %v", windows[1].PerMinute)
+	}
+
+	// Two minutes on the first batch has left the 1 minute window
+	clock.advance(2 * time.Minute)
+	stats.Record(true)
+	assertWindowStats(t, stats.WindowStats(), []windowCounts{{0, 1}, {3, 2}, {3, 2}})
+
+	// Ten minutes on only the 15 minute window still covers both
+	clock.advance(8 * time.Minute)
+	assertWindowStats(t, stats.WindowStats(), []windowCounts{{0, 0}, {0, 0}, {3, 2}})
+
+	// Once the ring wraps the oldest buckets are overwritten, not added to
+	clock.advance(6 * time.Minute)
+	stats.Record(false)
+	assertWindowStats(t, stats.WindowStats(), []windowCounts{{1, 0}, {1, 0}, {1, 1}})
+
+	clock.advance(time.Hour)
+	assertWindowStats(t, stats.WindowStats(), []windowCounts{{0, 0}, {0, 0}, {0, 0}})
+}
+
+func TestWindowStatsBucketBoundary(t *testing.T) {
+	clock := &steppedClock{now: time.Date(2025, 8, 30, 12, 0, 5, 0, time.UTC)}
+	stats := NewStatsAggregator(clock)
+
+	stats.Record(false)
+	// Still within the minute, a bucket at a time
+	for elapsed := StatsBucket; elapsed < time.Minute; elapsed += StatsBucket {
+		clock.advance(StatsBucket)
+		if window := stats.WindowStats()[0]; window.Completed != 1 {
+			t.Fatalf("expected the completion in the 1 minute window after %s, got %d", elapsed, window.Completed)
+		}
+	}
+	clock.advance(StatsBucket)
+	if window := stats.WindowStats()[0]; window.Completed != 0 {
+		t.Errorf("expected the completion to have left the 1 minute window, got %d", window.Completed)
+	}
+}
@@ -0,0 +1,64 @@
+package job
+
+import (
+	"errors"
+	"log"
+	"strings"
+)
+
+// LenientJobTypes lists the job types validated leniently by ParseJob, e.g.
+// during a migration where producers don't yet send every field. A lenient
+// type only needs the fields it can't run without. Types not listed, or
+// without a lenient validation, are validated strictly.
+var LenientJobTypes = map[JobType]bool{}
+
+// LenientValidator is implemented by jobs that support lenient validation.
+type LenientValidator interface {
+	ValidateLenient() error // ValidateLenient checks only the fields the job can't run without.
+}
+
+// ParseJobTypes parses a comma separated list of job types, e.g.
+// "report_generation,user_onboarding", ignoring unknown types.
+func ParseJobTypes(types string) map[JobType]bool {
+	parsed := map[JobType]bool{}
+	for _, jobType := range strings.Split(types, ",") {
+		jobType = strings.TrimSpace(jobType)
+		if jobType == "" {
+			continue
+		}
+		if _, ok := jobFactory(JobType(jobType)); !ok {
+			log.Printf("ignoring unknown job type %q", jobType)
+			continue
+		}
+		parsed[JobType(jobType)] = true
+	}
+	return parsed
+}
+
+// validateJob validates job strictly, or leniently if its type is listed in
+// LenientJobTypes and it supports lenient validation.
+func validateJob(jobType JobType, job Job) error {
+	if lenient, ok := job.(LenientValidator); ok && LenientJobTypes[jobType] {
+		return lenient.ValidateLenient()
+	}
+	return job.Validate()
+}
+
+// ValidateLenient allows reports without filters.
+func (j ReportGenerationJob) ValidateLenient() error {
+	if j.Filters != "" {
+		return j.Validate()
+	}
+	if j.ReportName == "" {
+		return errors.New("report_name is required")
+	}
+	return nil
+}
+
+// ValidateLenient allows users to be onboarded without a name.
+func (j UserOnboardingJob) ValidateLenient() error {
+	if j.UserID == "" {
+		return errors.New("user_id is required")
+	}
+	return validateUserID(j.UserID)
+}
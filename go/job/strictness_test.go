@@ -0,0 +1,70 @@
+package job
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLenientJobTypes(t *testing.T) {
+	tests := []struct {
+		name        string
+		lenient     map[JobType]bool
+		input       string
+		expectError bool
+	}{
+		{
+			name:        "Strict report without filters",
+			lenient:     map[JobType]bool{},
+			input:       `{"job_type": "report_generation", "message": {"report_name": "Sales Report"}}`,
+			expectError: true,
+		},
+		{
+			name:    "Lenient report without filters",
+			lenient: map[JobType]bool{ReportGeneration: true},
+			input:   `{"job_type": "report_generation", "message": {"report_name": "Sales Report"}}`,
+		},
+		{
+			name:        "Lenient report still needs a name",
+			lenient:     map[JobType]bool{ReportGeneration: true},
+			input:       `{"job_type": "report_generation", "message": {"filters": "region=US"}}`,
+			expectError: true,
+		},
+		{
+			name:        "Other types stay strict",
+			lenient:     map[JobType]bool{ReportGeneration: true},
+			input:       `{"job_type": "user_onboarding", "message": {"user_id": "user-001"}}`,
+			expectError: true,
+		},
+		{
+			name:    "Lenient onboarding without a name",
+			lenient: map[JobType]bool{UserOnboarding: true},
+			input:   `{"job_type": "user_onboarding", "message": {"user_id": "user-001"}}`,
+		},
+		{
+			name:        "Types without lenient validation stay strict",
+			lenient:     map[JobType]bool{DataCleanup: true},
+			input:       `{"job_type": "data_cleanup", "message": {"target_table": "users"}}`,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := LenientJobTypes
+			LenientJobTypes = tt.lenient
+			defer func() { LenientJobTypes = previous }()
+
+			_, _, _, err := ParseJob([]byte(tt.input))
+			if tt.expectError != (err != nil) {
+				t.Errorf("expected error %v, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
+
+func TestParseJobTypes(t *testing.T) {
+	expected := map[JobType]bool{ReportGeneration: true, UserOnboarding: true}
+	if actual := ParseJobTypes("report_generation, user_onboarding,,unknown_job"); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
@@ -0,0 +1,214 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// OTLP protocols the services can export traces and metrics with, as named
+// by OTEL_EXPORTER_OTLP_PROTOCOL.
+const (
+	OTLPProtocolHTTP = "http/protobuf"
+	OTLPProtocolGRPC = "grpc"
+)
+
+// Collector endpoints for the demo, by protocol.
+const (
+	defaultOTLPHTTPEndpoint = "otel_collector:4318"
+	defaultOTLPGRPCEndpoint = "otel_collector:4317"
+)
+
+// TelemetryConfig is how the ingester and processor reach the OpenTelemetry
+// collector.
+type TelemetryConfig struct {
+	Protocol     string
+	Endpoint     string  // host:port, spoken to without TLS, or a URL whose scheme says whether to use it
+	SampleRatio  float64 // fraction of new traces sampled, 1 for every trace
+	MetricsAddr  string  // serve metrics for Prometheus to scrape on /metrics here instead of exporting them over OTLP, "" to export over OTLP
+	StdoutTraces bool    // print spans for local debugging without a collector instead of exporting them over OTLP
+}
+
+// Metric exporters the services can be switched between with
+// OTEL_METRICS_EXPORTER.
+const (
+	MetricsExporterOTLP       = "otlp"
+	MetricsExporterPrometheus = "prometheus"
+)
+
+// LoadTelemetryConfig reads the telemetry config with lookup, normally
+// os.LookupEnv, from OTEL_EXPORTER_OTLP_PROTOCOL, OTEL_EXPORTER_OTLP_ENDPOINT
+// and OTEL_TRACES_SAMPLER_ARG. OTEL_TRACES_EXPORTER=stdout prints spans
+// rather than exporting them. Unset variables fall back to sampling every
+// trace and exporting it over HTTP to the demo collector, and to the
+// collector's port for the protocol when only the protocol is set.
+//
+// Metrics are served for Prometheus on METRICS_ADDR when it is set, unless
+// OTEL_METRICS_EXPORTER=otlp says to keep exporting them over OTLP.
+// OTEL_METRICS_EXPORTER=prometheus picks Prometheus outright. Left unset with
+// both METRICS_ADDR and OTEL_EXPORTER_OTLP_ENDPOINT configured, OTLP wins.
+func LoadTelemetryConfig(lookup func(string) (string, bool)) TelemetryConfig {
+	cfg := TelemetryConfig{Protocol: OTLPProtocolHTTP, Endpoint: defaultOTLPHTTPEndpoint, SampleRatio: 1}
+	if protocol, ok := lookup("OTEL_EXPORTER_OTLP_PROTOCOL"); ok && strings.TrimSpace(protocol) != "" {
+		cfg.Protocol = strings.ToLower(strings.TrimSpace(protocol))
+	}
+	if cfg.Protocol == OTLPProtocolGRPC {
+		cfg.Endpoint = defaultOTLPGRPCEndpoint
+	}
+	if endpoint, ok := lookup("OTEL_EXPORTER_OTLP_ENDPOINT"); ok && strings.TrimSpace(endpoint) != "" {
+		cfg.Endpoint = strings.TrimSpace(endpoint)
+	}
+	if value, ok := lookup("OTEL_TRACES_SAMPLER_ARG"); ok && strings.TrimSpace(value) != "" {
+		ratio, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			log.Printf("ignoring invalid OTEL_TRACES_SAMPLER_ARG %q, expected a ratio from 0 to 1", value)
+		} else {
+			cfg.SampleRatio = ratio
+		}
+	}
+
+	if exporter, ok := lookup("OTEL_TRACES_EXPORTER"); ok {
+		cfg.StdoutTraces = strings.ToLower(strings.TrimSpace(exporter)) == "stdout"
+	}
+
+	addr, _ := lookup("METRICS_ADDR")
+	addr = strings.TrimSpace(addr)
+	exporter, _ := lookup("OTEL_METRICS_EXPORTER")
+	switch exporter = strings.ToLower(strings.TrimSpace(exporter)); exporter {
+	case MetricsExporterPrometheus:
+		if addr == "" {
+			log.Printf("ignoring OTEL_METRICS_EXPORTER=%s without a METRICS_ADDR to serve /metrics on, exporting metrics over OTLP", exporter)
+		}
+		cfg.MetricsAddr = addr
+	case MetricsExporterOTLP:
+		if addr != "" {
+			log.Printf("ignoring METRICS_ADDR %q, OTEL_METRICS_EXPORTER=%s exports metrics over OTLP", addr, exporter)
+		}
+	default:
+		if exporter != "" {
+			log.Printf("ignoring invalid OTEL_METRICS_EXPORTER %q, expected %s or %s", exporter, MetricsExporterOTLP, MetricsExporterPrometheus)
+		}
+		if endpoint, ok := lookup("OTEL_EXPORTER_OTLP_ENDPOINT"); ok && strings.TrimSpace(endpoint) != "" && addr != "" {
+			log.Printf("ignoring METRICS_ADDR %q, OTEL_EXPORTER_OTLP_ENDPOINT is also set and metrics are exported over OTLP", addr)
+		} else {
+			cfg.MetricsAddr = addr
+		}
+	}
+	return cfg
+}
+
+// Sampler samples SampleRatio of new traces, but follows the decision of a
+// parent span, including one propagated from another service, so a trace is
+// either recorded end to end or not at all.
+func (c TelemetryConfig) Sampler() sdktrace.Sampler {
+	return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(c.SampleRatio))
+}
+
+// hasScheme reports whether endpoint is a URL rather than host:port.
+func (c TelemetryConfig) hasScheme() bool {
+	return strings.Contains(c.Endpoint, "://")
+}
+
+// NewTraceExporter creates the span exporter for cfg: one printing spans
+// when cfg.StdoutTraces is set, otherwise one exporting them over OTLP.
+func NewTraceExporter(ctx context.Context, cfg TelemetryConfig) (sdktrace.SpanExporter, error) {
+	if cfg.StdoutTraces {
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	}
+	return NewOTLPTraceExporter(ctx, cfg)
+}
+
+// NewOTLPTraceExporter creates the span exporter for cfg, over gRPC or HTTP.
+func NewOTLPTraceExporter(ctx context.Context, cfg TelemetryConfig) (sdktrace.SpanExporter, error) {
+	switch cfg.Protocol {
+	case OTLPProtocolGRPC:
+		if cfg.hasScheme() {
+			return otlptracegrpc.New(ctx, otlptracegrpc.WithEndpointURL(cfg.Endpoint))
+		}
+		return otlptracegrpc.New(ctx, otlptracegrpc.WithEndpoint(cfg.Endpoint), otlptracegrpc.WithInsecure())
+	case OTLPProtocolHTTP:
+		if cfg.hasScheme() {
+			return otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		}
+		return otlptracehttp.New(ctx, otlptracehttp.WithEndpoint(cfg.Endpoint), otlptracehttp.WithInsecure())
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol %q, expected %s or %s", cfg.Protocol, OTLPProtocolHTTP, OTLPProtocolGRPC)
+	}
+}
+
+// NewMetricReader creates the reader the services' meter provider collects
+// metrics with: a Prometheus exporter served on /metrics at cfg.MetricsAddr
+// when it is set, otherwise a periodic reader exporting over OTLP. The
+// returned func stops the /metrics server, and does nothing for OTLP.
+func NewMetricReader(ctx context.Context, cfg TelemetryConfig) (sdkmetric.Reader, func(), error) {
+	if cfg.MetricsAddr == "" {
+		exporter, err := NewOTLPMetricExporter(ctx, cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return sdkmetric.NewPeriodicReader(exporter), func() {}, nil
+	}
+
+	// A registry of its own keeps the Go runtime collectors registered by
+	// default out of what is scraped
+	registry := prometheus.NewRegistry()
+	reader, err := otelprometheus.New(otelprometheus.WithRegisterer(registry))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create Prometheus exporter: %w", err)
+	}
+	listener, err := net.Listen("tcp", cfg.MetricsAddr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to listen for /metrics on %s: %w", cfg.MetricsAddr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("/metrics server on %s stopped: %v", cfg.MetricsAddr, err)
+		}
+	}()
+	log.Printf("serving metrics on http://%s/metrics", listener.Addr())
+	return reader, func() {
+		if err := server.Close(); err != nil {
+			log.Printf("failed to stop /metrics server: %v", err)
+		}
+	}, nil
+}
+
+// NewOTLPMetricExporter creates the metric exporter for cfg, over gRPC or
+// HTTP.
+func NewOTLPMetricExporter(ctx context.Context, cfg TelemetryConfig) (sdkmetric.Exporter, error) {
+	switch cfg.Protocol {
+	case OTLPProtocolGRPC:
+		if cfg.hasScheme() {
+			return otlpmetricgrpc.New(ctx, otlpmetricgrpc.WithEndpointURL(cfg.Endpoint))
+		}
+		return otlpmetricgrpc.New(ctx, otlpmetricgrpc.WithEndpoint(cfg.Endpoint), otlpmetricgrpc.WithInsecure())
+	case OTLPProtocolHTTP:
+		if cfg.hasScheme() {
+			return otlpmetrichttp.New(ctx, otlpmetrichttp.WithEndpointURL(cfg.Endpoint))
+		}
+		return otlpmetrichttp.New(ctx, otlpmetrichttp.WithEndpoint(cfg.Endpoint), otlpmetrichttp.WithInsecure())
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol %q, expected %s or %s", cfg.Protocol, OTLPProtocolHTTP, OTLPProtocolGRPC)
+	}
+}
@@ -0,0 +1,224 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestLoadTelemetryConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected TelemetryConfig
+	}{
+		{name: "HTTP to the demo collector when unset", env: map[string]string{}, expected: TelemetryConfig{Protocol: "http/protobuf", Endpoint: "otel_collector:4318", SampleRatio: 1}},
+		{name: "gRPC to the demo collector", env: map[string]string{"OTEL_EXPORTER_OTLP_PROTOCOL": "grpc"}, expected: TelemetryConfig{Protocol: "grpc", Endpoint: "otel_collector:4317", SampleRatio: 1}},
+		{
+			name:     "gRPC to a production collector",
+			env:      map[string]string{"OTEL_EXPORTER_OTLP_PROTOCOL": " GRPC ", "OTEL_EXPORTER_OTLP_ENDPOINT": "https://collector.example.com:4317", "OTEL_TRACES_SAMPLER_ARG": "0.1"},
+			expected: TelemetryConfig{Protocol: "grpc", Endpoint: "https://collector.example.com:4317", SampleRatio: 0.1},
+		},
+		{name: "Sampling nothing new", env: map[string]string{"OTEL_TRACES_SAMPLER_ARG": "0"}, expected: TelemetryConfig{Protocol: "http/protobuf", Endpoint: "otel_collector:4318"}},
+		{name: "Out of range ratio ignored", env: map[string]string{"OTEL_TRACES_SAMPLER_ARG": "1.5"}, expected: TelemetryConfig{Protocol: "http/protobuf", Endpoint: "otel_collector:4318", SampleRatio: 1}},
+		{name: "Invalid ratio ignored", env: map[string]string{"OTEL_TRACES_SAMPLER_ARG": "half"}, expected: TelemetryConfig{Protocol: "http/protobuf", Endpoint: "otel_collector:4318", SampleRatio: 1}},
+		{name: "Prometheus on METRICS_ADDR", env: map[string]string{"METRICS_ADDR": ":9464"}, expected: TelemetryConfig{Protocol: "http/protobuf", Endpoint: "otel_collector:4318", SampleRatio: 1, MetricsAddr: ":9464"}},
+		{
+			name:     "Prometheus picked over a collector",
+			env:      map[string]string{"METRICS_ADDR": ":9464", "OTEL_METRICS_EXPORTER": " Prometheus ", "OTEL_EXPORTER_OTLP_ENDPOINT": "https://collector.example.com:4318"},
+			expected: TelemetryConfig{Protocol: "http/protobuf", Endpoint: "https://collector.example.com:4318", SampleRatio: 1, MetricsAddr: ":9464"},
+		},
+		{
+			name:     "OTLP wins when both are configured",
+			env:      map[string]string{"METRICS_ADDR": ":9464", "OTEL_EXPORTER_OTLP_ENDPOINT": "https://collector.example.com:4318"},
+			expected: TelemetryConfig{Protocol: "http/protobuf", Endpoint: "https://collector.example.com:4318", SampleRatio: 1},
+		},
+		{name: "OTLP picked over METRICS_ADDR", env: map[string]string{"METRICS_ADDR": ":9464", "OTEL_METRICS_EXPORTER": "otlp"}, expected: TelemetryConfig{Protocol: "http/protobuf", Endpoint: "otel_collector:4318", SampleRatio: 1}},
+		{name: "Prometheus without an address", env: map[string]string{"OTEL_METRICS_EXPORTER": "prometheus"}, expected: TelemetryConfig{Protocol: "http/protobuf", Endpoint: "otel_collector:4318", SampleRatio: 1}},
+		{name: "Stdout traces", env: map[string]string{"OTEL_TRACES_EXPORTER": " Stdout "}, expected: TelemetryConfig{Protocol: "http/protobuf", Endpoint: "otel_collector:4318", SampleRatio: 1, StdoutTraces: true}},
+		{name: "OTLP traces", env: map[string]string{"OTEL_TRACES_EXPORTER": "otlp"}, expected: TelemetryConfig{Protocol: "http/protobuf", Endpoint: "otel_collector:4318", SampleRatio: 1}},
+		{name: "Empty values fall back", env: map[string]string{"OTEL_EXPORTER_OTLP_PROTOCOL": "", "OTEL_EXPORTER_OTLP_ENDPOINT": "", "OTEL_TRACES_SAMPLER_ARG": ""}, expected: TelemetryConfig{Protocol: "http/protobuf", Endpoint: "otel_collector:4318", SampleRatio: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookup := func(name string) (string, bool) {
+				value, ok := tt.env[name]
+				return value, ok
+			}
+			if actual := LoadTelemetryConfig(lookup); actual != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestNewOTLPExporters(t *testing.T) {
+	tests := []struct {
+		name           string
+		cfg            TelemetryConfig
+		expectedTrace  string
+		expectedMetric string
+	}{
+		{name: "HTTP", cfg: TelemetryConfig{Protocol: OTLPProtocolHTTP, Endpoint: "otel_collector:4318"}, expectedTrace: "*otlptrace.Exporter", expectedMetric: "*otlpmetrichttp.Exporter"},
+		{name: "HTTP URL", cfg: TelemetryConfig{Protocol: OTLPProtocolHTTP, Endpoint: "http://otel_collector:4318"}, expectedTrace: "*otlptrace.Exporter", expectedMetric: "*otlpmetrichttp.Exporter"},
+		{name: "gRPC", cfg: TelemetryConfig{Protocol: OTLPProtocolGRPC, Endpoint: "otel_collector:4317"}, expectedTrace: "*otlptrace.Exporter", expectedMetric: "*otlpmetricgrpc.Exporter"},
+		{name: "gRPC URL", cfg: TelemetryConfig{Protocol: OTLPProtocolGRPC, Endpoint: "https://collector.example.com:4317"}, expectedTrace: "*otlptrace.Exporter", expectedMetric: "*otlpmetricgrpc.Exporter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			traces, err := NewOTLPTraceExporter(context.Background(), tt.cfg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer traces.Shutdown(context.Background())
+			if actual := fmt.Sprintf("%T", traces); actual != tt.expectedTrace {
+				t.Errorf("expected trace exporter %s, got %s", tt.expectedTrace, actual)
+			}
+
+			metrics, err := NewOTLPMetricExporter(context.Background(), tt.cfg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer metrics.Shutdown(context.Background())
+			if actual := fmt.Sprintf("%T", metrics); actual != tt.expectedMetric {
+				t.Errorf("expected metric exporter %s, got %s", tt.expectedMetric, actual)
+			}
+		})
+	}
+}
+
+func TestNewTraceExporter(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      TelemetryConfig
+		expected string
+	}{
+		{name: "OTLP by default", cfg: TelemetryConfig{Protocol: OTLPProtocolHTTP, Endpoint: "otel_collector:4318"}, expected: "*otlptrace.Exporter"},
+		{name: "Stdout", cfg: TelemetryConfig{Protocol: OTLPProtocolHTTP, Endpoint: "otel_collector:4318", StdoutTraces: true}, expected: "*stdouttrace.Exporter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter, err := NewTraceExporter(context.Background(), tt.cfg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer exporter.Shutdown(context.Background())
+
+			if actual := fmt.Sprintf("%T", exporter); actual != tt.expected {
+				t.Errorf("expected exporter %s, got %s", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestNewMetricReader(t *testing.T) {
+	t.Run("OTLP", func(t *testing.T) {
+		reader, stop, err := NewMetricReader(context.Background(), TelemetryConfig{Protocol: OTLPProtocolHTTP, Endpoint: "otel_collector:4318"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer stop()
+		defer reader.Shutdown(context.Background())
+		if actual := fmt.Sprintf("%T", reader); actual != "*metric.PeriodicReader" {
+			t.Errorf("expected a periodic reader, got %s", actual)
+		}
+	})
+
+	t.Run("Prometheus", func(t *testing.T) {
+		// Find a free port to serve /metrics on
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("failed to find a free port: %v", err)
+		}
+		addr := listener.Addr().String()
+		listener.Close()
+
+		reader, stop, err := NewMetricReader(context.Background(), TelemetryConfig{MetricsAddr: addr})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer stop()
+		provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+		defer provider.Shutdown(context.Background())
+		counter, _ := provider.Meter("test").Int64Counter("jobs_processed_total")
+		counter.Add(context.Background(), 3)
+
+		resp, err := http.Get("http://" + addr + "/metrics")
+		if err != nil {
+			t.Fatalf("failed to scrape /metrics: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if !strings.Contains(string(body), "jobs_processed_total") || !strings.Contains(string(body), " 3") {
+			t.Errorf("expected jobs_processed_total of 3 scraped, got %s", body)
+		}
+		if strings.Contains(string(body), "go_goroutines") {
+			t.Errorf("expected only the services' own metrics scraped, got %s", body)
+		}
+	})
+
+	t.Run("Address in use", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("failed to find a free port: %v", err)
+		}
+		defer listener.Close()
+		if _, _, err := NewMetricReader(context.Background(), TelemetryConfig{MetricsAddr: listener.Addr().String()}); err == nil {
+			t.Errorf("expected an error serving /metrics on an address in use")
+		}
+	})
+}
+
+func TestUnsupportedOTLPProtocol(t *testing.T) {
+	cfg := TelemetryConfig{Protocol: "http/json", Endpoint: "otel_collector:4318"}
+	if _, err := NewOTLPTraceExporter(context.Background(), cfg); err == nil {
+		t.Errorf("expected an error for an unsupported protocol")
+	}
+	if _, err := NewOTLPMetricExporter(context.Background(), cfg); err == nil {
+		t.Errorf("expected an error for an unsupported protocol")
+	}
+}
+
+func TestSamplerFollowsParent(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	parent := func(flags trace.TraceFlags) context.Context {
+		return trace.ContextWithRemoteSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: traceID, SpanID: spanID, TraceFlags: flags, Remote: true,
+		}))
+	}
+
+	tests := []struct {
+		name          string
+		ratio         float64
+		ctx           context.Context
+		expectSampled bool
+	}{
+		{name: "New trace, always on", ratio: 1, ctx: context.Background(), expectSampled: true},
+		{name: "New trace, always off", ratio: 0, ctx: context.Background()},
+		{name: "Sampled parent wins over the ratio", ratio: 0, ctx: parent(trace.FlagsSampled), expectSampled: true},
+		{name: "Sampled-out parent wins over the ratio", ratio: 1, ctx: parent(0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSampler(TelemetryConfig{SampleRatio: tt.ratio}.Sampler()))
+			_, span := provider.Tracer("test").Start(tt.ctx, "ProcessMessage")
+			defer span.End()
+			if sampled := span.SpanContext().IsSampled(); sampled != tt.expectSampled {
+				t.Errorf("expected sampled %v, got %v", tt.expectSampled, sampled)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package job
+
+// Why a propagated trace context would break the trace.
+const (
+	TraceContextMissing   = "missing"
+	TraceContextMalformed = "malformed"
+)
+
+// TraceContextGap reports why traceparent can't continue the trace, or ""
+// when it is a valid W3C traceparent.
+func TraceContextGap(traceparent string) string {
+	if traceparent == "" {
+		return TraceContextMissing
+	}
+	if _, err := ParseTraceContext(traceparent, ""); err != nil {
+		return TraceContextMalformed
+	}
+	return ""
+}
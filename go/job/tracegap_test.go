@@ -0,0 +1,27 @@
+package job
+
+import (
+	"testing"
+)
+
+func TestTraceContextGap(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+		expected    string
+	}{
+		{name: "Valid", traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", expected: ""},
+		{name: "Missing", traceparent: "", expected: TraceContextMissing},
+		{name: "Truncated", traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa", expected: TraceContextMalformed},
+		{name: "Not hex", traceparent: "00-4bf92f3577b34da6a3ce929d0e0e47zz-00f067aa0ba902b7-01", expected: TraceContextMalformed},
+		{name: "Zero trace ID", traceparent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01", expected: TraceContextMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := TraceContextGap(tt.traceparent); actual != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package job
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// How ParseJob treats fields in a job's message that its type doesn't define.
+const (
+	UnknownFieldsIgnore = "ignore" // drop them silently
+	UnknownFieldsWarn   = "warn"   // drop them but log their names
+	UnknownFieldsError  = "error"  // reject the job
+)
+
+// UnknownFields selects how ParseJob handles unknown message fields, one of
+// the UnknownFields constants. Ignore is the default.
+var UnknownFields = UnknownFieldsIgnore
+
+// ParseUnknownFields validates an UnknownFields mode, returning fallback when
+// it is empty or unrecognised.
+func ParseUnknownFields(value, fallback string) string {
+	switch mode := strings.ToLower(strings.TrimSpace(value)); mode {
+	case UnknownFieldsIgnore, UnknownFieldsWarn, UnknownFieldsError:
+		return mode
+	case "":
+		return fallback
+	default:
+		log.Printf("ignoring invalid unknown fields mode %q", value)
+		return fallback
+	}
+}
+
+// decodeMessage unmarshals a job's message into v, handling unknown fields
+// as mode, one of the UnknownFields constants.
+func decodeMessage(message json.RawMessage, v any, mode string) error {
+	switch mode {
+	case UnknownFieldsError:
+		decoder := json.NewDecoder(bytes.NewReader(message))
+		decoder.DisallowUnknownFields()
+		return decoder.Decode(v)
+	case UnknownFieldsWarn:
+		if err := json.Unmarshal(message, v); err != nil {
+			return err
+		}
+		if unknown := unknownFieldNames(message, v); len(unknown) > 0 {
+			slog.Warn("ignoring unknown fields in job message", "job", reflect.TypeOf(v).Elem().Name(), "fields", strings.Join(unknown, ", "))
+		}
+		return nil
+	default:
+		return json.Unmarshal(message, v)
+	}
+}
+
+// unknownFieldNames lists the top-level fields of message that the struct v
+// points to has no json field for.
+func unknownFieldNames(message json.RawMessage, v any) []string {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(message, &fields); err != nil {
+		return nil
+	}
+
+	known := map[string]bool{}
+	structType := reflect.TypeOf(v).Elem()
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" {
+			name = field.Name
+		}
+		known[strings.ToLower(name)] = true
+	}
+
+	var unknown []string
+	for name := range fields {
+		// encoding/json matches field names case-insensitively
+		if !known[strings.ToLower(name)] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
@@ -0,0 +1,115 @@
+package job
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestUnknownFields(t *testing.T) {
+	const input = `{"job_type": "user_onboarding", "message": {"user_id": "user-001", "user_name": "John Doe", "plan": "gold", "Referrer": "ads"}}`
+
+	tests := []struct {
+		mode        string
+		expectError bool
+		expectLog   string
+	}{
+		{mode: UnknownFieldsIgnore},
+		{mode: UnknownFieldsWarn, expectLog: "Referrer, plan"},
+		{mode: UnknownFieldsError, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			previous := UnknownFields
+			UnknownFields = tt.mode
+			defer func() { UnknownFields = previous }()
+
+			var logs bytes.Buffer
+			previousLogger := slog.Default()
+			slog.SetDefault(NewLogger(&logs, slog.LevelWarn))
+			defer slog.SetDefault(previousLogger)
+
+			job, _, _, err := ParseJob([]byte(input))
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if onboarding := job.(UserOnboardingJob); onboarding.UserID != "user-001" {
+				t.Errorf("expected known fields to be parsed, got %+v", onboarding)
+			}
+
+			if tt.expectLog == "" {
+				if strings.Contains(logs.String(), "unknown fields") {
+					t.Errorf("expected no unknown fields log, got %s", logs.String())
+				}
+			} else if !strings.Contains(logs.String(), tt.expectLog) || !strings.Contains(logs.String(), `"level":"WARN"`) {
+				t.Errorf("expected unknown fields %q to be logged as a warning, got %s", tt.expectLog, logs.String())
+			}
+		})
+	}
+}
+
+func TestUnknownFieldsAllowsKnownFields(t *testing.T) {
+	previous := UnknownFields
+	UnknownFields = UnknownFieldsError
+	defer func() { UnknownFields = previous }()
+
+	// Field names match case-insensitively, as encoding/json does
+	input := `{"job_type": "user_onboarding", "message": {"User_ID": "user-001", "user_name": "John Doe"}}`
+	if _, _, _, err := ParseJob([]byte(input)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestParseUnknownFields(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{value: "", expected: UnknownFieldsIgnore},
+		{value: "warn", expected: UnknownFieldsWarn},
+		{value: "ERROR", expected: UnknownFieldsError},
+		{value: "reject", expected: UnknownFieldsIgnore},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if actual := ParseUnknownFields(tt.value, UnknownFieldsIgnore); actual != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestParseJobStrict(t *testing.T) {
+	// UnknownFields is left lenient, ParseJobStrict must override it
+	previous := UnknownFields
+	UnknownFields = UnknownFieldsIgnore
+	defer func() { UnknownFields = previous }()
+
+	typo := `{"job_type": "report_generation", "message": {"report_nam": "Sales Report", "filters": "region=us-east"}}`
+	_, _, jobType, err := ParseJobStrict([]byte(typo))
+	if err == nil || !strings.Contains(err.Error(), `unknown field "report_nam"`) {
+		t.Errorf("expected an error naming report_nam, got %v", err)
+	}
+	if jobType == nil || *jobType != "report_generation" {
+		t.Errorf("expected the job type to be reported, got %v", jobType)
+	}
+
+	// The lenient parser still accepts it, failing only on validation
+	if _, _, _, err := ParseJob([]byte(typo)); err == nil || !strings.Contains(err.Error(), "report_name is required") {
+		t.Errorf("expected ParseJob to fail validation, got %v", err)
+	}
+
+	valid := `{"job_type": "report_generation", "message": {"report_name": "Sales Report", "filters": "region=us-east"}}`
+	if _, _, _, err := ParseJobStrict([]byte(valid)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+package job
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// UserIDFormat is the user_id format our systems issue, e.g. user_12345.
+const UserIDFormat = `^user_[0-9]+$`
+
+// UserIDPattern, when set, is the format user onboarding user_ids must
+// match. Nil accepts any non-empty user_id.
+var UserIDPattern *regexp.Regexp
+
+// ParseUserIDPattern compiles a user_id format regex. An empty or invalid
+// pattern is logged and leaves user_ids unchecked.
+func ParseUserIDPattern(pattern string) *regexp.Regexp {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return nil
+	}
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		log.Printf("ignoring invalid user_id pattern %q: %v", pattern, err)
+		return nil
+	}
+	return compiled
+}
+
+// validateUserID checks a non-empty user_id against UserIDPattern.
+func validateUserID(userID string) error {
+	if UserIDPattern != nil && !UserIDPattern.MatchString(userID) {
+		return fmt.Errorf("user_id %q does not match format %s", userID, UserIDPattern)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package job
+
+import (
+	"testing"
+)
+
+func TestParseUserIDPattern(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		expectNil bool
+	}{
+		{name: "Empty", input: "", expectNil: true},
+		{name: "Invalid", input: "user_[0-9", expectNil: true},
+		{name: "Valid", input: UserIDFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := ParseUserIDPattern(tt.input); (actual == nil) != tt.expectNil {
+				t.Errorf("expected nil %v, got %v", tt.expectNil, actual)
+			}
+		})
+	}
+}
+
+func TestUserOnboardingUserIDFormat(t *testing.T) {
+	previous := UserIDPattern
+	defer func() { UserIDPattern = previous }()
+
+	tests := []struct {
+		name        string
+		pattern     string
+		userID      string
+		expectError bool
+	}{
+		{name: "Conforming", pattern: UserIDFormat, userID: "user_12345"},
+		{name: "Missing prefix", pattern: UserIDFormat, userID: "12345", expectError: true},
+		{name: "Non-numeric suffix", pattern: UserIDFormat, userID: "user_abc", expectError: true},
+		{name: "Wrong separator", pattern: UserIDFormat, userID: "user-001", expectError: true},
+		{name: "Permissive by default", pattern: "", userID: "user-001"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			UserIDPattern = ParseUserIDPattern(tt.pattern)
+			err := UserOnboardingJob{UserID: tt.userID, UserName: "John Doe"}.Validate()
+			if tt.expectError != (err != nil) {
+				t.Errorf("expected error %v, got %v", tt.expectError, err)
+			}
+			if err := (UserOnboardingJob{UserID: tt.userID}).ValidateLenient(); tt.expectError != (err != nil) {
+				t.Errorf("expected lenient error %v, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
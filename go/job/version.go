@@ -0,0 +1,21 @@
+package job
+
+import "fmt"
+
+// CurrentSchemaVersion is the EnrichedPayload shape this module writes and
+// understands. Bump it when a payload change would be misread by an older
+// processor.
+const CurrentSchemaVersion = 1
+
+// CheckSchemaVersion rejects payloads written in a shape this module doesn't
+// understand. A missing version predates the field and is treated as 1.
+func CheckSchemaVersion(payload EnrichedPayload) error {
+	version := payload.SchemaVersion
+	if version == 0 {
+		version = 1
+	}
+	if version != CurrentSchemaVersion {
+		return fmt.Errorf("unsupported payload schema_version %d, expected %d", payload.SchemaVersion, CurrentSchemaVersion)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package job
+
+import "testing"
+
+func TestCheckSchemaVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		version   int
+		expectErr bool
+	}{
+		{name: "Current", version: CurrentSchemaVersion},
+		{name: "Missing treated as v1", version: 0},
+		{name: "Newer", version: CurrentSchemaVersion + 1, expectErr: true},
+		{name: "Negative", version: -1, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckSchemaVersion(EnrichedPayload{SchemaVersion: tt.version})
+			if (err != nil) != tt.expectErr {
+				t.Errorf("expected error %v, got %v", tt.expectErr, err)
+			}
+		})
+	}
+}
@@ -67,6 +67,9 @@ resource "aws_lambda_event_source_mapping" "ingester_event_source" {
   function_name    = aws_lambda_alias.ingester_alias.arn # Use the alias ARN for the Lambda function
   batch_size       = 1
   enabled          = true
+
+  # The ingester only reports failed messages with REPORT_BATCH_ITEM_FAILURES set
+  function_response_types = ["ReportBatchItemFailures"]
 }
 
 resource "aws_lambda_function" "ingester" {
@@ -140,7 +143,10 @@ resource "aws_iam_role_policy" "lambda_policy" {
           "sqs:SendMessage",
         ]
         Effect   = "Allow"
-        Resource = aws_sqs_queue.jobs_todo.arn
+        Resource = [
+          aws_sqs_queue.jobs_todo.arn,
+          aws_sqs_queue.jobs_parse_errors.arn
+        ]
       }
     ]
   })
@@ -0,0 +1,9 @@
+resource "aws_sqs_queue" "jobs_parse_errors" {
+  name                      = "jobs-parse-errors"
+  visibility_timeout_seconds = 30
+  message_retention_seconds  = 1209600 # 14 days for the schema owners to triage them
+}
+
+output "jobs_parse_errors_url" {
+  value = aws_sqs_queue.jobs_parse_errors.id
+}
@@ -40,6 +40,24 @@ resource "aws_iam_role" "processor_lambda_execution_role" {
           Effect = "Allow",
           Resource = aws_sqs_queue.jobs_todo.arn
         },
+        {
+          Action = [
+            "sqs:SendMessage"
+          ],
+          Effect = "Allow",
+          Resource = [
+            aws_sqs_queue.jobs_quarantine.arn,
+            aws_sqs_queue.jobs_parse_errors.arn,
+            aws_sqs_queue.jobs_todo.arn # throttled jobs are requeued with a delay
+          ]
+        },
+        {
+          Action = [
+            "events:PutEvents"
+          ],
+          Effect = "Allow",
+          Resource = "arn:aws:events:*:*:event-bus/default"
+        },
         {
           Action = [
             "logs:CreateLogGroup",
@@ -81,6 +99,9 @@ resource "aws_lambda_event_source_mapping" "processor_event_source" {
   function_name    = aws_lambda_function.processor.arn
   batch_size       = 1
   enabled          = true
+
+  # Honoured when REPORT_BATCH_ITEM_FAILURES is set, otherwise the response is always empty
+  function_response_types = ["ReportBatchItemFailures"]
 }
 
 
@@ -0,0 +1,9 @@
+resource "aws_sqs_queue" "jobs_quarantine" {
+  name                      = "jobs-quarantine"
+  visibility_timeout_seconds = 30
+  message_retention_seconds  = 1209600 # 14 days for an operator to release them
+}
+
+output "jobs_quarantine_url" {
+  value = aws_sqs_queue.jobs_quarantine.id
+}